@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/itout-datetoya/hack-info-timeline/usecases"
+)
+
+// BACKFILL_* の環境変数からバックフィルの設定を読み込む
+// BACKFILL_ENABLED が設定されていない場合は false を返す
+func backfillOptionsFromEnv() (usecases.BackfillOptions, bool, error) {
+	var opts usecases.BackfillOptions
+	if enabled, _ := strconv.ParseBool(os.Getenv("BACKFILL_ENABLED")); !enabled {
+		return opts, false, nil
+	}
+
+	if until := os.Getenv("BACKFILL_UNTIL"); until != "" {
+		t, err := time.Parse(time.DateOnly, until)
+		if err != nil {
+			if t, err = time.Parse(time.RFC3339, until); err != nil {
+				return opts, false, fmt.Errorf("invalid BACKFILL_UNTIL: %s", until)
+			}
+		}
+		opts.Until = t
+	}
+
+	// 例: hackchannel:1200,transferchannel:35000
+	if untilIDs := os.Getenv("BACKFILL_UNTIL_MESSAGE_IDS"); untilIDs != "" {
+		opts.UntilMessageIDs = make(map[string]int)
+		for _, pair := range strings.Split(untilIDs, ",") {
+			channel, idStr, ok := strings.Cut(strings.TrimSpace(pair), ":")
+			id, err := strconv.Atoi(idStr)
+			if !ok || channel == "" || err != nil || id < 0 {
+				return opts, false, fmt.Errorf("invalid BACKFILL_UNTIL_MESSAGE_IDS: %s", pair)
+			}
+			opts.UntilMessageIDs[channel] = id
+		}
+	}
+
+	if batchSizeStr := os.Getenv("BACKFILL_BATCH_SIZE"); batchSizeStr != "" {
+		batchSize, err := strconv.Atoi(batchSizeStr)
+		if err != nil || batchSize <= 0 || batchSize > 100 {
+			return opts, false, fmt.Errorf("invalid BACKFILL_BATCH_SIZE: %s", batchSizeStr)
+		}
+		opts.BatchSize = batchSize
+	}
+
+	if intervalStr := os.Getenv("BACKFILL_INTERVAL"); intervalStr != "" {
+		interval, err := time.ParseDuration(intervalStr)
+		if err != nil || interval <= 0 {
+			return opts, false, fmt.Errorf("invalid BACKFILL_INTERVAL: %s", intervalStr)
+		}
+		opts.Interval = interval
+	}
+
+	return opts, true, nil
+}
+
+// ハッキング情報と送金情報のチャンネルの過去の投稿を順に遡って取得
+// 中断した場合は、次回の起動時に保存済みの進捗から再開
+func runBackfill(ctx context.Context, hackingUsecase *usecases.HackingUsecase, transferUsecase *usecases.TransferUsecase, opts usecases.BackfillOptions) {
+	log.Println("Backfill started...")
+
+	if _, _, errs := hackingUsecase.Backfill(ctx, opts); len(errs) > 0 {
+		log.Printf("Hacking info backfill finished with errors: %v", errs)
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	if _, _, errs := transferUsecase.Backfill(ctx, opts); len(errs) > 0 {
+		log.Printf("Transfer info backfill finished with errors: %v", errs)
+	}
+}
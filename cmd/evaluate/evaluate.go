@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+// 抽出結果がない場合の値
+const notAvailable = "N/A"
+
+// 評価対象のフィールド
+const (
+	fieldProtocol     = "protocol"
+	fieldTokens       = "tokens"
+	fieldAttackVector = "attack_vector"
+)
+
+// 正解データの1件
+// attack_vector が空の場合、攻撃手法は評価しない
+type goldenCase struct {
+	ID           string   `json:"id"`
+	Text         string   `json:"text"`
+	Protocol     string   `json:"protocol"`
+	Tokens       []string `json:"tokens"`
+	AttackVector string   `json:"attack_vector,omitempty"`
+}
+
+// フィールド毎の適合率・再現率
+type fieldMetrics struct {
+	TruePositives  int     `json:"true_positives"`
+	FalsePositives int     `json:"false_positives"`
+	FalseNegatives int     `json:"false_negatives"`
+	Precision      float64 `json:"precision"`
+	Recall         float64 `json:"recall"`
+	F1             float64 `json:"f1"`
+}
+
+// 正解と抽出結果が一致しなかった項目
+type mismatch struct {
+	CaseID   string `json:"case_id"`
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// 評価結果
+type report struct {
+	PromptVersion string                   `json:"prompt_version"`
+	Cases         int                      `json:"cases"`
+	Fields        map[string]*fieldMetrics `json:"fields"`
+	Mismatches    []mismatch               `json:"mismatches"`
+	Errors        []string                 `json:"errors"`
+}
+
+// JSONL形式の正解データを読み込み
+func loadGoldenSet(path string) ([]goldenCase, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open golden set: %w", err)
+	}
+	defer file.Close()
+
+	var cases []goldenCase
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var c goldenCase
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			return nil, fmt.Errorf("failed to parse golden set at line %d: %w", lineNumber, err)
+		}
+		if c.ID == "" || c.Text == "" {
+			return nil, fmt.Errorf("golden set at line %d: id and text are required", lineNumber)
+		}
+		cases = append(cases, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read golden set: %w", err)
+	}
+	return cases, nil
+}
+
+// 正解データの投稿を分析し、フィールド毎に抽出結果を評価
+// 分析に失敗した投稿は、正解の値を全て見逃したものとして扱う
+func evaluate(ctx context.Context, llmGateway gateway.LLMGateway, cases []goldenCase) *report {
+	r := &report{
+		PromptVersion: llmGateway.PromptVersion(),
+		Cases:         len(cases),
+		Fields: map[string]*fieldMetrics{
+			fieldProtocol:     {},
+			fieldTokens:       {},
+			fieldAttackVector: {},
+		},
+	}
+
+	for _, c := range cases {
+		extractedInfo, err := llmGateway.AnalyzeAndExtract(ctx, &gateway.HackingPost{Text: c.Text})
+		if err != nil {
+			r.Errors = append(r.Errors, fmt.Sprintf("%s: %v", c.ID, err))
+			extractedInfo = &gateway.ExtractedHackingInfo{Protocol: notAvailable}
+		}
+
+		r.compareLabel(c.ID, fieldProtocol, c.Protocol, extractedInfo.Protocol)
+		r.compareTokens(c.ID, c.Tokens, extractedInfo.Tokens)
+		if c.AttackVector != "" {
+			r.compareLabel(c.ID, fieldAttackVector, c.AttackVector, extractedInfo.AttackVector)
+		}
+	}
+
+	for _, metrics := range r.Fields {
+		metrics.calculate()
+	}
+	return r
+}
+
+// 単一の値を比較
+// N/A・空は「抽出なし」として扱い、大文字小文字は区別しない
+func (r *report) compareLabel(caseID, field, expected, actual string) {
+	metrics := r.Fields[field]
+	expectedKey := normalizeLabel(expected)
+	actualKey := normalizeLabel(actual)
+
+	switch {
+	case expectedKey == actualKey:
+		if expectedKey != "" {
+			metrics.TruePositives++
+		}
+		return
+	case expectedKey == "":
+		metrics.FalsePositives++
+	case actualKey == "":
+		metrics.FalseNegatives++
+	default:
+		metrics.FalsePositives++
+		metrics.FalseNegatives++
+	}
+	r.Mismatches = append(r.Mismatches, mismatch{CaseID: caseID, Field: field, Expected: expected, Actual: actual})
+}
+
+// トークンの集合を比較
+func (r *report) compareTokens(caseID string, expected, actual []string) {
+	metrics := r.Fields[fieldTokens]
+	expectedSet := tokenSet(expected)
+	actualSet := tokenSet(actual)
+
+	var missing, extra []string
+	for key, token := range expectedSet {
+		if _, ok := actualSet[key]; ok {
+			metrics.TruePositives++
+		} else {
+			missing = append(missing, token)
+		}
+	}
+	for key, token := range actualSet {
+		if _, ok := expectedSet[key]; !ok {
+			extra = append(extra, token)
+		}
+	}
+	metrics.FalseNegatives += len(missing)
+	metrics.FalsePositives += len(extra)
+
+	if len(missing) > 0 || len(extra) > 0 {
+		r.Mismatches = append(r.Mismatches, mismatch{
+			CaseID:   caseID,
+			Field:    fieldTokens,
+			Expected: joinSorted(expected),
+			Actual:   joinSorted(actual),
+		})
+	}
+}
+
+// 適合率・再現率・F1値を計算
+// 分母が 0 の場合は 1 とする（誤りがない）
+func (m *fieldMetrics) calculate() {
+	m.Precision = ratio(m.TruePositives, m.TruePositives+m.FalsePositives)
+	m.Recall = ratio(m.TruePositives, m.TruePositives+m.FalseNegatives)
+	if m.Precision+m.Recall > 0 {
+		m.F1 = 2 * m.Precision * m.Recall / (m.Precision + m.Recall)
+	}
+}
+
+func ratio(numerator, denominator int) float64 {
+	if denominator == 0 {
+		return 1
+	}
+	return float64(numerator) / float64(denominator)
+}
+
+func normalizeLabel(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == strings.ToLower(notAvailable) {
+		return ""
+	}
+	return value
+}
+
+func tokenSet(tokens []string) map[string]string {
+	set := make(map[string]string, len(tokens))
+	for _, token := range tokens {
+		if key := normalizeLabel(token); key != "" {
+			set[key] = strings.TrimSpace(token)
+		}
+	}
+	return set
+}
+
+func joinSorted(tokens []string) string {
+	sorted := append([]string{}, tokens...)
+	sort.Strings(sorted)
+	return "[" + strings.Join(sorted, ", ") + "]"
+}
+
+// フィールド毎に F1 値が閾値を下回っているか
+func (r *report) below(minF1 float64) []string {
+	var fields []string
+	for _, field := range r.fieldNames() {
+		if r.Fields[field].F1 < minF1 {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func (r *report) fieldNames() []string {
+	return []string{fieldProtocol, fieldTokens, fieldAttackVector}
+}
+
+// 評価結果を表形式で出力
+func (r *report) writeText(w io.Writer) {
+	fmt.Fprintf(w, "prompt version: %s\n", r.PromptVersion)
+	fmt.Fprintf(w, "cases: %d, errors: %d\n\n", r.Cases, len(r.Errors))
+
+	fmt.Fprintf(w, "%-14s %9s %7s %6s %4s %4s %4s\n", "field", "precision", "recall", "f1", "tp", "fp", "fn")
+	for _, field := range r.fieldNames() {
+		m := r.Fields[field]
+		fmt.Fprintf(w, "%-14s %9.3f %7.3f %6.3f %4d %4d %4d\n",
+			field, m.Precision, m.Recall, m.F1, m.TruePositives, m.FalsePositives, m.FalseNegatives)
+	}
+
+	if len(r.Mismatches) > 0 {
+		fmt.Fprintf(w, "\nmismatches:\n")
+		for _, m := range r.Mismatches {
+			fmt.Fprintf(w, "  %s %s: expected %q, got %q\n", m.CaseID, m.Field, m.Expected, m.Actual)
+		}
+	}
+	if len(r.Errors) > 0 {
+		fmt.Fprintf(w, "\nerrors:\n")
+		for _, err := range r.Errors {
+			fmt.Fprintf(w, "  %s\n", err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// LLMによるハッキング情報の抽出精度を正解データで評価するコマンド
+//
+// 使用例:
+//
+//	# 記録済みの応答で評価（オフライン）
+//	go run ./cmd/evaluate -replay cmd/evaluate/testdata/recordings.jsonl
+//	# 設定済みのLLMで評価し、応答を記録
+//	go run ./cmd/evaluate -record cmd/evaluate/testdata/recordings.jsonl
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/itout-datetoya/hack-info-timeline/infrastructure/gateway"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	goldenPath := flag.String("golden", "cmd/evaluate/testdata/golden.jsonl", "JSONL file of posts with the expected protocol and tokens")
+	replayPath := flag.String("replay", "", "evaluate with the recorded LLM responses in this file instead of calling the LLM")
+	recordPath := flag.String("record", "", "append the LLM responses to this file")
+	promptVersion := flag.String("prompt-version", "", "prompt version to evaluate (default: LLM_PROMPT_VERSION or the default version)")
+	jsonOutput := flag.Bool("json", false, "print the report as JSON")
+	minF1 := flag.Float64("min-f1", 0, "exit with an error if the F1 score of any field is below this value")
+	flag.Parse()
+
+	if *replayPath != "" && *recordPath != "" {
+		log.Fatal("-replay and -record cannot be used together.")
+	}
+
+	// 設定の読み込み
+	var llmConfig gateway.LLMConfig
+	if *replayPath != "" {
+		llmConfig = gateway.LLMConfig{
+			Provider:      gateway.LLMProviderReplay,
+			RecordingFile: *replayPath,
+		}
+	} else {
+		if err := godotenv.Load(); err != nil {
+			log.Println("No .env file found")
+		}
+		var err error
+		llmConfig, err = gateway.LLMConfigFromEnv()
+		if err != nil {
+			log.Fatalf("Invalid LLM configuration: %v", err)
+		}
+		llmConfig.RecordingFile = *recordPath
+	}
+	if *promptVersion != "" {
+		llmConfig.PromptVersion = *promptVersion
+	}
+
+	cases, err := loadGoldenSet(*goldenPath)
+	if err != nil {
+		log.Fatalf("Failed to load golden set: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	llmGateway, err := gateway.NewLLMGateway(ctx, llmConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize LLM Gateway: %v", err)
+	}
+	defer llmGateway.Stop()
+
+	r := evaluate(ctx, llmGateway, cases)
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(r); err != nil {
+			log.Fatalf("Failed to encode report: %v", err)
+		}
+	} else {
+		r.writeText(os.Stdout)
+	}
+
+	if fields := r.below(*minF1); len(fields) > 0 {
+		log.Printf("F1 score is below %.3f: %v", *minF1, fields)
+		llmGateway.Stop()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,37 @@
+package entity
+
+// ハッキングの攻撃手法の分類
+const (
+	AttackVectorOracleManipulation   = "oracle_manipulation"
+	AttackVectorReentrancy           = "reentrancy"
+	AttackVectorAccessControl        = "access_control"
+	AttackVectorFlashLoan            = "flash_loan"
+	AttackVectorPriceManipulation    = "price_manipulation"
+	AttackVectorPrivateKeyCompromise = "private_key_compromise"
+	AttackVectorRugPull              = "rug_pull"
+	AttackVectorFirstDeposit         = "first_deposit_inflation"
+	AttackVectorOther                = "other"
+)
+
+// 攻撃手法の分類の一覧
+var AttackVectors = []string{
+	AttackVectorOracleManipulation,
+	AttackVectorReentrancy,
+	AttackVectorAccessControl,
+	AttackVectorFlashLoan,
+	AttackVectorPriceManipulation,
+	AttackVectorPrivateKeyCompromise,
+	AttackVectorRugPull,
+	AttackVectorFirstDeposit,
+	AttackVectorOther,
+}
+
+// 攻撃手法の分類に含まれるか判定
+func IsAttackVector(attackVector string) bool {
+	for _, v := range AttackVectors {
+		if v == attackVector {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package entity
+
+import "time"
+
+// バックフィルの状態
+const (
+	// 遡って取得中
+	BackfillStatusRunning = "running"
+	// 指定した日時・メッセージIDまで、またはチャンネルの最初の投稿まで取得済み
+	BackfillStatusCompleted = "completed"
+	// 過去の投稿を取得できないため停止（Bot API で取得している場合など）
+	BackfillStatusFailed = "failed"
+)
+
+// チャンネルの過去の投稿を遡って取得する処理（バックフィル）の進捗
+type ChannelBackfill struct {
+	ChannelUsername string `db:"channel_username"`
+	Status          string `db:"status"`
+	// 次はこのIDより古い投稿を取得する。0 の場合は最新の投稿から
+	CursorMessageID int `db:"cursor_message_id"`
+	// 取得した最も古い投稿の日時
+	OldestPostAt *time.Time `db:"oldest_post_at"`
+	// 遡る下限の日時・メッセージID（未指定の場合は最初の投稿まで）
+	UntilDate      *time.Time `db:"until_date"`
+	UntilMessageID int        `db:"until_message_id"`
+	// 保存件数、重複によるスキップ件数、失敗件数
+	Processed   int        `db:"processed"`
+	Skipped     int        `db:"skipped"`
+	Failed      int        `db:"failed"`
+	LastError   string     `db:"last_error"`
+	StartedAt   time.Time  `db:"started_at"`
+	UpdatedAt   time.Time  `db:"updated_at"`
+	CompletedAt *time.Time `db:"completed_at"`
+}
@@ -4,12 +4,46 @@ import "time"
 
 // ハッキング情報
 type HackingInfo struct {
-	ID         int64     `db:"id"`
-	Protocol   string    `db:"protocol"`
-	Network    string    `db:"network"`
-	Amount     string    `db:"amount"`
-	TxHash     string    `db:"tx_hash"`
-	ReportTime time.Time `db:"report_time"`
-	MessageID  int       `db:"message_id"`
-	Tags       []*Tag
+	ID              int64     `db:"id"`
+	Protocol        string    `db:"protocol"`
+	Network         string    `db:"network"`
+	Amount          string    `db:"amount"`
+	TxHash          string    `db:"tx_hash"`
+	ReportTime      time.Time `db:"report_time"`
+	MessageID       int       `db:"message_id"`
+	ChannelUsername string    `db:"channel_username"`
+	AttackVector    string    `db:"attack_vector"`
+	// 数値に正規化した金額と単位（USD、またはトークンのシンボル）。正規化できない場合は nil
+	// 精度を保つため、金額は10進数の文字列で保持
+	AmountValue *string `db:"amount_value"`
+	AmountUnit  string  `db:"amount_unit"`
+	// 報告時点のUSD建ての金額。価格が不明な場合は nil
+	AmountUSD *string `db:"amount_usd"`
+	// 分析に使用したプロンプトのバージョン・LLMのプロバイダー・モデル
+	PromptVersion string `db:"prompt_version"`
+	LLMProvider   string `db:"llm_provider"`
+	LLMModel      string `db:"llm_model"`
+	// 元の投稿本文とリプライ先の投稿本文
+	// APIのレスポンスには含めず、要求された場合のみ個別の情報の取得で返す
+	PostText    string `db:"post_text" json:"-"`
+	ReplyToText string `db:"reply_to_text" json:"-"`
+	// チャンネルで投稿が削除された場合 true
+	Deleted bool `db:"deleted"`
+	Tags    []*Tag
+	// 投稿の編集による変更履歴（古い順）
+	Edits []*HackingInfoEdit `json:",omitempty"`
+}
+
+// 投稿の編集により変更される前のハッキング情報
+type HackingInfoEdit struct {
+	ID           int64     `db:"id"`
+	InfoID       int64     `db:"info_id"`
+	Protocol     string    `db:"protocol"`
+	Network      string    `db:"network"`
+	Amount       string    `db:"amount"`
+	TxHash       string    `db:"tx_hash"`
+	AttackVector string    `db:"attack_vector"`
+	PostText     string    `db:"post_text" json:"-"`
+	ReplyToText  string    `db:"reply_to_text" json:"-"`
+	EditedAt     time.Time `db:"edited_at"`
 }
@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+// LLMによる分析結果のキャッシュ
+type LLMAnalysisCache struct {
+	// プロンプトのバージョンと投稿本文のハッシュ
+	Key           string    `db:"cache_key"`
+	PromptVersion string    `db:"prompt_version"`
+	Result        string    `db:"result"`
+	CreatedAt     time.Time `db:"created_at"`
+}
@@ -0,0 +1,33 @@
+package entity
+
+import "time"
+
+// 隔離した投稿の状態
+const (
+	// パースに失敗し、LLMでも抽出していない・できない
+	QuarantineStatusQuarantined = "quarantined"
+	// LLMで抽出したが確信度が閾値未満のため、公開せずに確認を待つ
+	QuarantineStatusNeedsReview = "needs_review"
+	// LLMで抽出して公開済み（確認後に公開した投稿を含む）
+	QuarantineStatusPublished = "published"
+)
+
+// パースに失敗し、隔離した投稿
+// 投稿の形式の変更を調査するため、投稿本文とパーサーのエラーを保存する
+type QuarantinedPost struct {
+	ID              int64  `db:"id"`
+	ChannelUsername string `db:"channel_username"`
+	MessageID       int    `db:"message_id"`
+	Text            string `db:"text"`
+	// リプライ先の投稿本文（ハッキング情報の場合のみ）
+	ReplyToText string    `db:"reply_to_text"`
+	Error       string    `db:"error"`
+	ReportTime  time.Time `db:"report_time"`
+	Status      string    `db:"status"`
+	// LLMで抽出した投稿（JSON、抽出していない場合は空）
+	Extracted string `db:"extracted"`
+	// LLMによる抽出結果の確信度
+	Confidence float64   `db:"confidence"`
+	CreatedAt  time.Time `db:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at"`
+}
@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+// リトライ状態
+const (
+	// 再試行待ち
+	RetryStatusPending = "pending"
+	// 最大試行回数を超えて再試行を停止
+	RetryStatusDead = "dead"
+)
+
+// 処理に失敗した投稿のリトライ情報
+type RetryPost struct {
+	ID              int64     `db:"id"`
+	ChannelUsername string    `db:"channel_username"`
+	MessageID       int       `db:"message_id"`
+	Payload         string    `db:"payload"`
+	Attempts        int       `db:"attempts"`
+	LastError       string    `db:"last_error"`
+	NextAttemptAt   time.Time `db:"next_attempt_at"`
+	Status          string    `db:"status"`
+	CreatedAt       time.Time `db:"created_at"`
+	UpdatedAt       time.Time `db:"updated_at"`
+}
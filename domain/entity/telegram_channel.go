@@ -1,7 +1,11 @@
 package entity
 
-// 各種情報に付けられる検索タグ
+// Telegramチャンネルの取得状況
 type TelegramChannel struct {
 	ChannelUsername string `db:"username"`
 	LastMessageID   int    `db:"last_message_id"`
+	// 解決済みのチャンネルID・アクセスハッシュ。未解決の場合は 0
+	ChannelID  int64  `db:"channel_id"`
+	AccessHash int64  `db:"access_hash"`
+	Title      string `db:"title"`
 }
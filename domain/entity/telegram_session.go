@@ -0,0 +1,10 @@
+package entity
+
+import "time"
+
+// 暗号化したTelegramのセッション
+type TelegramSession struct {
+	Name      string    `db:"name"`
+	Data      []byte    `db:"data"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+// トークンの日毎のUSD建ての価格
+type TokenPrice struct {
+	// トークンのシンボル（大文字）
+	Symbol    string    `db:"symbol"`
+	PriceDate time.Time `db:"price_date"`
+	// 精度を保つため、価格は10進数の文字列で保持
+	PriceUSD string `db:"price_usd"`
+}
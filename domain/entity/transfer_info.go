@@ -4,12 +4,34 @@ import "time"
 
 // 送金情報
 type TransferInfo struct {
-	ID         int64     `db:"id"`
-	Token      string    `db:"token"`
-	Amount     string    `db:"amount"`
-	From       string    `db:"from_address"`
-	To         string    `db:"to_address"`
-	ReportTime time.Time `db:"report_time"`
-	MessageID  int       `db:"message_id"`
-	Tags       []*Tag
+	ID              int64     `db:"id"`
+	Token           string    `db:"token"`
+	Amount          string    `db:"amount"`
+	From            string    `db:"from_address"`
+	To              string    `db:"to_address"`
+	ReportTime      time.Time `db:"report_time"`
+	MessageID       int       `db:"message_id"`
+	ChannelUsername string    `db:"channel_username"`
+	// 数値に正規化した金額と単位（トークンのシンボル）。正規化できない場合は nil
+	// 精度を保つため、金額は10進数の文字列で保持
+	AmountValue *string `db:"amount_value"`
+	AmountUnit  string  `db:"amount_unit"`
+	// 報告時点のUSD建ての金額。価格が不明な場合は nil
+	AmountUSD *string `db:"amount_usd"`
+	// チャンネルで投稿が削除された場合 true
+	Deleted bool `db:"deleted"`
+	Tags    []*Tag
+	// 投稿の編集による変更履歴（古い順）
+	Edits []*TransferInfoEdit `json:",omitempty"`
+}
+
+// 投稿の編集により変更される前の送金情報
+type TransferInfoEdit struct {
+	ID       int64     `db:"id"`
+	InfoID   int64     `db:"info_id"`
+	Token    string    `db:"token"`
+	Amount   string    `db:"amount"`
+	From     string    `db:"from_address"`
+	To       string    `db:"to_address"`
+	EditedAt time.Time `db:"edited_at"`
 }
@@ -7,12 +7,15 @@ import (
 
 // ハッキング情報の投稿
 type HackingPost struct {
-	Text       string
-	Network    string
-	Amount     string
-	TxHash     string
-	ReportTime time.Time
-	MessageID  int
+	Text string
+	// リプライ先の投稿本文
+	ReplyToText     string
+	Network         string
+	Amount          string
+	TxHash          string
+	ReportTime      time.Time
+	MessageID       int
+	ChannelUsername string
 }
 
 // 抽出されたハッキング情報
@@ -22,6 +25,16 @@ type ExtractedHackingInfo struct {
 	Amount   string
 	TxHash   string
 	TagNames []string
+	// 投稿から抽出したトークン名（TagNames からプロトコル名を除いたもの）
+	Tokens []string
+	// 攻撃手法の分類（entity.AttackVectors のいずれか）
+	AttackVector string
+	// プロトコル名の抽出結果の確信度（0〜1）
+	Confidence float64
+	// 分析に使用したプロンプトのバージョン・プロバイダー・モデル
+	PromptVersion string
+	Provider      string
+	Model         string
 }
 
 // Telegram APIとのハッキング情報の通信を抽象化
@@ -29,6 +42,22 @@ type TelegramHackingPostGateway interface {
 	SetLastMessageID(lastMessageID int)
 	LastMessageID() int
 	ChannelUsername() string
+	// 解決済みのチャンネル情報を設定
+	SetChannelPeer(peer *ChannelPeer)
+	// 解決済みのチャンネル情報を取得（未解決の場合は nil）
+	ChannelPeer() *ChannelPeer
 	GetPosts(ctx context.Context, limit int) ([]*HackingPost, error)
 	GetPostsOver100(ctx context.Context, limit int) ([]*HackingPost, error)
+	// 新しい投稿の通知を購読し、最後に取得した投稿以降の投稿を handler に渡す
+	SubscribeNewPosts(ctx context.Context, handler func(ctx context.Context, posts []*HackingPost)) error
+	// 指定したメッセージIDの投稿を再取得
+	// 削除された投稿のメッセージIDは deletedIDs として返す
+	GetPostsByMessageIDs(ctx context.Context, messageIDs []int) (posts []*HackingPost, deletedIDs []int, err error)
+	// 投稿の編集・削除の通知を購読
+	SubscribeChanges(ctx context.Context, handler func(ctx context.Context)) error
+	// maxID より前の投稿を新しい順に limit 件遡って取得（maxID が 0 の場合は最新の投稿から）
+	// 最後に取得した投稿のIDは更新しない
+	GetPostsBefore(ctx context.Context, maxID int, limit int) ([]*HackingPost, *HistoryPage, error)
+	// パースに失敗した投稿の通知先を設定（未設定の場合はログに出力するのみ）
+	SetRejectedPostHandler(handler RejectedPostHandler)
 }
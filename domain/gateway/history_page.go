@@ -0,0 +1,18 @@
+package gateway
+
+import (
+	"errors"
+	"time"
+)
+
+// 過去の投稿を取得できない場合のエラー（Bot API など）
+var ErrHistoryUnavailable = errors.New("channel history is unavailable")
+
+// 過去の投稿を遡って取得した範囲
+// 次のページは OldestMessageID より前から取得する
+type HistoryPage struct {
+	// 取得した中で最も古い投稿のID（投稿がなかった場合は 0）
+	OldestMessageID int
+	// 取得した中で最も古い投稿の日時
+	OldestDate time.Time
+}
@@ -0,0 +1,37 @@
+package gateway
+
+import (
+	"context"
+)
+
+// LLMとの通信を抽象化
+// 利用するプロバイダー（Gemini、OpenAI互換APIなど）は実装側で切り替える
+type LLMGateway interface {
+	AnalyzeAndExtract(ctx context.Context, post *HackingPost) (*ExtractedHackingInfo, error)
+	// パースに失敗した投稿から、ハッキング情報の投稿の値と確信度（0〜1）を抽出
+	// 投稿がハッキング情報でない場合は ErrPostNotExtracted を返す
+	ExtractHackingPost(ctx context.Context, post *RejectedPost) (*HackingPost, float64, error)
+	// パースに失敗した投稿から、送金情報の投稿の値と確信度（0〜1）を抽出
+	// 投稿が送金情報でない場合は ErrPostNotExtracted を返す
+	ExtractTransferPost(ctx context.Context, post *RejectedPost) (*TransferPost, float64, error)
+	// 分析に使用するプロンプトのバージョン
+	PromptVersion() string
+	// 分析に使用するLLMのプロバイダーとモデル
+	Provider() string
+	Model() string
+	Stop() error
+}
+
+type analysisCacheBypassKey struct{}
+
+// 分析結果のキャッシュを参照せずにLLMで分析するコンテキストを生成
+// 分析結果はキャッシュに上書き保存される
+func WithAnalysisCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, analysisCacheBypassKey{}, true)
+}
+
+// 分析結果のキャッシュを参照しないか判定
+func IsAnalysisCacheBypassed(ctx context.Context) bool {
+	bypassed, _ := ctx.Value(analysisCacheBypassKey{}).(bool)
+	return bypassed
+}
@@ -0,0 +1,19 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+)
+
+// 指定した時点のトークンの価格が見つからない場合のエラー
+var ErrPriceNotFound = errors.New("price not found")
+
+// トークンのUSD建ての価格の取得を抽象化
+// 価格の取得元（CSVファイル、DBなど）は実装側で切り替える
+type PriceSource interface {
+	// 指定した時点のトークンのUSD建ての価格を取得
+	// 価格が見つからない場合は ErrPriceNotFound を返す
+	PriceUSD(ctx context.Context, symbol string, at time.Time) (*big.Rat, error)
+}
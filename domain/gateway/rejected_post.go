@@ -0,0 +1,25 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// パースに失敗した投稿から、LLMでも情報を抽出できない場合のエラー
+var ErrPostNotExtracted = errors.New("post could not be extracted")
+
+// パースに失敗した投稿
+type RejectedPost struct {
+	ChannelUsername string
+	MessageID       int
+	Text            string
+	// リプライ先の投稿本文（ハッキング情報の場合はリプライ先をパースする）
+	ReplyToText string
+	ReportTime  time.Time
+	// パーサーのエラー
+	Error string
+}
+
+// パースに失敗した投稿を受け取る handler
+type RejectedPostHandler func(ctx context.Context, post *RejectedPost)
@@ -0,0 +1,9 @@
+package gateway
+
+// 解決済みのTelegramチャンネル情報
+// チャンネル名の解決を省略するため、IDとアクセスハッシュを保持する
+type ChannelPeer struct {
+	ID         int64
+	AccessHash int64
+	Title      string
+}
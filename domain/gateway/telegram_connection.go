@@ -0,0 +1,29 @@
+package gateway
+
+import "time"
+
+// Telegramクライアントの接続状態
+type ConnectionState string
+
+const (
+	// 接続中（再接続を含む）
+	ConnectionConnecting ConnectionState = "connecting"
+	// 接続・認証済みでAPIを呼び出せる状態
+	ConnectionReady ConnectionState = "ready"
+	// セッションが未認証・失効しており、再ログインが必要な状態
+	ConnectionUnauthorized ConnectionState = "unauthorized"
+	// 接続に失敗し、再接続を待機している状態
+	ConnectionFailed ConnectionState = "failed"
+)
+
+// 接続状態と、その状態になった日時・原因のエラー
+type ConnectionStatus struct {
+	State     ConnectionState
+	Since     time.Time
+	LastError string
+}
+
+// Telegramクライアントの接続状態を提供
+type TelegramConnection interface {
+	Status() ConnectionStatus
+}
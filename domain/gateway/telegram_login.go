@@ -0,0 +1,27 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// クライアントが認証済み、または接続されていないためログインを受け付けられない
+	ErrTelegramLoginUnavailable = errors.New("telegram client is not waiting for login")
+	// 認証コードの送信前に、コード・パスワードが入力された
+	ErrTelegramLoginNotStarted = errors.New("telegram login is not started")
+	// 電話番号・認証コード・パスワードが誤っている、または期限切れ
+	ErrTelegramInvalidCredential = errors.New("invalid telegram credential")
+)
+
+// 未認証のTelegramクライアントへのログイン
+// サーバーを再起動せずに、認証コードと2段階認証のパスワードでサインインする
+type TelegramLogin interface {
+	// 電話番号宛てに認証コードを送信
+	StartLogin(ctx context.Context, phone string) error
+	// 認証コードでサインイン
+	// 2段階認証のパスワードが必要な場合は true を返す
+	SubmitCode(ctx context.Context, code string) (bool, error)
+	// 2段階認証のパスワードでサインイン
+	SubmitPassword(ctx context.Context, password string) error
+}
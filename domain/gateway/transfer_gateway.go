@@ -7,13 +7,15 @@ import (
 
 // 送金情報の投稿
 type TransferPost struct {
-	Token      string
-	Amount     string
-	From       string
-	To         string
-	ReportTime time.Time
-	MessageID  int
-	TagNames   []string
+	Text            string
+	Token           string
+	Amount          string
+	From            string
+	To              string
+	ReportTime      time.Time
+	MessageID       int
+	ChannelUsername string
+	TagNames        []string
 }
 
 // Telegram APIとの送金情報の通信を抽象化
@@ -21,5 +23,21 @@ type TelegramTransferPostGateway interface {
 	SetLastMessageID(lastMessageID int)
 	LastMessageID() int
 	ChannelUsername() string
+	// 解決済みのチャンネル情報を設定
+	SetChannelPeer(peer *ChannelPeer)
+	// 解決済みのチャンネル情報を取得（未解決の場合は nil）
+	ChannelPeer() *ChannelPeer
 	GetPosts(ctx context.Context, limit int) ([]*TransferPost, error)
+	// 新しい投稿の通知を購読し、最後に取得した投稿以降の投稿を handler に渡す
+	SubscribeNewPosts(ctx context.Context, handler func(ctx context.Context, posts []*TransferPost)) error
+	// 指定したメッセージIDの投稿を再取得
+	// 削除された投稿のメッセージIDは deletedIDs として返す
+	GetPostsByMessageIDs(ctx context.Context, messageIDs []int) (posts []*TransferPost, deletedIDs []int, err error)
+	// 投稿の編集・削除の通知を購読
+	SubscribeChanges(ctx context.Context, handler func(ctx context.Context)) error
+	// maxID より前の投稿を新しい順に limit 件遡って取得（maxID が 0 の場合は最新の投稿から）
+	// 最後に取得した投稿のIDは更新しない
+	GetPostsBefore(ctx context.Context, maxID int, limit int) ([]*TransferPost, *HistoryPage, error)
+	// パースに失敗した投稿の通知先を設定（未設定の場合はログに出力するのみ）
+	SetRejectedPostHandler(handler RejectedPostHandler)
 }
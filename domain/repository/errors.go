@@ -0,0 +1,6 @@
+package repository
+
+import "errors"
+
+// 同じチャンネル・メッセージIDの情報が既に保存されている場合のエラー
+var ErrDuplicateInfo = errors.New("info already stored")
@@ -3,15 +3,21 @@ package repository
 import (
 	"context"
 	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"time"
 )
 
 // ハッキング情報の永続化
 type HackingRepository interface {
-	// 指定したタグ名に一致するハッキング情報を最新から指定の件数取得
-	GetInfosByTagNames(ctx context.Context, tagNames []string, infoNumber int) ([]*entity.HackingInfo, error)
+	// 指定したタグ名・攻撃手法に一致するハッキング情報を最新から指定の件数取得
+	// タグ名・攻撃手法が空の場合は絞り込まない
+	GetInfosByTagNames(ctx context.Context, tagNames []string, attackVectors []string, infoNumber int) ([]*entity.HackingInfo, error)
 
-	// 指定したタグ名に一致するハッキング情報の内、指定した情報より過去から指定の件数取得
-	GetPrevInfosByTagNames(ctx context.Context, tagNames []string, prevInfoID int64, infoNumber int) ([]*entity.HackingInfo, error)
+	// 指定したタグ名・攻撃手法に一致するハッキング情報の内、指定した情報より過去から指定の件数取得
+	GetPrevInfosByTagNames(ctx context.Context, tagNames []string, attackVectors []string, prevInfoID int64, infoNumber int) ([]*entity.HackingInfo, error)
+
+	// IDで指定したハッキング情報を、タグと編集履歴を含めて取得
+	// 削除済みの情報も取得する。見つからない場合は nil を返す
+	GetInfoByID(ctx context.Context, id int64) (*entity.HackingInfo, error)
 
 	// 存在するすべてのタグを出力
 	GetAllTags(ctx context.Context) ([]*entity.Tag, error)
@@ -21,7 +27,32 @@ type HackingRepository interface {
 
 	// 新しいハッキング情報をトランザクション内で保存
 	// 新しいタグの保存と、中間テーブルへの関連付けも実行
+	// 同じチャンネル・メッセージIDの情報が保存済みの場合、既存のIDと ErrDuplicateInfo を返す
 	StoreInfo(ctx context.Context, info *entity.HackingInfo, tagNames []string) (int64, error)
+	// チャンネル・メッセージIDで指定したハッキング情報のIDを取得
+	// 見つからない場合は 0 を返す
+	GetInfoIDByMessage(ctx context.Context, channelUsername string, messageID int) (int64, error)
+
+	// 再分析の対象となるハッキング情報を、指定したIDより大きいIDから昇順に指定の件数取得
+	// 投稿本文が保存されていない情報と、削除された投稿の情報は対象外。promptVersion が空でない場合はそのバージョンで分析した情報に絞り込み
+	GetInfosForReanalysis(ctx context.Context, afterID int64, promptVersion string, limit int) ([]*entity.HackingInfo, error)
+	// ハッキング情報の分析結果（プロトコル名・攻撃手法・分析の出所）をトランザクション内で更新
+	// 関連付けられたタグは指定したタグに置き換え
+	UpdateInfoAnalysis(ctx context.Context, info *entity.HackingInfo, tagNames []string) error
+
+	// USD建ての金額が未設定のハッキング情報を、指定したIDより大きいIDから昇順に指定の件数取得
+	// 金額が空の情報は対象外
+	GetInfosWithoutAmountUSD(ctx context.Context, afterID int64, limit int) ([]*entity.HackingInfo, error)
+	// ハッキング情報の正規化した金額・単位・USD建ての金額を更新
+	UpdateInfoAmount(ctx context.Context, info *entity.HackingInfo) error
+
+	// 指定したチャンネルの削除されていないハッキング情報を、メッセージIDの新しい順に指定の件数取得
+	GetRecentInfosByChannel(ctx context.Context, channelUsername string, limit int) ([]*entity.HackingInfo, error)
+	// 投稿の編集に合わせてハッキング情報をトランザクション内で更新
+	// 変更前の情報は編集履歴に保存し、関連付けられたタグは指定したタグに置き換え
+	UpdateInfoFromEdit(ctx context.Context, info *entity.HackingInfo, tagNames []string) error
+	// 投稿の削除に合わせてハッキング情報を論理削除
+	MarkInfoDeleted(ctx context.Context, id int64) error
 
 	// チャンネル情報を保存
 	StoreChannelStatus(ctx context.Context, channelStatus *entity.TelegramChannel) error
@@ -29,4 +60,36 @@ type HackingRepository interface {
 	UpdateChannelStatus(ctx context.Context, channelStatus *entity.TelegramChannel) error
 	// チャンネル情報を取得
 	GetChannelStatusByUsername(ctx context.Context, username string) (*entity.TelegramChannel, error)
+
+	// 処理に失敗したハッキング情報の投稿をリトライキューに保存
+	// 同じチャンネル・メッセージIDの投稿が存在する場合は試行回数を加算し、デッドレター状態は維持
+	StoreRetryPost(ctx context.Context, retryPost *entity.RetryPost) error
+	// 指定したチャンネルの再試行時刻を過ぎた投稿を指定の件数取得
+	GetDueRetryPosts(ctx context.Context, channelUsername string, now time.Time, limit int) ([]*entity.RetryPost, error)
+	// リトライキューの投稿を新しい順に指定の件数取得
+	// status が空の場合は全ての状態の投稿を取得
+	GetRetryPosts(ctx context.Context, status string, limit int) ([]*entity.RetryPost, error)
+	// IDで指定したリトライキューの投稿を取得
+	GetRetryPostByID(ctx context.Context, id int64) (*entity.RetryPost, error)
+	// リトライキューから投稿を削除
+	DeleteRetryPost(ctx context.Context, id int64) error
+
+	// 指定したチャンネルのハッキング情報のバックフィルの進捗を取得
+	GetBackfill(ctx context.Context, channelUsername string) (*entity.ChannelBackfill, error)
+	// バックフィルの進捗を保存
+	// 同じチャンネルの進捗が存在する場合は上書き
+	StoreBackfill(ctx context.Context, backfill *entity.ChannelBackfill) error
+	// 全てのチャンネルのハッキング情報のバックフィルの進捗を取得
+	GetBackfills(ctx context.Context) ([]*entity.ChannelBackfill, error)
+
+	// パースに失敗したハッキング情報の投稿を隔離して保存
+	// 同じチャンネル・メッセージIDの投稿が存在する場合は投稿本文・エラー・抽出結果を更新
+	StoreQuarantinedPost(ctx context.Context, post *entity.QuarantinedPost) error
+	// 隔離した投稿を新しい順に指定の件数取得
+	// channelUsername・status が空の場合は絞り込まない
+	GetQuarantinedPosts(ctx context.Context, channelUsername string, status string, limit int) ([]*entity.QuarantinedPost, error)
+	// チャンネル・メッセージIDで指定した隔離した投稿を取得。見つからない場合は nil を返す
+	GetQuarantinedPost(ctx context.Context, channelUsername string, messageID int) (*entity.QuarantinedPost, error)
+	// IDで指定した隔離した投稿を取得。見つからない場合は nil を返す
+	GetQuarantinedPostByID(ctx context.Context, id int64) (*entity.QuarantinedPost, error)
 }
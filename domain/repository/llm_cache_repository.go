@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+)
+
+// LLMによる分析結果のキャッシュの永続化
+type LLMCacheRepository interface {
+	// キーで指定したキャッシュを取得
+	GetAnalysisCache(ctx context.Context, key string) (*entity.LLMAnalysisCache, error)
+	// キャッシュを保存
+	// 同じキーのキャッシュが存在する場合は上書き
+	StoreAnalysisCache(ctx context.Context, analysisCache *entity.LLMAnalysisCache) error
+	// 指定したプロンプトのバージョンのキャッシュを削除し、削除した件数を返す
+	// promptVersion が空の場合は全てのキャッシュを削除
+	DeleteAnalysisCaches(ctx context.Context, promptVersion string) (int64, error)
+}
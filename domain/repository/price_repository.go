@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+)
+
+// トークンの価格の永続化
+type PriceRepository interface {
+	// 指定したトークンの、指定した日付以前で最新の価格を取得。見つからない場合は nil を返す
+	GetLatestPrice(ctx context.Context, symbol string, date time.Time) (*entity.TokenPrice, error)
+	// 価格をトランザクション内で保存
+	// 同じトークン・日付の価格が存在する場合は上書き
+	StorePrices(ctx context.Context, prices []*entity.TokenPrice) error
+}
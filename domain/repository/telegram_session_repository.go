@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+)
+
+// Telegramのセッションの永続化
+type TelegramSessionRepository interface {
+	// 名前で指定したセッションを取得
+	GetSession(ctx context.Context, name string) (*entity.TelegramSession, error)
+	// セッションを保存
+	// 同じ名前のセッションが存在する場合は上書き
+	StoreSession(ctx context.Context, session *entity.TelegramSession) error
+}
@@ -7,6 +7,8 @@ toolchain go1.23.10
 require (
 	github.com/gotd/td v0.126.0
 	github.com/jmoiron/sqlx v1.4.0
+	golang.org/x/term v0.32.0
+	golang.org/x/time v0.5.0
 	google.golang.org/api v0.186.0
 )
 
@@ -51,7 +53,6 @@ require (
 	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.54.0 // indirect
 	golang.org/x/arch v0.8.0 // indirect
 	golang.org/x/oauth2 v0.21.0 // indirect
-	golang.org/x/time v0.5.0 // indirect
 	google.golang.org/genproto/googleapis/api v0.0.0-20240617180043-68d350f18fd4 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240617180043-68d350f18fd4 // indirect
 	google.golang.org/grpc v1.64.1 // indirect
@@ -5,8 +5,11 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/repository"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -21,17 +24,20 @@ func NewDbHackingRepository(db *sqlx.DB) *dbHackingRepository {
 	return &dbHackingRepository{db: db}
 }
 
-// 指定したタグ名に一致する情報を指定の件数取得
-func (r *dbHackingRepository) GetInfosByTagNames(ctx context.Context, tagNames []string, infoNumber int) ([]*entity.HackingInfo, error) {
-	// 条件に合うハッキング情報を取得
-
+// 条件に合うハッキング情報を取得するクエリを組み立て
+// 削除済みの情報は除外
+// タグ名・攻撃手法が指定されている場合は、いずれかに一致する情報に絞り込み
+// prevInfoID が正の場合は、指定した情報より過去の情報に絞り込み
+func (r *dbHackingRepository) buildInfosQuery(tagNames []string, attackVectors []string, prevInfoID int64, infoNumber int) (string, []interface{}, error) {
 	// ハッキング情報テーブルから重複を排除して選択
 	query := `
 		SELECT DISTINCT
-			hi.id, hi.protocol, hi.network, hi.amount, hi.tx_hash, hi.report_time, hi.message_id
+			hi.id, hi.protocol, hi.network, hi.amount, hi.amount_value, hi.amount_unit, hi.amount_usd, hi.tx_hash, hi.report_time, hi.message_id, hi.channel_username, hi.attack_vector,
+			hi.prompt_version, hi.llm_provider, hi.llm_model
 		FROM hacking_infos hi
 	`
 
+	conditions := []string{"hi.deleted = FALSE"}
 	args := []interface{}{}
 
 	// タグ名が指定されている場合、JOINとWHERE句を追加
@@ -41,22 +47,73 @@ func (r *dbHackingRepository) GetInfosByTagNames(ctx context.Context, tagNames [
 		query += `
 			JOIN hacking_info_tags it ON hi.id = it.info_id
 			JOIN tags t ON it.tag_id = t.id
-			WHERE t.name IN (?)
 		`
-		// スライスに含まれるタグを持つハッキング情報を指定
-		var err error
-		query, args, err = sqlx.In(query, tagNames)
-		if err != nil {
-			return nil, fmt.Errorf("failed to expand IN clause: %w", err)
-		}
+		conditions = append(conditions, "t.name IN (?)")
+		args = append(args, tagNames)
+	}
+
+	// 攻撃手法が指定されている場合、WHERE句を追加
+	if len(attackVectors) > 0 {
+		conditions = append(conditions, "hi.attack_vector IN (?)")
+		args = append(args, attackVectors)
+	}
+
+	// すでに取得している情報のIDより過去の情報を取得
+	if prevInfoID > 0 {
+		conditions = append(conditions, "hi.id < ?")
+		args = append(args, prevInfoID)
 	}
 
+	query += " WHERE " + strings.Join(conditions, " AND ")
+
 	// タイムスタンプ順に整列、指定件数取得
 	query += " ORDER BY hi.report_time DESC LIMIT ?"
 	args = append(args, infoNumber)
 
+	// スライスに含まれるタグ・攻撃手法を持つハッキング情報を指定
+	query, args, err := sqlx.In(query, args...)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to expand IN clause: %w", err)
+	}
+
 	// データベースドライバに合わせてプレースホルダーを変換
-	query = r.db.Rebind(query)
+	return r.db.Rebind(query), args, nil
+}
+
+// 指定したタグ名・攻撃手法に一致する情報を指定の件数取得
+func (r *dbHackingRepository) GetInfosByTagNames(ctx context.Context, tagNames []string, attackVectors []string, infoNumber int) ([]*entity.HackingInfo, error) {
+	// 条件に合うハッキング情報を取得
+	query, args, err := r.buildInfosQuery(tagNames, attackVectors, 0, infoNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	// クエリ実行
+	var infos []*entity.HackingInfo
+	if err := r.db.SelectContext(ctx, &infos, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to select infos: %w", err)
+	}
+
+	// ハッキング情報が見つからなければ、処理を終了
+	if len(infos) == 0 {
+		return infos, nil
+	}
+
+	// 取得したハッキング情報IDに紐づく全てのタグを取得
+	if err := r.attachTags(ctx, infos); err != nil {
+		return nil, err
+	}
+
+	return infos, nil
+}
+
+// 指定したタグ名・攻撃手法に一致する情報の内、指定した情報より過去から指定の件数取得
+func (r *dbHackingRepository) GetPrevInfosByTagNames(ctx context.Context, tagNames []string, attackVectors []string, prevInfoID int64, infoNumber int) ([]*entity.HackingInfo, error) {
+	// 条件に合うハッキング情報を取得
+	query, args, err := r.buildInfosQuery(tagNames, attackVectors, prevInfoID, infoNumber)
+	if err != nil {
+		return nil, err
+	}
 
 	// クエリ実行
 	var infos []*entity.HackingInfo
@@ -70,6 +127,56 @@ func (r *dbHackingRepository) GetInfosByTagNames(ctx context.Context, tagNames [
 	}
 
 	// 取得したハッキング情報IDに紐づく全てのタグを取得
+	if err := r.attachTags(ctx, infos); err != nil {
+		return nil, err
+	}
+
+	return infos, nil
+}
+
+// IDで指定したハッキング情報を、タグと編集履歴を含めて取得
+// 削除済みの情報も取得する
+func (r *dbHackingRepository) GetInfoByID(ctx context.Context, id int64) (*entity.HackingInfo, error) {
+	var info entity.HackingInfo
+
+	query := `
+		SELECT
+			id, protocol, network, amount, amount_value, amount_unit, amount_usd, tx_hash, report_time, message_id, channel_username, attack_vector,
+			prompt_version, llm_provider, llm_model, post_text, reply_to_text, deleted
+		FROM hacking_infos
+		WHERE id = $1
+	`
+
+	if err := r.db.GetContext(ctx, &info, query, id); err != nil {
+		// 見つからなかった場合は、nil と nil を返す
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get info: %w", err)
+	}
+
+	// ハッキング情報に紐づく全てのタグを取得
+	if err := r.attachTags(ctx, []*entity.HackingInfo{&info}); err != nil {
+		return nil, err
+	}
+
+	// 編集履歴を古い順に取得
+	edits := []*entity.HackingInfoEdit{}
+	if err := r.db.SelectContext(ctx, &edits, `
+		SELECT id, info_id, protocol, network, amount, tx_hash, attack_vector, post_text, reply_to_text, edited_at
+		FROM hacking_info_edits
+		WHERE info_id = $1
+		ORDER BY edited_at, id
+	`, id); err != nil {
+		return nil, fmt.Errorf("failed to select info edits: %w", err)
+	}
+	info.Edits = edits
+
+	return &info, nil
+}
+
+// ハッキング情報のスライスに、それぞれに紐づく全てのタグをセット
+func (r *dbHackingRepository) attachTags(ctx context.Context, infos []*entity.HackingInfo) error {
 	infoIDs := make([]int64, len(infos))
 	for i, info := range infos {
 		infoIDs[i] = info.ID
@@ -93,7 +200,7 @@ func (r *dbHackingRepository) GetInfosByTagNames(ctx context.Context, tagNames [
 	// 取得したハッキング情報のタグを指定
 	query, args, err := sqlx.In(tagsQuery, infoIDs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to expand IN clause for tags: %w", err)
+		return fmt.Errorf("failed to expand IN clause for tags: %w", err)
 	}
 
 	// データベースドライバに合わせてプレースホルダーを変換
@@ -101,7 +208,7 @@ func (r *dbHackingRepository) GetInfosByTagNames(ctx context.Context, tagNames [
 
 	// クエリ実行
 	if err := r.db.SelectContext(ctx, &tags, query, args...); err != nil {
-		return nil, fmt.Errorf("failed to select tags for infos: %w", err)
+		return fmt.Errorf("failed to select tags for infos: %w", err)
 	}
 
 	// 取得したタグをハッキング情報にマッピング
@@ -118,60 +225,58 @@ func (r *dbHackingRepository) GetInfosByTagNames(ctx context.Context, tagNames [
 		}
 	}
 
-	return infos, nil
+	return nil
 }
 
-// 指定したタグ名に一致する情報の内、指定した情報より過去から指定の件数取得
-func (r *dbHackingRepository) GetPrevInfosByTagNames(ctx context.Context, tagNames []string, prevInfoID int64, infoNumber int) ([]*entity.HackingInfo, error) {
-	// 条件に合うハッキング情報を取得
+// 存在するすべてのタグを取得
+func (r *dbHackingRepository) GetAllTags(ctx context.Context) ([]*entity.Tag, error) {
+	var tags []*entity.Tag
+	query := "SELECT id, name FROM tags ORDER BY name"
+	if err := r.db.SelectContext(ctx, &tags, query); err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	return tags, nil
+}
 
-	// ハッキング情報テーブルから重複を排除して選択
+// チャンネル・メッセージIDで指定したハッキング情報のIDを取得
+// 見つからない場合は 0 を返す
+func (r *dbHackingRepository) GetInfoIDByMessage(ctx context.Context, channelUsername string, messageID int) (int64, error) {
+	var infoID int64
+	if err := r.db.GetContext(ctx, &infoID, "SELECT id FROM hacking_infos WHERE channel_username = $1 AND message_id = $2", channelUsername, messageID); err != nil {
+		// 見つからなかった場合は、0 と nil を返す
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get info id: %w", err)
+	}
+	return infoID, nil
+}
+
+// 再分析の対象となるハッキング情報を、指定したIDより大きいIDから昇順に指定の件数取得
+// 投稿本文が保存されていない情報と、削除された投稿の情報は対象外
+func (r *dbHackingRepository) GetInfosForReanalysis(ctx context.Context, afterID int64, promptVersion string, limit int) ([]*entity.HackingInfo, error) {
 	query := `
-		SELECT DISTINCT
-			hi.id, hi.protocol, hi.network, hi.amount, hi.tx_hash, hi.report_time, hi.message_id
-		FROM hacking_infos hi
+		SELECT
+			id, protocol, network, amount, amount_value, amount_unit, amount_usd, tx_hash, report_time, message_id, channel_username, attack_vector,
+			prompt_version, llm_provider, llm_model, post_text, reply_to_text
+		FROM hacking_infos
+		WHERE id > ? AND post_text <> '' AND deleted = FALSE
 	`
+	args := []interface{}{afterID}
 
-	args := []interface{}{}
-
-	// タグ名が指定されている場合、JOINとWHERE句を追加
-	if len(tagNames) > 0 {
-		// ハッキング情報テーブルと中間テーブルをハッキング情報IDで結合
-		// 中間テーブルとタグテーブルをタグIDで結合
-		query += `
-			JOIN hacking_info_tags it ON hi.id = it.info_id
-			JOIN tags t ON it.tag_id = t.id
-			WHERE t.name IN (?)
-		`
-		// スライスに含まれるタグを持つハッキング情報を指定
-		var err error
-		query, args, err = sqlx.In(query, tagNames)
-		if err != nil {
-			return nil, fmt.Errorf("failed to expand IN clause: %w", err)
-		}
-		// すでに取得している情報のIDより過去の情報を取得
-		if prevInfoID > 0 {
-			query += " AND hi.id < ?"
-			args = append(args, prevInfoID)
-		}
-	} else {
-		// すでに取得している情報のIDより過去の情報を取得
-		if prevInfoID > 0 {
-			query += " WHERE hi.id < ?"
-			args = append(args, prevInfoID)
-		}
+	// プロンプトのバージョンが指定されている場合、そのバージョンで分析した情報に絞り込み
+	if promptVersion != "" {
+		query += " AND prompt_version = ?"
+		args = append(args, promptVersion)
 	}
 
-	// タイムスタンプ順に整列、指定件数取得
-	query += " ORDER BY hi.report_time DESC LIMIT ?"
-	args = append(args, infoNumber)
-	// データベースドライバに合わせてプレースホルダーを変換
-	query = r.db.Rebind(query)
+	query += " ORDER BY id ASC LIMIT ?"
+	args = append(args, limit)
 
 	// クエリ実行
 	var infos []*entity.HackingInfo
-	if err := r.db.SelectContext(ctx, &infos, query, args...); err != nil {
-		return nil, fmt.Errorf("failed to select infos: %w", err)
+	if err := r.db.SelectContext(ctx, &infos, r.db.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to select infos for reanalysis: %w", err)
 	}
 
 	// ハッキング情報が見つからなければ、処理を終了
@@ -180,65 +285,170 @@ func (r *dbHackingRepository) GetPrevInfosByTagNames(ctx context.Context, tagNam
 	}
 
 	// 取得したハッキング情報IDに紐づく全てのタグを取得
-	infoIDs := make([]int64, len(infos))
-	for i, info := range infos {
-		infoIDs[i] = info.ID
+	if err := r.attachTags(ctx, infos); err != nil {
+		return nil, err
 	}
 
-	// タグテーブルに中間テーブルをタグIDで結合
-	tagsQuery := `
-		SELECT t.id, t.name, it.info_id
-		FROM tags t
-		JOIN hacking_info_tags it ON t.id = it.tag_id
-		WHERE it.info_id IN (?)
-	`
+	return infos, nil
+}
 
-	// タグ取得用の構造体
-	type infoTag struct {
-		entity.Tag
-		InfoID int64 `db:"info_id"`
+// ハッキング情報の分析結果をトランザクション内で更新
+// 関連付けられたタグは指定したタグに置き換え
+func (r *dbHackingRepository) UpdateInfoAnalysis(ctx context.Context, info *entity.HackingInfo, tagNames []string) error {
+	// トランザクションを開始
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	var tags []infoTag
+	// 関数を抜ける際にエラーがあればロールバック
+	defer tx.Rollback()
 
-	// 取得したハッキング情報のタグを指定
-	query, args, err := sqlx.In(tagsQuery, infoIDs)
-	if err != nil {
-		return nil, fmt.Errorf("failed to expand IN clause for tags: %w", err)
+	if _, err := tx.NamedExecContext(ctx, `
+		UPDATE hacking_infos SET
+			protocol = :protocol,
+			attack_vector = :attack_vector,
+			prompt_version = :prompt_version,
+			llm_provider = :llm_provider,
+			llm_model = :llm_model
+		WHERE id = :id
+	`, info); err != nil {
+		return fmt.Errorf("failed to update info analysis: %w", err)
 	}
 
-	// データベースドライバに合わせてプレースホルダーを変換
-	query = r.db.Rebind(query)
+	// 既存のタグの関連付けを削除
+	if _, err := tx.ExecContext(ctx, "DELETE FROM hacking_info_tags WHERE info_id = $1", info.ID); err != nil {
+		return fmt.Errorf("failed to delete hacking_info_tags: %w", err)
+	}
+
+	// タグを保存してハッキング情報と関連付け
+	if err := storeInfoTags(ctx, tx, info.ID, tagNames); err != nil {
+		return err
+	}
+
+	// トランザクションをコミットして変更を確定
+	return tx.Commit()
+}
+
+// USD建ての金額が未設定のハッキング情報を、指定したIDより大きいIDから昇順に指定の件数取得
+// 金額が空の情報は対象外
+func (r *dbHackingRepository) GetInfosWithoutAmountUSD(ctx context.Context, afterID int64, limit int) ([]*entity.HackingInfo, error) {
+	query := `
+		SELECT
+			id, protocol, network, amount, amount_value, amount_unit, amount_usd, tx_hash, report_time, message_id, channel_username, attack_vector,
+			prompt_version, llm_provider, llm_model, post_text, reply_to_text, deleted
+		FROM hacking_infos
+		WHERE id > ? AND amount <> '' AND amount_usd IS NULL
+		ORDER BY id ASC
+		LIMIT ?
+	`
 
 	// クエリ実行
-	if err := r.db.SelectContext(ctx, &tags, query, args...); err != nil {
-		return nil, fmt.Errorf("failed to select tags for infos: %w", err)
+	var infos []*entity.HackingInfo
+	if err := r.db.SelectContext(ctx, &infos, r.db.Rebind(query), afterID, limit); err != nil {
+		return nil, fmt.Errorf("failed to select infos without amount usd: %w", err)
 	}
 
-	// 取得したタグをハッキング情報にマッピング
-	tagsByInfoID := make(map[int64][]*entity.Tag)
-	for _, t := range tags {
-		tag := &entity.Tag{ID: t.ID, Name: t.Name}
-		tagsByInfoID[t.InfoID] = append(tagsByInfoID[t.InfoID], tag)
+	return infos, nil
+}
+
+// ハッキング情報の正規化した金額・単位・USD建ての金額を更新
+func (r *dbHackingRepository) UpdateInfoAmount(ctx context.Context, info *entity.HackingInfo) error {
+	if _, err := r.db.NamedExecContext(ctx, `
+		UPDATE hacking_infos SET
+			amount_value = :amount_value,
+			amount_unit = :amount_unit,
+			amount_usd = :amount_usd
+		WHERE id = :id
+	`, info); err != nil {
+		return fmt.Errorf("failed to update info amount: %w", err)
 	}
 
-	// ハッキング情報のスライスにタグをセット
-	for _, info := range infos {
-		if associatedTags, ok := tagsByInfoID[info.ID]; ok {
-			info.Tags = associatedTags
-		}
+	return nil
+}
+
+// 指定したチャンネルの削除されていないハッキング情報を、メッセージIDの新しい順に指定の件数取得
+func (r *dbHackingRepository) GetRecentInfosByChannel(ctx context.Context, channelUsername string, limit int) ([]*entity.HackingInfo, error) {
+	query := `
+		SELECT
+			id, protocol, network, amount, amount_value, amount_unit, amount_usd, tx_hash, report_time, message_id, channel_username, attack_vector,
+			prompt_version, llm_provider, llm_model, post_text, reply_to_text, deleted
+		FROM hacking_infos
+		WHERE channel_username = ? AND deleted = FALSE
+		ORDER BY message_id DESC
+		LIMIT ?
+	`
+
+	// クエリ実行
+	var infos []*entity.HackingInfo
+	if err := r.db.SelectContext(ctx, &infos, r.db.Rebind(query), channelUsername, limit); err != nil {
+		return nil, fmt.Errorf("failed to select recent infos: %w", err)
 	}
 
 	return infos, nil
 }
 
-// 存在するすべてのタグを取得
-func (r *dbHackingRepository) GetAllTags(ctx context.Context) ([]*entity.Tag, error) {
-	var tags []*entity.Tag
-	query := "SELECT id, name FROM tags ORDER BY name"
-	if err := r.db.SelectContext(ctx, &tags, query); err != nil {
-		return nil, fmt.Errorf("failed to list tags: %w", err)
+// 投稿の編集に合わせてハッキング情報をトランザクション内で更新
+// 変更前の情報は編集履歴に保存し、関連付けられたタグは指定したタグに置き換え
+func (r *dbHackingRepository) UpdateInfoFromEdit(ctx context.Context, info *entity.HackingInfo, tagNames []string) error {
+	// トランザクションを開始
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	return tags, nil
+	// 関数を抜ける際にエラーがあればロールバック
+	defer tx.Rollback()
+
+	// 変更前の情報を編集履歴に保存
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO hacking_info_edits (info_id, protocol, network, amount, tx_hash, attack_vector, post_text, reply_to_text)
+		SELECT id, protocol, network, amount, tx_hash, attack_vector, post_text, reply_to_text
+		FROM hacking_infos
+		WHERE id = $1
+	`, info.ID); err != nil {
+		return fmt.Errorf("failed to insert info edit: %w", err)
+	}
+
+	if _, err := tx.NamedExecContext(ctx, `
+		UPDATE hacking_infos SET
+			protocol = :protocol,
+			network = :network,
+			amount = :amount,
+			amount_value = :amount_value,
+			amount_unit = :amount_unit,
+			amount_usd = :amount_usd,
+			tx_hash = :tx_hash,
+			attack_vector = :attack_vector,
+			prompt_version = :prompt_version,
+			llm_provider = :llm_provider,
+			llm_model = :llm_model,
+			post_text = :post_text,
+			reply_to_text = :reply_to_text
+		WHERE id = :id
+	`, info); err != nil {
+		return fmt.Errorf("failed to update info: %w", err)
+	}
+
+	// 既存のタグの関連付けを削除
+	if _, err := tx.ExecContext(ctx, "DELETE FROM hacking_info_tags WHERE info_id = $1", info.ID); err != nil {
+		return fmt.Errorf("failed to delete hacking_info_tags: %w", err)
+	}
+
+	// タグを保存してハッキング情報と関連付け
+	if err := storeInfoTags(ctx, tx, info.ID, tagNames); err != nil {
+		return err
+	}
+
+	// トランザクションをコミットして変更を確定
+	return tx.Commit()
+}
+
+// 投稿の削除に合わせてハッキング情報を論理削除
+func (r *dbHackingRepository) MarkInfoDeleted(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, "UPDATE hacking_infos SET deleted = TRUE WHERE id = $1", id); err != nil {
+		return fmt.Errorf("failed to mark info deleted: %w", err)
+	}
+
+	return nil
 }
 
 // 新しいハッキング情報と関連タグをトランザクション内で保存
@@ -253,8 +463,11 @@ func (r *dbHackingRepository) StoreInfo(ctx context.Context, info *entity.Hackin
 
 	// ハッキング情報を保存するクエリ文を設定
 	stmt, err := tx.PrepareNamedContext(ctx, `
-		INSERT INTO hacking_infos (protocol, network, amount, tx_hash, report_time, message_id)
-		VALUES (:protocol, :network, :amount, :tx_hash, :report_time, :message_id)
+		INSERT INTO hacking_infos (protocol, network, amount, amount_value, amount_unit, amount_usd, tx_hash, report_time,
+			message_id, channel_username, attack_vector, prompt_version, llm_provider, llm_model, post_text, reply_to_text)
+		VALUES (:protocol, :network, :amount, :amount_value, :amount_unit, :amount_usd, :tx_hash, :report_time,
+			:message_id, :channel_username, :attack_vector, :prompt_version, :llm_provider, :llm_model, :post_text, :reply_to_text)
+		ON CONFLICT (channel_username, message_id) WHERE channel_username <> '' DO NOTHING
 		RETURNING id
 	`)
 	if err != nil {
@@ -266,9 +479,27 @@ func (r *dbHackingRepository) StoreInfo(ctx context.Context, info *entity.Hackin
 	// ハッキング情報を `hacking_infos` テーブルに保存
 	// ハッキング情報のIDを取得
 	if err := stmt.GetContext(ctx, &infoID, info); err != nil {
+		// 同じチャンネル・メッセージIDの情報が保存済みの場合、既存のIDを返す
+		if errors.Is(err, sql.ErrNoRows) {
+			if err := tx.GetContext(ctx, &infoID, "SELECT id FROM hacking_infos WHERE channel_username = $1 AND message_id = $2", info.ChannelUsername, info.MessageID); err != nil {
+				return 0, fmt.Errorf("failed to get existing info: %w", err)
+			}
+			return infoID, repository.ErrDuplicateInfo
+		}
 		return 0, fmt.Errorf("failed to execute info statement: %w", err)
 	}
 
+	// タグを保存してハッキング情報と関連付け
+	if err := storeInfoTags(ctx, tx, infoID, tagNames); err != nil {
+		return 0, err
+	}
+
+	// トランザクションをコミットして変更を確定
+	return infoID, tx.Commit()
+}
+
+// タグを `tags` テーブルに保存し、中間テーブルでハッキング情報と関連付け
+func storeInfoTags(ctx context.Context, tx *sqlx.Tx, infoID int64, tagNames []string) error {
 	// タグを `tags` テーブルに保存
 	tagIDs := []int64{}
 	for _, name := range tagNames {
@@ -279,7 +510,7 @@ func (r *dbHackingRepository) StoreInfo(ctx context.Context, info *entity.Hackin
 			// 存在しない場合、新しく保存してIDを取得
 			err = tx.QueryRowxContext(ctx, "INSERT INTO tags (name) VALUES ($1) RETURNING id", name).Scan(&tagID)
 			if err != nil {
-				return 0, fmt.Errorf("failed to insert tag: %w", err)
+				return fmt.Errorf("failed to insert tag: %w", err)
 			}
 		}
 		tagIDs = append(tagIDs, tagID)
@@ -289,12 +520,11 @@ func (r *dbHackingRepository) StoreInfo(ctx context.Context, info *entity.Hackin
 	for _, tagID := range tagIDs {
 		_, err := tx.ExecContext(ctx, "INSERT INTO hacking_info_tags (info_id, tag_id) VALUES ($1, $2)", infoID, tagID)
 		if err != nil {
-			return 0, fmt.Errorf("failed to insert into hacking_info_tags: %w", err)
+			return fmt.Errorf("failed to insert into hacking_info_tags: %w", err)
 		}
 	}
 
-	// トランザクションをコミットして変更を確定
-	return infoID, tx.Commit()
+	return nil
 }
 
 // チャンネル情報をトランザクション内で保存
@@ -309,8 +539,8 @@ func (r *dbHackingRepository) StoreChannelStatus(ctx context.Context, channelSta
 
 	// チャンネル情報を保存するクエリ文を設定
 	stmt, err := tx.PrepareNamedContext(ctx, `
-		INSERT INTO telegram_channel (username, last_message_id)
-		VALUES (:username, :last_message_id)
+		INSERT INTO telegram_channel (username, last_message_id, channel_id, access_hash, title)
+		VALUES (:username, :last_message_id, :channel_id, :access_hash, :title)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
@@ -339,7 +569,10 @@ func (r *dbHackingRepository) UpdateChannelStatus(ctx context.Context, channelSt
 	// チャンネル情報を保存するクエリ文を設定
 	stmt, err := tx.PrepareNamedContext(ctx, `
 		UPDATE telegram_channel
-		SET last_message_id = :last_message_id
+		SET last_message_id = :last_message_id,
+			channel_id = :channel_id,
+			access_hash = :access_hash,
+			title = :title
 		WHERE username = :username
 	`)
 	if err != nil {
@@ -363,7 +596,7 @@ func (r *dbHackingRepository) GetChannelStatusByUsername(ctx context.Context, us
 
 	// チャンネル情報を取得するクエリ文
 	query := `
-		SELECT username, last_message_id
+		SELECT username, last_message_id, channel_id, access_hash, title
 		FROM telegram_channel
 		WHERE username = ?
 	`
@@ -384,3 +617,287 @@ func (r *dbHackingRepository) GetChannelStatusByUsername(ctx context.Context, us
 
 	return &channel, nil
 }
+
+// 処理に失敗した投稿をリトライキューに保存
+// 同じチャンネル・メッセージIDの投稿が存在する場合は試行回数を加算して試行状態を更新
+// デッドレター状態の投稿は、新規の投稿として再び失敗した場合もデッドレター状態のまま
+func (r *dbHackingRepository) StoreRetryPost(ctx context.Context, retryPost *entity.RetryPost) error {
+	query := `
+		INSERT INTO retry_posts (kind, channel_username, message_id, payload, attempts, last_error, next_attempt_at, status)
+		VALUES ('hacking', :channel_username, :message_id, :payload, :attempts, :last_error, :next_attempt_at, :status)
+		ON CONFLICT (kind, channel_username, message_id) DO UPDATE SET
+			payload = EXCLUDED.payload,
+			attempts = retry_posts.attempts + 1,
+			last_error = EXCLUDED.last_error,
+			next_attempt_at = EXCLUDED.next_attempt_at,
+			status = CASE WHEN retry_posts.status = 'dead' THEN retry_posts.status ELSE EXCLUDED.status END,
+			updated_at = NOW()
+	`
+
+	if _, err := r.db.NamedExecContext(ctx, query, retryPost); err != nil {
+		return fmt.Errorf("failed to store retry post: %w", err)
+	}
+
+	return nil
+}
+
+// 指定したチャンネルの再試行時刻を過ぎた投稿を指定の件数取得
+func (r *dbHackingRepository) GetDueRetryPosts(ctx context.Context, channelUsername string, now time.Time, limit int) ([]*entity.RetryPost, error) {
+	query := `
+		SELECT id, channel_username, message_id, payload, attempts, last_error, next_attempt_at, status, created_at, updated_at
+		FROM retry_posts
+		WHERE kind = 'hacking' AND channel_username = ? AND status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at
+		LIMIT ?
+	`
+
+	// データベースドライバに合わせてプレースホルダーを変換
+	query = r.db.Rebind(query)
+
+	var retryPosts []*entity.RetryPost
+	if err := r.db.SelectContext(ctx, &retryPosts, query, channelUsername, entity.RetryStatusPending, now, limit); err != nil {
+		return nil, fmt.Errorf("failed to select retry posts: %w", err)
+	}
+
+	return retryPosts, nil
+}
+
+// リトライキューの投稿を新しい順に指定の件数取得
+// status が空の場合は全ての状態の投稿を取得
+func (r *dbHackingRepository) GetRetryPosts(ctx context.Context, status string, limit int) ([]*entity.RetryPost, error) {
+	query := `
+		SELECT id, channel_username, message_id, payload, attempts, last_error, next_attempt_at, status, created_at, updated_at
+		FROM retry_posts
+		WHERE kind = 'hacking'
+	`
+
+	args := []interface{}{}
+
+	// 状態が指定されている場合、WHERE句を追加
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+
+	query += " ORDER BY updated_at DESC LIMIT ?"
+	args = append(args, limit)
+
+	// データベースドライバに合わせてプレースホルダーを変換
+	query = r.db.Rebind(query)
+
+	var retryPosts []*entity.RetryPost
+	if err := r.db.SelectContext(ctx, &retryPosts, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to select retry posts: %w", err)
+	}
+
+	return retryPosts, nil
+}
+
+// IDで指定したリトライキューの投稿を取得
+func (r *dbHackingRepository) GetRetryPostByID(ctx context.Context, id int64) (*entity.RetryPost, error) {
+	var retryPost entity.RetryPost
+
+	query := `
+		SELECT id, channel_username, message_id, payload, attempts, last_error, next_attempt_at, status, created_at, updated_at
+		FROM retry_posts
+		WHERE kind = 'hacking' AND id = $1
+	`
+
+	if err := r.db.GetContext(ctx, &retryPost, query, id); err != nil {
+		// 見つからなかった場合は、nil と nil を返す
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get retry post: %w", err)
+	}
+
+	return &retryPost, nil
+}
+
+// リトライキューから投稿を削除
+func (r *dbHackingRepository) DeleteRetryPost(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM retry_posts WHERE kind = 'hacking' AND id = $1", id); err != nil {
+		return fmt.Errorf("failed to delete retry post: %w", err)
+	}
+
+	return nil
+}
+
+// 指定したチャンネルのバックフィルの進捗を取得
+func (r *dbHackingRepository) GetBackfill(ctx context.Context, channelUsername string) (*entity.ChannelBackfill, error) {
+	var backfill entity.ChannelBackfill
+
+	query := `
+		SELECT channel_username, status, cursor_message_id, oldest_post_at, until_date, until_message_id,
+			processed, skipped, failed, last_error, started_at, updated_at, completed_at
+		FROM channel_backfills
+		WHERE kind = 'hacking' AND channel_username = $1
+	`
+
+	if err := r.db.GetContext(ctx, &backfill, query, channelUsername); err != nil {
+		// 見つからなかった場合は、nil と nil を返す
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get backfill: %w", err)
+	}
+
+	return &backfill, nil
+}
+
+// バックフィルの進捗を保存
+// 同じチャンネルの進捗が存在する場合は上書き
+func (r *dbHackingRepository) StoreBackfill(ctx context.Context, backfill *entity.ChannelBackfill) error {
+	query := `
+		INSERT INTO channel_backfills (kind, channel_username, status, cursor_message_id, oldest_post_at, until_date, until_message_id,
+			processed, skipped, failed, last_error, completed_at)
+		VALUES ('hacking', :channel_username, :status, :cursor_message_id, :oldest_post_at, :until_date, :until_message_id,
+			:processed, :skipped, :failed, :last_error, :completed_at)
+		ON CONFLICT (kind, channel_username) DO UPDATE SET
+			status = EXCLUDED.status,
+			cursor_message_id = EXCLUDED.cursor_message_id,
+			oldest_post_at = EXCLUDED.oldest_post_at,
+			until_date = EXCLUDED.until_date,
+			until_message_id = EXCLUDED.until_message_id,
+			processed = EXCLUDED.processed,
+			skipped = EXCLUDED.skipped,
+			failed = EXCLUDED.failed,
+			last_error = EXCLUDED.last_error,
+			completed_at = EXCLUDED.completed_at,
+			updated_at = NOW()
+	`
+
+	if _, err := r.db.NamedExecContext(ctx, query, backfill); err != nil {
+		return fmt.Errorf("failed to store backfill: %w", err)
+	}
+
+	return nil
+}
+
+// 全てのチャンネルのバックフィルの進捗を取得
+func (r *dbHackingRepository) GetBackfills(ctx context.Context) ([]*entity.ChannelBackfill, error) {
+	query := `
+		SELECT channel_username, status, cursor_message_id, oldest_post_at, until_date, until_message_id,
+			processed, skipped, failed, last_error, started_at, updated_at, completed_at
+		FROM channel_backfills
+		WHERE kind = 'hacking'
+		ORDER BY channel_username
+	`
+
+	var backfills []*entity.ChannelBackfill
+	if err := r.db.SelectContext(ctx, &backfills, query); err != nil {
+		return nil, fmt.Errorf("failed to select backfills: %w", err)
+	}
+
+	return backfills, nil
+}
+
+// パースに失敗した投稿を隔離して保存
+// 同じチャンネル・メッセージIDの投稿が存在する場合は投稿本文・エラー・抽出結果を更新
+func (r *dbHackingRepository) StoreQuarantinedPost(ctx context.Context, post *entity.QuarantinedPost) error {
+	query := `
+		INSERT INTO quarantined_posts (
+			kind, channel_username, message_id, text, reply_to_text, error, report_time, status, extracted, confidence
+		)
+		VALUES (
+			'hacking', :channel_username, :message_id, :text, :reply_to_text, :error, :report_time, :status, :extracted, :confidence
+		)
+		ON CONFLICT (kind, channel_username, message_id) DO UPDATE SET
+			text = EXCLUDED.text,
+			reply_to_text = EXCLUDED.reply_to_text,
+			error = EXCLUDED.error,
+			report_time = EXCLUDED.report_time,
+			status = EXCLUDED.status,
+			extracted = EXCLUDED.extracted,
+			confidence = EXCLUDED.confidence,
+			updated_at = NOW()
+	`
+
+	if _, err := r.db.NamedExecContext(ctx, query, post); err != nil {
+		return fmt.Errorf("failed to store quarantined post: %w", err)
+	}
+
+	return nil
+}
+
+// 隔離した投稿を新しい順に指定の件数取得
+// channelUsername・status が空の場合は絞り込まない
+func (r *dbHackingRepository) GetQuarantinedPosts(ctx context.Context, channelUsername string, status string, limit int) ([]*entity.QuarantinedPost, error) {
+	query := `
+		SELECT
+			id, channel_username, message_id, text, reply_to_text, error, report_time, status, extracted, confidence,
+			created_at, updated_at
+		FROM quarantined_posts
+		WHERE kind = 'hacking'
+	`
+
+	args := []interface{}{}
+
+	// チャンネルが指定されている場合、WHERE句を追加
+	if channelUsername != "" {
+		query += " AND channel_username = ?"
+		args = append(args, channelUsername)
+	}
+	// ステータスが指定されている場合、WHERE句を追加
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+
+	query += " ORDER BY updated_at DESC LIMIT ?"
+	args = append(args, limit)
+
+	// データベースドライバに合わせてプレースホルダーを変換
+	query = r.db.Rebind(query)
+
+	var posts []*entity.QuarantinedPost
+	if err := r.db.SelectContext(ctx, &posts, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to select quarantined posts: %w", err)
+	}
+
+	return posts, nil
+}
+
+// チャンネル・メッセージIDで指定した隔離した投稿を取得
+func (r *dbHackingRepository) GetQuarantinedPost(ctx context.Context, channelUsername string, messageID int) (*entity.QuarantinedPost, error) {
+	query := `
+		SELECT
+			id, channel_username, message_id, text, reply_to_text, error, report_time, status, extracted, confidence,
+			created_at, updated_at
+		FROM quarantined_posts
+		WHERE kind = 'hacking' AND channel_username = $1 AND message_id = $2
+	`
+
+	var post entity.QuarantinedPost
+	if err := r.db.GetContext(ctx, &post, query, channelUsername, messageID); err != nil {
+		// 見つからなかった場合は、nil と nil を返す
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get quarantined post: %w", err)
+	}
+
+	return &post, nil
+}
+
+// IDで指定した隔離した投稿を取得
+func (r *dbHackingRepository) GetQuarantinedPostByID(ctx context.Context, id int64) (*entity.QuarantinedPost, error) {
+	query := `
+		SELECT
+			id, channel_username, message_id, text, reply_to_text, error, report_time, status, extracted, confidence,
+			created_at, updated_at
+		FROM quarantined_posts
+		WHERE kind = 'hacking' AND id = $1
+	`
+
+	var post entity.QuarantinedPost
+	if err := r.db.GetContext(ctx, &post, query, id); err != nil {
+		// 見つからなかった場合は、nil と nil を返す
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get quarantined post: %w", err)
+	}
+
+	return &post, nil
+}
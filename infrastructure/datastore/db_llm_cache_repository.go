@@ -0,0 +1,84 @@
+package datastore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// LLMCacheRepository インターフェースを実装する構造体
+type dbLLMCacheRepository struct {
+	db *sqlx.DB
+}
+
+// dbLLMCacheRepository の新しいインスタンスを生成
+func NewDbLLMCacheRepository(db *sqlx.DB) *dbLLMCacheRepository {
+	return &dbLLMCacheRepository{db: db}
+}
+
+// キーで指定したキャッシュを取得
+func (r *dbLLMCacheRepository) GetAnalysisCache(ctx context.Context, key string) (*entity.LLMAnalysisCache, error) {
+	var analysisCache entity.LLMAnalysisCache
+
+	query := `
+		SELECT cache_key, prompt_version, result, created_at
+		FROM llm_analysis_caches
+		WHERE cache_key = $1
+	`
+
+	if err := r.db.GetContext(ctx, &analysisCache, query, key); err != nil {
+		// 見つからなかった場合は、nil と nil を返す
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get analysis cache: %w", err)
+	}
+
+	return &analysisCache, nil
+}
+
+// キャッシュを保存
+func (r *dbLLMCacheRepository) StoreAnalysisCache(ctx context.Context, analysisCache *entity.LLMAnalysisCache) error {
+	query := `
+		INSERT INTO llm_analysis_caches (cache_key, prompt_version, result)
+		VALUES (:cache_key, :prompt_version, :result)
+		ON CONFLICT (cache_key) DO UPDATE SET
+			prompt_version = EXCLUDED.prompt_version,
+			result = EXCLUDED.result,
+			created_at = NOW()
+	`
+
+	if _, err := r.db.NamedExecContext(ctx, query, analysisCache); err != nil {
+		return fmt.Errorf("failed to store analysis cache: %w", err)
+	}
+
+	return nil
+}
+
+// 指定したプロンプトのバージョンのキャッシュを削除
+func (r *dbLLMCacheRepository) DeleteAnalysisCaches(ctx context.Context, promptVersion string) (int64, error) {
+	query := "DELETE FROM llm_analysis_caches"
+	args := []interface{}{}
+
+	if promptVersion != "" {
+		query += " WHERE prompt_version = ?"
+		args = append(args, promptVersion)
+	}
+
+	result, err := r.db.ExecContext(ctx, r.db.Rebind(query), args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete analysis caches: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get deleted analysis cache count: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,78 @@
+package datastore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PriceRepository インターフェースを実装する構造体
+type dbPriceRepository struct {
+	db *sqlx.DB
+}
+
+// dbPriceRepository の新しいインスタンスを生成
+func NewDbPriceRepository(db *sqlx.DB) *dbPriceRepository {
+	return &dbPriceRepository{db: db}
+}
+
+// 指定したトークンの、指定した日付以前で最新の価格を取得
+func (r *dbPriceRepository) GetLatestPrice(ctx context.Context, symbol string, date time.Time) (*entity.TokenPrice, error) {
+	var price entity.TokenPrice
+
+	query := `
+		SELECT symbol, price_date, price_usd
+		FROM token_prices
+		WHERE symbol = $1 AND price_date <= $2
+		ORDER BY price_date DESC
+		LIMIT 1
+	`
+
+	if err := r.db.GetContext(ctx, &price, query, symbol, date.UTC().Format(time.DateOnly)); err != nil {
+		// 見つからなかった場合は、nil と nil を返す
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get token price: %w", err)
+	}
+
+	return &price, nil
+}
+
+// 価格をトランザクション内で保存
+// 同じトークン・日付の価格が存在する場合は上書き
+func (r *dbPriceRepository) StorePrices(ctx context.Context, prices []*entity.TokenPrice) error {
+	// トランザクションを開始
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// 関数を抜ける際にエラーがあればロールバック
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareNamedContext(ctx, `
+		INSERT INTO token_prices (symbol, price_date, price_usd)
+		VALUES (:symbol, :price_date, :price_usd)
+		ON CONFLICT (symbol, price_date) DO UPDATE SET
+			price_usd = EXCLUDED.price_usd
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare price statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, price := range prices {
+		if _, err := stmt.ExecContext(ctx, price); err != nil {
+			return fmt.Errorf("failed to store price of %s on %s: %w", price.Symbol, price.PriceDate.Format(time.DateOnly), err)
+		}
+	}
+
+	// トランザクションをコミットして変更を確定
+	return tx.Commit()
+}
@@ -0,0 +1,60 @@
+package datastore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TelegramSessionRepository インターフェースを実装する構造体
+type dbTelegramSessionRepository struct {
+	db *sqlx.DB
+}
+
+// dbTelegramSessionRepository の新しいインスタンスを生成
+func NewDbTelegramSessionRepository(db *sqlx.DB) *dbTelegramSessionRepository {
+	return &dbTelegramSessionRepository{db: db}
+}
+
+// 名前で指定したセッションを取得
+func (r *dbTelegramSessionRepository) GetSession(ctx context.Context, name string) (*entity.TelegramSession, error) {
+	var session entity.TelegramSession
+
+	query := `
+		SELECT name, data, updated_at
+		FROM telegram_sessions
+		WHERE name = $1
+	`
+
+	if err := r.db.GetContext(ctx, &session, query, name); err != nil {
+		// 見つからなかった場合は、nil と nil を返す
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get telegram session: %w", err)
+	}
+
+	return &session, nil
+}
+
+// セッションを保存
+func (r *dbTelegramSessionRepository) StoreSession(ctx context.Context, session *entity.TelegramSession) error {
+	query := `
+		INSERT INTO telegram_sessions (name, data)
+		VALUES (:name, :data)
+		ON CONFLICT (name) DO UPDATE SET
+			data = EXCLUDED.data,
+			updated_at = NOW()
+	`
+
+	if _, err := r.db.NamedExecContext(ctx, query, session); err != nil {
+		return fmt.Errorf("failed to store telegram session: %w", err)
+	}
+
+	return nil
+}
@@ -22,16 +22,22 @@ func NewHackingRepository(dbRepo *dbHackingRepository, cache *cache.Cache) *hack
 	return &hackingRepository{dbRepo: dbRepo, cache: cache}
 }
 
-// 指定したタグ名に一致する情報を指定の件数取得
-func (r *hackingRepository) GetInfosByTagNames(ctx context.Context, tagNames []string, infoNumber int) ([]*entity.HackingInfo, error) {
+// 指定したタグ名・攻撃手法に一致する情報を指定の件数取得
+func (r *hackingRepository) GetInfosByTagNames(ctx context.Context, tagNames []string, attackVectors []string, infoNumber int) ([]*entity.HackingInfo, error) {
 
-	return r.dbRepo.GetInfosByTagNames(ctx, tagNames, infoNumber)
+	return r.dbRepo.GetInfosByTagNames(ctx, tagNames, attackVectors, infoNumber)
 }
 
-// 指定したタグ名に一致する情報の内、指定した情報より過去から指定の件数取得
-func (r *hackingRepository) GetPrevInfosByTagNames(ctx context.Context, tagNames []string, prevInfoID int64, infoNumber int) ([]*entity.HackingInfo, error) {
+// 指定したタグ名・攻撃手法に一致する情報の内、指定した情報より過去から指定の件数取得
+func (r *hackingRepository) GetPrevInfosByTagNames(ctx context.Context, tagNames []string, attackVectors []string, prevInfoID int64, infoNumber int) ([]*entity.HackingInfo, error) {
 
-	return r.dbRepo.GetPrevInfosByTagNames(ctx, tagNames, prevInfoID, infoNumber)
+	return r.dbRepo.GetPrevInfosByTagNames(ctx, tagNames, attackVectors, prevInfoID, infoNumber)
+}
+
+// IDで指定したハッキング情報を、タグと編集履歴を含めて取得
+func (r *hackingRepository) GetInfoByID(ctx context.Context, id int64) (*entity.HackingInfo, error) {
+
+	return r.dbRepo.GetInfoByID(ctx, id)
 }
 
 // 存在するすべてのタグを取得
@@ -71,12 +77,60 @@ func (r *hackingRepository) SetTagToCache(ctx context.Context) error {
 	return nil
 }
 
+// 再分析の対象となるハッキング情報を指定の件数取得
+func (r *hackingRepository) GetInfosForReanalysis(ctx context.Context, afterID int64, promptVersion string, limit int) ([]*entity.HackingInfo, error) {
+
+	return r.dbRepo.GetInfosForReanalysis(ctx, afterID, promptVersion, limit)
+}
+
+// ハッキング情報の分析結果をトランザクション内で更新
+func (r *hackingRepository) UpdateInfoAnalysis(ctx context.Context, info *entity.HackingInfo, tagNames []string) error {
+
+	return r.dbRepo.UpdateInfoAnalysis(ctx, info, tagNames)
+}
+
+// USD建ての金額が未設定のハッキング情報を指定の件数取得
+func (r *hackingRepository) GetInfosWithoutAmountUSD(ctx context.Context, afterID int64, limit int) ([]*entity.HackingInfo, error) {
+
+	return r.dbRepo.GetInfosWithoutAmountUSD(ctx, afterID, limit)
+}
+
+// ハッキング情報の正規化した金額を更新
+func (r *hackingRepository) UpdateInfoAmount(ctx context.Context, info *entity.HackingInfo) error {
+
+	return r.dbRepo.UpdateInfoAmount(ctx, info)
+}
+
+// 指定したチャンネルの削除されていないハッキング情報を、メッセージIDの新しい順に指定の件数取得
+func (r *hackingRepository) GetRecentInfosByChannel(ctx context.Context, channelUsername string, limit int) ([]*entity.HackingInfo, error) {
+
+	return r.dbRepo.GetRecentInfosByChannel(ctx, channelUsername, limit)
+}
+
+// 投稿の編集に合わせてハッキング情報をトランザクション内で更新
+func (r *hackingRepository) UpdateInfoFromEdit(ctx context.Context, info *entity.HackingInfo, tagNames []string) error {
+
+	return r.dbRepo.UpdateInfoFromEdit(ctx, info, tagNames)
+}
+
+// 投稿の削除に合わせてハッキング情報を論理削除
+func (r *hackingRepository) MarkInfoDeleted(ctx context.Context, id int64) error {
+
+	return r.dbRepo.MarkInfoDeleted(ctx, id)
+}
+
 // 新しいハッキング情報と関連タグをトランザクション内で保存
 func (r *hackingRepository) StoreInfo(ctx context.Context, info *entity.HackingInfo, tagNames []string) (int64, error) {
 
 	return r.dbRepo.StoreInfo(ctx, info, tagNames)
 }
 
+// チャンネル・メッセージIDで指定したハッキング情報のIDを取得
+func (r *hackingRepository) GetInfoIDByMessage(ctx context.Context, channelUsername string, messageID int) (int64, error) {
+
+	return r.dbRepo.GetInfoIDByMessage(ctx, channelUsername, messageID)
+}
+
 // チャンネル情報をトランザクション内で保存
 func (r *hackingRepository) StoreChannelStatus(ctx context.Context, channelStatus *entity.TelegramChannel) error {
 
@@ -94,3 +148,75 @@ func (r *hackingRepository) GetChannelStatusByUsername(ctx context.Context, user
 
 	return r.dbRepo.GetChannelStatusByUsername(ctx, username)
 }
+
+// 処理に失敗した投稿をリトライキューに保存
+func (r *hackingRepository) StoreRetryPost(ctx context.Context, retryPost *entity.RetryPost) error {
+
+	return r.dbRepo.StoreRetryPost(ctx, retryPost)
+}
+
+// 指定したチャンネルの再試行時刻を過ぎた投稿を指定の件数取得
+func (r *hackingRepository) GetDueRetryPosts(ctx context.Context, channelUsername string, now time.Time, limit int) ([]*entity.RetryPost, error) {
+
+	return r.dbRepo.GetDueRetryPosts(ctx, channelUsername, now, limit)
+}
+
+// リトライキューの投稿を新しい順に指定の件数取得
+func (r *hackingRepository) GetRetryPosts(ctx context.Context, status string, limit int) ([]*entity.RetryPost, error) {
+
+	return r.dbRepo.GetRetryPosts(ctx, status, limit)
+}
+
+// IDで指定したリトライキューの投稿を取得
+func (r *hackingRepository) GetRetryPostByID(ctx context.Context, id int64) (*entity.RetryPost, error) {
+
+	return r.dbRepo.GetRetryPostByID(ctx, id)
+}
+
+// リトライキューから投稿を削除
+func (r *hackingRepository) DeleteRetryPost(ctx context.Context, id int64) error {
+
+	return r.dbRepo.DeleteRetryPost(ctx, id)
+}
+
+// 指定したチャンネルのバックフィルの進捗を取得
+func (r *hackingRepository) GetBackfill(ctx context.Context, channelUsername string) (*entity.ChannelBackfill, error) {
+
+	return r.dbRepo.GetBackfill(ctx, channelUsername)
+}
+
+// バックフィルの進捗を保存
+func (r *hackingRepository) StoreBackfill(ctx context.Context, backfill *entity.ChannelBackfill) error {
+
+	return r.dbRepo.StoreBackfill(ctx, backfill)
+}
+
+// 全てのチャンネルのバックフィルの進捗を取得
+func (r *hackingRepository) GetBackfills(ctx context.Context) ([]*entity.ChannelBackfill, error) {
+
+	return r.dbRepo.GetBackfills(ctx)
+}
+
+// パースに失敗した投稿を隔離して保存
+func (r *hackingRepository) StoreQuarantinedPost(ctx context.Context, post *entity.QuarantinedPost) error {
+
+	return r.dbRepo.StoreQuarantinedPost(ctx, post)
+}
+
+// 隔離した投稿を新しい順に指定の件数取得
+func (r *hackingRepository) GetQuarantinedPosts(ctx context.Context, channelUsername string, status string, limit int) ([]*entity.QuarantinedPost, error) {
+
+	return r.dbRepo.GetQuarantinedPosts(ctx, channelUsername, status, limit)
+}
+
+// チャンネル・メッセージIDで指定した隔離した投稿を取得
+func (r *hackingRepository) GetQuarantinedPost(ctx context.Context, channelUsername string, messageID int) (*entity.QuarantinedPost, error) {
+
+	return r.dbRepo.GetQuarantinedPost(ctx, channelUsername, messageID)
+}
+
+// IDで指定した隔離した投稿を取得
+func (r *hackingRepository) GetQuarantinedPostByID(ctx context.Context, id int64) (*entity.QuarantinedPost, error) {
+
+	return r.dbRepo.GetQuarantinedPostByID(ctx, id)
+}
@@ -34,6 +34,42 @@ func (r *transferRepository) GetPrevInfosByTagNames(ctx context.Context, tagName
 	return r.dbRepo.GetPrevInfosByTagNames(ctx, tagNames, prevInfoID, infoNumber)
 }
 
+// IDで指定した送金情報を、タグと編集履歴を含めて取得
+func (r *transferRepository) GetInfoByID(ctx context.Context, id int64) (*entity.TransferInfo, error) {
+
+	return r.dbRepo.GetInfoByID(ctx, id)
+}
+
+// USD建ての金額が未設定の送金情報を指定の件数取得
+func (r *transferRepository) GetInfosWithoutAmountUSD(ctx context.Context, afterID int64, limit int) ([]*entity.TransferInfo, error) {
+
+	return r.dbRepo.GetInfosWithoutAmountUSD(ctx, afterID, limit)
+}
+
+// 送金情報の正規化した金額を更新
+func (r *transferRepository) UpdateInfoAmount(ctx context.Context, info *entity.TransferInfo) error {
+
+	return r.dbRepo.UpdateInfoAmount(ctx, info)
+}
+
+// 指定したチャンネルの削除されていない送金情報を、メッセージIDの新しい順に指定の件数取得
+func (r *transferRepository) GetRecentInfosByChannel(ctx context.Context, channelUsername string, limit int) ([]*entity.TransferInfo, error) {
+
+	return r.dbRepo.GetRecentInfosByChannel(ctx, channelUsername, limit)
+}
+
+// 投稿の編集に合わせて送金情報をトランザクション内で更新
+func (r *transferRepository) UpdateInfoFromEdit(ctx context.Context, info *entity.TransferInfo, tagNames []string) error {
+
+	return r.dbRepo.UpdateInfoFromEdit(ctx, info, tagNames)
+}
+
+// 投稿の削除に合わせて送金情報を論理削除
+func (r *transferRepository) MarkInfoDeleted(ctx context.Context, id int64) error {
+
+	return r.dbRepo.MarkInfoDeleted(ctx, id)
+}
+
 // 存在するすべてのタグを取得
 func (r *transferRepository) GetAllTags(ctx context.Context) ([]*entity.Tag, error) {
 	var tags []*entity.Tag
@@ -94,3 +130,75 @@ func (r *transferRepository) GetChannelStatusByUsername(ctx context.Context, use
 
 	return r.dbRepo.GetChannelStatusByUsername(ctx, username)
 }
+
+// 処理に失敗した投稿をリトライキューに保存
+func (r *transferRepository) StoreRetryPost(ctx context.Context, retryPost *entity.RetryPost) error {
+
+	return r.dbRepo.StoreRetryPost(ctx, retryPost)
+}
+
+// 指定したチャンネルの再試行時刻を過ぎた投稿を指定の件数取得
+func (r *transferRepository) GetDueRetryPosts(ctx context.Context, channelUsername string, now time.Time, limit int) ([]*entity.RetryPost, error) {
+
+	return r.dbRepo.GetDueRetryPosts(ctx, channelUsername, now, limit)
+}
+
+// リトライキューの投稿を新しい順に指定の件数取得
+func (r *transferRepository) GetRetryPosts(ctx context.Context, status string, limit int) ([]*entity.RetryPost, error) {
+
+	return r.dbRepo.GetRetryPosts(ctx, status, limit)
+}
+
+// IDで指定したリトライキューの投稿を取得
+func (r *transferRepository) GetRetryPostByID(ctx context.Context, id int64) (*entity.RetryPost, error) {
+
+	return r.dbRepo.GetRetryPostByID(ctx, id)
+}
+
+// リトライキューから投稿を削除
+func (r *transferRepository) DeleteRetryPost(ctx context.Context, id int64) error {
+
+	return r.dbRepo.DeleteRetryPost(ctx, id)
+}
+
+// 指定したチャンネルのバックフィルの進捗を取得
+func (r *transferRepository) GetBackfill(ctx context.Context, channelUsername string) (*entity.ChannelBackfill, error) {
+
+	return r.dbRepo.GetBackfill(ctx, channelUsername)
+}
+
+// バックフィルの進捗を保存
+func (r *transferRepository) StoreBackfill(ctx context.Context, backfill *entity.ChannelBackfill) error {
+
+	return r.dbRepo.StoreBackfill(ctx, backfill)
+}
+
+// 全てのチャンネルのバックフィルの進捗を取得
+func (r *transferRepository) GetBackfills(ctx context.Context) ([]*entity.ChannelBackfill, error) {
+
+	return r.dbRepo.GetBackfills(ctx)
+}
+
+// パースに失敗した投稿を隔離して保存
+func (r *transferRepository) StoreQuarantinedPost(ctx context.Context, post *entity.QuarantinedPost) error {
+
+	return r.dbRepo.StoreQuarantinedPost(ctx, post)
+}
+
+// 隔離した投稿を新しい順に指定の件数取得
+func (r *transferRepository) GetQuarantinedPosts(ctx context.Context, channelUsername string, status string, limit int) ([]*entity.QuarantinedPost, error) {
+
+	return r.dbRepo.GetQuarantinedPosts(ctx, channelUsername, status, limit)
+}
+
+// チャンネル・メッセージIDで指定した隔離した投稿を取得
+func (r *transferRepository) GetQuarantinedPost(ctx context.Context, channelUsername string, messageID int) (*entity.QuarantinedPost, error) {
+
+	return r.dbRepo.GetQuarantinedPost(ctx, channelUsername, messageID)
+}
+
+// IDで指定した隔離した投稿を取得
+func (r *transferRepository) GetQuarantinedPostByID(ctx context.Context, id int64) (*entity.QuarantinedPost, error) {
+
+	return r.dbRepo.GetQuarantinedPostByID(ctx, id)
+}
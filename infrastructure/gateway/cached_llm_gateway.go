@@ -0,0 +1,171 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+	"github.com/itout-datetoya/hack-info-timeline/domain/repository"
+)
+
+// 分析結果をDBにキャッシュするLLMゲートウェイ
+// 同じプロンプトのバージョン・LLMのプロバイダーとモデル・投稿本文の分析はLLMを呼び出さずにキャッシュから返す
+type cachedLLMGateway struct {
+	llmGateway gateway.LLMGateway
+	cacheRepo  repository.LLMCacheRepository
+}
+
+// LLMゲートウェイをキャッシュでラップ
+func NewCachedLLMGateway(llmGateway gateway.LLMGateway, cacheRepo repository.LLMCacheRepository) gateway.LLMGateway {
+	return &cachedLLMGateway{llmGateway: llmGateway, cacheRepo: cacheRepo}
+}
+
+func (g *cachedLLMGateway) PromptVersion() string {
+	return g.llmGateway.PromptVersion()
+}
+
+func (g *cachedLLMGateway) Provider() string {
+	return g.llmGateway.Provider()
+}
+
+func (g *cachedLLMGateway) Model() string {
+	return g.llmGateway.Model()
+}
+
+func (g *cachedLLMGateway) Stop() error {
+	return g.llmGateway.Stop()
+}
+
+func (g *cachedLLMGateway) AnalyzeAndExtract(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error) {
+	promptVersion := g.llmGateway.PromptVersion()
+	key := analysisCacheKey(promptVersion, g.llmGateway.Provider(), g.llmGateway.Model(), post.Text)
+
+	// キャッシュの取得に失敗した場合はLLMで分析
+	if !gateway.IsAnalysisCacheBypassed(ctx) {
+		analysisCache, err := g.cacheRepo.GetAnalysisCache(ctx, key)
+		if err != nil {
+			log.Printf("Failed to get analysis cache: %v", err)
+		}
+		if analysisCache != nil {
+			var extractedInfo gateway.ExtractedHackingInfo
+			if err := json.Unmarshal([]byte(analysisCache.Result), &extractedInfo); err == nil {
+				// 投稿から取得する情報は現在の投稿の値を使用
+				extractedInfo.Network = post.Network
+				extractedInfo.Amount = post.Amount
+				extractedInfo.TxHash = post.TxHash
+				return &extractedInfo, nil
+			}
+			log.Printf("Failed to unmarshal analysis cache %s: %v", key, err)
+		}
+	}
+
+	extractedInfo, err := g.llmGateway.AnalyzeAndExtract(ctx, post)
+	if err != nil {
+		return nil, err
+	}
+
+	// キャッシュの保存に失敗しても分析結果は返す
+	result, err := json.Marshal(extractedInfo)
+	if err != nil {
+		log.Printf("Failed to marshal analysis cache: %v", err)
+		return extractedInfo, nil
+	}
+	if err := g.cacheRepo.StoreAnalysisCache(ctx, &entity.LLMAnalysisCache{
+		Key:           key,
+		PromptVersion: promptVersion,
+		Result:        string(result),
+	}); err != nil {
+		log.Printf("Failed to store analysis cache: %v", err)
+	}
+
+	return extractedInfo, nil
+}
+
+// プロンプトのバージョン・LLMのプロバイダーとモデル・投稿本文からキャッシュのキーを生成
+// モデルを変更した場合に、以前のモデルの分析結果を返さないようにする
+func analysisCacheKey(promptVersion, provider, model, text string) string {
+	hash := sha256.Sum256([]byte(promptVersion + "\x00" + provider + "\x00" + model + "\x00" + text))
+	return hex.EncodeToString(hash[:])
+}
+
+// キャッシュする抽出結果
+type cachedPostExtraction[T any] struct {
+	Post       *T
+	Confidence float64
+}
+
+func (g *cachedLLMGateway) ExtractHackingPost(ctx context.Context, post *gateway.RejectedPost) (*gateway.HackingPost, float64, error) {
+	extracted, confidence, err := cachedExtract(ctx, g.cacheRepo, g.llmGateway, HackingPostExtractionPromptVersion, post, g.llmGateway.ExtractHackingPost)
+	if err != nil {
+		return nil, 0, err
+	}
+	// 投稿から取得する情報は現在の投稿の値を使用
+	extracted.Text = post.Text
+	extracted.ReplyToText = post.ReplyToText
+	extracted.ReportTime = post.ReportTime
+	extracted.MessageID = post.MessageID
+	extracted.ChannelUsername = post.ChannelUsername
+	return extracted, confidence, nil
+}
+
+func (g *cachedLLMGateway) ExtractTransferPost(ctx context.Context, post *gateway.RejectedPost) (*gateway.TransferPost, float64, error) {
+	extracted, confidence, err := cachedExtract(ctx, g.cacheRepo, g.llmGateway, TransferPostExtractionPromptVersion, post, g.llmGateway.ExtractTransferPost)
+	if err != nil {
+		return nil, 0, err
+	}
+	// 投稿から取得する情報は現在の投稿の値を使用
+	extracted.Text = post.Text
+	extracted.ReportTime = post.ReportTime
+	extracted.MessageID = post.MessageID
+	extracted.ChannelUsername = post.ChannelUsername
+	return extracted, confidence, nil
+}
+
+// 抽出結果をキャッシュから取得し、存在しない場合は extract で抽出してキャッシュに保存
+// 抽出できなかった投稿はキャッシュしない
+func cachedExtract[T any](ctx context.Context, cacheRepo repository.LLMCacheRepository, llmGateway gateway.LLMGateway, promptVersion string, post *gateway.RejectedPost,
+	extract func(ctx context.Context, post *gateway.RejectedPost) (*T, float64, error)) (*T, float64, error) {
+	key := analysisCacheKey(promptVersion, llmGateway.Provider(), llmGateway.Model(), post.Text+"\x00"+post.ReplyToText)
+
+	// キャッシュの取得に失敗した場合はLLMで抽出
+	if !gateway.IsAnalysisCacheBypassed(ctx) {
+		analysisCache, err := cacheRepo.GetAnalysisCache(ctx, key)
+		if err != nil {
+			log.Printf("Failed to get analysis cache: %v", err)
+		}
+		if analysisCache != nil {
+			// 抽出結果を含まないキャッシュは、キャッシュがない場合と同じくLLMで抽出
+			var cached cachedPostExtraction[T]
+			if err := json.Unmarshal([]byte(analysisCache.Result), &cached); err != nil {
+				log.Printf("Failed to unmarshal analysis cache %s: %v", key, err)
+			} else if cached.Post != nil {
+				return cached.Post, cached.Confidence, nil
+			}
+		}
+	}
+
+	extracted, confidence, err := extract(ctx, post)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// キャッシュの保存に失敗しても抽出結果は返す
+	result, err := json.Marshal(cachedPostExtraction[T]{Post: extracted, Confidence: confidence})
+	if err != nil {
+		log.Printf("Failed to marshal analysis cache: %v", err)
+		return extracted, confidence, nil
+	}
+	if err := cacheRepo.StoreAnalysisCache(ctx, &entity.LLMAnalysisCache{
+		Key:           key,
+		PromptVersion: promptVersion,
+		Result:        string(result),
+	}); err != nil {
+		log.Printf("Failed to store analysis cache: %v", err)
+	}
+
+	return extracted, confidence, nil
+}
@@ -0,0 +1,241 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+// fakeLLMCacheRepository はメモリ上でキャッシュを保持する LLMCacheRepository の実装
+type fakeLLMCacheRepository struct {
+	caches   map[string]*entity.LLMAnalysisCache
+	getError error
+}
+
+func (r *fakeLLMCacheRepository) GetAnalysisCache(ctx context.Context, key string) (*entity.LLMAnalysisCache, error) {
+	if r.getError != nil {
+		return nil, r.getError
+	}
+	return r.caches[key], nil
+}
+
+func (r *fakeLLMCacheRepository) StoreAnalysisCache(ctx context.Context, analysisCache *entity.LLMAnalysisCache) error {
+	r.caches[analysisCache.Key] = analysisCache
+	return nil
+}
+
+func (r *fakeLLMCacheRepository) DeleteAnalysisCaches(ctx context.Context, promptVersion string) (int64, error) {
+	var deleted int64
+	for key, analysisCache := range r.caches {
+		if promptVersion == "" || analysisCache.PromptVersion == promptVersion {
+			delete(r.caches, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// countingLLMGateway は呼び出し回数を記録する LLMGateway の実装
+type countingLLMGateway struct {
+	promptVersion string
+	model         string
+	calls         int
+}
+
+func (g *countingLLMGateway) AnalyzeAndExtract(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error) {
+	g.calls++
+	return &gateway.ExtractedHackingInfo{
+		Protocol:     "Resupply.fi",
+		Network:      post.Network,
+		Amount:       post.Amount,
+		TxHash:       post.TxHash,
+		TagNames:     []string{"wstUSR", "resupply"},
+		AttackVector: entity.AttackVectorFirstDeposit,
+	}, nil
+}
+
+func (g *countingLLMGateway) ExtractHackingPost(ctx context.Context, post *gateway.RejectedPost) (*gateway.HackingPost, float64, error) {
+	g.calls++
+	return &gateway.HackingPost{
+		Text:            post.Text,
+		ReplyToText:     post.ReplyToText,
+		Network:         "BSC",
+		Amount:          "$1,200,000",
+		MessageID:       post.MessageID,
+		ChannelUsername: post.ChannelUsername,
+	}, 0.8, nil
+}
+
+func (g *countingLLMGateway) ExtractTransferPost(ctx context.Context, post *gateway.RejectedPost) (*gateway.TransferPost, float64, error) {
+	g.calls++
+	return nil, 0, gateway.ErrPostNotExtracted
+}
+
+func (g *countingLLMGateway) PromptVersion() string {
+	return g.promptVersion
+}
+
+func (g *countingLLMGateway) Provider() string {
+	return LLMProviderGemini
+}
+
+func (g *countingLLMGateway) Model() string {
+	return g.model
+}
+
+func (g *countingLLMGateway) Stop() error {
+	return nil
+}
+
+func TestCachedLLMGateway(t *testing.T) {
+	ctx := context.Background()
+	post := &gateway.HackingPost{Text: "Attack on Resupply.fi", Network: "mainnet", Amount: "$9.3M", TxHash: "0xabc123"}
+
+	t.Run("identical text is served from cache", func(t *testing.T) {
+		llm := &countingLLMGateway{promptVersion: "v1"}
+		cacheRepo := &fakeLLMCacheRepository{caches: map[string]*entity.LLMAnalysisCache{}}
+		cached := NewCachedLLMGateway(llm, cacheRepo)
+
+		if _, err := cached.AnalyzeAndExtract(ctx, post); err != nil {
+			t.Fatal(err)
+		}
+		// 同じ本文で別のトランザクションの投稿
+		otherPost := *post
+		otherPost.TxHash = "0xdef456"
+		extractedInfo, err := cached.AnalyzeAndExtract(ctx, &otherPost)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if llm.calls != 1 {
+			t.Errorf("LLM calls = %d, want 1", llm.calls)
+		}
+		if extractedInfo.Protocol != "Resupply.fi" || extractedInfo.AttackVector != entity.AttackVectorFirstDeposit {
+			t.Errorf("cached extractedInfo = %+v", extractedInfo)
+		}
+		if extractedInfo.TxHash != "0xdef456" {
+			t.Errorf("TxHash = %s, want value from current post", extractedInfo.TxHash)
+		}
+	})
+
+	t.Run("prompt version change misses cache", func(t *testing.T) {
+		cacheRepo := &fakeLLMCacheRepository{caches: map[string]*entity.LLMAnalysisCache{}}
+		if _, err := NewCachedLLMGateway(&countingLLMGateway{promptVersion: "v1"}, cacheRepo).AnalyzeAndExtract(ctx, post); err != nil {
+			t.Fatal(err)
+		}
+
+		llm := &countingLLMGateway{promptVersion: "v2"}
+		if _, err := NewCachedLLMGateway(llm, cacheRepo).AnalyzeAndExtract(ctx, post); err != nil {
+			t.Fatal(err)
+		}
+		if llm.calls != 1 {
+			t.Errorf("LLM calls = %d, want 1", llm.calls)
+		}
+		if len(cacheRepo.caches) != 2 {
+			t.Errorf("cache entries = %d, want 2", len(cacheRepo.caches))
+		}
+	})
+
+	t.Run("model change misses cache", func(t *testing.T) {
+		cacheRepo := &fakeLLMCacheRepository{caches: map[string]*entity.LLMAnalysisCache{}}
+		if _, err := NewCachedLLMGateway(&countingLLMGateway{promptVersion: "v1", model: "gemini-2.5-flash-lite"}, cacheRepo).AnalyzeAndExtract(ctx, post); err != nil {
+			t.Fatal(err)
+		}
+
+		// プロンプトのバージョンが同じでも、モデルを変更した場合はLLMで分析
+		llm := &countingLLMGateway{promptVersion: "v1", model: "gemini-2.5-flash"}
+		if _, err := NewCachedLLMGateway(llm, cacheRepo).AnalyzeAndExtract(ctx, post); err != nil {
+			t.Fatal(err)
+		}
+		if llm.calls != 1 {
+			t.Errorf("LLM calls = %d, want 1", llm.calls)
+		}
+		if len(cacheRepo.caches) != 2 {
+			t.Errorf("cache entries = %d, want 2", len(cacheRepo.caches))
+		}
+	})
+
+	t.Run("bypass skips cache but refreshes it", func(t *testing.T) {
+		llm := &countingLLMGateway{promptVersion: "v1"}
+		cacheRepo := &fakeLLMCacheRepository{caches: map[string]*entity.LLMAnalysisCache{}}
+		cached := NewCachedLLMGateway(llm, cacheRepo)
+
+		if _, err := cached.AnalyzeAndExtract(ctx, post); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cached.AnalyzeAndExtract(gateway.WithAnalysisCacheBypass(ctx), post); err != nil {
+			t.Fatal(err)
+		}
+		if llm.calls != 2 {
+			t.Errorf("LLM calls = %d, want 2", llm.calls)
+		}
+		if len(cacheRepo.caches) != 1 {
+			t.Errorf("cache entries = %d, want 1", len(cacheRepo.caches))
+		}
+	})
+
+	t.Run("cache read error falls back to LLM", func(t *testing.T) {
+		llm := &countingLLMGateway{promptVersion: "v1"}
+		cacheRepo := &fakeLLMCacheRepository{caches: map[string]*entity.LLMAnalysisCache{}, getError: errors.New("connection refused")}
+
+		if _, err := NewCachedLLMGateway(llm, cacheRepo).AnalyzeAndExtract(ctx, post); err != nil {
+			t.Fatalf("AnalyzeAndExtract() error = %v, want nil", err)
+		}
+		if llm.calls != 1 {
+			t.Errorf("LLM calls = %d, want 1", llm.calls)
+		}
+	})
+}
+
+func TestCachedLLMGateway_ExtractPost(t *testing.T) {
+	ctx := context.Background()
+	llm := &countingLLMGateway{promptVersion: "hacking-v1"}
+	cacheRepo := &fakeLLMCacheRepository{caches: make(map[string]*entity.LLMAnalysisCache)}
+	cached := NewCachedLLMGateway(llm, cacheRepo)
+
+	rejected := &gateway.RejectedPost{ChannelUsername: "hackchannel", MessageID: 31, Text: "Onyx Protocol", ReplyToText: "Exploit on BSC, $1.2M lost"}
+	if _, _, err := cached.ExtractHackingPost(ctx, rejected); err != nil {
+		t.Fatal(err)
+	}
+
+	// 同じ本文の投稿はキャッシュから返し、投稿から取得する情報は現在の投稿の値を使用
+	again := *rejected
+	again.MessageID = 32
+	post, confidence, err := cached.ExtractHackingPost(ctx, &again)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if llm.calls != 1 {
+		t.Errorf("calls = %d, want 1", llm.calls)
+	}
+	if post.Amount != "$1,200,000" || post.Network != "BSC" || post.MessageID != 32 || confidence != 0.8 {
+		t.Errorf("post = %+v, confidence = %v", post, confidence)
+	}
+	if cacheRepo.caches[analysisCacheKey(HackingPostExtractionPromptVersion, LLMProviderGemini, "", "Onyx Protocol\x00Exploit on BSC, $1.2M lost")] == nil {
+		t.Error("extraction is not cached with the extraction prompt version")
+	}
+
+	// 抽出できなかった投稿はキャッシュしない
+	for range 2 {
+		if _, _, err := cached.ExtractTransferPost(ctx, &gateway.RejectedPost{Text: "gm"}); !errors.Is(err, gateway.ErrPostNotExtracted) {
+			t.Errorf("err = %v, want ErrPostNotExtracted", err)
+		}
+	}
+	if llm.calls != 3 {
+		t.Errorf("calls = %d, want 3", llm.calls)
+	}
+
+	// 抽出結果を含まないキャッシュはLLMで抽出し直す
+	key := analysisCacheKey(HackingPostExtractionPromptVersion, LLMProviderGemini, "", "Onyx Protocol\x00Exploit on BSC, $1.2M lost")
+	cacheRepo.caches[key] = &entity.LLMAnalysisCache{Key: key, PromptVersion: HackingPostExtractionPromptVersion, Result: `{"Post":null,"Confidence":0}`}
+	post, _, err = cached.ExtractHackingPost(ctx, rejected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if llm.calls != 4 || post.Network != "BSC" {
+		t.Errorf("calls = %d, post = %+v, want extraction by LLM", llm.calls, post)
+	}
+}
@@ -0,0 +1,120 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/gotd/td/telegram/auth"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+// チャンネルの取得に使用するTelegramアカウントを選択
+// TelegramClientManager（単一のアカウント）と TelegramClientPool（複数のアカウント）が実装
+type TelegramClientSelector interface {
+	// チャンネルの取得に使用するアカウントを優先順に返す
+	// 先頭はチャンネルに割り当てられたアカウント
+	accountsFor(channelUsername string) []*TelegramClientManager
+}
+
+// チャンネルの取得に使用するアカウントと、アカウント毎の解決済みのチャンネル情報
+// アクセスハッシュはアカウント毎に異なるため、フェイルオーバー先のアカウントでは別に解決する
+type channelClients struct {
+	selector TelegramClientSelector
+	username string
+
+	mu    sync.Mutex
+	peers map[string]*channelPeerCache
+}
+
+func newChannelClients(selector TelegramClientSelector, username string) *channelClients {
+	return &channelClients{
+		selector: selector,
+		username: username,
+		peers:    make(map[string]*channelPeerCache),
+	}
+}
+
+// アカウントで解決したチャンネル情報
+func (c *channelClients) peerOf(account string) *channelPeerCache {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	peer, ok := c.peers[account]
+	if !ok {
+		peer = &channelPeerCache{username: c.username}
+		c.peers[account] = peer
+	}
+	return peer
+}
+
+// チャンネルに割り当てられたアカウントで解決したチャンネル情報（DBに保存する）
+func (c *channelClients) primaryPeer() *channelPeerCache {
+	return c.peerOf(c.selector.accountsFor(c.username)[0].name)
+}
+
+// 解決済みのチャンネル情報で fn を実行
+// 割り当てられたアカウントが未接続・FLOOD_WAIT の待機中の場合は、次のアカウントで再実行
+func (c *channelClients) withChannel(ctx context.Context, fn func(api *tg.Client, channel *tg.InputChannel) error) error {
+	_, err := c.failover(func(m *TelegramClientManager, api *tg.Client) error {
+		return c.peerOf(m.name).withChannel(ctx, api, func(channel *tg.InputChannel) error {
+			return fn(api, channel)
+		})
+	})
+	return err
+}
+
+// チャンネル名を解決し、解決に使用したアカウントとチャンネル情報を返す
+// 割り当てられたアカウントが使用できない場合は、次のアカウントで解決
+func (c *channelClients) resolve(ctx context.Context) (*TelegramClientManager, *tg.InputChannel, error) {
+	var channel *tg.InputChannel
+	m, err := c.failover(func(m *TelegramClientManager, api *tg.Client) error {
+		var err error
+		channel, err = c.peerOf(m.name).resolve(ctx, api)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, channel, nil
+}
+
+// 優先順にアカウントで fn を実行し、成功したアカウントを返す
+func (c *channelClients) failover(fn func(m *TelegramClientManager, api *tg.Client) error) (*TelegramClientManager, error) {
+	accounts := c.selector.accountsFor(c.username)
+
+	var errs []error
+	for i, m := range accounts {
+		api, err := m.readyAPI()
+		if err == nil {
+			err = fn(m, api)
+		}
+		if err == nil {
+			if i > 0 {
+				telegramFailovers.Add(m.name, 1)
+			}
+			return m, nil
+		}
+		if len(accounts) == 1 || !shouldFailover(err) {
+			return nil, err
+		}
+
+		errs = append(errs, fmt.Errorf("account %s: %w", m.name, err))
+		if i+1 < len(accounts) {
+			log.Printf("Telegram account %s is unavailable for %s, failing over to %s: %v", m.name, c.username, accounts[i+1].name, err)
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// 他のアカウントで再実行すべきエラーか
+// 未接続・未認証・FLOOD_WAIT の場合は、アカウントを切り替えれば呼び出せる
+func shouldFailover(err error) bool {
+	if errors.Is(err, ErrTelegramNotReady) || errors.Is(err, ErrFloodWait) || auth.IsUnauthorized(err) {
+		return true
+	}
+	_, ok := tgerr.AsFloodWait(err)
+	return ok
+}
@@ -0,0 +1,99 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+// 解決済みのチャンネル情報を保持し、チャンネル名の解決を必要な場合のみ行う
+// ContactsResolveUsername は呼び出し回数の制限が厳しく、FLOOD_WAIT の原因になるため
+type channelPeerCache struct {
+	username string
+	mu       sync.Mutex
+	peer     *gateway.ChannelPeer
+}
+
+func (c *channelPeerCache) get() *gateway.ChannelPeer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.peer == nil {
+		return nil
+	}
+	peer := *c.peer
+	return &peer
+}
+
+func (c *channelPeerCache) set(peer *gateway.ChannelPeer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if peer == nil {
+		c.peer = nil
+		return
+	}
+	p := *peer
+	c.peer = &p
+}
+
+// 解決済みのチャンネル情報を取得
+// 未解決の場合はチャンネル名を解決して保持
+func (c *channelPeerCache) resolve(ctx context.Context, api *tg.Client) (*tg.InputChannel, error) {
+	if peer := c.get(); peer != nil {
+		return &tg.InputChannel{ChannelID: peer.ID, AccessHash: peer.AccessHash}, nil
+	}
+
+	resolved, err := api.ContactsResolveUsername(ctx, &tg.ContactsResolveUsernameRequest{
+		Username: c.username,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve username %s: %w", c.username, err)
+	}
+	if len(resolved.Chats) == 0 {
+		return nil, fmt.Errorf("resolved peer of %s is not found", c.username)
+	}
+	channel, ok := resolved.Chats[0].(*tg.Channel)
+	if !ok {
+		return nil, fmt.Errorf("resolved peer is not a channel")
+	}
+
+	input := channel.AsInput()
+	c.set(&gateway.ChannelPeer{ID: input.ChannelID, AccessHash: input.AccessHash, Title: channel.Title})
+	return input, nil
+}
+
+// 解決済みのチャンネル情報で fn を実行
+// 保持していたチャンネル情報が無効な場合は、チャンネル名を解決し直して一度だけ再実行
+func (c *channelPeerCache) withChannel(ctx context.Context, api *tg.Client, fn func(channel *tg.InputChannel) error) error {
+	channel, err := c.resolve(ctx, api)
+	if err != nil {
+		return err
+	}
+
+	err = fn(channel)
+	if !isInvalidPeerError(err) {
+		return err
+	}
+
+	log.Printf("Cached peer of %s is invalid, resolving again: %v", c.username, err)
+	c.set(nil)
+	channel, err = c.resolve(ctx, api)
+	if err != nil {
+		return err
+	}
+	return fn(channel)
+}
+
+// 保持していたチャンネル情報が無効になった場合のエラーか
+func isInvalidPeerError(err error) bool {
+	return tgerr.Is(err, tg.ErrChannelInvalid, tg.ErrPeerIDInvalid)
+}
+
+func inputPeer(channel *tg.InputChannel) *tg.InputPeerChannel {
+	return &tg.InputPeerChannel{ChannelID: channel.ChannelID, AccessHash: channel.AccessHash}
+}
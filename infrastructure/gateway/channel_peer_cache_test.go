@@ -0,0 +1,115 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+// チャンネル名の解決のみに応答するInvoker
+type resolveUsernameInvoker struct {
+	channel  *tg.Channel
+	resolved int
+}
+
+func (i *resolveUsernameInvoker) Invoke(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+	out, ok := output.(*tg.ContactsResolvedPeer)
+	if !ok {
+		return tgerr.New(400, "METHOD_INVALID")
+	}
+	i.resolved++
+	*out = tg.ContactsResolvedPeer{
+		Peer:  &tg.PeerChannel{ChannelID: i.channel.ID},
+		Chats: []tg.ChatClass{i.channel},
+	}
+	return nil
+}
+
+func TestChannelPeerCache(t *testing.T) {
+	ctx := context.Background()
+	channel := &tg.Channel{ID: 1001, Title: "Alerts"}
+	channel.SetAccessHash(2002)
+	invoker := &resolveUsernameInvoker{channel: channel}
+	api := tg.NewClient(invoker)
+
+	t.Run("resolve once and reuse", func(t *testing.T) {
+		invoker.resolved = 0
+		cache := &channelPeerCache{username: "alerts"}
+		for i := 0; i < 3; i++ {
+			channel, err := cache.resolve(ctx, api)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if channel.ChannelID != 1001 || channel.AccessHash != 2002 {
+				t.Errorf("channel = %+v", channel)
+			}
+		}
+		if invoker.resolved != 1 {
+			t.Errorf("resolved = %d, want 1", invoker.resolved)
+		}
+		if peer := cache.get(); peer == nil || peer.Title != "Alerts" {
+			t.Errorf("peer = %+v", peer)
+		}
+	})
+
+	t.Run("use stored peer without resolving", func(t *testing.T) {
+		invoker.resolved = 0
+		cache := &channelPeerCache{username: "alerts"}
+		cache.set(&gateway.ChannelPeer{ID: 1001, AccessHash: 2002, Title: "Alerts"})
+
+		err := cache.withChannel(ctx, api, func(channel *tg.InputChannel) error {
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if invoker.resolved != 0 {
+			t.Errorf("resolved = %d, want 0", invoker.resolved)
+		}
+	})
+
+	t.Run("resolve again on invalid peer", func(t *testing.T) {
+		invoker.resolved = 0
+		cache := &channelPeerCache{username: "alerts"}
+		cache.set(&gateway.ChannelPeer{ID: 1001, AccessHash: 9999, Title: "Alerts"})
+
+		var accessHashes []int64
+		err := cache.withChannel(ctx, api, func(channel *tg.InputChannel) error {
+			accessHashes = append(accessHashes, channel.AccessHash)
+			if channel.AccessHash != 2002 {
+				return tgerr.New(400, tg.ErrChannelInvalid)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if invoker.resolved != 1 || len(accessHashes) != 2 || accessHashes[1] != 2002 {
+			t.Errorf("resolved = %d, access hashes = %v", invoker.resolved, accessHashes)
+		}
+		if peer := cache.get(); peer.AccessHash != 2002 {
+			t.Errorf("cached access hash = %d, want 2002", peer.AccessHash)
+		}
+	})
+
+	t.Run("other errors are returned without resolving", func(t *testing.T) {
+		invoker.resolved = 0
+		cache := &channelPeerCache{username: "alerts"}
+		cache.set(&gateway.ChannelPeer{ID: 1001, AccessHash: 2002})
+
+		err := cache.withChannel(ctx, api, func(channel *tg.InputChannel) error {
+			return tgerr.New(420, "FLOOD_WAIT_30")
+		})
+		if !tgerr.Is(err, "FLOOD_WAIT") {
+			t.Errorf("err = %v, want FLOOD_WAIT", err)
+		}
+		if invoker.resolved != 0 {
+			t.Errorf("resolved = %d, want 0", invoker.resolved)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+// Geminiのデフォルトモデル
+const defaultGeminiModel = "gemini-2.5-flash-lite"
+
+type geminiClient struct {
+	client *genai.Client
+	model  *genai.GenerativeModel
+}
+
+// genai ライブラリを使ってクライアントを初期化
+func newGeminiClient(ctx context.Context, cfg LLMConfig) (*geminiClient, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("gemini API key is missing")
+	}
+	client, err := genai.NewClient(ctx, option.WithAPIKey(cfg.APIKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create genai client: %w", err)
+	}
+
+	// 使用するモデルを指定
+	model := client.GenerativeModel(cfg.Model)
+	if cfg.Temperature != nil {
+		model.SetTemperature(*cfg.Temperature)
+	}
+
+	return &geminiClient{client: client, model: model}, nil
+}
+
+func (c *geminiClient) Close() error {
+	return c.client.Close()
+}
+
+func (c *geminiClient) GenerateJSON(ctx context.Context, prompt string, schema *llmSchema) (string, error) {
+	// JSONモードでスキーマを指定
+	// モデルは複数のgoroutineから共有されるため、呼び出し毎に設定をコピー
+	model := *c.model
+	model.ResponseMIMEType = "application/json"
+	model.ResponseSchema = toGenaiSchema(schema)
+
+	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate content from Gemini API: %w", err)
+	}
+
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return "", fmt.Errorf("invalid response structure from Gemini API")
+	}
+
+	var text strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		partText, ok := part.(genai.Text)
+		if !ok {
+			return "", fmt.Errorf("unexpected response part type: %T", part)
+		}
+		text.WriteString(string(partText))
+	}
+	return text.String(), nil
+}
+
+// 共通のスキーマ定義を genai.Schema に変換
+func toGenaiSchema(schema *llmSchema) *genai.Schema {
+	if schema == nil {
+		return nil
+	}
+
+	genaiSchema := &genai.Schema{
+		Description: schema.Description,
+		Required:    schema.Required,
+		Enum:        schema.Enum,
+		Items:       toGenaiSchema(schema.Items),
+	}
+	if len(schema.Enum) > 0 {
+		genaiSchema.Format = "enum"
+	}
+
+	switch schema.Type {
+	case "object":
+		genaiSchema.Type = genai.TypeObject
+	case "array":
+		genaiSchema.Type = genai.TypeArray
+	case "number":
+		genaiSchema.Type = genai.TypeNumber
+	case "integer":
+		genaiSchema.Type = genai.TypeInteger
+	case "boolean":
+		genaiSchema.Type = genai.TypeBoolean
+	default:
+		genaiSchema.Type = genai.TypeString
+	}
+
+	if len(schema.Properties) > 0 {
+		genaiSchema.Properties = make(map[string]*genai.Schema, len(schema.Properties))
+		for name, property := range schema.Properties {
+			genaiSchema.Properties[name] = toGenaiSchema(property)
+		}
+	}
+	return genaiSchema
+}
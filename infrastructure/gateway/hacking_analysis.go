@@ -0,0 +1,138 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+// LLMの応答がスキーマに違反している場合のエラー
+var ErrInvalidAnalysis = errors.New("invalid analysis response")
+
+// プロトコル名が特定できない場合の値
+const notAvailable = "N/A"
+
+// プロバイダー共通の応答スキーマ定義
+// OpenAI互換APIにはそのままJSON Schemaとして送信し、Geminiには genai.Schema に変換して送信
+type llmSchema struct {
+	Type                 string                `json:"type"`
+	Description          string                `json:"description,omitempty"`
+	Properties           map[string]*llmSchema `json:"properties,omitempty"`
+	Required             []string              `json:"required,omitempty"`
+	Items                *llmSchema            `json:"items,omitempty"`
+	Enum                 []string              `json:"enum,omitempty"`
+	AdditionalProperties *bool                 `json:"additionalProperties,omitempty"`
+}
+
+var noAdditionalProperties = false
+
+// ハッキング情報の分析結果のスキーマ
+var hackingAnalysisSchema = &llmSchema{
+	Type: "object",
+	Properties: map[string]*llmSchema{
+		"protocol_name": {
+			Type:        "string",
+			Description: "Name of the hacked protocol exactly as it appears in the text, or N/A",
+		},
+		"normalized_protocol_name": {
+			Type:        "string",
+			Description: "Lowercased protocol name without generic suffixes and domain extensions, or N/A",
+		},
+		"tokens": {
+			Type:        "array",
+			Description: "Ticker symbols of the tokens involved in the exploit",
+			Items:       &llmSchema{Type: "string"},
+		},
+		"attack_vector": {
+			Type:        "string",
+			Description: "Classification of the attack technique",
+			Enum:        entity.AttackVectors,
+		},
+		"confidence": {
+			Type:        "number",
+			Description: "Confidence in the extracted protocol name between 0 and 1",
+		},
+	},
+	Required:             []string{"protocol_name", "normalized_protocol_name", "tokens", "attack_vector", "confidence"},
+	AdditionalProperties: &noAdditionalProperties,
+}
+
+// ハッキング情報の分析結果
+type hackingAnalysis struct {
+	ProtocolName           string   `json:"protocol_name"`
+	NormalizedProtocolName string   `json:"normalized_protocol_name"`
+	Tokens                 []string `json:"tokens"`
+	AttackVector           string   `json:"attack_vector"`
+	Confidence             float64  `json:"confidence"`
+}
+
+// LLMの応答をパースし、スキーマに従っているか検証
+func parseHackingAnalysis(resp string) (*hackingAnalysis, error) {
+	// 必須フィールドの存在確認
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(resp), &fields); err != nil {
+		return nil, fmt.Errorf("%w: response is not a JSON object: %v", ErrInvalidAnalysis, err)
+	}
+	for _, name := range hackingAnalysisSchema.Required {
+		if _, ok := fields[name]; !ok {
+			return nil, fmt.Errorf("%w: missing required field %q", ErrInvalidAnalysis, name)
+		}
+	}
+
+	var analysis hackingAnalysis
+	decoder := json.NewDecoder(bytes.NewReader([]byte(resp)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&analysis); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysis, err)
+	}
+
+	if strings.TrimSpace(analysis.ProtocolName) == "" {
+		return nil, fmt.Errorf("%w: protocol_name must not be empty", ErrInvalidAnalysis)
+	}
+	if !entity.IsAttackVector(analysis.AttackVector) {
+		return nil, fmt.Errorf("%w: attack_vector must be one of %s, got %q", ErrInvalidAnalysis, strings.Join(entity.AttackVectors, ", "), analysis.AttackVector)
+	}
+	if analysis.Confidence < 0 || analysis.Confidence > 1 {
+		return nil, fmt.Errorf("%w: confidence must be between 0 and 1, got %v", ErrInvalidAnalysis, analysis.Confidence)
+	}
+
+	return &analysis, nil
+}
+
+// 分析結果を抽出されたハッキング情報に変換
+func (a *hackingAnalysis) toExtractedInfo(post *gateway.HackingPost) *gateway.ExtractedHackingInfo {
+	protocol := strings.TrimSpace(a.ProtocolName)
+	normalizedProtocol := strings.TrimSpace(a.NormalizedProtocolName)
+	if protocol == notAvailable || normalizedProtocol == "" || normalizedProtocol == notAvailable {
+		protocol = notAvailable
+		normalizedProtocol = "Protocol:N/A"
+	}
+
+	var tokens []string
+	for _, token := range a.Tokens {
+		token = strings.TrimSpace(token)
+		if token == "" || token == notAvailable {
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	tagNames := append([]string{}, tokens...)
+	// 表記ゆれ防止のため小文字化
+	tagNames = append(tagNames, strings.ToLower(normalizedProtocol))
+
+	return &gateway.ExtractedHackingInfo{
+		Protocol:     protocol,
+		Network:      post.Network,
+		Amount:       post.Amount,
+		TxHash:       post.TxHash,
+		TagNames:     tagNames,
+		Tokens:       tokens,
+		AttackVector: a.AttackVector,
+		Confidence:   a.Confidence,
+	}
+}
@@ -0,0 +1,94 @@
+package gateway
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+func TestParseHackingAnalysis(t *testing.T) {
+	post := &gateway.HackingPost{
+		Network: "mainnet",
+		Amount:  "$4,204.55",
+		TxHash:  "0xabc123",
+	}
+
+	tests := []struct {
+		name         string
+		resp         string
+		wantErr      bool
+		wantProtocol string
+		wantTagNames []string
+	}{
+		{
+			name:         "valid response",
+			resp:         `{"protocol_name":"Asymmetry Finance","normalized_protocol_name":"asymmetry","tokens":["sUSDe","scrvUSD"],"attack_vector":"oracle_manipulation","confidence":0.8}`,
+			wantProtocol: "Asymmetry Finance",
+			wantTagNames: []string{"sUSDe", "scrvUSD", "asymmetry"},
+		},
+		{
+			name:         "protocol not found",
+			resp:         `{"protocol_name":"N/A","normalized_protocol_name":"N/A","tokens":["N/A"],"attack_vector":"other","confidence":0.1}`,
+			wantProtocol: "N/A",
+			wantTagNames: []string{"protocol:n/a"},
+		},
+		{
+			name:    "not a JSON object",
+			resp:    "Asymmetry Finance,asymmetry",
+			wantErr: true,
+		},
+		{
+			name:    "missing required field",
+			resp:    `{"protocol_name":"Asymmetry Finance","normalized_protocol_name":"asymmetry","tokens":[],"attack_vector":"other"}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			resp:    `{"protocol_name":"Asymmetry Finance","normalized_protocol_name":"asymmetry","tokens":[],"attack_vector":"other","confidence":0.5,"chain":"eth"}`,
+			wantErr: true,
+		},
+		{
+			name:    "wrong field type",
+			resp:    `{"protocol_name":"Asymmetry Finance","normalized_protocol_name":"asymmetry","tokens":"sUSDe","attack_vector":"other","confidence":0.5}`,
+			wantErr: true,
+		},
+		{
+			name:    "attack vector out of vocabulary",
+			resp:    `{"protocol_name":"Asymmetry Finance","normalized_protocol_name":"asymmetry","tokens":[],"attack_vector":"oracle manipulation","confidence":0.5}`,
+			wantErr: true,
+		},
+		{
+			name:    "confidence out of range",
+			resp:    `{"protocol_name":"Asymmetry Finance","normalized_protocol_name":"asymmetry","tokens":[],"attack_vector":"other","confidence":1.5}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis, err := parseHackingAnalysis(tt.resp)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidAnalysis) {
+					t.Errorf("parseHackingAnalysis() error = %v, want ErrInvalidAnalysis", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseHackingAnalysis() unexpected error = %v", err)
+			}
+
+			extractedInfo := analysis.toExtractedInfo(post)
+			if extractedInfo.Protocol != tt.wantProtocol {
+				t.Errorf("Protocol = %s, want %s", extractedInfo.Protocol, tt.wantProtocol)
+			}
+			if !reflect.DeepEqual(extractedInfo.TagNames, tt.wantTagNames) {
+				t.Errorf("TagNames = %v, want %v", extractedInfo.TagNames, tt.wantTagNames)
+			}
+			if extractedInfo.TxHash != post.TxHash || extractedInfo.Network != post.Network || extractedInfo.Amount != post.Amount {
+				t.Errorf("post fields not copied: %+v", extractedInfo)
+			}
+		})
+	}
+}
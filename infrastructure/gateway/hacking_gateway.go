@@ -13,26 +13,44 @@ import (
 	"github.com/gotd/td/tg"
 )
 
+// 新しい投稿の通知を受けた際に取得する投稿の上限
+const realtimeFetchLimit = 100
+
+// リプライ先の投稿が削除されている場合のエラー
+var errRepliedMessageDeleted = errors.New("replied message is deleted")
+
 // TelegramHackingPostGatewayを実装する構造体
 type telegramHackingPostGateway struct {
-	manager         *TelegramClientManager
+	clients         *channelClients
 	channelUsername string
-	channel         *tg.Channel
+	parse           HackingPostParser
+	rejected        rejectedPostNotifier
+	refetched       refetchedPosts[gateway.HackingPost]
 	lastMessageID   int
 	oldestMessageID int
 	mu              sync.Mutex
 }
 
 // 新しいtelegramHackingPostGatewayを生成
-func NewTelegramHackingPostGateway(manager *TelegramClientManager, channelUsername string) gateway.TelegramHackingPostGateway {
-	return &telegramHackingPostGateway{manager: manager, channelUsername: channelUsername}
+// selector には単一のアカウントの TelegramClientManager か、複数のアカウントの TelegramClientPool を指定
+// parse にはチャンネルの投稿の形式に合わせたパーサーを指定（PostParserRegistry で取得）
+func NewTelegramHackingPostGateway(selector TelegramClientSelector, channelUsername string, parse HackingPostParser) gateway.TelegramHackingPostGateway {
+	return &telegramHackingPostGateway{
+		clients:         newChannelClients(selector, channelUsername),
+		channelUsername: channelUsername,
+		parse:           parse,
+	}
 }
 
 func (g *telegramHackingPostGateway) SetLastMessageID(lastMessageID int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	g.lastMessageID = lastMessageID
 }
 
 func (g *telegramHackingPostGateway) LastMessageID() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	return g.lastMessageID
 }
 
@@ -40,72 +58,77 @@ func (g *telegramHackingPostGateway) ChannelUsername() string {
 	return g.channelUsername
 }
 
-// 最後に取得した投稿以降、最新の投稿を100件以下取得
-func (g *telegramHackingPostGateway) GetPosts(ctx context.Context, limit int) ([]*gateway.HackingPost, error) {
-	api := g.manager.API()
-	if api == nil {
-		return nil, errors.New("telegram client is not ready")
-	}
+func (g *telegramHackingPostGateway) SetChannelPeer(peer *gateway.ChannelPeer) {
+	g.clients.primaryPeer().set(peer)
+}
 
-	// チャンネル名を解決してPeer情報を取得
-	resolved, err := api.ContactsResolveUsername(ctx, &tg.ContactsResolveUsernameRequest{
-		Username: g.channelUsername,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("gateway A: failed to resolve username %s: %w", g.channelUsername, err)
-	}
-	channel, ok := resolved.Chats[0].(*tg.Channel)
-	if !ok {
-		return nil, fmt.Errorf("gateway A: resolved peer is not a channel")
-	}
-	g.channel = channel
-	inputPeer := channel.AsInputPeer()
+func (g *telegramHackingPostGateway) ChannelPeer() *gateway.ChannelPeer {
+	return g.clients.primaryPeer().get()
+}
+
+func (g *telegramHackingPostGateway) SetRejectedPostHandler(handler gateway.RejectedPostHandler) {
+	g.rejected.set(handler)
+}
 
+// 最後に取得した投稿以降、最新の投稿を100件以下取得
+func (g *telegramHackingPostGateway) GetPosts(ctx context.Context, limit int) ([]*gateway.HackingPost, error) {
+	// パースに失敗した投稿は、ロックを解放してから通知
+	rejected := g.rejected.collect()
+	defer rejected.flush(ctx)
 	g.mu.Lock()
 	defer g.mu.Unlock()
-	// 最後に取得した投稿以降、最新の投稿を取得
-	history, err := api.MessagesGetHistory(ctx, &tg.MessagesGetHistoryRequest{
-		Peer:  inputPeer,
-		MinID: g.lastMessageID,
-		Limit: limit,
+	// 解決済みのチャンネル情報で、最後に取得した投稿以降、最新の投稿を取得
+	var api *tg.Client
+	var channel *tg.InputChannel
+	var history tg.MessagesMessagesClass
+	err := g.clients.withChannel(ctx, func(a *tg.Client, c *tg.InputChannel) error {
+		var err error
+		api, channel = a, c
+		history, err = api.MessagesGetHistory(ctx, &tg.MessagesGetHistoryRequest{
+			Peer:  inputPeer(c),
+			MinID: g.lastMessageID,
+			Limit: limit,
+		})
+		return err
 	})
 	if err != nil {
 		return nil, fmt.Errorf("gateway A: failed to get channel history: %w", err)
 	}
 
 	// 取得した投稿の内、ハッキング情報を含むものをHackingPostに変換
-	return g.convertMessages(ctx, history)
+	return g.convertMessages(ctx, api, channel, history, rejected)
 }
 
 // 最後に取得した投稿以降、最新の投稿を101件以上取得
-func (g *telegramHackingPostGateway) GetPostsOver100(ctx context.Context, limit int) ([]*gateway.HackingPost, error) {
-	api := g.manager.API()
-	if api == nil {
-		return nil, errors.New("telegram client is not ready")
-	}
-
-	// チャンネル名を解決してPeer情報を取得
-	resolved, err := api.ContactsResolveUsername(ctx, &tg.ContactsResolveUsernameRequest{
-		Username: g.channelUsername,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("gateway A: failed to resolve username %s: %w", g.channelUsername, err)
-	}
-	channel, ok := resolved.Chats[0].(*tg.Channel)
-	if !ok {
-		return nil, fmt.Errorf("gateway A: resolved peer is not a channel")
-	}
-	g.channel = channel
-	inputPeer := channel.AsInputPeer()
-
+func (g *telegramHackingPostGateway) GetPostsOver100(ctx context.Context, limit int) (_ []*gateway.HackingPost, err error) {
+	// パースに失敗した投稿は、ロックを解放してから通知
+	rejected := g.rejected.collect()
+	defer rejected.flush(ctx)
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	// 最後に取得した投稿以降、最新の投稿を101件以上取得
-	history, err := api.MessagesGetHistory(ctx, &tg.MessagesGetHistoryRequest{
-		Peer:  inputPeer,
-		MinID: g.lastMessageID,
-		Limit: limit,
+	// 途中でエラーになった場合は、次回に最初から取得し直す
+	lastMessageID, oldestMessageID := g.lastMessageID, g.oldestMessageID
+	defer func() {
+		if err != nil {
+			g.lastMessageID, g.oldestMessageID = lastMessageID, oldestMessageID
+		}
+	}()
+
+	// 解決済みのチャンネル情報で、最後に取得した投稿以降、最新の投稿を101件以上取得
+	// 以降のページも最初に取得したアカウントで取得
+	var api *tg.Client
+	var channel *tg.InputChannel
+	var history tg.MessagesMessagesClass
+	err = g.clients.withChannel(ctx, func(a *tg.Client, c *tg.InputChannel) error {
+		var err error
+		api, channel = a, c
+		history, err = api.MessagesGetHistory(ctx, &tg.MessagesGetHistoryRequest{
+			Peer:  inputPeer(c),
+			MinID: g.lastMessageID,
+			Limit: limit,
+		})
+		return err
 	})
 	if err != nil {
 		return nil, fmt.Errorf("gateway A: failed to get channel history: %w", err)
@@ -117,7 +140,7 @@ func (g *telegramHackingPostGateway) GetPostsOver100(ctx context.Context, limit
 
 	var allPosts []*gateway.HackingPost
 
-	posts, err := g.convertMessages(ctx, history)
+	posts, err := g.convertMessages(ctx, api, channel, history, rejected)
 	if err != nil {
 		return nil, err
 	}
@@ -127,7 +150,7 @@ func (g *telegramHackingPostGateway) GetPostsOver100(ctx context.Context, limit
 
 	for restLimit > 0 && getPostsNumber > 0 {
 		history, err := api.MessagesGetHistory(ctx, &tg.MessagesGetHistoryRequest{
-			Peer:  inputPeer,
+			Peer:  inputPeer(channel),
 			MaxID: g.oldestMessageID,
 			Limit: restLimit,
 		})
@@ -146,7 +169,7 @@ func (g *telegramHackingPostGateway) GetPostsOver100(ctx context.Context, limit
 			}
 		}
 
-		posts, err := g.convertMessages(ctx, history)
+		posts, err := g.convertMessages(ctx, api, channel, history, rejected)
 		if err != nil {
 			return nil, err
 		}
@@ -158,71 +181,283 @@ func (g *telegramHackingPostGateway) GetPostsOver100(ctx context.Context, limit
 	return allPosts, nil
 }
 
+// maxID より前の投稿を遡って取得
+// 最後に取得した投稿のIDは更新しない
+func (g *telegramHackingPostGateway) GetPostsBefore(ctx context.Context, maxID int, limit int) ([]*gateway.HackingPost, *gateway.HistoryPage, error) {
+	rejected := g.rejected.collect()
+	defer rejected.flush(ctx)
+
+	var api *tg.Client
+	var channel *tg.InputChannel
+	var history tg.MessagesMessagesClass
+	err := g.clients.withChannel(ctx, func(a *tg.Client, c *tg.InputChannel) error {
+		var err error
+		api, channel = a, c
+		history, err = api.MessagesGetHistory(ctx, &tg.MessagesGetHistoryRequest{
+			Peer:  inputPeer(c),
+			MaxID: maxID,
+			Limit: limit,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("gateway A: failed to get channel history: %w", err)
+	}
+	channelMessages, ok := history.(*tg.MessagesChannelMessages)
+	if !ok {
+		return nil, nil, fmt.Errorf("gateway A: failed to cast history to ChannelMessages")
+	}
+
+	var posts []*gateway.HackingPost
+	for _, msg := range channelMessages.Messages {
+		if message, ok := msg.(*tg.Message); ok && message.Message != "" {
+			post, err := g.convertMessage(ctx, api, channel, message, false, rejected)
+			switch {
+			case errors.Is(err, errRepliedMessageDeleted):
+				// リプライ先が削除済みの投稿は使用しない
+			case err != nil:
+				return nil, nil, err
+			case post != nil:
+				posts = append(posts, post)
+			}
+		}
+	}
+	return posts, historyPage(channelMessages.Messages), nil
+}
+
+// 取得した投稿の範囲を返す
+// 本文のない投稿やサービスメッセージも含めて、最も古い投稿を次のページの起点とする
+// MTProto のゲートウェイで共通
+func historyPage(messages []tg.MessageClass) *gateway.HistoryPage {
+	var page gateway.HistoryPage
+	for _, msg := range messages {
+		if page.OldestMessageID != 0 && msg.GetID() >= page.OldestMessageID {
+			continue
+		}
+		page.OldestMessageID = msg.GetID()
+		switch message := msg.(type) {
+		case *tg.Message:
+			page.OldestDate = time.Unix(int64(message.Date), 0)
+		case *tg.MessageService:
+			page.OldestDate = time.Unix(int64(message.Date), 0)
+		}
+	}
+	return &page
+}
+
 // 取得した投稿の内、ハッキング情報を含むものをHackingPostに変換
 // 関連ポストを追加で取得
-func (g *telegramHackingPostGateway) convertMessages(ctx context.Context, history tg.MessagesMessagesClass) ([]*gateway.HackingPost, error) {
+func (g *telegramHackingPostGateway) convertMessages(ctx context.Context, api *tg.Client, channel *tg.InputChannel, history tg.MessagesMessagesClass, rejected *rejectedPosts) ([]*gateway.HackingPost, error) {
 	// 取得したデータを投稿のスライスに変換
 	channelMessages, ok := history.(*tg.MessagesChannelMessages)
 	if !ok {
 		return nil, fmt.Errorf("gateway A: failed to cast history to ChannelMessages")
 	}
 
+	// 途中でエラーになった場合に次回の取得で再取得できるよう、全て変換できてから取得済みのIDを更新
+	oldestMessageID, lastMessageID := g.oldestMessageID, g.lastMessageID
 	var posts []*gateway.HackingPost
 	for _, msg := range channelMessages.Messages {
 		// チャンネルの投稿か確認
 		if message, ok := msg.(*tg.Message); ok && message.Message != "" {
-			// リプライ先があるか確認
-			if replyTo, ok := message.GetReplyTo(); ok {
-				// リプライ先IDが取得可能か確認
-				if messageReplyTo, ok := replyTo.(*tg.MessageReplyHeader); ok {
-					// リプライ先IDを取得
-					replyToID, _ := messageReplyTo.GetReplyToMsgID()
-					id := []tg.InputMessageClass{&tg.InputMessageID{ID: replyToID}}
-					// リプライ先の投稿を取得
-					inputChannel := g.channel.AsInput()
-					repliedMsgs, err := g.manager.api.ChannelsGetMessages(ctx, &tg.ChannelsGetMessagesRequest{
-						Channel: inputChannel,
-						ID:      id,
-					})
-					if err != nil {
-						return nil, fmt.Errorf("gateway A: failed to get replied message: %w", err)
-					}
-					repliedMessages, _ := repliedMsgs.(*tg.MessagesChannelMessages)
-					repliedMessage, _ := repliedMessages.Messages[0].(*tg.Message)
-
-					// リプライ先にさらにリプライがあればその投稿は使用しない
-					if _, ok := repliedMessage.GetReplyTo(); !ok {
-						// リプライ先からハッキング情報を取得
-						post, err := g.parseHackingMessage(repliedMessage.Message)
-						if err == nil {
-							// 投稿内容を添付
-							date := repliedMessage.GetDate()
-							post.ReportTime = time.Unix(int64(date), 0)
-							post.MessageID = message.ID
-							post.Text = message.Message
-							posts = append(posts, post)
-						} else {
-							log.Printf("Failed to parse hacking message with error: %v", err)
-						}
-					}
-				}
+			post, err := g.convertMessage(ctx, api, channel, message, false, rejected)
+			switch {
+			case errors.Is(err, errRepliedMessageDeleted):
+				// リプライ先が削除済みの投稿は使用しない
+			case err != nil:
+				return nil, err
+			case post != nil:
+				posts = append(posts, post)
 			}
+
 			// 取得した投稿の中で最も古い投稿のIDを更新
-			if g.oldestMessageID == 0 || message.ID < g.oldestMessageID {
-				g.oldestMessageID = message.ID
+			if oldestMessageID == 0 || message.ID < oldestMessageID {
+				oldestMessageID = message.ID
 			}
 
 			// 取得した投稿の中で最も新しい投稿のIDを更新
-			if message.ID > g.lastMessageID {
-				g.lastMessageID = message.ID
+			if message.ID > lastMessageID {
+				lastMessageID = message.ID
 			}
 		}
 	}
+	g.oldestMessageID, g.lastMessageID = oldestMessageID, lastMessageID
 	return posts, nil
 }
 
+// リプライ先の投稿からハッキング情報を取得し、HackingPostに変換
+// リプライでない投稿や、ハッキング情報を含まない投稿の場合は nil を返す
+// パースに失敗した投稿は rejected に追加
+// refetch は編集の確認での再取得の場合に指定し、本文が変わっていない投稿は前回の結果を使用してパースの失敗を再度通知しない
+func (g *telegramHackingPostGateway) convertMessage(ctx context.Context, api *tg.Client, channel *tg.InputChannel, message *tg.Message, refetch bool, rejected *rejectedPosts) (*gateway.HackingPost, error) {
+	// リプライ先があるか確認
+	replyTo, ok := message.GetReplyTo()
+	if !ok {
+		return nil, nil
+	}
+	// リプライ先IDが取得可能か確認
+	messageReplyTo, ok := replyTo.(*tg.MessageReplyHeader)
+	if !ok {
+		return nil, nil
+	}
+
+	// リプライ先IDを取得
+	replyToID, _ := messageReplyTo.GetReplyToMsgID()
+	id := []tg.InputMessageClass{&tg.InputMessageID{ID: replyToID}}
+	// リプライ先の投稿を取得
+	repliedMsgs, err := api.ChannelsGetMessages(ctx, &tg.ChannelsGetMessagesRequest{
+		Channel: channel,
+		ID:      id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gateway A: failed to get replied message: %w", err)
+	}
+	repliedMessages, ok := repliedMsgs.(*tg.MessagesChannelMessages)
+	if !ok || len(repliedMessages.Messages) == 0 {
+		return nil, errRepliedMessageDeleted
+	}
+	repliedMessage, ok := repliedMessages.Messages[0].(*tg.Message)
+	if !ok {
+		return nil, errRepliedMessageDeleted
+	}
+
+	// リプライ先にさらにリプライがあればその投稿は使用しない
+	if _, ok := repliedMessage.GetReplyTo(); ok {
+		return nil, nil
+	}
+
+	// リプライ先からハッキング情報を取得
+	var post *gateway.HackingPost
+	reused := false
+	if refetch {
+		post, reused, err = g.refetched.parse(message.ID, message.Message+"\x00"+repliedMessage.Message, func() (*gateway.HackingPost, error) {
+			return g.parse(repliedMessage.Message)
+		})
+	} else {
+		post, err = g.parse(repliedMessage.Message)
+	}
+	if err != nil {
+		if reused {
+			return nil, nil
+		}
+		rejected.add(&gateway.RejectedPost{
+			ChannelUsername: g.channelUsername,
+			MessageID:       message.ID,
+			Text:            message.Message,
+			ReplyToText:     repliedMessage.Message,
+			ReportTime:      time.Unix(int64(repliedMessage.GetDate()), 0),
+			Error:           err.Error(),
+		})
+		return nil, nil
+	}
+
+	// 投稿内容を添付
+	date := repliedMessage.GetDate()
+	post.ReportTime = time.Unix(int64(date), 0)
+	post.MessageID = message.ID
+	post.ChannelUsername = g.channelUsername
+	post.Text = message.Message
+	post.ReplyToText = repliedMessage.Message
+	return post, nil
+}
+
+// 指定したメッセージIDの投稿を再取得してHackingPostに変換
+// 投稿またはリプライ先の投稿が削除されている場合は、そのメッセージIDを deletedIDs として返す
+func (g *telegramHackingPostGateway) GetPostsByMessageIDs(ctx context.Context, messageIDs []int) ([]*gateway.HackingPost, []int, error) {
+	if len(messageIDs) == 0 {
+		return nil, nil, nil
+	}
+	rejected := g.rejected.collect()
+	defer rejected.flush(ctx)
+
+	ids := make([]tg.InputMessageClass, len(messageIDs))
+	for i, messageID := range messageIDs {
+		ids[i] = &tg.InputMessageID{ID: messageID}
+	}
+	var api *tg.Client
+	var channel *tg.InputChannel
+	var msgs tg.MessagesMessagesClass
+	err := g.clients.withChannel(ctx, func(a *tg.Client, c *tg.InputChannel) error {
+		var err error
+		api, channel = a, c
+		msgs, err = api.ChannelsGetMessages(ctx, &tg.ChannelsGetMessagesRequest{
+			Channel: c,
+			ID:      ids,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("gateway A: failed to get messages: %w", err)
+	}
+	channelMessages, ok := msgs.(*tg.MessagesChannelMessages)
+	if !ok {
+		return nil, nil, fmt.Errorf("gateway A: failed to cast messages to ChannelMessages")
+	}
+
+	var posts []*gateway.HackingPost
+	var deletedIDs []int
+	for _, msg := range channelMessages.Messages {
+		switch message := msg.(type) {
+		case *tg.MessageEmpty:
+			deletedIDs = append(deletedIDs, message.ID)
+		case *tg.Message:
+			post, err := g.convertMessage(ctx, api, channel, message, true, rejected)
+			switch {
+			case errors.Is(err, errRepliedMessageDeleted):
+				deletedIDs = append(deletedIDs, message.ID)
+			case err != nil:
+				return nil, nil, err
+			case post != nil:
+				posts = append(posts, post)
+			}
+		}
+	}
+	g.refetched.retain(messageIDs)
+	return posts, deletedIDs, nil
+}
+
+// チャンネルの投稿の編集・削除の通知を購読
+// 通知はチャンネル名の解決に使用したアカウントで受信
+func (g *telegramHackingPostGateway) SubscribeChanges(ctx context.Context, handler func(ctx context.Context)) error {
+	m, channel, err := g.clients.resolve(ctx)
+	if err != nil {
+		return fmt.Errorf("gateway A: %w", err)
+	}
+
+	m.OnChannelChange(channel.ChannelID, handler)
+	return nil
+}
+
+// チャンネルの新しい投稿の通知を購読
+// 通知を受けたら、最後に取得した投稿以降の投稿を取得して handler に渡す
+// 通知はチャンネル名の解決に使用したアカウントで受信
+func (g *telegramHackingPostGateway) SubscribeNewPosts(ctx context.Context, handler func(ctx context.Context, posts []*gateway.HackingPost)) error {
+	// 解決済みのチャンネル情報からチャンネルIDを取得
+	m, channel, err := g.clients.resolve(ctx)
+	if err != nil {
+		return fmt.Errorf("gateway A: %w", err)
+	}
+
+	m.OnChannelMessage(channel.ChannelID, func(ctx context.Context, messageID int) {
+		// 取得済みの投稿は無視
+		if messageID <= g.LastMessageID() {
+			return
+		}
+
+		// 通知された投稿だけでなく、最後に取得した投稿以降を取得して取りこぼしを防ぐ
+		posts, err := g.GetPosts(ctx, realtimeFetchLimit)
+		if err != nil {
+			log.Printf("gateway A: failed to get new posts from %s: %v", g.channelUsername, err)
+			return
+		}
+		handler(ctx, posts)
+	})
+	return nil
+}
+
 // 投稿の形式からパースしてハッキング情報を取得
-func (g *telegramHackingPostGateway) parseHackingMessage(message string) (*gateway.HackingPost, error) {
+// 組み込みのパーサー（BuiltinHackingParser）
+func parseHackingPost(message string) (*gateway.HackingPost, error) {
 	// スペースで分割
 	tokens := strings.Fields(message)
 
@@ -231,17 +466,17 @@ func (g *telegramHackingPostGateway) parseHackingMessage(message string) (*gatew
 
 	// "Network:", "Exploit:", "Balance" を基準にパース
 	for i, token := range tokens {
-		if token == "Network:" && i < len(tokens) {
+		if token == "Network:" && i+1 < len(tokens) {
 			// "Network:" の次の単語が「ネットワーク」
 			post.Network = tokens[i+1]
 			continue
 		}
-		if token == "Exploit:" && i < len(tokens) {
+		if token == "Exploit:" && i+1 < len(tokens) {
 			// "Exploit:" の次の単語が「TX Hash」
 			post.TxHash = tokens[i+1]
 			continue
 		}
-		if token == "Balance" && i+1 < len(tokens) {
+		if token == "Balance" && i+2 < len(tokens) {
 			// "Balance" の2つ先の単語が「送金額」
 			post.Amount = tokens[i+2]
 			found = true
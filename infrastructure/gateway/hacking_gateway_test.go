@@ -1,240 +1,155 @@
 package gateway
 
 import (
+	"context"
+	"reflect"
 	"testing"
+	"time"
 
-	"context"
-	"fmt"
 	dm_gateway "github.com/itout-datetoya/hack-info-timeline/domain/gateway"
-	"log"
-	"os"
-	"os/signal"
-	"path/filepath"
-	"strconv"
-	"strings"
-	"sync"
-	"syscall"
-
-	"github.com/gotd/td/session"
-	"github.com/gotd/td/telegram"
-	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
-	"go.uber.org/zap"
 )
 
-func TestHackingGatewayGetPosts(t *testing.T) {
-	config := zap.NewDevelopmentConfig()
-	config.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
-	logger, _ := config.Build()
-	defer logger.Sync()
-
-	err := godotenv.Load()
-	if err != nil {
-		log.Println("Warning: .env file not found")
-	}
+const telegramFixturePath = "testdata/telegram/channels.json"
 
-	// 設定の読み込み
-	telegramAppIDStr := os.Getenv("TELEGRAM_APP_ID")
-	telegramAppHash := os.Getenv("TELEGRAM_APP_HASH")
-	phone := os.Getenv("TELEGRAM_PHONE_NUMBER")
-	hash := os.Getenv("TELEGRAM_AUTH_HASH")
-	code := os.Getenv("TELEGRAM_CODE")
-	telegramHackingChannels := strings.Split(os.Getenv("TELEGRAM_HACKING_CHANNEL_USERNAMES"), ",")
-	telegramTransferChannels := strings.Split(os.Getenv("TELEGRAM_TRANSFER_CHANNEL_USERNAMES"), ",")
+func TestHackingGatewayGetPosts_Fixture(t *testing.T) {
+	ctx := context.Background()
+	m, invoker := newFixtureAccount(t, telegramFixturePath)
+	gw := NewTelegramHackingPostGateway(m, "hackchannel", parseHackingPost)
 
-	if telegramAppIDStr == "" || telegramAppHash == "" || telegramHackingChannels[0] == "" ||
-		telegramTransferChannels[0] == "" || phone == "" {
-		log.Fatal("Telegram user client environment variables not fully set.")
-	}
-	telegramAppID, err := strconv.Atoi(telegramAppIDStr)
+	posts, err := gw.GetPosts(ctx, 100)
 	if err != nil {
-		log.Fatalf("Invalid TELEGRAM_APP_ID: %v", err)
-	}
-
-	// 依存性の注入 (DI)
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer stop()
-
-	sessionDir := ".td"
-	os.MkdirAll(sessionDir, 0755)
-	client := telegram.NewClient(telegramAppID, telegramAppHash, telegram.Options{
-		Logger: logger,
-		SessionStorage: &session.FileStorage{
-			Path: filepath.Join(sessionDir, "session.json"),
+		t.Fatal(err)
+	}
+
+	// リプライ先の投稿からハッキング情報を取得し、リプライの投稿を単位として返す
+	// リプライでない投稿、リプライ先がリプライ・削除済み・ハッキング情報でない投稿は除外
+	want := []*dm_gateway.HackingPost{
+		{
+			Text:            "Onyx Protocol",
+			ReplyToText:     "🚨 Exploit detected\nNetwork: BSC\nExploit: 0x7f1e2d3c4b5a69788796a5b4c3d2e1f0a9b8c7d6e5f4a3b2c1d0e9f8a7b6c5d4\nBalance change: $1,200,000",
+			Network:         "BSC",
+			Amount:          "$1,200,000",
+			TxHash:          "0x7f1e2d3c4b5a69788796a5b4c3d2e1f0a9b8c7d6e5f4a3b2c1d0e9f8a7b6c5d4",
+			ReportTime:      time.Unix(1700001000, 0),
+			MessageID:       21,
+			ChannelUsername: "hackchannel",
+		},
+		{
+			Text:            "Resupply",
+			ReplyToText:     "🚨 Exploit detected\nNetwork: Ethereum\nExploit: 0x3a9d1f2c4b5e6a7980c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3\nBalance change: $9,600,000",
+			Network:         "Ethereum",
+			Amount:          "$9,600,000",
+			TxHash:          "0x3a9d1f2c4b5e6a7980c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3",
+			ReportTime:      time.Unix(1700000000, 0),
+			MessageID:       11,
+			ChannelUsername: "hackchannel",
 		},
-	})
-	telegramClientManager := &TelegramClientManager{client: client}
-
-	// Runメソッドを呼び出して接続を開始
-	if err := telegramClientManager.Run(ctx, phone, hash, code); err != nil {
-		log.Fatalf("Failed to run Telegram Gateway: %v", err)
 	}
-	log.Println("Telegram client connected and ready.")
-
-	// 各gatewayの初期化
-	var telegramHackingGateways []dm_gateway.TelegramHackingPostGateway
-	for _, channel := range telegramHackingChannels {
-		telegramHackingGateways = append(telegramHackingGateways,
-			NewTelegramHackingPostGateway(
-				telegramClientManager,
-				channel,
-			))
+	if !reflect.DeepEqual(posts, want) {
+		t.Errorf("posts = %+v, want %+v", posts, want)
 	}
-
-	limit := 100
-
-	var wg sync.WaitGroup
-	errsChan := make(chan error, len(telegramHackingGateways))
-	var posts []*dm_gateway.HackingPost
-	var mu sync.Mutex
-
-	for _, gw := range telegramHackingGateways {
-		wg.Add(1)
-		go func(gw dm_gateway.TelegramHackingPostGateway) {
-			defer wg.Done()
-			newPosts, err := gw.GetPosts(ctx, limit)
-			if err != nil {
-				errsChan <- fmt.Errorf("failed to get posts from telegram: %w", err)
-				return
-			}
-			mu.Lock()
-			posts = append(posts, newPosts...)
-			mu.Unlock()
-		}(gw)
+	if gw.LastMessageID() != 23 {
+		t.Errorf("LastMessageID() = %d, want 23", gw.LastMessageID())
+	}
+	if peer := gw.ChannelPeer(); peer == nil || peer.ID != 1001 || peer.AccessHash != 5001 || peer.Title != "Hack Alerts" {
+		t.Errorf("ChannelPeer() = %+v", peer)
 	}
 
-	wg.Wait()
-	close(errsChan)
-
-	var getPostsErrors []error
-	for err := range errsChan {
-		getPostsErrors = append(getPostsErrors, err)
+	// チャンネル名は一度だけ解決し、リプライの投稿毎にリプライ先を取得
+	wantMethods := []string{"contacts.resolveUsername", "messages.getHistory"}
+	for range 5 {
+		wantMethods = append(wantMethods, "channels.getMessages")
+	}
+	if got := invoker.methods(); !reflect.DeepEqual(got, wantMethods) {
+		t.Errorf("calls = %v, want %v", got, wantMethods)
 	}
 
-	if len(getPostsErrors) != 0 {
-		log.Fatalf("failed to get posts from telegram: %v", getPostsErrors)
+	// 取得済みの投稿は再度取得しない
+	posts, err = gw.GetPosts(ctx, 100)
+	if err != nil || len(posts) != 0 {
+		t.Errorf("GetPosts() again = %+v, %v, want no posts", posts, err)
 	}
+}
 
-	stop() // 他のコンテキストユーザーにキャンセルを通知
-	log.Println("Shutting down server...")
+func TestHackingGatewayGetPostsOver100_Fixture(t *testing.T) {
+	m, invoker := newFixtureAccount(t, telegramFixturePath)
+	// 1回で返す投稿を減らし、複数ページに分けて取得させる
+	invoker.pageSize = 4
+	gw := NewTelegramHackingPostGateway(m, "hackchannel", parseHackingPost)
 
-	// Telegramクライアントを停止
-	if err := telegramClientManager.Stop(); err != nil {
-		log.Println("Failed to stop telegram client:", err)
+	posts, err := gw.GetPostsOver100(context.Background(), 200)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	log.Println("Server exiting")
-	log.Printf("Get %d infos", len(posts))
-
+	var messageIDs []int
 	for _, post := range posts {
-		log.Println(post)
+		messageIDs = append(messageIDs, post.MessageID)
+	}
+	if !reflect.DeepEqual(messageIDs, []int{21, 11}) {
+		t.Errorf("message IDs = %v, want [21 11]", messageIDs)
+	}
+	// 取得した中で最も古い投稿より前のページを順に取得し、サービスメッセージのみのページで終了
+	if got := invoker.historyMaxIDs(); !reflect.DeepEqual(got, []int{0, 20, 11, 10}) {
+		t.Errorf("history max IDs = %v, want [0 20 11 10]", got)
+	}
+	if gw.LastMessageID() != 23 {
+		t.Errorf("LastMessageID() = %d, want 23", gw.LastMessageID())
 	}
 }
 
-func TestHackingGatewayGetOver100Posts(t *testing.T) {
-	config := zap.NewDevelopmentConfig()
-	config.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
-	logger, _ := config.Build()
-	defer logger.Sync()
+func TestHackingGatewayGetPostsByMessageIDs_Fixture(t *testing.T) {
+	m, _ := newFixtureAccount(t, telegramFixturePath)
+	gw := NewTelegramHackingPostGateway(m, "hackchannel", parseHackingPost)
 
-	err := godotenv.Load()
+	posts, deletedIDs, err := gw.GetPostsByMessageIDs(context.Background(), []int{11, 14, 15})
 	if err != nil {
-		log.Println("Warning: .env file not found")
+		t.Fatal(err)
 	}
-
-	// 設定の読み込み
-	telegramAppIDStr := os.Getenv("TELEGRAM_APP_ID")
-	telegramAppHash := os.Getenv("TELEGRAM_APP_HASH")
-	phone := os.Getenv("TELEGRAM_PHONE_NUMBER")
-	hash := os.Getenv("TELEGRAM_AUTH_HASH")
-	code := os.Getenv("TELEGRAM_CODE")
-	telegramHackingChannels := strings.Split(os.Getenv("TELEGRAM_HACKING_CHANNEL_USERNAMES"), ",")
-	telegramTransferChannels := strings.Split(os.Getenv("TELEGRAM_TRANSFER_CHANNEL_USERNAMES"), ",")
-
-	if telegramAppIDStr == "" || telegramAppHash == "" || telegramHackingChannels[0] == "" ||
-		telegramTransferChannels[0] == "" || phone == "" {
-		log.Fatal("Telegram user client environment variables not fully set.")
+	if len(posts) != 1 || posts[0].MessageID != 11 || posts[0].Amount != "$9,600,000" {
+		t.Errorf("posts = %+v", posts)
 	}
-	telegramAppID, err := strconv.Atoi(telegramAppIDStr)
-	if err != nil {
-		log.Fatalf("Invalid TELEGRAM_APP_ID: %v", err)
+	// 削除された投稿と、リプライ先が削除された投稿
+	if !reflect.DeepEqual(deletedIDs, []int{14, 15}) {
+		t.Errorf("deletedIDs = %v, want [14 15]", deletedIDs)
 	}
+}
 
-	// 依存性の注入 (DI)
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer stop()
-
-	sessionDir := ".td"
-	os.MkdirAll(sessionDir, 0755)
-	client := telegram.NewClient(telegramAppID, telegramAppHash, telegram.Options{
-		Logger: logger,
-		SessionStorage: &session.FileStorage{
-			Path: filepath.Join(sessionDir, "session.json"),
-		},
-	})
-	telegramClientManager := &TelegramClientManager{client: client}
+func TestHackingGatewayGetPostsBefore_Fixture(t *testing.T) {
+	ctx := context.Background()
+	m, invoker := newFixtureAccount(t, telegramFixturePath)
+	gw := NewTelegramHackingPostGateway(m, "hackchannel", parseHackingPost)
+	// 保存済みのチャンネル情報が無効な場合は、チャンネル名を解決し直す
+	gw.SetChannelPeer(&dm_gateway.ChannelPeer{ID: 1001, AccessHash: 1})
 
-	// Runメソッドを呼び出して接続を開始
-	if err := telegramClientManager.Run(ctx, phone, hash, code); err != nil {
-		log.Fatalf("Failed to run Telegram Gateway: %v", err)
+	posts, page, err := gw.GetPostsBefore(ctx, 20, 4)
+	if err != nil {
+		t.Fatal(err)
 	}
-	log.Println("Telegram client connected and ready.")
-
-	// 各gatewayの初期化
-	var telegramHackingGateways []dm_gateway.TelegramHackingPostGateway
-	for _, channel := range telegramHackingChannels {
-		telegramHackingGateways = append(telegramHackingGateways,
-			NewTelegramHackingPostGateway(
-				telegramClientManager,
-				channel,
-			))
+	if len(posts) != 1 || posts[0].MessageID != 11 {
+		t.Errorf("posts = %+v, want message 11", posts)
 	}
-
-	limit := 300
-
-	var wg sync.WaitGroup
-	errsChan := make(chan error, len(telegramHackingGateways))
-	var posts []*dm_gateway.HackingPost
-	var mu sync.Mutex
-
-	for _, gw := range telegramHackingGateways {
-		wg.Add(1)
-		go func(gw dm_gateway.TelegramHackingPostGateway) {
-			defer wg.Done()
-			newPosts, err := gw.GetPostsOver100(ctx, limit)
-			if err != nil {
-				errsChan <- fmt.Errorf("failed to get posts from telegram: %w", err)
-				return
-			}
-			mu.Lock()
-			posts = append(posts, newPosts...)
-			mu.Unlock()
-		}(gw)
+	if page.OldestMessageID != 11 || !page.OldestDate.Equal(time.Unix(1700000100, 0)) {
+		t.Errorf("page = %+v, want oldest message 11", page)
 	}
-
-	wg.Wait()
-	close(errsChan)
-
-	var getPostsErrors []error
-	for err := range errsChan {
-		getPostsErrors = append(getPostsErrors, err)
+	if peer := gw.ChannelPeer(); peer.AccessHash != 5001 {
+		t.Errorf("ChannelPeer() = %+v, want resolved again", peer)
 	}
 
-	if len(getPostsErrors) != 0 {
-		log.Fatalf("failed to get posts from telegram: %v", getPostsErrors)
+	// 最初の投稿まで遡ると、それより前の投稿は返されない
+	_, page, err = gw.GetPostsBefore(ctx, 10, 4)
+	if err != nil || page.OldestMessageID != 1 || !page.OldestDate.Equal(time.Unix(1690000000, 0)) {
+		t.Errorf("page = %+v, err = %v, want oldest message 1", page, err)
 	}
-
-	stop() // 他のコンテキストユーザーにキャンセルを通知
-	log.Println("Shutting down server...")
-
-	// Telegramクライアントを停止
-	if err := telegramClientManager.Stop(); err != nil {
-		log.Println("Failed to stop telegram client:", err)
+	_, page, err = gw.GetPostsBefore(ctx, 1, 4)
+	if err != nil || page.OldestMessageID != 0 {
+		t.Errorf("page = %+v, err = %v, want empty page", page, err)
 	}
-
-	log.Println("Server exiting")
-
-	for _, post := range posts {
-		t.Log(post)
+	// 遡って取得しても、最後に取得した投稿のIDは変更しない
+	if gw.LastMessageID() != 0 {
+		t.Errorf("LastMessageID() = %d, want 0", gw.LastMessageID())
+	}
+	if got := invoker.historyMaxIDs(); !reflect.DeepEqual(got, []int{20, 10, 1}) {
+		t.Errorf("history max IDs = %v", got)
 	}
 }
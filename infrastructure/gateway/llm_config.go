@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// LLMプロバイダー
+const (
+	LLMProviderGemini = "gemini"
+	LLMProviderOpenAI = "openai"
+	// 記録済みの応答を再生するプロバイダー（評価・テスト用）
+	LLMProviderReplay = "replay"
+)
+
+// LLMゲートウェイの設定
+type LLMConfig struct {
+	// 使用するプロバイダー（gemini, openai または replay）
+	Provider string
+	// モデル名。空の場合はプロバイダーのデフォルト
+	Model string
+	// APIキー。OpenAI互換APIでローカルサーバーを使う場合は空でも可
+	APIKey string
+	// OpenAI互換APIのベースURL（例: http://localhost:11434/v1）
+	BaseURL string
+	// 生成時のtemperature。nil の場合はプロバイダーのデフォルト
+	Temperature *float32
+	// 1リクエストあたりのタイムアウト。0 の場合は無制限
+	Timeout time.Duration
+	// 使用するプロンプトのバージョン。空の場合はデフォルトのバージョン
+	PromptVersion string
+	// LLMの応答を記録するJSONLファイル
+	// replay の場合は読み込み、それ以外のプロバイダーでは応答を追記する
+	RecordingFile string
+}
+
+// 環境変数からLLMの設定を読み込み
+// APIキーは LLM_API_KEY が未設定の場合 GEMINI_API_KEY を使用
+func LLMConfigFromEnv() (LLMConfig, error) {
+	cfg := LLMConfig{
+		Provider:      os.Getenv("LLM_PROVIDER"),
+		Model:         os.Getenv("LLM_MODEL"),
+		APIKey:        os.Getenv("LLM_API_KEY"),
+		BaseURL:       os.Getenv("LLM_BASE_URL"),
+		PromptVersion: os.Getenv("LLM_PROMPT_VERSION"),
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = LLMProviderGemini
+	}
+	if cfg.APIKey == "" && cfg.Provider == LLMProviderGemini {
+		cfg.APIKey = os.Getenv("GEMINI_API_KEY")
+	}
+
+	if temperatureStr := os.Getenv("LLM_TEMPERATURE"); temperatureStr != "" {
+		temperature, err := strconv.ParseFloat(temperatureStr, 32)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid LLM_TEMPERATURE: %w", err)
+		}
+		t := float32(temperature)
+		cfg.Temperature = &t
+	}
+
+	if timeoutStr := os.Getenv("LLM_TIMEOUT"); timeoutStr != "" {
+		timeout, err := time.ParseDuration(timeoutStr)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid LLM_TIMEOUT: %w", err)
+		}
+		cfg.Timeout = timeout
+	}
+
+	return cfg, nil
+}
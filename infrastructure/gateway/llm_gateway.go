@@ -0,0 +1,175 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+	"math/rand/v2"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// プロバイダー毎のテキスト生成クライアント
+// 応答はスキーマに従うJSON文字列として返す
+type llmClient interface {
+	GenerateJSON(ctx context.Context, prompt string, schema *llmSchema) (string, error)
+	Close() error
+}
+
+type llmGateway struct {
+	client        llmClient
+	timeout       time.Duration
+	provider      string
+	model         string
+	promptVersion string
+	prompt        *template.Template
+}
+
+// 設定に応じたプロバイダーのクライアントを初期化
+func NewLLMGateway(ctx context.Context, cfg LLMConfig) (gateway.LLMGateway, error) {
+	if cfg.PromptVersion == "" {
+		cfg.PromptVersion = DefaultHackingPromptVersion
+	}
+	prompt, err := loadPromptTemplate(cfg.PromptVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	var client llmClient
+
+	switch cfg.Provider {
+	case LLMProviderGemini, "":
+		cfg.Provider = LLMProviderGemini
+		if cfg.Model == "" {
+			cfg.Model = defaultGeminiModel
+		}
+		client, err = newGeminiClient(ctx, cfg)
+	case LLMProviderOpenAI:
+		client, err = newOpenAIClient(cfg)
+	case LLMProviderReplay:
+		if cfg.Model == "" {
+			cfg.Model = defaultReplayModel
+		}
+		client, err = newReplayClient(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported LLM provider: %s", cfg.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 評価用に実際の応答を記録
+	if cfg.RecordingFile != "" && cfg.Provider != LLMProviderReplay {
+		recorder, err := newRecordingClient(client, cfg.RecordingFile)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+		client = recorder
+	}
+
+	return &llmGateway{
+		client:        client,
+		timeout:       cfg.Timeout,
+		provider:      cfg.Provider,
+		model:         cfg.Model,
+		promptVersion: cfg.PromptVersion,
+		prompt:        prompt,
+	}, nil
+}
+
+func (g *llmGateway) PromptVersion() string {
+	return g.promptVersion
+}
+
+func (g *llmGateway) Provider() string {
+	return g.provider
+}
+
+func (g *llmGateway) Model() string {
+	return g.model
+}
+
+func (g *llmGateway) Stop() error {
+	return g.client.Close()
+}
+
+// 再試行までの最小の待機時間と、それに加えるランダムな待機時間の上限
+const (
+	llmRetryMinDelay  = 1 * time.Second
+	llmRetryMaxJitter = 4 * time.Second
+)
+
+// プロンプトからスキーマに従うJSONを生成
+// 失敗した場合はランダムな待機時間の後に1度だけ再試行
+// 記録済みの応答が存在しない場合は再試行しない
+func (g *llmGateway) generate(ctx context.Context, prompt string, schema *llmSchema) (string, error) {
+	text, err := g.generateOnce(ctx, prompt, schema)
+	if errors.Is(err, ErrRecordingNotFound) {
+		return "", err
+	}
+	if err != nil {
+		// 待機中にキャンセルされた場合は再試行しない
+		timer := time.NewTimer(llmRetryMinDelay + rand.N(llmRetryMaxJitter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", ctx.Err()
+		case <-timer.C:
+		}
+
+		text, err = g.generateOnce(ctx, prompt, schema)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate content from LLM: %w", err)
+		}
+	}
+	return text, nil
+}
+
+func (g *llmGateway) generateOnce(ctx context.Context, prompt string, schema *llmSchema) (string, error) {
+	if g.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+	}
+	return g.client.GenerateJSON(ctx, prompt, schema)
+}
+
+func (g *llmGateway) AnalyzeAndExtract(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error) {
+	// 分析用のプロンプト
+	prompt, err := g.renderPrompt(post)
+	if err != nil {
+		return nil, err
+	}
+
+	// LLM呼び出し
+	resp, err := g.generate(ctx, prompt, hackingAnalysisSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis, err := parseHackingAnalysis(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	extractedInfo := analysis.toExtractedInfo(post)
+
+	// 分析に使用したプロンプト・モデルを記録
+	extractedInfo.PromptVersion = g.promptVersion
+	extractedInfo.Provider = g.provider
+	extractedInfo.Model = g.model
+
+	return extractedInfo, nil
+}
+
+// 投稿本文からプロンプトを生成
+func (g *llmGateway) renderPrompt(post *gateway.HackingPost) (string, error) {
+	var prompt strings.Builder
+	if err := g.prompt.Execute(&prompt, promptData{Text: post.Text}); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s: %w", g.promptVersion, err)
+	}
+	return prompt.String(), nil
+}
@@ -1,3 +1,5 @@
+//go:build integration
+
 package gateway
 
 import (
@@ -12,7 +14,6 @@ import (
 	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
 
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
 )
 
 func TestAnalyzeAndExtract(t *testing.T) {
@@ -28,9 +29,9 @@ func TestAnalyzeAndExtract(t *testing.T) {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	geminiGateway, err := NewGeminiGateway(ctx, geminiAPIKey)
+	llmGateway, err := NewLLMGateway(ctx, LLMConfig{Provider: LLMProviderGemini, APIKey: geminiAPIKey})
 	if err != nil {
-		log.Fatalf("Failed to initialize Gemini Gateway: %v", err)
+		log.Fatalf("Failed to initialize LLM Gateway: %v", err)
 	}
 
 	post := &gateway.HackingPost{
@@ -40,8 +41,8 @@ func TestAnalyzeAndExtract(t *testing.T) {
 		TxHash:  "0xc3192361c65347c94935912188a94a923ff77da8",
 	}
 
-	// Geminiでテキストを分析
-	extractedInfo, err := geminiGateway.AnalyzeAndExtract(ctx, post)
+	// LLMでテキストを分析
+	extractedInfo, err := llmGateway.AnalyzeAndExtract(ctx, post)
 	if err != nil {
 		t.Error(err)
 	}
@@ -49,9 +50,9 @@ func TestAnalyzeAndExtract(t *testing.T) {
 	stop() // 他のコンテキストユーザーにキャンセルを通知
 	log.Println("Shutting down server...")
 
-	// Geminiクライアントを停止
-	if err := geminiGateway.Stop(); err != nil {
-		log.Println("Failed to stop gemini client:", err)
+	// LLMクライアントを停止
+	if err := llmGateway.Stop(); err != nil {
+		log.Println("Failed to stop llm client:", err)
 	}
 
 	log.Println("Server exiting")
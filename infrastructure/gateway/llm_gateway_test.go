@@ -0,0 +1,258 @@
+package gateway
+
+import (
+	"testing"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+
+	_ "github.com/lib/pq"
+)
+
+func TestAnalyzeAndExtract_OpenAICompatible(t *testing.T) {
+	var gotModel string
+	var gotAuth string
+	var gotFormat *openAIResponseFormat
+	requestCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("request path = %s, want /v1/chat/completions", r.URL.Path)
+		}
+		gotAuth = r.Header.Get("Authorization")
+
+		var req openAIChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		gotModel = req.Model
+		gotFormat = req.ResponseFormat
+		requestCount++
+
+		content := `{"protocol_name":"Resupply.fi","normalized_protocol_name":"resupply","tokens":["wstUSR","crvUSD"],"attack_vector":"first_deposit_inflation","confidence":0.95}`
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{
+				{"message": map[string]string{"role": "assistant", "content": content}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	llmGateway, err := NewLLMGateway(context.Background(), LLMConfig{
+		Provider: LLMProviderOpenAI,
+		Model:    "local-model",
+		BaseURL:  server.URL + "/v1/",
+		Timeout:  5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer llmGateway.Stop()
+
+	post := &gateway.HackingPost{
+		Text:    "Attack on Resupply.fi",
+		Network: "mainnet",
+		Amount:  "$9.3M",
+		TxHash:  "0xabc123",
+	}
+	extractedInfo, err := llmGateway.AnalyzeAndExtract(context.Background(), post)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requestCount != 1 {
+		t.Errorf("request count = %d, want 1", requestCount)
+	}
+	if gotFormat == nil || gotFormat.Type != "json_schema" || !reflect.DeepEqual(gotFormat.JSONSchema.Schema, hackingAnalysisSchema) {
+		t.Errorf("response_format = %+v, want json_schema", gotFormat)
+	}
+	if gotModel != "local-model" {
+		t.Errorf("model = %s, want local-model", gotModel)
+	}
+	if gotAuth != "" {
+		t.Errorf("Authorization header = %q, want empty", gotAuth)
+	}
+	if extractedInfo.Protocol != "Resupply.fi" || extractedInfo.TxHash != "0xabc123" ||
+		extractedInfo.AttackVector != entity.AttackVectorFirstDeposit || extractedInfo.Confidence != 0.95 {
+		t.Errorf("extractedInfo = %+v", extractedInfo)
+	}
+	if extractedInfo.PromptVersion != DefaultHackingPromptVersion || extractedInfo.Provider != LLMProviderOpenAI || extractedInfo.Model != "local-model" {
+		t.Errorf("provenance = %s/%s/%s, want %s/%s/local-model",
+			extractedInfo.PromptVersion, extractedInfo.Provider, extractedInfo.Model, DefaultHackingPromptVersion, LLMProviderOpenAI)
+	}
+	wantTags := []string{"wstUSR", "crvUSD", "resupply"}
+	if !reflect.DeepEqual(extractedInfo.TagNames, wantTags) {
+		t.Errorf("TagNames = %v, want %v", extractedInfo.TagNames, wantTags)
+	}
+}
+
+func TestExtractTransferPost_OpenAICompatible(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *gateway.TransferPost
+		wantErr error
+	}{
+		{
+			name:    "extracted",
+			content: `{"token":"#USDT","amount":"1,500,000","from":"#Binance","to":"unknown wallet","confidence":0.8}`,
+			want:    &gateway.TransferPost{Token: "USDT", Amount: "1500000", From: "Binance", To: "unknown wallet", TagNames: []string{"USDT"}},
+		},
+		{
+			name:    "not a transfer",
+			content: `{"token":"","amount":"","from":"","to":"","confidence":0.9}`,
+			wantErr: gateway.ErrPostNotExtracted,
+		},
+		{
+			name:    "missing field",
+			content: `{"token":"USDT","amount":"100","confidence":0.9}`,
+			wantErr: ErrInvalidAnalysis,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotFormat *openAIResponseFormat
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req openAIChatRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Fatal(err)
+				}
+				gotFormat = req.ResponseFormat
+				json.NewEncoder(w).Encode(map[string]any{
+					"choices": []map[string]any{
+						{"message": map[string]string{"role": "assistant", "content": tt.content}},
+					},
+				})
+			}))
+			defer server.Close()
+
+			llmGateway, err := NewLLMGateway(context.Background(), LLMConfig{
+				Provider: LLMProviderOpenAI,
+				Model:    "local-model",
+				BaseURL:  server.URL + "/v1/",
+				Timeout:  5 * time.Second,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer llmGateway.Stop()
+
+			reportTime := time.Unix(1700000000, 0)
+			post, confidence, err := llmGateway.ExtractTransferPost(context.Background(), &gateway.RejectedPost{
+				ChannelUsername: "transferchannel",
+				MessageID:       7,
+				Text:            "1,500,000 #USDT moved from #Binance to unknown wallet",
+				ReportTime:      reportTime,
+			})
+			if !reflect.DeepEqual(gotFormat.JSONSchema.Schema, transferPostExtractionSchema) {
+				t.Errorf("response_format = %+v, want transfer post extraction schema", gotFormat)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.want.Text = "1,500,000 #USDT moved from #Binance to unknown wallet"
+			tt.want.ReportTime = reportTime
+			tt.want.MessageID = 7
+			tt.want.ChannelUsername = "transferchannel"
+			if !reflect.DeepEqual(post, tt.want) {
+				t.Errorf("post = %+v, want %+v", post, tt.want)
+			}
+			if confidence != 0.8 {
+				t.Errorf("confidence = %v, want 0.8", confidence)
+			}
+		})
+	}
+}
+
+func TestNewLLMGateway_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  LLMConfig
+	}{
+		{name: "unsupported provider", cfg: LLMConfig{Provider: "unknown"}},
+		{name: "unknown prompt version", cfg: LLMConfig{Provider: LLMProviderOpenAI, Model: "local-model", PromptVersion: "hacking-v0"}},
+		{name: "openai without model", cfg: LLMConfig{Provider: LLMProviderOpenAI}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewLLMGateway(context.Background(), tt.cfg); err == nil {
+				t.Error("NewLLMGateway() error = nil, want error")
+			}
+		})
+	}
+}
+
+// 常に失敗するクライアント
+type failingLLMClient struct {
+	calls int
+}
+
+func (c *failingLLMClient) GenerateJSON(ctx context.Context, prompt string, schema *llmSchema) (string, error) {
+	c.calls++
+	return "", errors.New("unavailable")
+}
+
+func (c *failingLLMClient) Close() error {
+	return nil
+}
+
+func TestGenerate_CanceledDuringRetryDelay(t *testing.T) {
+	client := &failingLLMClient{}
+	g := &llmGateway{client: client}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := g.generate(ctx, "prompt", hackingAnalysisSchema)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("generate() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed >= llmRetryMinDelay {
+		t.Errorf("generate() returned after %v, want before the retry delay", elapsed)
+	}
+	if client.calls != 1 {
+		t.Errorf("GenerateJSON() calls = %d, want 1", client.calls)
+	}
+}
+
+func TestPromptTemplates(t *testing.T) {
+	versions := PromptVersions()
+	if len(versions) == 0 {
+		t.Fatal("no prompt templates found")
+	}
+
+	for _, version := range versions {
+		t.Run(version, func(t *testing.T) {
+			tmpl, err := loadPromptTemplate(version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			g := &llmGateway{promptVersion: version, prompt: tmpl}
+			prompt, err := g.renderPrompt(&gateway.HackingPost{Text: "Attack on Resupply.fi"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(prompt, "Attack on Resupply.fi") {
+				t.Errorf("rendered prompt does not contain post text")
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// OpenAI互換APIのデフォルトのベースURL
+const defaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// OpenAI互換の Chat Completions API クライアント
+// llama.cpp や Ollama などのローカルサーバーにも接続可能
+type openAIClient struct {
+	httpClient  *http.Client
+	baseURL     string
+	apiKey      string
+	model       string
+	temperature *float32
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIJSONSchema struct {
+	Name   string     `json:"name"`
+	Strict bool       `json:"strict"`
+	Schema *llmSchema `json:"schema"`
+}
+
+type openAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type openAIChatRequest struct {
+	Model          string                `json:"model"`
+	Messages       []openAIMessage       `json:"messages"`
+	Temperature    *float32              `json:"temperature,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+}
+
+type openAIErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func newOpenAIClient(cfg LLMConfig) (*openAIClient, error) {
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("openai model name is missing")
+	}
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+
+	return &openAIClient{
+		httpClient:  &http.Client{},
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		apiKey:      cfg.APIKey,
+		model:       cfg.Model,
+		temperature: cfg.Temperature,
+	}, nil
+}
+
+func (c *openAIClient) Close() error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+func (c *openAIClient) GenerateJSON(ctx context.Context, prompt string, schema *llmSchema) (string, error) {
+	reqBody, err := json.Marshal(openAIChatRequest{
+		Model:       c.model,
+		Messages:    []openAIMessage{{Role: "user", Content: prompt}},
+		Temperature: c.temperature,
+		// Structured Outputs でスキーマを指定
+		ResponseFormat: &openAIResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &openAIJSONSchema{Name: "response", Strict: true, Schema: schema},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal chat completion request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(reqBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to create chat completion request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call chat completion API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read chat completion response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp openAIErrorResponse
+		if json.Unmarshal(respBody, &errResp) == nil && errResp.Error.Message != "" {
+			return "", fmt.Errorf("chat completion API returned status %d: %s", resp.StatusCode, errResp.Error.Message)
+		}
+		return "", fmt.Errorf("chat completion API returned status %d", resp.StatusCode)
+	}
+
+	var chatResp openAIChatResponse
+	if err := json.Unmarshal(respBody, &chatResp); err != nil {
+		return "", fmt.Errorf("failed to unmarshal chat completion response: %w", err)
+	}
+	if len(chatResp.Choices) == 0 {
+		return "", fmt.Errorf("invalid response structure from chat completion API")
+	}
+
+	return strings.TrimSpace(chatResp.Choices[0].Message.Content), nil
+}
@@ -0,0 +1,206 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+// パースに失敗した投稿から値を抽出するプロンプトのテンプレート
+// 分析用のプロンプト（prompts）とはバージョンを分けて管理する
+//
+//go:embed extraction_prompts/*.tmpl
+var extractionPromptFS embed.FS
+
+// 抽出に使用するプロンプトのバージョン
+// プロンプトを変更する場合は、既存のテンプレートを編集せずに新しいバージョンを追加する
+const (
+	HackingPostExtractionPromptVersion  = "hacking-post-v1"
+	TransferPostExtractionPromptVersion = "transfer-post-v1"
+)
+
+var (
+	hackingPostExtractionPrompt  = mustLoadExtractionPrompt(HackingPostExtractionPromptVersion)
+	transferPostExtractionPrompt = mustLoadExtractionPrompt(TransferPostExtractionPromptVersion)
+)
+
+// 抽出用のプロンプトのテンプレートに渡すデータ
+type extractionPromptData struct {
+	Text        string
+	ReplyToText string
+}
+
+func mustLoadExtractionPrompt(version string) *template.Template {
+	content, err := extractionPromptFS.ReadFile("extraction_prompts/" + version + ".tmpl")
+	if err != nil {
+		panic(err)
+	}
+	return template.Must(template.New(version).Option("missingkey=error").Parse(string(content)))
+}
+
+// ハッキング情報の投稿の抽出結果のスキーマ
+var hackingPostExtractionSchema = &llmSchema{
+	Type: "object",
+	Properties: map[string]*llmSchema{
+		"network": {
+			Type:        "string",
+			Description: "Blockchain network of the exploit, or an empty string",
+		},
+		"tx_hash": {
+			Type:        "string",
+			Description: "Transaction hash of the exploit, or an empty string",
+		},
+		"amount": {
+			Type:        "string",
+			Description: "Amount lost including the unit or currency symbol, or an empty string",
+		},
+		"confidence": {
+			Type:        "number",
+			Description: "Confidence that the post reports a hack and the values are correct between 0 and 1",
+		},
+	},
+	Required:             []string{"network", "tx_hash", "amount", "confidence"},
+	AdditionalProperties: &noAdditionalProperties,
+}
+
+// 送金情報の投稿の抽出結果のスキーマ
+var transferPostExtractionSchema = &llmSchema{
+	Type: "object",
+	Properties: map[string]*llmSchema{
+		"token": {
+			Type:        "string",
+			Description: "Ticker symbol of the transferred token, or an empty string",
+		},
+		"amount": {
+			Type:        "string",
+			Description: "Transferred amount without separators, or an empty string",
+		},
+		"from": {
+			Type:        "string",
+			Description: "Sender name or address, or an empty string",
+		},
+		"to": {
+			Type:        "string",
+			Description: "Recipient name or address, or an empty string",
+		},
+		"confidence": {
+			Type:        "number",
+			Description: "Confidence that the post reports a transfer and the values are correct between 0 and 1",
+		},
+	},
+	Required:             []string{"token", "amount", "from", "to", "confidence"},
+	AdditionalProperties: &noAdditionalProperties,
+}
+
+// ハッキング情報の投稿の抽出結果
+type hackingPostExtraction struct {
+	Network    string  `json:"network"`
+	TxHash     string  `json:"tx_hash"`
+	Amount     string  `json:"amount"`
+	Confidence float64 `json:"confidence"`
+}
+
+// 送金情報の投稿の抽出結果
+type transferPostExtraction struct {
+	Token      string  `json:"token"`
+	Amount     string  `json:"amount"`
+	From       string  `json:"from"`
+	To         string  `json:"to"`
+	Confidence float64 `json:"confidence"`
+}
+
+func (g *llmGateway) ExtractHackingPost(ctx context.Context, post *gateway.RejectedPost) (*gateway.HackingPost, float64, error) {
+	var extraction hackingPostExtraction
+	if err := g.extractPost(ctx, hackingPostExtractionPrompt, hackingPostExtractionSchema, post, &extraction); err != nil {
+		return nil, 0, err
+	}
+
+	// ハッキング情報は金額を必須とする（組み込みのパーサーと同じ）
+	amount := strings.TrimSpace(extraction.Amount)
+	if amount == "" || extraction.Confidence == 0 {
+		return nil, 0, gateway.ErrPostNotExtracted
+	}
+
+	return &gateway.HackingPost{
+		Text:            post.Text,
+		ReplyToText:     post.ReplyToText,
+		Network:         strings.TrimSpace(extraction.Network),
+		Amount:          amount,
+		TxHash:          strings.TrimSpace(extraction.TxHash),
+		ReportTime:      post.ReportTime,
+		MessageID:       post.MessageID,
+		ChannelUsername: post.ChannelUsername,
+	}, extraction.Confidence, nil
+}
+
+func (g *llmGateway) ExtractTransferPost(ctx context.Context, post *gateway.RejectedPost) (*gateway.TransferPost, float64, error) {
+	var extraction transferPostExtraction
+	if err := g.extractPost(ctx, transferPostExtractionPrompt, transferPostExtractionSchema, post, &extraction); err != nil {
+		return nil, 0, err
+	}
+
+	// 送金情報はトークンを必須とする（組み込みのパーサーと同じ）
+	token := strings.TrimPrefix(strings.TrimSpace(extraction.Token), "#")
+	if token == "" || extraction.Confidence == 0 {
+		return nil, 0, gateway.ErrPostNotExtracted
+	}
+
+	// 組み込みのパーサーと同じく、ハッシュタグの "#" と金額の区切り文字を取り除く
+	// 投稿のハッシュタグは取得できないため、トークンをタグとする
+	return &gateway.TransferPost{
+		Text:            post.Text,
+		Token:           token,
+		Amount:          normalizeTransferAmount(strings.TrimSpace(extraction.Amount)),
+		From:            strings.TrimPrefix(strings.TrimSpace(extraction.From), "#"),
+		To:              strings.TrimPrefix(strings.TrimSpace(extraction.To), "#"),
+		ReportTime:      post.ReportTime,
+		MessageID:       post.MessageID,
+		ChannelUsername: post.ChannelUsername,
+		TagNames:        []string{token},
+	}, extraction.Confidence, nil
+}
+
+// 投稿からプロンプトを生成し、LLMの応答をスキーマに従って extraction にデコード
+func (g *llmGateway) extractPost(ctx context.Context, prompt *template.Template, schema *llmSchema, post *gateway.RejectedPost, extraction any) error {
+	var rendered strings.Builder
+	if err := prompt.Execute(&rendered, extractionPromptData{Text: post.Text, ReplyToText: post.ReplyToText}); err != nil {
+		return fmt.Errorf("failed to render prompt %s: %w", prompt.Name(), err)
+	}
+
+	resp, err := g.generate(ctx, rendered.String(), schema)
+	if err != nil {
+		return err
+	}
+	return parsePostExtraction(resp, schema, extraction)
+}
+
+// LLMの応答をパースし、スキーマに従っているか検証
+func parsePostExtraction(resp string, schema *llmSchema, extraction any) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(resp), &fields); err != nil {
+		return fmt.Errorf("%w: response is not a JSON object: %v", ErrInvalidAnalysis, err)
+	}
+	for _, name := range schema.Required {
+		if _, ok := fields[name]; !ok {
+			return fmt.Errorf("%w: missing required field %q", ErrInvalidAnalysis, name)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(resp)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(extraction); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAnalysis, err)
+	}
+
+	var confidence float64
+	if err := json.Unmarshal(fields["confidence"], &confidence); err != nil || confidence < 0 || confidence > 1 {
+		return fmt.Errorf("%w: confidence must be between 0 and 1, got %s", ErrInvalidAnalysis, fields["confidence"])
+	}
+	return nil
+}
@@ -0,0 +1,380 @@
+package gateway
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+// パーサーが対象とする投稿の種類
+const (
+	PostKindHacking  = "hacking"
+	PostKindTransfer = "transfer"
+)
+
+// 組み込みのパーサー名
+const (
+	// "Network:", "Exploit:", "Balance" を含むハッキング情報の投稿
+	BuiltinHackingParser = "builtin-hacking"
+	// "X TOKEN transferred from A to B" 形式の送金情報の投稿
+	BuiltinTransferParser = "builtin-transfer"
+)
+
+// テンプレートの名前付きグループに指定できるフィールド
+// 各種類の先頭のフィールドは必須
+var postParserFields = map[string][]string{
+	PostKindHacking:  {"amount", "network", "tx_hash"},
+	PostKindTransfer: {"token", "amount", "from", "to"},
+}
+
+// 直近の投稿の失敗率を判定するデフォルトの件数と閾値
+const (
+	defaultParseFailureWindow    = 50
+	defaultParseFailureThreshold = 0.5
+)
+
+// パース結果（/v1/admin/metrics で公開）
+var (
+	// チャンネル毎のパースを試みた投稿の件数
+	postParseAttempts = expvar.NewMap("post_parse_attempts")
+	// チャンネル毎のパースに失敗した投稿の件数
+	postParseFailures = expvar.NewMap("post_parse_failures")
+	// チャンネル毎の直近の投稿のパースに失敗した割合
+	postParseRecentFailureRate = expvar.NewMap("post_parse_recent_failure_rate")
+	// チャンネル毎の直近の投稿の失敗率が閾値を超えた回数
+	postParseWarnings = expvar.NewMap("post_parse_warnings")
+)
+
+func init() {
+	// チャンネル毎のパースに失敗した割合
+	// 投稿の形式が変わると 1 に近づく
+	expvar.Publish("post_parse_failure_rate", expvar.Func(func() any {
+		rates := make(map[string]float64)
+		postParseAttempts.Do(func(kv expvar.KeyValue) {
+			attempts := kv.Value.(*expvar.Int).Value()
+			if attempts == 0 {
+				return
+			}
+			var failures int64
+			if v, ok := postParseFailures.Get(kv.Key).(*expvar.Int); ok {
+				failures = v.Value()
+			}
+			rates[kv.Key] = float64(failures) / float64(attempts)
+		})
+		return rates
+	}))
+}
+
+// 投稿本文からハッキング情報を取得するパーサー
+type HackingPostParser func(message string) (*gateway.HackingPost, error)
+
+// 投稿本文から送金情報を取得するパーサー
+type TransferPostParser func(message string) (*gateway.TransferPost, error)
+
+// 正規表現のテンプレート
+// 名前付きグループ（例: (?P<amount>\S+)）で取得したフィールドを投稿の値とする
+type PostParserTemplate struct {
+	Kind    string `json:"kind"`
+	Pattern string `json:"pattern"`
+}
+
+// チャンネル毎のパーサーの設定
+type PostParserConfig struct {
+	// テンプレート名とテンプレート
+	Templates map[string]PostParserTemplate `json:"templates"`
+	// チャンネル名とパーサー名（テンプレート名または組み込みのパーサー名）
+	// 指定のないチャンネルは組み込みのパーサーを使用
+	Channels map[string]string `json:"channels"`
+	// 失敗率を判定する直近の投稿の件数（省略時は 50）
+	FailureWindow int `json:"failure_window"`
+	// 直近の投稿の失敗率がこの値以上になった場合に警告（省略時は 0.5）
+	FailureThreshold float64 `json:"failure_threshold"`
+}
+
+// POST_PARSER_CONFIG で指定したJSONファイルからパーサーの設定を読み込む
+// 未設定の場合は、全てのチャンネルで組み込みのパーサーを使用
+func PostParserConfigFromEnv() (PostParserConfig, error) {
+	var config PostParserConfig
+	if path := os.Getenv("POST_PARSER_CONFIG"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return config, fmt.Errorf("failed to read post parser config: %w", err)
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return config, fmt.Errorf("failed to parse post parser config: %w", err)
+		}
+	}
+
+	// 失敗率の判定は環境変数の設定を優先
+	if windowStr := os.Getenv("POST_PARSE_FAILURE_WINDOW"); windowStr != "" {
+		window, err := strconv.Atoi(windowStr)
+		if err != nil || window <= 0 {
+			return config, fmt.Errorf("invalid POST_PARSE_FAILURE_WINDOW: %s", windowStr)
+		}
+		config.FailureWindow = window
+	}
+	if thresholdStr := os.Getenv("POST_PARSE_FAILURE_THRESHOLD"); thresholdStr != "" {
+		threshold, err := strconv.ParseFloat(thresholdStr, 64)
+		if err != nil || threshold <= 0 || threshold > 1 {
+			return config, fmt.Errorf("invalid POST_PARSE_FAILURE_THRESHOLD: %s", thresholdStr)
+		}
+		config.FailureThreshold = threshold
+	}
+	return config, nil
+}
+
+// チャンネル毎のパーサー
+type PostParserRegistry struct {
+	hacking  map[string]HackingPostParser
+	transfer map[string]TransferPostParser
+	// 小文字のチャンネル名とパーサー名
+	channels map[string]string
+	// 失敗率を判定する直近の投稿の件数と閾値
+	failureWindow    int
+	failureThreshold float64
+}
+
+// 設定のテンプレートを組み込みのパーサーと共に登録
+func NewPostParserRegistry(config PostParserConfig) (*PostParserRegistry, error) {
+	r := &PostParserRegistry{
+		hacking:  map[string]HackingPostParser{BuiltinHackingParser: parseHackingPost},
+		transfer: map[string]TransferPostParser{BuiltinTransferParser: parseTransferPost},
+		channels: make(map[string]string),
+
+		failureWindow:    defaultParseFailureWindow,
+		failureThreshold: defaultParseFailureThreshold,
+	}
+	if config.FailureWindow < 0 {
+		return nil, fmt.Errorf("invalid post parser failure_window: %d", config.FailureWindow)
+	}
+	if config.FailureWindow > 0 {
+		r.failureWindow = config.FailureWindow
+	}
+	if config.FailureThreshold < 0 || config.FailureThreshold > 1 {
+		return nil, fmt.Errorf("invalid post parser failure_threshold: %v", config.FailureThreshold)
+	}
+	if config.FailureThreshold > 0 {
+		r.failureThreshold = config.FailureThreshold
+	}
+
+	for name, template := range config.Templates {
+		if name == BuiltinHackingParser || name == BuiltinTransferParser {
+			return nil, fmt.Errorf("post parser template %s conflicts with a built-in parser", name)
+		}
+		re, err := compilePostParserTemplate(template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid post parser template %s: %w", name, err)
+		}
+		switch template.Kind {
+		case PostKindHacking:
+			r.hacking[name] = hackingTemplateParser(name, re)
+		case PostKindTransfer:
+			r.transfer[name] = transferTemplateParser(name, re)
+		}
+	}
+
+	for channel, name := range config.Channels {
+		_, isHacking := r.hacking[name]
+		_, isTransfer := r.transfer[name]
+		if !isHacking && !isTransfer {
+			return nil, fmt.Errorf("unknown post parser %s for channel %s", name, channel)
+		}
+		r.channels[strings.ToLower(channel)] = name
+	}
+	return r, nil
+}
+
+// チャンネルのハッキング情報のパーサー
+// パース結果はチャンネル毎に集計
+func (r *PostParserRegistry) HackingParser(channelUsername string) (HackingPostParser, error) {
+	name := r.parserName(channelUsername, BuiltinHackingParser)
+	parser, ok := r.hacking[name]
+	if !ok {
+		return nil, fmt.Errorf("post parser %s for channel %s is not a %s parser", name, channelUsername, PostKindHacking)
+	}
+
+	counter := r.newParseResultCounter(PostKindHacking + "/" + channelUsername)
+	return func(message string) (*gateway.HackingPost, error) {
+		post, err := parser(message)
+		counter.count(err)
+		return post, err
+	}, nil
+}
+
+// チャンネルの送金情報のパーサー
+// パース結果はチャンネル毎に集計
+func (r *PostParserRegistry) TransferParser(channelUsername string) (TransferPostParser, error) {
+	name := r.parserName(channelUsername, BuiltinTransferParser)
+	parser, ok := r.transfer[name]
+	if !ok {
+		return nil, fmt.Errorf("post parser %s for channel %s is not a %s parser", name, channelUsername, PostKindTransfer)
+	}
+
+	counter := r.newParseResultCounter(PostKindTransfer + "/" + channelUsername)
+	return func(message string) (*gateway.TransferPost, error) {
+		post, err := parser(message)
+		counter.count(err)
+		return post, err
+	}, nil
+}
+
+// チャンネルに指定されたパーサー名（指定がない場合は builtin）
+func (r *PostParserRegistry) parserName(channelUsername string, builtin string) string {
+	if name, ok := r.channels[strings.ToLower(channelUsername)]; ok {
+		return name
+	}
+	return builtin
+}
+
+// チャンネル毎のパース結果の集計
+// 直近の投稿の失敗率が閾値を超えた場合は、投稿の形式が変わった可能性があるため警告
+type parseResultCounter struct {
+	key       string
+	threshold float64
+
+	mu sync.Mutex
+	// 直近の投稿のパース結果（true は失敗）を循環して保持
+	recent   []bool
+	next     int
+	filled   bool
+	failures int
+	warning  bool
+}
+
+func (r *PostParserRegistry) newParseResultCounter(key string) *parseResultCounter {
+	return &parseResultCounter{
+		key:       key,
+		threshold: r.failureThreshold,
+		recent:    make([]bool, r.failureWindow),
+	}
+}
+
+func (c *parseResultCounter) count(err error) {
+	postParseAttempts.Add(c.key, 1)
+	if err != nil {
+		postParseFailures.Add(c.key, 1)
+	} else {
+		// 失敗がなくても割合を 0 として公開するため、キーを作成
+		postParseFailures.Add(c.key, 0)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	failed := err != nil
+	if c.recent[c.next] {
+		c.failures--
+	}
+	if failed {
+		c.failures++
+	}
+	c.recent[c.next] = failed
+	c.next = (c.next + 1) % len(c.recent)
+	if c.next == 0 {
+		c.filled = true
+	}
+
+	// 判定に必要な件数の投稿をパースするまでは判定しない
+	size := len(c.recent)
+	if !c.filled {
+		size = c.next
+	}
+	rate := float64(c.failures) / float64(size)
+	rateVar := new(expvar.Float)
+	rateVar.Set(rate)
+	postParseRecentFailureRate.Set(c.key, rateVar)
+	if !c.filled {
+		return
+	}
+
+	switch {
+	case !c.warning && rate >= c.threshold:
+		c.warning = true
+		postParseWarnings.Add(c.key, 1)
+		log.Printf("WARNING: %s: %d of the last %d posts failed to parse. The post format may have changed.", c.key, c.failures, size)
+	case c.warning && rate < c.threshold:
+		c.warning = false
+		log.Printf("%s: parse failure rate of the last %d posts recovered to %.0f%%", c.key, size, rate*100)
+	}
+}
+
+// テンプレートの正規表現をコンパイルし、名前付きグループを検証
+func compilePostParserTemplate(template PostParserTemplate) (*regexp.Regexp, error) {
+	fields, ok := postParserFields[template.Kind]
+	if !ok {
+		return nil, fmt.Errorf("invalid kind: %q", template.Kind)
+	}
+	re, err := regexp.Compile(template.Pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]bool)
+	for _, group := range re.SubexpNames() {
+		if group == "" {
+			continue
+		}
+		if !slices.Contains(fields, group) {
+			return nil, fmt.Errorf("unknown field %q (available: %s)", group, strings.Join(fields, ", "))
+		}
+		groups[group] = true
+	}
+	if !groups[fields[0]] {
+		return nil, fmt.Errorf("required field %q is not captured", fields[0])
+	}
+	return re, nil
+}
+
+// テンプレートに一致した投稿の、名前付きグループとその値
+func matchTemplate(name string, re *regexp.Regexp, message string) (map[string]string, error) {
+	match := re.FindStringSubmatch(message)
+	if match == nil {
+		return nil, fmt.Errorf("post parser template %s does not match message", name)
+	}
+	fields := make(map[string]string)
+	for i, group := range re.SubexpNames() {
+		if group != "" {
+			fields[group] = strings.TrimSpace(match[i])
+		}
+	}
+	return fields, nil
+}
+
+func hackingTemplateParser(name string, re *regexp.Regexp) HackingPostParser {
+	return func(message string) (*gateway.HackingPost, error) {
+		fields, err := matchTemplate(name, re, message)
+		if err != nil {
+			return nil, err
+		}
+		return &gateway.HackingPost{
+			Network: fields["network"],
+			Amount:  fields["amount"],
+			TxHash:  fields["tx_hash"],
+		}, nil
+	}
+}
+
+// 組み込みのパーサーと同じく、ハッシュタグの "#" と金額の区切り文字を取り除く
+func transferTemplateParser(name string, re *regexp.Regexp) TransferPostParser {
+	return func(message string) (*gateway.TransferPost, error) {
+		fields, err := matchTemplate(name, re, message)
+		if err != nil {
+			return nil, err
+		}
+		return &gateway.TransferPost{
+			Token:  strings.TrimPrefix(fields["token"], "#"),
+			Amount: normalizeTransferAmount(fields["amount"]),
+			From:   strings.TrimPrefix(fields["from"], "#"),
+			To:     strings.TrimSuffix(strings.TrimPrefix(fields["to"], "#"), "."),
+		}, nil
+	}
+}
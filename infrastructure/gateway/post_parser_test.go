@@ -0,0 +1,233 @@
+package gateway
+
+import (
+	"expvar"
+	"strings"
+	"testing"
+)
+
+func TestPostParserRegistry_Template(t *testing.T) {
+	registry, err := NewPostParserRegistry(PostParserConfig{
+		Templates: map[string]PostParserTemplate{
+			"whale-alert": {
+				Kind:    PostKindTransfer,
+				Pattern: `(?P<amount>[\d,.]+) #(?P<token>\w+) \(.*?\) moved from (?P<from>\S+) to (?P<to>\S+)`,
+			},
+		},
+		Channels: map[string]string{"WhaleChannel": "whale-alert"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// チャンネル名の大文字・小文字は区別しない
+	parse, err := registry.TransferParser("whalechannel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	post, err := parse("🚨 1,500,000 #USDC (1,499,850 USD) moved from #Binance to #Coinbase.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Token != "USDC" || post.Amount != "1500000" || post.From != "Binance" || post.To != "Coinbase" {
+		t.Errorf("post = %+v", post)
+	}
+
+	if _, err := parse("⚠️141,271.0 #USDT transferred from A to B."); err == nil {
+		t.Error("expected error for message in the built-in format")
+	}
+
+	// 指定のないチャンネルは組み込みのパーサー
+	builtin, err := registry.TransferParser("transferchannel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	post, err = builtin("⚠️141,271.0 #USDT transferred from A to B.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Token != "USDT" || post.Amount != "141271.0" {
+		t.Errorf("post = %+v", post)
+	}
+}
+
+func TestPostParserRegistry_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config PostParserConfig
+		want   string
+	}{
+		{
+			name: "unknown kind",
+			config: PostParserConfig{Templates: map[string]PostParserTemplate{
+				"t": {Kind: "swap", Pattern: `(?P<amount>\d+)`},
+			}},
+			want: "invalid kind",
+		},
+		{
+			name: "invalid pattern",
+			config: PostParserConfig{Templates: map[string]PostParserTemplate{
+				"t": {Kind: PostKindHacking, Pattern: `(?P<amount>\d+`},
+			}},
+			want: "missing closing )",
+		},
+		{
+			name: "unknown field",
+			config: PostParserConfig{Templates: map[string]PostParserTemplate{
+				"t": {Kind: PostKindHacking, Pattern: `(?P<amount>\d+) (?P<token>\w+)`},
+			}},
+			want: `unknown field "token"`,
+		},
+		{
+			name: "missing required field",
+			config: PostParserConfig{Templates: map[string]PostParserTemplate{
+				"t": {Kind: PostKindTransfer, Pattern: `(?P<amount>\d+)`},
+			}},
+			want: `required field "token"`,
+		},
+		{
+			name: "built-in name",
+			config: PostParserConfig{Templates: map[string]PostParserTemplate{
+				BuiltinHackingParser: {Kind: PostKindHacking, Pattern: `(?P<amount>\d+)`},
+			}},
+			want: "conflicts with a built-in parser",
+		},
+		{
+			name:   "invalid threshold",
+			config: PostParserConfig{FailureThreshold: 1.5},
+			want:   "invalid post parser failure_threshold",
+		},
+		{
+			name:   "unknown parser",
+			config: PostParserConfig{Channels: map[string]string{"hackchannel": "missing"}},
+			want:   "unknown post parser missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPostParserRegistry(tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHackingPost_Truncated(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		wantErr bool
+	}{
+		{name: "ends with network", message: "Exploit: 0x1234\nNetwork:", wantErr: true},
+		{name: "ends with exploit", message: "Network: Ethereum\nExploit:", wantErr: true},
+		{name: "balance is second to last", message: "Network: Ethereum\nBalance change:", wantErr: true},
+		{name: "balance is last", message: "Network: Ethereum\nBalance", wantErr: true},
+		{name: "complete", message: "Network: Ethereum\nExploit: 0x1234\nBalance change: $100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post, err := parseHackingPost(tt.message)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (post.Network != "Ethereum" || post.TxHash != "0x1234" || post.Amount != "$100") {
+				t.Errorf("post = %+v", post)
+			}
+		})
+	}
+}
+
+func TestPostParserRegistry_KindMismatch(t *testing.T) {
+	registry, err := NewPostParserRegistry(PostParserConfig{
+		Channels: map[string]string{"hackchannel": BuiltinTransferParser},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.HackingParser("hackchannel"); err == nil {
+		t.Error("expected error for transfer parser assigned to hacking channel")
+	}
+}
+
+func TestPostParserRegistry_Metrics(t *testing.T) {
+	registry, err := NewPostParserRegistry(PostParserConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parse, err := registry.HackingParser("metricschannel")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parse("Network: Ethereum\nExploit: 0x1234\nBalance 100 ETH")
+	parse("unrelated message")
+	parse("another unrelated message")
+
+	key := PostKindHacking + "/metricschannel"
+	if got := postParseAttempts.Get(key).(*expvar.Int).Value(); got != 3 {
+		t.Errorf("attempts = %d, want 3", got)
+	}
+	if got := postParseFailures.Get(key).(*expvar.Int).Value(); got != 2 {
+		t.Errorf("failures = %d, want 2", got)
+	}
+	rates := expvar.Get("post_parse_failure_rate").(expvar.Func)().(map[string]float64)
+	if got := rates[key]; got < 0.66 || got > 0.67 {
+		t.Errorf("failure rate = %v, want 2/3", got)
+	}
+}
+
+func TestPostParserRegistry_FailureWindow(t *testing.T) {
+	registry, err := NewPostParserRegistry(PostParserConfig{FailureWindow: 4, FailureThreshold: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parse, err := registry.TransferParser("windowchannel")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := PostKindTransfer + "/windowchannel"
+	warnings := func() int64 {
+		if v, ok := postParseWarnings.Get(key).(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+	valid := "1,000 #USDT transferred from A to B."
+
+	// 判定に必要な件数の投稿をパースするまでは警告しない
+	parse(valid)
+	parse("unrelated")
+	parse("unrelated")
+	if got := warnings(); got != 0 {
+		t.Errorf("warnings before window is filled = %d, want 0", got)
+	}
+
+	// 直近4件の内3件が失敗
+	parse("unrelated")
+	if got := warnings(); got != 1 {
+		t.Errorf("warnings = %d, want 1", got)
+	}
+	if got := postParseRecentFailureRate.Get(key).(*expvar.Float).Value(); got != 0.75 {
+		t.Errorf("recent failure rate = %v, want 0.75", got)
+	}
+
+	// 閾値を下回るまでは再度警告しない
+	parse("unrelated")
+	parse(valid)
+	if got := warnings(); got != 1 {
+		t.Errorf("warnings while failing = %d, want 1", got)
+	}
+
+	// 閾値を下回った後に再び超えた場合は警告
+	parse(valid)
+	parse(valid)
+	parse("unrelated")
+	parse("unrelated")
+	if got := warnings(); got != 2 {
+		t.Errorf("warnings after recovery = %d, want 2", got)
+	}
+}
@@ -0,0 +1,171 @@
+package gateway
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+	"github.com/itout-datetoya/hack-info-timeline/domain/repository"
+)
+
+// 価格表の価格を使用する期間
+// 報告日以前で最新の価格がこれより古い場合は、価格が不明として扱う
+const maxPriceAge = 7 * 24 * time.Hour
+
+// 価格表のCSVのヘッダー
+var priceCSVHeader = []string{"symbol", "date", "price_usd"}
+
+// 価格表のCSVを読み込む
+// 1行目はヘッダー（symbol,date,price_usd）、日付は YYYY-MM-DD（UTC）
+func ParsePriceCSV(r io.Reader) ([]*entity.TokenPrice, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(priceCSVHeader)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price csv header: %w", err)
+	}
+	for i, name := range priceCSVHeader {
+		if !strings.EqualFold(strings.TrimSpace(header[i]), name) {
+			return nil, fmt.Errorf("invalid price csv header: %s, want %s", strings.Join(header, ","), strings.Join(priceCSVHeader, ","))
+		}
+	}
+
+	var prices []*entity.TokenPrice
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read price csv: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		symbol := normalizePriceSymbol(record[0])
+		if symbol == "" {
+			return nil, fmt.Errorf("invalid price csv line %d: empty symbol", line)
+		}
+		date, err := time.Parse(time.DateOnly, strings.TrimSpace(record[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid price csv line %d: invalid date %q", line, record[1])
+		}
+		priceUSD := strings.TrimSpace(record[2])
+		if price, ok := parsePriceUSD(priceUSD); !ok || price.Sign() < 0 {
+			return nil, fmt.Errorf("invalid price csv line %d: invalid price %q", line, record[2])
+		}
+
+		prices = append(prices, &entity.TokenPrice{Symbol: symbol, PriceDate: date, PriceUSD: priceUSD})
+	}
+
+	return prices, nil
+}
+
+// トークンのシンボルを価格表の形式（大文字、"#" なし）に変換
+func normalizePriceSymbol(symbol string) string {
+	return strings.ToUpper(strings.TrimPrefix(strings.TrimSpace(symbol), "#"))
+}
+
+// 10進数の文字列の価格を変換
+// 分数（"1/3"）の形式は価格として扱わない
+func parsePriceUSD(priceUSD string) (*big.Rat, bool) {
+	if strings.Contains(priceUSD, "/") {
+		return nil, false
+	}
+	return new(big.Rat).SetString(priceUSD)
+}
+
+// 報告日以前で最新の価格が、報告日の価格として使用できるか判定
+func priceAvailable(price *entity.TokenPrice, at time.Time) bool {
+	return price != nil && priceDate(at).Sub(price.PriceDate) <= maxPriceAge
+}
+
+// 価格表の日付（UTC）
+func priceDate(at time.Time) time.Time {
+	return at.UTC().Truncate(24 * time.Hour)
+}
+
+// CSVファイルから読み込んだ価格表
+// DBに接続せずに使用できる（評価・オフラインの実行など）
+type csvPriceSource struct {
+	// トークン毎の価格（日付の昇順）
+	prices map[string][]*entity.TokenPrice
+}
+
+// CSVファイルから価格表を読み込む
+func NewCSVPriceSource(path string) (gateway.PriceSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open price csv: %w", err)
+	}
+	defer file.Close()
+
+	prices, err := ParsePriceCSV(file)
+	if err != nil {
+		return nil, err
+	}
+	return newCSVPriceSource(prices), nil
+}
+
+func newCSVPriceSource(prices []*entity.TokenPrice) *csvPriceSource {
+	s := &csvPriceSource{prices: make(map[string][]*entity.TokenPrice)}
+	for _, price := range prices {
+		s.prices[price.Symbol] = append(s.prices[price.Symbol], price)
+	}
+	for _, symbolPrices := range s.prices {
+		sort.Slice(symbolPrices, func(i, j int) bool { return symbolPrices[i].PriceDate.Before(symbolPrices[j].PriceDate) })
+	}
+	return s
+}
+
+func (s *csvPriceSource) PriceUSD(ctx context.Context, symbol string, at time.Time) (*big.Rat, error) {
+	symbolPrices := s.prices[normalizePriceSymbol(symbol)]
+	date := priceDate(at)
+
+	// 報告日以前で最新の価格
+	i := sort.Search(len(symbolPrices), func(i int) bool { return symbolPrices[i].PriceDate.After(date) })
+	if i == 0 || !priceAvailable(symbolPrices[i-1], at) {
+		return nil, gateway.ErrPriceNotFound
+	}
+	return priceRat(symbolPrices[i-1])
+}
+
+// DBに保存した価格表
+// 価格は import-prices サブコマンドでCSVから読み込む
+type dbPriceSource struct {
+	repo repository.PriceRepository
+}
+
+func NewDBPriceSource(repo repository.PriceRepository) gateway.PriceSource {
+	return &dbPriceSource{repo: repo}
+}
+
+func (s *dbPriceSource) PriceUSD(ctx context.Context, symbol string, at time.Time) (*big.Rat, error) {
+	price, err := s.repo.GetLatestPrice(ctx, normalizePriceSymbol(symbol), at)
+	if err != nil {
+		return nil, err
+	}
+	if !priceAvailable(price, at) {
+		return nil, gateway.ErrPriceNotFound
+	}
+	return priceRat(price)
+}
+
+// 価格表の価格を計算に使用する形式に変換
+func priceRat(price *entity.TokenPrice) (*big.Rat, error) {
+	priceUSD, ok := parsePriceUSD(price.PriceUSD)
+	if !ok {
+		return nil, fmt.Errorf("invalid price of %s at %s: %q", price.Symbol, price.PriceDate.Format(time.DateOnly), price.PriceUSD)
+	}
+	return priceUSD, nil
+}
@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+func TestParsePriceCSV(t *testing.T) {
+	prices, err := ParsePriceCSV(strings.NewReader("symbol,date,price_usd\n#eth,2024-01-01,2281.5\nUSDT, 2024-01-01, 1.0001\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prices) != 2 {
+		t.Fatalf("parsed %d prices, want 2", len(prices))
+	}
+	if prices[0].Symbol != "ETH" || !prices[0].PriceDate.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || prices[0].PriceUSD != "2281.5" {
+		t.Errorf("price = %+v", prices[0])
+	}
+
+	tests := []struct {
+		name string
+		csv  string
+		want string
+	}{
+		{name: "invalid header", csv: "token,date,price\nETH,2024-01-01,1\n", want: "invalid price csv header"},
+		{name: "invalid date", csv: "symbol,date,price_usd\nETH,01/01/2024,1\n", want: "line 2: invalid date"},
+		{name: "invalid price", csv: "symbol,date,price_usd\nETH,2024-01-01,-1\n", want: "line 2: invalid price"},
+		{name: "fraction price", csv: "symbol,date,price_usd\nETH,2024-01-01,1/3\n", want: "line 2: invalid price"},
+		{name: "missing column", csv: "symbol,date,price_usd\nETH,2024-01-01\n", want: "wrong number of fields"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePriceCSV(strings.NewReader(tt.csv))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVPriceSource(t *testing.T) {
+	prices, err := ParsePriceCSV(strings.NewReader("symbol,date,price_usd\nETH,2024-01-03,2300\nETH,2024-01-01,2200\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := newCSVPriceSource(prices)
+
+	tests := []struct {
+		name    string
+		symbol  string
+		at      time.Time
+		want    string
+		wantErr error
+	}{
+		{name: "same day", symbol: "ETH", at: time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC), want: "2200"},
+		{name: "latest before report", symbol: "eth", at: time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC), want: "2200"},
+		{name: "utc date", symbol: "ETH", at: time.Date(2024, 1, 3, 8, 0, 0, 0, time.FixedZone("JST", 9*60*60)), want: "2200"},
+		{name: "within max age", symbol: "ETH", at: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), want: "2300"},
+		{name: "stale", symbol: "ETH", at: time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC), wantErr: gateway.ErrPriceNotFound},
+		{name: "before first price", symbol: "ETH", at: time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), wantErr: gateway.ErrPriceNotFound},
+		{name: "unknown token", symbol: "BTC", at: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), wantErr: gateway.ErrPriceNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := source.PriceUSD(context.Background(), tt.symbol, tt.at)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if want, _ := new(big.Rat).SetString(tt.want); got.Cmp(want) != 0 {
+				t.Errorf("price = %v, want %v", got.FloatString(2), tt.want)
+			}
+		})
+	}
+}
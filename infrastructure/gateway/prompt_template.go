@@ -0,0 +1,56 @@
+package gateway
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// バージョン毎のプロンプトのテンプレート
+// ファイル名（拡張子を除く）をプロンプトのバージョンとして扱う
+//
+//go:embed prompts/*.tmpl
+var promptFS embed.FS
+
+// デフォルトで使用するハッキング情報の分析用プロンプトのバージョン
+// プロンプトを変更する場合は、既存のテンプレートを編集せずに新しいバージョンを追加する
+const DefaultHackingPromptVersion = "hacking-v1"
+
+// プロンプトのテンプレートに渡すデータ
+type promptData struct {
+	Text string
+}
+
+// 指定したバージョンのプロンプトのテンプレートを読み込み
+func loadPromptTemplate(version string) (*template.Template, error) {
+	content, err := promptFS.ReadFile(path.Join("prompts", version+".tmpl"))
+	if err != nil {
+		return nil, fmt.Errorf("prompt version %q is not found (available: %s): %w",
+			version, strings.Join(PromptVersions(), ", "), err)
+	}
+
+	tmpl, err := template.New(version).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt template %q: %w", version, err)
+	}
+	return tmpl, nil
+}
+
+// 利用可能なプロンプトのバージョンの一覧
+func PromptVersions() []string {
+	entries, err := fs.ReadDir(promptFS, "prompts")
+	if err != nil {
+		return nil
+	}
+
+	versions := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		versions = append(versions, strings.TrimSuffix(entry.Name(), ".tmpl"))
+	}
+	sort.Strings(versions)
+	return versions
+}
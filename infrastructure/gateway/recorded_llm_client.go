@@ -0,0 +1,120 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// 記録済みの応答が存在しない場合のエラー
+var ErrRecordingNotFound = errors.New("recorded LLM response not found")
+
+// replay プロバイダーのデフォルトのモデル名
+const defaultReplayModel = "recorded"
+
+// 記録ファイルの1行
+// プロンプトのハッシュと、LLMが返したJSON文字列の組
+type llmRecording struct {
+	PromptHash string `json:"prompt_sha256"`
+	Response   string `json:"response"`
+}
+
+// プロンプトを記録ファイルのキーに変換
+func recordingKey(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// 記録済みの応答を返すクライアント
+// プロンプトが一致する応答のみを返すため、プロンプトや投稿本文を変更した場合は再記録が必要
+type replayClient struct {
+	responses map[string]string
+}
+
+func newReplayClient(cfg LLMConfig) (*replayClient, error) {
+	if cfg.RecordingFile == "" {
+		return nil, errors.New("recording file is required for replay provider")
+	}
+
+	file, err := os.Open(cfg.RecordingFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording file: %w", err)
+	}
+	defer file.Close()
+
+	responses := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var recording llmRecording
+		if err := json.Unmarshal(scanner.Bytes(), &recording); err != nil {
+			return nil, fmt.Errorf("failed to parse recording file at line %d: %w", lineNumber, err)
+		}
+		// 同じプロンプトが複数回記録されている場合は後の応答を優先
+		responses[recording.PromptHash] = recording.Response
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recording file: %w", err)
+	}
+
+	return &replayClient{responses: responses}, nil
+}
+
+func (c *replayClient) GenerateJSON(ctx context.Context, prompt string, schema *llmSchema) (string, error) {
+	key := recordingKey(prompt)
+	response, ok := c.responses[key]
+	if !ok {
+		return "", fmt.Errorf("%w: prompt_sha256=%s", ErrRecordingNotFound, key)
+	}
+	return response, nil
+}
+
+func (c *replayClient) Close() error {
+	return nil
+}
+
+// 実際のLLMの応答を記録ファイルに追記するクライアント
+type recordingClient struct {
+	client  llmClient
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+func newRecordingClient(client llmClient, path string) (*recordingClient, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording file: %w", err)
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetEscapeHTML(false)
+	return &recordingClient{client: client, file: file, encoder: encoder}, nil
+}
+
+func (c *recordingClient) GenerateJSON(ctx context.Context, prompt string, schema *llmSchema) (string, error) {
+	response, err := c.client.GenerateJSON(ctx, prompt, schema)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.encoder.Encode(llmRecording{PromptHash: recordingKey(prompt), Response: response}); err != nil {
+		return "", fmt.Errorf("failed to write recording: %w", err)
+	}
+	return response, nil
+}
+
+func (c *recordingClient) Close() error {
+	return errors.Join(c.client.Close(), c.file.Close())
+}
@@ -0,0 +1,61 @@
+package gateway
+
+import "sync"
+
+// 編集の確認で再取得した投稿のパース結果
+// 本文が変わっていない投稿を再度パースせず、パース結果の集計やパースの失敗の通知が重複しないようにする
+type refetchedPosts[T any] struct {
+	mu      sync.Mutex
+	results map[int]refetchedPost[T]
+}
+
+type refetchedPost[T any] struct {
+	text string
+	post *T
+	err  error
+}
+
+// 再取得した投稿を parse でパース
+// text にはパースに使用する本文を指定し、前回の再取得から変わっていなければ、パースせずに前回の結果を返して reused を true にする
+func (r *refetchedPosts[T]) parse(messageID int, text string, parse func() (*T, error)) (post *T, reused bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if result, ok := r.results[messageID]; ok && result.text == text {
+		if result.err != nil {
+			return nil, true, result.err
+		}
+		// 呼び出し元で投稿の情報を添付するため、コピーを返す
+		copied := *result.post
+		return &copied, true, nil
+	}
+
+	post, err = parse()
+	if r.results == nil {
+		r.results = make(map[int]refetchedPost[T])
+	}
+	result := refetchedPost[T]{text: text, err: err}
+	if err == nil {
+		copied := *post
+		result.post = &copied
+	}
+	r.results[messageID] = result
+	return post, false, err
+}
+
+// 指定したメッセージID以外のパース結果を破棄
+// 再取得の対象は直近の投稿のため、対象から外れた投稿の結果を保持し続けないようにする
+func (r *refetchedPosts[T]) retain(messageIDs []int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keep := make(map[int]bool, len(messageIDs))
+	for _, messageID := range messageIDs {
+		keep[messageID] = true
+	}
+	for messageID := range r.results {
+		if !keep[messageID] {
+			delete(r.results, messageID)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package gateway
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+// パースに失敗した投稿の通知先
+type rejectedPostNotifier struct {
+	mu      sync.RWMutex
+	handler gateway.RejectedPostHandler
+}
+
+func (n *rejectedPostNotifier) set(handler gateway.RejectedPostHandler) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.handler = handler
+}
+
+// パースに失敗した投稿をログに出力し、handler に渡す
+func (n *rejectedPostNotifier) notify(ctx context.Context, post *gateway.RejectedPost) {
+	log.Printf("Failed to parse message %d in %s with error: %s", post.MessageID, post.ChannelUsername, post.Error)
+
+	n.mu.RLock()
+	handler := n.handler
+	n.mu.RUnlock()
+	if handler != nil {
+		handler(ctx, post)
+	}
+}
+
+// 投稿の取得中にパースに失敗した投稿
+// handler では保存や LLM での抽出を行うため、ゲートウェイのロックを解放してから flush で通知する
+type rejectedPosts struct {
+	notifier *rejectedPostNotifier
+	posts    []*gateway.RejectedPost
+}
+
+// 取得毎に、パースに失敗した投稿を集める
+func (n *rejectedPostNotifier) collect() *rejectedPosts {
+	return &rejectedPosts{notifier: n}
+}
+
+func (r *rejectedPosts) add(post *gateway.RejectedPost) {
+	r.posts = append(r.posts, post)
+}
+
+// 集めた投稿を通知
+func (r *rejectedPosts) flush(ctx context.Context) {
+	for _, post := range r.posts {
+		r.notifier.notify(ctx, post)
+	}
+	r.posts = nil
+}
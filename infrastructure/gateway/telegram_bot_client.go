@@ -0,0 +1,466 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+// Bot API のデフォルトのベースURL
+const DefaultTelegramBotAPIURL = "https://api.telegram.org"
+
+// 接続状態のメトリクス・ヘルスチェックで使用する名前
+const TelegramBotAccountName = "bot"
+
+// Webhook のリクエストに付与されるシークレットトークンのヘッダー
+const telegramBotSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// getUpdates のロングポーリングのデフォルトの待機時間
+const defaultTelegramBotPollTimeout = 50 * time.Second
+
+// 受信する更新の種類
+var telegramBotAllowedUpdates = []string{"channel_post", "edited_channel_post"}
+
+// 種類毎の受信した更新の件数（/v1/admin/metrics で公開）
+var telegramBotUpdates = expvar.NewMap("telegram_bot_updates")
+
+// Bot API クライアントの設定
+type TelegramBotConfig struct {
+	// BotFather で発行したボットのトークン
+	Token string
+	// Bot API のベースURL。空の場合は DefaultTelegramBotAPIURL（テストではローカルのサーバーを指定）
+	BaseURL string
+	// Webhook で更新を受信する場合の公開URL。空の場合は getUpdates で受信
+	WebhookURL string
+	// Webhook のリクエストを検証するシークレットトークン
+	WebhookSecret string
+	// getUpdates のロングポーリングの待機時間
+	PollTimeout time.Duration
+}
+
+// 環境変数から Bot API クライアントの設定を読み込み
+func TelegramBotConfigFromEnv() (TelegramBotConfig, error) {
+	cfg := TelegramBotConfig{
+		Token:         os.Getenv("TELEGRAM_BOT_TOKEN"),
+		BaseURL:       os.Getenv("TELEGRAM_BOT_API_URL"),
+		WebhookURL:    os.Getenv("TELEGRAM_BOT_WEBHOOK_URL"),
+		WebhookSecret: os.Getenv("TELEGRAM_BOT_WEBHOOK_SECRET"),
+		PollTimeout:   defaultTelegramBotPollTimeout,
+	}
+	if cfg.Token == "" {
+		return cfg, fmt.Errorf("TELEGRAM_BOT_TOKEN is not set")
+	}
+	// シークレットトークンがないと、誰でも投稿を送り込めてしまう
+	if cfg.WebhookURL != "" && cfg.WebhookSecret == "" {
+		return cfg, fmt.Errorf("TELEGRAM_BOT_WEBHOOK_SECRET is required when TELEGRAM_BOT_WEBHOOK_URL is set")
+	}
+
+	if timeoutStr := os.Getenv("TELEGRAM_BOT_POLL_TIMEOUT"); timeoutStr != "" {
+		timeout, err := time.ParseDuration(timeoutStr)
+		if err != nil || timeout < 0 {
+			return cfg, fmt.Errorf("invalid TELEGRAM_BOT_POLL_TIMEOUT: %s", timeoutStr)
+		}
+		cfg.PollTimeout = timeout
+	}
+	return cfg, nil
+}
+
+// Bot API のエラー応答
+type telegramBotAPIError struct {
+	Method      string
+	Code        int
+	Description string
+	// 429 の場合に再試行までの待機時間
+	RetryAfter time.Duration
+}
+
+func (e *telegramBotAPIError) Error() string {
+	return fmt.Sprintf("bot API %s returned %d: %s", e.Method, e.Code, e.Description)
+}
+
+type telegramBotResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+	Parameters  *struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+type telegramBotUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+type telegramBotUpdate struct {
+	UpdateID          int                 `json:"update_id"`
+	ChannelPost       *telegramBotMessage `json:"channel_post"`
+	EditedChannelPost *telegramBotMessage `json:"edited_channel_post"`
+}
+
+type telegramBotChat struct {
+	ID       int64  `json:"id"`
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Username string `json:"username"`
+}
+
+type telegramBotMessageEntity struct {
+	Type   string `json:"type"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+}
+
+// Bot API のメッセージ
+// reply_to_message には、さらにその先のリプライ先は含まれない
+type telegramBotMessage struct {
+	MessageID       int                        `json:"message_id"`
+	Date            int64                      `json:"date"`
+	Chat            telegramBotChat            `json:"chat"`
+	Text            string                     `json:"text"`
+	Entities        []telegramBotMessageEntity `json:"entities"`
+	Caption         string                     `json:"caption"`
+	CaptionEntities []telegramBotMessageEntity `json:"caption_entities"`
+	ReplyToMessage  *telegramBotMessage        `json:"reply_to_message"`
+}
+
+// 投稿本文（メディアの投稿はキャプション）
+func (m *telegramBotMessage) text() string {
+	if m.Text != "" {
+		return m.Text
+	}
+	return m.Caption
+}
+
+func (m *telegramBotMessage) entities() []telegramBotMessageEntity {
+	if m.Text != "" {
+		return m.Entities
+	}
+	return m.CaptionEntities
+}
+
+// Telegram Bot API で、ボットが管理者のチャンネルの投稿を受信するクライアント
+// ユーザーアカウントでのログインが不要な代わりに、過去の投稿は取得できず、受信した投稿のみ取得できる
+type TelegramBotClient struct {
+	httpClient *http.Client
+	baseURL    string
+	token      string
+	config     TelegramBotConfig
+
+	mu     sync.RWMutex
+	status gateway.ConnectionStatus
+
+	// 購読者を実行するコンテキスト（Run で設定）
+	ctx  context.Context
+	wg   sync.WaitGroup
+	stop context.CancelFunc
+
+	// 初めて接続できた時点で閉じられる
+	ready     chan struct{}
+	readyOnce sync.Once
+
+	// getUpdates に失敗した場合の再試行の待機時間（テストで差し替え）
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	// チャンネル名（小文字）毎の受信した投稿
+	channelsMu sync.Mutex
+	channels   map[string]*telegramBotChannel
+}
+
+// 新しいTelegramBotClientを生成
+func NewTelegramBotClient(config TelegramBotConfig) (*TelegramBotClient, error) {
+	if config.Token == "" {
+		return nil, fmt.Errorf("telegram bot token is missing")
+	}
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultTelegramBotAPIURL
+	}
+
+	c := &TelegramBotClient{
+		httpClient: &http.Client{},
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      config.Token,
+		config:     config,
+		ctx:        context.Background(),
+		ready:      make(chan struct{}),
+		minBackoff: minReconnectBackoff,
+		maxBackoff: time.Minute,
+		channels:   make(map[string]*telegramBotChannel),
+	}
+	c.setStatus(gateway.ConnectionConnecting, nil)
+	return c, nil
+}
+
+// ボットのトークンを確認し、更新の受信を開始
+// Webhook のURLが設定されている場合は Webhook を登録し、それ以外は getUpdates をバックグラウンドで実行
+func (c *TelegramBotClient) Run(ctx context.Context) error {
+	var me telegramBotUser
+	if err := c.call(ctx, "getMe", nil, &me); err != nil {
+		c.setStatus(gateway.ConnectionFailed, err)
+		return fmt.Errorf("failed to get bot info: %w", err)
+	}
+	log.Printf("Telegram bot @%s connected.", me.Username)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	c.mu.Lock()
+	c.ctx, c.stop = runCtx, cancel
+	c.mu.Unlock()
+
+	if c.config.WebhookURL != "" {
+		err := c.call(ctx, "setWebhook", map[string]any{
+			"url":             c.config.WebhookURL,
+			"secret_token":    c.config.WebhookSecret,
+			"allowed_updates": telegramBotAllowedUpdates,
+		}, nil)
+		if err != nil {
+			cancel()
+			c.setStatus(gateway.ConnectionFailed, err)
+			return fmt.Errorf("failed to set webhook: %w", err)
+		}
+		c.setStatus(gateway.ConnectionReady, nil)
+		return nil
+	}
+
+	// Webhook が登録されていると getUpdates を使用できない
+	if err := c.call(ctx, "deleteWebhook", nil, nil); err != nil {
+		cancel()
+		c.setStatus(gateway.ConnectionFailed, err)
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	c.setStatus(gateway.ConnectionReady, nil)
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.poll(runCtx)
+	}()
+	return nil
+}
+
+// 更新の受信を停止し、実行中の購読者の終了を待機
+func (c *TelegramBotClient) Stop() error {
+	c.mu.RLock()
+	stop := c.stop
+	c.mu.RUnlock()
+	if stop != nil {
+		stop()
+	}
+	c.wg.Wait()
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// 初めて接続できた時点で閉じられるチャネル
+func (c *TelegramBotClient) Ready() <-chan struct{} {
+	return c.ready
+}
+
+func (c *TelegramBotClient) Status() gateway.ConnectionStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.status
+}
+
+// 接続状態を更新し、変化した場合はログとメトリクスに記録
+func (c *TelegramBotClient) setStatus(state gateway.ConnectionState, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := gateway.ConnectionStatus{State: state, Since: c.status.Since}
+	if err != nil {
+		status.LastError = err.Error()
+	}
+	if state != c.status.State {
+		status.Since = time.Now()
+		if c.status.State != "" {
+			log.Printf("Telegram bot connection state: %s -> %s", c.status.State, state)
+		}
+		var value expvar.String
+		value.Set(string(state))
+		telegramConnectionState.Set(TelegramBotAccountName, &value)
+		telegramConnectionTransitions.Add(TelegramBotAccountName+"/"+string(state), 1)
+	}
+	if state == gateway.ConnectionReady {
+		c.readyOnce.Do(func() { close(c.ready) })
+	}
+	c.status = status
+}
+
+// getUpdates で更新を受信し続ける
+// 確認済みの更新のIDを offset で通知するまで、未取得の更新はTelegram側で保持される
+func (c *TelegramBotClient) poll(ctx context.Context) {
+	offset := 0
+	backoff := c.minBackoff
+	for {
+		var updates []*telegramBotUpdate
+		err := c.call(ctx, "getUpdates", map[string]any{
+			"offset":          offset,
+			"timeout":         int(c.config.PollTimeout / time.Second),
+			"allowed_updates": telegramBotAllowedUpdates,
+		}, &updates)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			wait := backoff
+			var apiErr *telegramBotAPIError
+			if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+				wait = apiErr.RetryAfter
+			} else {
+				backoff = min(backoff*2, c.maxBackoff)
+			}
+			log.Printf("Failed to get Telegram bot updates, retrying in %s: %v", wait, err)
+			c.setStatus(gateway.ConnectionFailed, err)
+			if sleepContext(ctx, wait) != nil {
+				return
+			}
+			continue
+		}
+
+		backoff = c.minBackoff
+		c.setStatus(gateway.ConnectionReady, nil)
+		for _, update := range updates {
+			c.handleUpdate(update)
+			if update.UpdateID >= offset {
+				offset = update.UpdateID + 1
+			}
+		}
+	}
+}
+
+// Webhook で更新を受信するハンドラー
+// シークレットトークンが一致しないリクエストは拒否
+func (c *TelegramBotClient) WebhookHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := r.Header.Get(telegramBotSecretHeader)
+		if c.config.WebhookSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(c.config.WebhookSecret)) != 1 {
+			http.Error(w, "invalid secret token", http.StatusUnauthorized)
+			return
+		}
+
+		var update telegramBotUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, "invalid update", http.StatusBadRequest)
+			return
+		}
+		c.handleUpdate(&update)
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// 受信した投稿を保持し、購読者に通知
+// 購読していないチャンネルの投稿は無視
+func (c *TelegramBotClient) handleUpdate(update *telegramBotUpdate) {
+	switch {
+	case update.ChannelPost != nil:
+		telegramBotUpdates.Add("channel_post", 1)
+		if channel := c.channelOf(update.ChannelPost.Chat); channel != nil {
+			channel.receive(c.handlerContext(), update.ChannelPost, false, &c.wg)
+		}
+	case update.EditedChannelPost != nil:
+		telegramBotUpdates.Add("edited_channel_post", 1)
+		if channel := c.channelOf(update.EditedChannelPost.Chat); channel != nil {
+			channel.receive(c.handlerContext(), update.EditedChannelPost, true, &c.wg)
+		}
+	}
+}
+
+func (c *TelegramBotClient) handlerContext() context.Context {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ctx
+}
+
+// 受信した投稿を保持するチャンネル（ゲートウェイの生成時に登録）
+func (c *TelegramBotClient) channel(username string) *telegramBotChannel {
+	c.channelsMu.Lock()
+	defer c.channelsMu.Unlock()
+
+	key := strings.ToLower(strings.TrimPrefix(username, "@"))
+	channel, ok := c.channels[key]
+	if !ok {
+		channel = newTelegramBotChannel()
+		c.channels[key] = channel
+	}
+	return channel
+}
+
+// 投稿されたチャンネルが登録済みであれば返す
+func (c *TelegramBotClient) channelOf(chat telegramBotChat) *telegramBotChannel {
+	if chat.Username == "" {
+		return nil
+	}
+	c.channelsMu.Lock()
+	defer c.channelsMu.Unlock()
+	return c.channels[strings.ToLower(chat.Username)]
+}
+
+// Bot API のメソッドを呼び出し、結果を result にデコード
+func (c *TelegramBotClient) call(ctx context.Context, method string, params any, result any) error {
+	if params == nil {
+		params = struct{}{}
+	}
+	reqBody, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", method, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/bot"+c.token+"/"+method, bytes.NewReader(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// URLに含まれるトークンをエラーに残さない
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to call bot API %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %w", method, err)
+	}
+
+	var botResp telegramBotResponse
+	if err := json.Unmarshal(respBody, &botResp); err != nil {
+		return fmt.Errorf("failed to unmarshal %s response (status %d): %w", method, resp.StatusCode, err)
+	}
+	if !botResp.OK {
+		apiErr := &telegramBotAPIError{Method: method, Code: botResp.ErrorCode, Description: botResp.Description}
+		if botResp.Parameters != nil {
+			apiErr.RetryAfter = time.Duration(botResp.Parameters.RetryAfter) * time.Second
+		}
+		return apiErr
+	}
+
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(botResp.Result, result); err != nil {
+		return fmt.Errorf("failed to unmarshal %s result: %w", method, err)
+	}
+	return nil
+}
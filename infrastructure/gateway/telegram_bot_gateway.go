@@ -0,0 +1,482 @@
+package gateway
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+// チャンネル毎に保持する受信した投稿の上限（編集の再取得に使用）
+const telegramBotChannelCapacity = 1000
+
+// Bot API で受信したチャンネルの投稿と購読者
+// Bot API では過去の投稿や指定したIDの投稿を取得できないため、受信した投稿を保持して取得に使用する
+type telegramBotChannel struct {
+	mu       sync.Mutex
+	messages map[int]*telegramBotMessage
+	// 保持している投稿のID（昇順）
+	ids             []int
+	messageHandlers []channelMessageHandler
+	changeHandlers  []channelChangeHandler
+}
+
+func newTelegramBotChannel() *telegramBotChannel {
+	return &telegramBotChannel{messages: make(map[int]*telegramBotMessage)}
+}
+
+func (ch *telegramBotChannel) onMessage(handler channelMessageHandler) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.messageHandlers = append(ch.messageHandlers, handler)
+}
+
+func (ch *telegramBotChannel) onChange(handler channelChangeHandler) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.changeHandlers = append(ch.changeHandlers, handler)
+}
+
+// 受信した投稿を保持し、購読者を別のゴルーチンで実行
+// 編集された投稿をリプライ先とする投稿は、リプライ先の本文も更新する
+func (ch *telegramBotChannel) receive(ctx context.Context, message *telegramBotMessage, edited bool, wg *sync.WaitGroup) {
+	ch.mu.Lock()
+	ch.store(message)
+	if edited {
+		for id, reply := range ch.messages {
+			if reply.ReplyToMessage == nil || reply.ReplyToMessage.MessageID != message.MessageID {
+				continue
+			}
+			// 取得中の投稿を書き換えないよう、コピーを保持
+			updated := *reply
+			replied := *message
+			replied.ReplyToMessage = nil
+			updated.ReplyToMessage = &replied
+			ch.messages[id] = &updated
+		}
+	}
+	messageHandlers, changeHandlers := ch.messageHandlers, ch.changeHandlers
+	ch.mu.Unlock()
+
+	if edited {
+		for _, handler := range changeHandlers {
+			wg.Add(1)
+			go func(handler channelChangeHandler) {
+				defer wg.Done()
+				handler(ctx)
+			}(handler)
+		}
+		return
+	}
+	for _, handler := range messageHandlers {
+		wg.Add(1)
+		go func(handler channelMessageHandler) {
+			defer wg.Done()
+			handler(ctx, message.MessageID)
+		}(handler)
+	}
+}
+
+// 上限を超えた場合は古い投稿から破棄
+func (ch *telegramBotChannel) store(message *telegramBotMessage) {
+	if _, ok := ch.messages[message.MessageID]; !ok {
+		i := sort.SearchInts(ch.ids, message.MessageID)
+		ch.ids = append(ch.ids, 0)
+		copy(ch.ids[i+1:], ch.ids[i:])
+		ch.ids[i] = message.MessageID
+	}
+	ch.messages[message.MessageID] = message
+
+	for len(ch.ids) > telegramBotChannelCapacity {
+		delete(ch.messages, ch.ids[0])
+		ch.ids = ch.ids[1:]
+	}
+}
+
+// minID より新しい投稿を、新しい順に limit 件以下返す
+func (ch *telegramBotChannel) messagesAfter(minID int, limit int) []*telegramBotMessage {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	var messages []*telegramBotMessage
+	for i := len(ch.ids) - 1; i >= 0 && ch.ids[i] > minID && len(messages) < limit; i-- {
+		messages = append(messages, ch.messages[ch.ids[i]])
+	}
+	return messages
+}
+
+// 保持している投稿の内、指定したIDの投稿を返す
+func (ch *telegramBotChannel) messagesByIDs(messageIDs []int) []*telegramBotMessage {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	var messages []*telegramBotMessage
+	for _, messageID := range messageIDs {
+		if message, ok := ch.messages[messageID]; ok {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// Bot API で受信した投稿から取得するTelegramHackingPostGatewayの実装
+type telegramBotHackingPostGateway struct {
+	channel         *telegramBotChannel
+	channelUsername string
+	parse           HackingPostParser
+	rejected        rejectedPostNotifier
+	refetched       refetchedPosts[gateway.HackingPost]
+	lastMessageID   int
+	peer            *gateway.ChannelPeer
+	mu              sync.Mutex
+}
+
+// ボットが管理者のチャンネルから、受信した投稿を取得するTelegramHackingPostGatewayを生成
+// parse にはチャンネルの投稿の形式に合わせたパーサーを指定（PostParserRegistry で取得）
+func NewTelegramBotHackingPostGateway(client *TelegramBotClient, channelUsername string, parse HackingPostParser) gateway.TelegramHackingPostGateway {
+	return &telegramBotHackingPostGateway{
+		channel:         client.channel(channelUsername),
+		channelUsername: channelUsername,
+		parse:           parse,
+	}
+}
+
+func (g *telegramBotHackingPostGateway) SetLastMessageID(lastMessageID int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.lastMessageID = lastMessageID
+}
+
+func (g *telegramBotHackingPostGateway) LastMessageID() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.lastMessageID
+}
+
+func (g *telegramBotHackingPostGateway) ChannelUsername() string {
+	return g.channelUsername
+}
+
+// Bot API ではチャンネル名の解決が不要なため、設定されたチャンネル情報をそのまま保持
+// ユーザーアカウントでの取得に戻した場合に、保存済みのアクセスハッシュを失わないようにする
+func (g *telegramBotHackingPostGateway) SetChannelPeer(peer *gateway.ChannelPeer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.peer = peer
+}
+
+func (g *telegramBotHackingPostGateway) ChannelPeer() *gateway.ChannelPeer {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.peer
+}
+
+func (g *telegramBotHackingPostGateway) SetRejectedPostHandler(handler gateway.RejectedPostHandler) {
+	g.rejected.set(handler)
+}
+
+// 最後に取得した投稿以降に受信した投稿を取得
+func (g *telegramBotHackingPostGateway) GetPosts(ctx context.Context, limit int) ([]*gateway.HackingPost, error) {
+	// パースに失敗した投稿は、ロックを解放してから通知
+	rejected := g.rejected.collect()
+	defer rejected.flush(ctx)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var posts []*gateway.HackingPost
+	for _, message := range g.channel.messagesAfter(g.lastMessageID, limit) {
+		if message.text() == "" {
+			continue
+		}
+		if post := g.convertMessage(message, false, rejected); post != nil {
+			posts = append(posts, post)
+		}
+		if message.MessageID > g.lastMessageID {
+			g.lastMessageID = message.MessageID
+		}
+	}
+	return posts, nil
+}
+
+// Bot API では過去の投稿を遡れないため、GetPosts と同じく受信した投稿のみ取得
+func (g *telegramBotHackingPostGateway) GetPostsOver100(ctx context.Context, limit int) ([]*gateway.HackingPost, error) {
+	return g.GetPosts(ctx, limit)
+}
+
+// 受信済みの投稿の内、指定したメッセージIDの最新の投稿をHackingPostに変換
+// Bot API では削除が通知されないため、deletedIDs は常に空
+func (g *telegramBotHackingPostGateway) GetPostsByMessageIDs(ctx context.Context, messageIDs []int) ([]*gateway.HackingPost, []int, error) {
+	rejected := g.rejected.collect()
+	defer rejected.flush(ctx)
+
+	var posts []*gateway.HackingPost
+	for _, message := range g.channel.messagesByIDs(messageIDs) {
+		if post := g.convertMessage(message, true, rejected); post != nil {
+			posts = append(posts, post)
+		}
+	}
+	g.refetched.retain(messageIDs)
+	return posts, nil, nil
+}
+
+// Bot API では過去の投稿を遡れない
+func (g *telegramBotHackingPostGateway) GetPostsBefore(ctx context.Context, maxID int, limit int) ([]*gateway.HackingPost, *gateway.HistoryPage, error) {
+	return nil, nil, gateway.ErrHistoryUnavailable
+}
+
+// チャンネルの投稿の編集の通知を購読
+func (g *telegramBotHackingPostGateway) SubscribeChanges(ctx context.Context, handler func(ctx context.Context)) error {
+	g.channel.onChange(handler)
+	return nil
+}
+
+// チャンネルの新しい投稿の通知を購読
+// 通知を受けたら、最後に取得した投稿以降の投稿を取得して handler に渡す
+func (g *telegramBotHackingPostGateway) SubscribeNewPosts(ctx context.Context, handler func(ctx context.Context, posts []*gateway.HackingPost)) error {
+	g.channel.onMessage(func(ctx context.Context, messageID int) {
+		// 取得済みの投稿は無視
+		if messageID <= g.LastMessageID() {
+			return
+		}
+
+		posts, err := g.GetPosts(ctx, realtimeFetchLimit)
+		if err != nil {
+			log.Printf("Failed to get new posts from %s: %v", g.channelUsername, err)
+			return
+		}
+		handler(ctx, posts)
+	})
+	return nil
+}
+
+// リプライ先の投稿からハッキング情報を取得し、HackingPostに変換
+// リプライでない投稿や、ハッキング情報を含まない投稿の場合は nil を返す
+// Bot API のリプライ先にはその先のリプライ先が含まれないため、リプライ先がさらにリプライかは判定できない
+// パースに失敗した投稿は rejected に追加
+// refetch は編集の確認での再取得の場合に指定し、本文が変わっていない投稿は前回の結果を使用してパースの失敗を再度通知しない
+func (g *telegramBotHackingPostGateway) convertMessage(message *telegramBotMessage, refetch bool, rejected *rejectedPosts) *gateway.HackingPost {
+	replied := message.ReplyToMessage
+	if replied == nil {
+		return nil
+	}
+
+	var post *gateway.HackingPost
+	var err error
+	reused := false
+	if refetch {
+		post, reused, err = g.refetched.parse(message.MessageID, message.text()+"\x00"+replied.text(), func() (*gateway.HackingPost, error) {
+			return g.parse(replied.text())
+		})
+	} else {
+		post, err = g.parse(replied.text())
+	}
+	if err != nil {
+		if reused {
+			return nil
+		}
+		rejected.add(&gateway.RejectedPost{
+			ChannelUsername: g.channelUsername,
+			MessageID:       message.MessageID,
+			Text:            message.text(),
+			ReplyToText:     replied.text(),
+			ReportTime:      time.Unix(replied.Date, 0),
+			Error:           err.Error(),
+		})
+		return nil
+	}
+
+	post.ReportTime = time.Unix(replied.Date, 0)
+	post.MessageID = message.MessageID
+	post.ChannelUsername = g.channelUsername
+	post.Text = message.text()
+	post.ReplyToText = replied.text()
+	return post
+}
+
+// Bot API で受信した投稿から取得するTelegramTransferPostGatewayの実装
+type telegramBotTransferPostGateway struct {
+	channel         *telegramBotChannel
+	channelUsername string
+	parse           TransferPostParser
+	rejected        rejectedPostNotifier
+	refetched       refetchedPosts[gateway.TransferPost]
+	lastMessageID   int
+	peer            *gateway.ChannelPeer
+	mu              sync.Mutex
+}
+
+// ボットが管理者のチャンネルから、受信した投稿を取得するTelegramTransferPostGatewayを生成
+// parse にはチャンネルの投稿の形式に合わせたパーサーを指定（PostParserRegistry で取得）
+func NewTelegramBotTransferPostGateway(client *TelegramBotClient, channelUsername string, parse TransferPostParser) gateway.TelegramTransferPostGateway {
+	return &telegramBotTransferPostGateway{
+		channel:         client.channel(channelUsername),
+		channelUsername: channelUsername,
+		parse:           parse,
+	}
+}
+
+func (g *telegramBotTransferPostGateway) SetLastMessageID(lastMessageID int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.lastMessageID = lastMessageID
+}
+
+func (g *telegramBotTransferPostGateway) LastMessageID() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.lastMessageID
+}
+
+func (g *telegramBotTransferPostGateway) ChannelUsername() string {
+	return g.channelUsername
+}
+
+// Bot API ではチャンネル名の解決が不要なため、設定されたチャンネル情報をそのまま保持
+func (g *telegramBotTransferPostGateway) SetChannelPeer(peer *gateway.ChannelPeer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.peer = peer
+}
+
+func (g *telegramBotTransferPostGateway) ChannelPeer() *gateway.ChannelPeer {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.peer
+}
+
+func (g *telegramBotTransferPostGateway) SetRejectedPostHandler(handler gateway.RejectedPostHandler) {
+	g.rejected.set(handler)
+}
+
+// 最後に取得した投稿以降に受信した投稿を取得
+func (g *telegramBotTransferPostGateway) GetPosts(ctx context.Context, limit int) ([]*gateway.TransferPost, error) {
+	// パースに失敗した投稿は、ロックを解放してから通知
+	rejected := g.rejected.collect()
+	defer rejected.flush(ctx)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var posts []*gateway.TransferPost
+	for _, message := range g.channel.messagesAfter(g.lastMessageID, limit) {
+		if message.text() == "" {
+			continue
+		}
+		if post := g.convertMessage(message, false, rejected); post != nil {
+			posts = append(posts, post)
+		}
+		if message.MessageID > g.lastMessageID {
+			g.lastMessageID = message.MessageID
+		}
+	}
+	return posts, nil
+}
+
+// 受信済みの投稿の内、指定したメッセージIDの最新の投稿をTransferPostに変換
+// Bot API では削除が通知されないため、deletedIDs は常に空
+func (g *telegramBotTransferPostGateway) GetPostsByMessageIDs(ctx context.Context, messageIDs []int) ([]*gateway.TransferPost, []int, error) {
+	rejected := g.rejected.collect()
+	defer rejected.flush(ctx)
+
+	var posts []*gateway.TransferPost
+	for _, message := range g.channel.messagesByIDs(messageIDs) {
+		if post := g.convertMessage(message, true, rejected); post != nil {
+			posts = append(posts, post)
+		}
+	}
+	g.refetched.retain(messageIDs)
+	return posts, nil, nil
+}
+
+// Bot API では過去の投稿を遡れない
+func (g *telegramBotTransferPostGateway) GetPostsBefore(ctx context.Context, maxID int, limit int) ([]*gateway.TransferPost, *gateway.HistoryPage, error) {
+	return nil, nil, gateway.ErrHistoryUnavailable
+}
+
+// チャンネルの投稿の編集の通知を購読
+func (g *telegramBotTransferPostGateway) SubscribeChanges(ctx context.Context, handler func(ctx context.Context)) error {
+	g.channel.onChange(handler)
+	return nil
+}
+
+// チャンネルの新しい投稿の通知を購読
+// 通知を受けたら、最後に取得した投稿以降の投稿を取得して handler に渡す
+func (g *telegramBotTransferPostGateway) SubscribeNewPosts(ctx context.Context, handler func(ctx context.Context, posts []*gateway.TransferPost)) error {
+	g.channel.onMessage(func(ctx context.Context, messageID int) {
+		// 取得済みの投稿は無視
+		if messageID <= g.LastMessageID() {
+			return
+		}
+
+		posts, err := g.GetPosts(ctx, realtimeFetchLimit)
+		if err != nil {
+			log.Printf("Failed to get new posts from %s: %v", g.channelUsername, err)
+			return
+		}
+		handler(ctx, posts)
+	})
+	return nil
+}
+
+// 投稿から送金情報を取得し、TransferPostに変換
+// 送金情報を含まない投稿の場合は nil を返し、パースに失敗した投稿は rejected に追加
+// refetch は編集の確認での再取得の場合に指定し、本文が変わっていない投稿は前回の結果を使用してパースの失敗を再度通知しない
+func (g *telegramBotTransferPostGateway) convertMessage(message *telegramBotMessage, refetch bool, rejected *rejectedPosts) *gateway.TransferPost {
+	var post *gateway.TransferPost
+	var err error
+	reused := false
+	if refetch {
+		post, reused, err = g.refetched.parse(message.MessageID, message.text(), func() (*gateway.TransferPost, error) {
+			return g.parse(message.text())
+		})
+	} else {
+		post, err = g.parse(message.text())
+	}
+	if err != nil {
+		if reused {
+			return nil
+		}
+		rejected.add(&gateway.RejectedPost{
+			ChannelUsername: g.channelUsername,
+			MessageID:       message.MessageID,
+			Text:            message.text(),
+			ReportTime:      time.Unix(message.Date, 0),
+			Error:           err.Error(),
+		})
+		return nil
+	}
+
+	post.ReportTime = time.Unix(message.Date, 0)
+	post.MessageID = message.MessageID
+	post.ChannelUsername = g.channelUsername
+	post.Text = message.text()
+	post.TagNames = extractBotHashtags(message.text(), message.entities())
+	return post
+}
+
+// 投稿に付けられたハッシュタグを取得
+// Bot API のエンティティのオフセットはUTF-16のコード単位
+func extractBotHashtags(message string, entities []telegramBotMessageEntity) []string {
+	var tags []string
+	if len(entities) == 0 {
+		return tags
+	}
+
+	units := utf16.Encode([]rune(message))
+	for _, entity := range entities {
+		if entity.Type != "hashtag" {
+			continue
+		}
+		start := entity.Offset
+		end := entity.Offset + entity.Length
+		if start >= 0 && end <= len(units) {
+			tag := string(utf16.Decode(units[start:end]))
+			tags = append(tags, strings.TrimPrefix(tag, "#"))
+		}
+	}
+	return tags
+}
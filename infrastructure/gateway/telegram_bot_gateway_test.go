@@ -0,0 +1,337 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+const testHackingOriginal = "🚨 Exploit detected Network: Ethereum Exploit: 0xabc Balance change: $1,200,000"
+
+// Bot API のリクエストを記録し、メソッド毎に応答するローカルのサーバー
+type fakeBotAPI struct {
+	mu      sync.Mutex
+	calls   []string
+	offsets []int
+	// getUpdates の応答（順に返し、尽きたら空の応答）
+	updates [][]byte
+}
+
+func (f *fakeBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method, ok := strings.CutPrefix(r.URL.Path, "/bottest-token/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	var params struct {
+		Offset int `json:"offset"`
+	}
+	json.NewDecoder(r.Body).Decode(&params)
+
+	f.mu.Lock()
+	f.calls = append(f.calls, method)
+	var response []byte
+	switch method {
+	case "getMe":
+		response = []byte(`{"ok":true,"result":{"id":1,"username":"test_bot"}}`)
+	case "deleteWebhook", "setWebhook":
+		response = []byte(`{"ok":true,"result":true}`)
+	case "getUpdates":
+		f.offsets = append(f.offsets, params.Offset)
+		if len(f.updates) > 0 {
+			response, f.updates = f.updates[0], f.updates[1:]
+		}
+	}
+	f.mu.Unlock()
+
+	if response == nil {
+		// ロングポーリングの代わりに少し待って空の応答を返す
+		time.Sleep(10 * time.Millisecond)
+		response = []byte(`{"ok":true,"result":[]}`)
+	}
+	w.Write(response)
+}
+
+func (f *fakeBotAPI) getUpdatesOffsets() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int(nil), f.offsets...)
+}
+
+func newTestBotClient(t *testing.T, server *httptest.Server, webhookURL string) *TelegramBotClient {
+	client, err := NewTelegramBotClient(TelegramBotConfig{
+		Token:         "test-token",
+		BaseURL:       server.URL,
+		WebhookURL:    webhookURL,
+		WebhookSecret: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.minBackoff = time.Millisecond
+	t.Cleanup(func() { client.Stop() })
+	return client
+}
+
+func TestTelegramBotClient_GetUpdates(t *testing.T) {
+	api := &fakeBotAPI{updates: [][]byte{
+		// 一時的なエラーは再試行
+		[]byte(`{"ok":false,"error_code":502,"description":"Bad Gateway"}`),
+		[]byte(`{"ok":true,"result":[
+			{"update_id":100,"channel_post":{"message_id":10,"date":1700000000,"chat":{"id":-1001,"type":"channel","username":"HackChannel"},"text":"` + testHackingOriginal + `"}},
+			{"update_id":101,"channel_post":{"message_id":11,"date":1700000100,"chat":{"id":-1001,"type":"channel","username":"HackChannel"},"text":"Resupply",
+				"reply_to_message":{"message_id":10,"date":1700000000,"chat":{"id":-1001,"type":"channel","username":"HackChannel"},"text":"` + testHackingOriginal + `"}}},
+			{"update_id":102,"channel_post":{"message_id":5,"date":1700000200,"chat":{"id":-1002,"type":"channel","username":"transferchannel"},
+				"text":"🚨 141,271.0 #USDT transferred from #Binance to TUtjxCskyxs4WbPP1bT7GCA4zsZUVaHqHn.",
+				"entities":[{"type":"hashtag","offset":13,"length":5},{"type":"hashtag","offset":36,"length":8}]}},
+			{"update_id":103,"channel_post":{"message_id":7,"date":1700000300,"chat":{"id":-1003,"type":"channel","username":"other"},"text":"ignored"}}
+		]}`),
+	}}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	client := newTestBotClient(t, server, "")
+	hackingGW := NewTelegramBotHackingPostGateway(client, "hackchannel", parseHackingPost)
+	transferGW := NewTelegramBotTransferPostGateway(client, "transferchannel", parseTransferPost)
+
+	received := make(chan []*gateway.HackingPost, 2)
+	hackingGW.SubscribeNewPosts(context.Background(), func(ctx context.Context, posts []*gateway.HackingPost) {
+		received <- posts
+	})
+
+	if err := client.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	select {
+	case <-client.Ready():
+	default:
+		t.Error("Ready() is not closed after Run")
+	}
+
+	// 通知された投稿以降をまとめて取得するため、新しい投稿はいずれかの通知で一度だけ渡される
+	var posts []*gateway.HackingPost
+	timeout := time.After(5 * time.Second)
+	for len(posts) == 0 {
+		select {
+		case p := <-received:
+			posts = append(posts, p...)
+		case <-timeout:
+			t.Fatal("new posts are not notified")
+		}
+	}
+	want := &gateway.HackingPost{
+		Text:            "Resupply",
+		ReplyToText:     testHackingOriginal,
+		Network:         "Ethereum",
+		Amount:          "$1,200,000",
+		TxHash:          "0xabc",
+		ReportTime:      time.Unix(1700000000, 0),
+		MessageID:       11,
+		ChannelUsername: "hackchannel",
+	}
+	if len(posts) != 1 || !reflect.DeepEqual(posts[0], want) {
+		t.Errorf("posts = %+v, want [%+v]", posts, want)
+	}
+	if hackingGW.LastMessageID() != 11 {
+		t.Errorf("LastMessageID() = %d, want 11", hackingGW.LastMessageID())
+	}
+
+	transferPosts, err := transferGW.GetPosts(context.Background(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transferPosts) != 1 {
+		t.Fatalf("transfer posts = %+v, want 1 post", transferPosts)
+	}
+	got := transferPosts[0]
+	if got.Token != "USDT" || got.Amount != "141271.0" || got.From != "Binance" || got.To != "TUtjxCskyxs4WbPP1bT7GCA4zsZUVaHqHn" || got.MessageID != 5 {
+		t.Errorf("transfer post = %+v", got)
+	}
+	// 絵文字を含む投稿でも、UTF-16のオフセットでハッシュタグを取得
+	if !reflect.DeepEqual(got.TagNames, []string{"USDT", "Binance"}) {
+		t.Errorf("TagNames = %v, want [USDT Binance]", got.TagNames)
+	}
+	// 取得済みの投稿は再度取得しない
+	if again, _ := transferGW.GetPosts(context.Background(), 100); len(again) != 0 {
+		t.Errorf("GetPosts() again = %+v, want no posts", again)
+	}
+
+	// 処理した更新の次のIDを offset で通知
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		offsets := api.getUpdatesOffsets()
+		if offsets[len(offsets)-1] == 104 {
+			if offsets[0] != 0 || offsets[1] != 0 {
+				t.Errorf("offsets = %v, want to start from 0", offsets)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("offsets = %v, want 104", offsets)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if client.Status().State != gateway.ConnectionReady {
+		t.Errorf("Status() = %+v, want ready", client.Status())
+	}
+}
+
+func TestTelegramBotClient_Webhook(t *testing.T) {
+	api := &fakeBotAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	client := newTestBotClient(t, server, "https://example.com/v1/telegram/webhook")
+	hackingGW := NewTelegramBotHackingPostGateway(client, "hackchannel", parseHackingPost)
+	changed := make(chan struct{}, 1)
+	hackingGW.SubscribeChanges(context.Background(), func(ctx context.Context) {
+		changed <- struct{}{}
+	})
+
+	if err := client.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if calls := api.calls; !reflect.DeepEqual(calls, []string{"getMe", "setWebhook"}) {
+		t.Errorf("calls = %v, want [getMe setWebhook]", calls)
+	}
+
+	post := func(secret string, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/telegram/webhook", strings.NewReader(body))
+		req.Header.Set(telegramBotSecretHeader, secret)
+		rec := httptest.NewRecorder()
+		client.WebhookHandler().ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	reply := `{"update_id":1,"channel_post":{"message_id":11,"date":1700000100,"chat":{"id":-1001,"type":"channel","username":"hackchannel"},"text":"Resupply",
+		"reply_to_message":{"message_id":10,"date":1700000000,"chat":{"id":-1001,"type":"channel","username":"hackchannel"},"text":"` + testHackingOriginal + `"}}}`
+	if code := post("wrong", reply); code != http.StatusUnauthorized {
+		t.Errorf("status with wrong secret = %d, want 401", code)
+	}
+	if code := post("secret", reply); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+
+	// リプライ先の投稿が編集されると、リプライの投稿のリプライ先の本文も更新
+	edited := strings.Replace(testHackingOriginal, "$1,200,000", "$2,100,000", 1)
+	if code := post("secret", `{"update_id":2,"edited_channel_post":{"message_id":10,"date":1700000000,"chat":{"id":-1001,"type":"channel","username":"hackchannel"},"text":"`+edited+`"}}`); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("change is not notified")
+	}
+
+	posts, deletedIDs, err := hackingGW.GetPostsByMessageIDs(context.Background(), []int{10, 11, 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 受信していない投稿は、削除されたとは判断しない
+	if len(deletedIDs) != 0 {
+		t.Errorf("deletedIDs = %v, want none", deletedIDs)
+	}
+	if len(posts) != 1 || posts[0].MessageID != 11 || posts[0].Amount != "$2,100,000" || posts[0].ReplyToText != edited {
+		t.Errorf("posts = %+v", posts)
+	}
+}
+
+func TestTelegramBotChannel_Capacity(t *testing.T) {
+	ch := newTelegramBotChannel()
+	for id := telegramBotChannelCapacity + 10; id > 0; id-- {
+		ch.store(&telegramBotMessage{MessageID: id})
+	}
+
+	// 上限を超えた分は古い投稿から破棄
+	if len(ch.ids) != telegramBotChannelCapacity || ch.ids[0] != 11 {
+		t.Errorf("ids = %d from %d, want %d from 11", len(ch.ids), ch.ids[0], telegramBotChannelCapacity)
+	}
+	var ids []int
+	for _, message := range ch.messagesAfter(telegramBotChannelCapacity+7, 10) {
+		ids = append(ids, message.MessageID)
+	}
+	if !reflect.DeepEqual(ids, []int{telegramBotChannelCapacity + 10, telegramBotChannelCapacity + 9, telegramBotChannelCapacity + 8}) {
+		t.Errorf("messagesAfter() = %v", ids)
+	}
+}
+
+func TestTelegramBotGateway_RejectedPostAfterUnlock(t *testing.T) {
+	ch := newTelegramBotChannel()
+	ch.store(&telegramBotMessage{MessageID: 1, Date: 1700000000, Text: "Not a hacking post"})
+	ch.store(&telegramBotMessage{MessageID: 2, Date: 1700000100, Text: "Resupply",
+		ReplyToMessage: &telegramBotMessage{MessageID: 1, Date: 1700000000, Text: "Not a hacking post"}})
+	g := &telegramBotHackingPostGateway{channel: ch, channelUsername: "hackchannel", parse: parseHackingPost}
+
+	// handler からゲートウェイを呼び出しても、取得のロックで止まらない
+	var rejected []*gateway.RejectedPost
+	g.SetRejectedPostHandler(func(ctx context.Context, post *gateway.RejectedPost) {
+		g.LastMessageID()
+		rejected = append(rejected, post)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := g.GetPosts(context.Background(), 100); err != nil {
+			t.Error(err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetPosts() is blocked by the rejected post handler")
+	}
+	if len(rejected) != 1 || rejected[0].MessageID != 2 || rejected[0].ReplyToText != "Not a hacking post" {
+		t.Errorf("rejected = %+v, want message 2", rejected)
+	}
+}
+
+func TestTelegramBotGateway_RefetchUnchangedPosts(t *testing.T) {
+	ch := newTelegramBotChannel()
+	original := &telegramBotMessage{MessageID: 1, Date: 1700000000, Text: testHackingOriginal}
+	ch.store(&telegramBotMessage{MessageID: 2, Date: 1700000100, Text: "Resupply", ReplyToMessage: original})
+	ch.store(&telegramBotMessage{MessageID: 3, Date: 1700000200, Text: "Resupply",
+		ReplyToMessage: &telegramBotMessage{MessageID: 4, Date: 1700000000, Text: "Not a hacking post"}})
+
+	parsed := 0
+	g := &telegramBotHackingPostGateway{channel: ch, channelUsername: "hackchannel", parse: func(message string) (*gateway.HackingPost, error) {
+		parsed++
+		return parseHackingPost(message)
+	}}
+	var rejected []int
+	g.SetRejectedPostHandler(func(ctx context.Context, post *gateway.RejectedPost) {
+		rejected = append(rejected, post.MessageID)
+	})
+
+	// 本文が変わっていない投稿は、再取得してもパースせず、パースの失敗も再度通知しない
+	for i := 0; i < 2; i++ {
+		posts, _, err := g.GetPostsByMessageIDs(context.Background(), []int{2, 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(posts) != 1 || posts[0].MessageID != 2 || posts[0].Amount != "$1,200,000" || posts[0].Text != "Resupply" {
+			t.Errorf("posts = %+v, want message 2", posts)
+		}
+	}
+	if parsed != 2 || !reflect.DeepEqual(rejected, []int{3}) {
+		t.Errorf("parsed = %d, rejected = %v, want 2 and [3]", parsed, rejected)
+	}
+
+	// 編集された投稿は再度パース
+	ch.store(&telegramBotMessage{MessageID: 3, Date: 1700000200, Text: "Resupply",
+		ReplyToMessage: &telegramBotMessage{MessageID: 4, Date: 1700000000, Text: "Still not a hacking post"}})
+	if _, _, err := g.GetPostsByMessageIDs(context.Background(), []int{2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if parsed != 3 || !reflect.DeepEqual(rejected, []int{3, 3}) {
+		t.Errorf("parsed = %d, rejected = %v, want 3 and [3 3]", parsed, rejected)
+	}
+}
@@ -0,0 +1,227 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+// 記録したチャンネルの投稿のファイル（testdata/telegram）
+type telegramFixture struct {
+	Channels []*telegramFixtureChannel `json:"channels"`
+}
+
+type telegramFixtureChannel struct {
+	Username   string                    `json:"username"`
+	ID         int64                     `json:"id"`
+	AccessHash int64                     `json:"access_hash"`
+	Title      string                    `json:"title"`
+	Messages   []*telegramFixtureMessage `json:"messages"`
+}
+
+type telegramFixtureMessage struct {
+	ID      int    `json:"id"`
+	Date    int    `json:"date"`
+	Message string `json:"message"`
+	ReplyTo int    `json:"reply_to"`
+	// サービスメッセージ（チャンネルの作成など）
+	Service bool `json:"service"`
+	// 削除済みの投稿（履歴に含まれず、IDで取得すると MessageEmpty を返す）
+	Deleted bool `json:"deleted"`
+	// オフセットはUTF-16のコード単位
+	Entities []struct {
+		Type   string `json:"type"`
+		Offset int    `json:"offset"`
+		Length int    `json:"length"`
+	} `json:"entities"`
+}
+
+// 記録したチャンネルの投稿を返すローカルのTelegram API
+// ContactsResolveUsername、MessagesGetHistory、ChannelsGetMessages に応答する
+type fixtureInvoker struct {
+	channels map[string]*telegramFixtureChannel
+	// 1回で返す投稿の上限（Telegram API では100件）
+	pageSize int
+
+	mu    sync.Mutex
+	calls []string
+	// MessagesGetHistory のリクエスト
+	historyRequests []*tg.MessagesGetHistoryRequest
+}
+
+func newFixtureInvoker(t *testing.T, path string) *fixtureInvoker {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture telegramFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatalf("failed to parse %s: %v", path, err)
+	}
+
+	f := &fixtureInvoker{channels: make(map[string]*telegramFixtureChannel), pageSize: 100}
+	for _, channel := range fixture.Channels {
+		// 新しい投稿から返すため、IDの降順に並べる
+		sort.Slice(channel.Messages, func(i, j int) bool { return channel.Messages[i].ID > channel.Messages[j].ID })
+		f.channels[strings.ToLower(channel.Username)] = channel
+	}
+	return f
+}
+
+// 記録したチャンネルを返すアカウント
+func newFixtureAccount(t *testing.T, path string) (*TelegramClientManager, *fixtureInvoker) {
+	t.Helper()
+	invoker := newFixtureInvoker(t, path)
+	m := newTestAccount(DefaultTelegramAccountName, gateway.ConnectionReady)
+	m.api = tg.NewClient(invoker)
+	return m, invoker
+}
+
+func (f *fixtureInvoker) Invoke(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, telegramMethod(input))
+
+	switch req := input.(type) {
+	case *tg.ContactsResolveUsernameRequest:
+		channel, ok := f.channels[strings.ToLower(req.Username)]
+		if !ok {
+			return tgerr.New(400, tg.ErrUsernameNotOccupied)
+		}
+		*output.(*tg.ContactsResolvedPeer) = tg.ContactsResolvedPeer{
+			Peer:  &tg.PeerChannel{ChannelID: channel.ID},
+			Chats: []tg.ChatClass{channel.tgChannel()},
+		}
+		return nil
+
+	case *tg.MessagesGetHistoryRequest:
+		peer, ok := req.Peer.(*tg.InputPeerChannel)
+		if !ok {
+			return tgerr.New(400, tg.ErrPeerIDInvalid)
+		}
+		channel, err := f.channel(peer.ChannelID, peer.AccessHash)
+		if err != nil {
+			return err
+		}
+		copied := *req
+		f.historyRequests = append(f.historyRequests, &copied)
+
+		// MaxID より古く、MinID より新しい投稿を新しい順に返す
+		limit := min(req.Limit, f.pageSize)
+		var messages []tg.MessageClass
+		for _, message := range channel.Messages {
+			if len(messages) >= limit {
+				break
+			}
+			if message.Deleted || (req.MaxID > 0 && message.ID >= req.MaxID) || message.ID <= req.MinID {
+				continue
+			}
+			messages = append(messages, message.tgMessage(channel.ID))
+		}
+		output.(*tg.MessagesMessagesBox).Messages = &tg.MessagesChannelMessages{
+			Count:    len(channel.Messages),
+			Messages: messages,
+			Chats:    []tg.ChatClass{channel.tgChannel()},
+		}
+		return nil
+
+	case *tg.ChannelsGetMessagesRequest:
+		input, ok := req.Channel.(*tg.InputChannel)
+		if !ok {
+			return tgerr.New(400, tg.ErrChannelInvalid)
+		}
+		channel, err := f.channel(input.ChannelID, input.AccessHash)
+		if err != nil {
+			return err
+		}
+
+		// 存在しない投稿・削除済みの投稿は MessageEmpty を返す
+		var messages []tg.MessageClass
+		for _, id := range req.ID {
+			messageID := id.(*tg.InputMessageID).ID
+			var found tg.MessageClass = &tg.MessageEmpty{ID: messageID}
+			for _, message := range channel.Messages {
+				if message.ID == messageID && !message.Deleted {
+					found = message.tgMessage(channel.ID)
+				}
+			}
+			messages = append(messages, found)
+		}
+		output.(*tg.MessagesMessagesBox).Messages = &tg.MessagesChannelMessages{
+			Count:    len(messages),
+			Messages: messages,
+			Chats:    []tg.ChatClass{channel.tgChannel()},
+		}
+		return nil
+	}
+	return fmt.Errorf("fixture invoker: unexpected request %s", telegramMethod(input))
+}
+
+// チャンネルIDとアクセスハッシュが一致するチャンネル
+func (f *fixtureInvoker) channel(id, accessHash int64) (*telegramFixtureChannel, error) {
+	for _, channel := range f.channels {
+		if channel.ID == id && channel.AccessHash == accessHash {
+			return channel, nil
+		}
+	}
+	return nil, tgerr.New(400, tg.ErrChannelInvalid)
+}
+
+func (f *fixtureInvoker) methods() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+func (f *fixtureInvoker) historyMaxIDs() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var maxIDs []int
+	for _, req := range f.historyRequests {
+		maxIDs = append(maxIDs, req.MaxID)
+	}
+	return maxIDs
+}
+
+func (c *telegramFixtureChannel) tgChannel() *tg.Channel {
+	channel := &tg.Channel{ID: c.ID, Title: c.Title, Broadcast: true}
+	channel.SetAccessHash(c.AccessHash)
+	channel.SetUsername(c.Username)
+	return channel
+}
+
+func (m *telegramFixtureMessage) tgMessage(channelID int64) tg.MessageClass {
+	peer := &tg.PeerChannel{ChannelID: channelID}
+	if m.Service {
+		return &tg.MessageService{ID: m.ID, Date: m.Date, PeerID: peer, Action: &tg.MessageActionChannelCreate{}}
+	}
+
+	message := &tg.Message{ID: m.ID, Date: m.Date, Message: m.Message, PeerID: peer, Post: true}
+	if m.ReplyTo != 0 {
+		var replyTo tg.MessageReplyHeader
+		replyTo.SetReplyToMsgID(m.ReplyTo)
+		message.SetReplyTo(&replyTo)
+	}
+	var entities []tg.MessageEntityClass
+	for _, entity := range m.Entities {
+		if entity.Type == "hashtag" {
+			entities = append(entities, &tg.MessageEntityHashtag{Offset: entity.Offset, Length: entity.Length})
+		}
+	}
+	if len(entities) > 0 {
+		message.SetEntities(entities)
+	}
+	return message
+}
@@ -0,0 +1,152 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/telegram/auth"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+// ログインを待機しているクライアントと、送信した認証コードの情報
+type pendingLogin struct {
+	client           *telegram.Client
+	phone            string
+	codeHash         string
+	passwordRequired bool
+}
+
+// ログインが完了するまで、未認証の状態で待機
+func (m *TelegramClientManager) waitForLogin(ctx context.Context, client *telegram.Client) error {
+	// 以前のログインの完了通知は破棄
+	select {
+	case <-m.loggedIn:
+	default:
+	}
+
+	m.mu.Lock()
+	m.login = &pendingLogin{client: client}
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.login = nil
+		m.mu.Unlock()
+	}()
+
+	m.setStatus(gateway.ConnectionUnauthorized, nil)
+	log.Println("Telegram session is not authorized. Waiting for login.")
+
+	select {
+	case <-m.loggedIn:
+		log.Println("Telegram login completed.")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// 電話番号宛てに認証コードを送信
+func (m *TelegramClientManager) StartLogin(ctx context.Context, phone string) error {
+	m.loginMu.Lock()
+	defer m.loginMu.Unlock()
+
+	login, err := m.pendingLogin()
+	if err != nil {
+		return err
+	}
+
+	sentCode, err := login.client.Auth().SendCode(ctx, strings.TrimSpace(phone), auth.SendCodeOptions{})
+	if err != nil {
+		return loginError("failed to send auth code", err)
+	}
+	authSentCode, ok := sentCode.(*tg.AuthSentCode)
+	if !ok {
+		return fmt.Errorf("failed to send auth code: unexpected response %T", sentCode)
+	}
+
+	login.phone = strings.TrimSpace(phone)
+	login.codeHash = authSentCode.PhoneCodeHash
+	login.passwordRequired = false
+	return nil
+}
+
+// 認証コードでサインイン
+// 2段階認証のパスワードが必要な場合は true を返す
+func (m *TelegramClientManager) SubmitCode(ctx context.Context, code string) (bool, error) {
+	m.loginMu.Lock()
+	defer m.loginMu.Unlock()
+
+	login, err := m.pendingLogin()
+	if err != nil {
+		return false, err
+	}
+	if login.codeHash == "" {
+		return false, gateway.ErrTelegramLoginNotStarted
+	}
+
+	_, err = login.client.Auth().SignIn(ctx, login.phone, strings.TrimSpace(code), login.codeHash)
+	if errors.Is(err, auth.ErrPasswordAuthNeeded) {
+		login.passwordRequired = true
+		return true, nil
+	}
+	if err != nil {
+		return false, loginError("failed to sign in", err)
+	}
+
+	m.completeLogin()
+	return false, nil
+}
+
+// 2段階認証のパスワードでサインイン
+func (m *TelegramClientManager) SubmitPassword(ctx context.Context, password string) error {
+	m.loginMu.Lock()
+	defer m.loginMu.Unlock()
+
+	login, err := m.pendingLogin()
+	if err != nil {
+		return err
+	}
+	if !login.passwordRequired {
+		return gateway.ErrTelegramLoginNotStarted
+	}
+
+	if _, err := login.client.Auth().Password(ctx, password); err != nil {
+		return loginError("failed to check password", err)
+	}
+
+	m.completeLogin()
+	return nil
+}
+
+func (m *TelegramClientManager) pendingLogin() (*pendingLogin, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.login == nil {
+		return nil, fmt.Errorf("%w (state: %s)", gateway.ErrTelegramLoginUnavailable, m.status.State)
+	}
+	return m.login, nil
+}
+
+// 待機しているクライアントにログインの完了を通知
+func (m *TelegramClientManager) completeLogin() {
+	select {
+	case m.loggedIn <- struct{}{}:
+	default:
+	}
+}
+
+// 入力値の誤りによるエラーを ErrTelegramInvalidCredential として返す
+func loginError(message string, err error) error {
+	if errors.Is(err, auth.ErrPasswordInvalid) ||
+		tgerr.Is(err, "PHONE_NUMBER_INVALID", "PHONE_CODE_INVALID", "PHONE_CODE_EXPIRED", "PHONE_CODE_EMPTY", "PASSWORD_HASH_INVALID") {
+		return fmt.Errorf("%s: %w: %w", message, gateway.ErrTelegramInvalidCredential, err)
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
@@ -3,135 +3,449 @@ package gateway
 import (
 	"context"
 	"errors"
+	"expvar"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
+	"time"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
 
 	"github.com/gotd/td/session"
 	"github.com/gotd/td/telegram"
-	"github.com/gotd/td/telegram/auth"
 	"github.com/gotd/td/tg"
 )
 
+// チャンネルの新しい投稿を受信した際に呼び出される関数
+type channelMessageHandler func(ctx context.Context, messageID int)
+
+// チャンネルの投稿が編集・削除された際に呼び出される関数
+type channelChangeHandler func(ctx context.Context)
+
+// 再接続の待機時間
+const (
+	minReconnectBackoff = time.Second
+	maxReconnectBackoff = 5 * time.Minute
+	// この時間以上接続が続いた後に切断された場合は、待機時間をリセット
+	stableConnectionDuration = time.Minute
+)
+
+// 接続・認証済みでないためAPIを呼び出せない場合のエラー
+var ErrTelegramNotReady = errors.New("telegram client is not ready")
+
+// アカウント名を指定しなかった場合の名前
+const DefaultTelegramAccountName = "default"
+
+// アカウント毎の接続状態（/v1/admin/metrics で公開）
+var (
+	telegramConnectionState       = expvar.NewMap("telegram_connection_state")
+	telegramConnectionTransitions = expvar.NewMap("telegram_connection_transitions")
+)
+
 // gotdクライアント接続を管理する構造体
+// クライアントが終了した場合は、待機時間を空けて新しいクライアントで再接続する
 type TelegramClientManager struct {
+	name    string
+	appID   int
+	appHash string
+	options telegram.Options
+
+	// 実行中のクライアントと、認証済みのAPIクライアント
+	mu     sync.RWMutex
 	client *telegram.Client
 	api    *tg.Client
-	wg     sync.WaitGroup
-	stop   context.CancelFunc
-}
+	status gateway.ConnectionStatus
+
+	wg   sync.WaitGroup
+	stop context.CancelFunc
 
-// gotdクライアントをセットアップして、TelegramClientManagerを生成
-func NewTelegramClientManager(appID int, appHash string) *TelegramClientManager {
-	sessionDir := ".td"
-	os.MkdirAll(sessionDir, 0755)
+	// ログインを待機しているクライアント（ログイン操作は loginMu で直列化）
+	loginMu  sync.Mutex
+	login    *pendingLogin
+	loggedIn chan struct{}
+
+	// 初めて接続・認証済みになった時点で閉じられる
+	ready     chan struct{}
+	readyOnce sync.Once
+
+	// クライアントを1回実行する関数と再接続の待機時間（テストで差し替え）
+	runOnce    func(ctx context.Context, onConnected func()) error
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	// 初回の接続に失敗した場合も、エラーを返した後にバックグラウンドで再接続を続ける（TelegramClientPool で使用）
+	retryFirstConnection bool
+
+	// チャンネルID毎の新しい投稿の購読者
+	handlersMu     sync.RWMutex
+	handlers       map[int64][]channelMessageHandler
+	changeHandlers map[int64][]channelChangeHandler
+	// Stop の後は購読者を実行しない（wg.Add と Stop の wg.Wait が並行しないよう handlersMu で保護）
+	stopped bool
+	// 再接続などで更新を取りこぼした可能性がある場合に通知
+	gapFill chan struct{}
+}
 
-	client := telegram.NewClient(appID, appHash, telegram.Options{
-		SessionStorage: &session.FileStorage{
+// gotdクライアントをセットアップして、アカウント name の TelegramClientManager を生成
+// storage が nil の場合は、セッションを .td/session.json（default 以外のアカウントは .td/<name>/session.json）に保存
+func NewTelegramClientManager(name string, appID int, appHash string, storage telegram.SessionStorage) *TelegramClientManager {
+	if name == "" {
+		name = DefaultTelegramAccountName
+	}
+	if storage == nil {
+		sessionDir := ".td"
+		if name != DefaultTelegramAccountName {
+			sessionDir = filepath.Join(sessionDir, name)
+		}
+		os.MkdirAll(sessionDir, 0755)
+		storage = &session.FileStorage{
 			Path: filepath.Join(sessionDir, "session.json"),
+		}
+	}
+	rateLimiter := newTelegramRateLimiter(defaultTelegramCallInterval, defaultTelegramCallBurst, defaultMaxFloodWaitSleep)
+	rateLimiter.account = name
+
+	m := &TelegramClientManager{
+		name:           name,
+		appID:          appID,
+		appHash:        appHash,
+		handlers:       make(map[int64][]channelMessageHandler),
+		changeHandlers: make(map[int64][]channelChangeHandler),
+		gapFill:        make(chan struct{}, 1),
+		loggedIn:       make(chan struct{}, 1),
+		ready:          make(chan struct{}),
+		minBackoff:     minReconnectBackoff,
+		maxBackoff:     maxReconnectBackoff,
+	}
+	m.runOnce = m.runClient
+	m.setStatus(gateway.ConnectionConnecting, nil)
+
+	dispatcher := tg.NewUpdateDispatcher()
+	dispatcher.OnNewChannelMessage(m.onNewChannelMessage)
+	dispatcher.OnEditChannelMessage(m.onEditChannelMessage)
+	dispatcher.OnDeleteChannelMessages(m.onDeleteChannelMessages)
+
+	// 再接続時も同じセッション・更新の購読者・呼び出し間隔の制限を引き継ぐ
+	m.options = telegram.Options{
+		SessionStorage: &reconnectNotifyingStorage{
+			SessionStorage: storage,
+			notify:         m.notifyGap,
 		},
-	})
-	return &TelegramClientManager{client: client}
+		Middlewares: []telegram.Middleware{
+			rateLimiter,
+		},
+		UpdateHandler: telegram.UpdateHandlerFunc(func(ctx context.Context, u tg.UpdatesClass) error {
+			// サーバー側で未配信の更新が多すぎる場合、個別の更新は届かない
+			if _, ok := u.(*tg.UpdatesTooLong); ok {
+				m.notifyGap()
+			}
+			return dispatcher.Handle(ctx, u)
+		}),
+	}
+	m.client = telegram.NewClient(m.appID, m.appHash, m.options)
+	return m
 }
 
-// クライアントをバックグラウンドで実行し、接続と認証を処理
-// 接続が確立されるまでブロック
-func (m *TelegramClientManager) Run(ctx context.Context, phone string, hash string, password string) error {
+// クライアントをバックグラウンドで実行
+// 接続が確立されるまでブロックし、初回の接続に失敗した場合はエラーを返す
+// セッションが未認証の場合は、ログインを待機している状態で返る
+// 接続の確立後にクライアントが終了した場合は、バックグラウンドで再接続する
+// retryFirstConnection が true の場合は、初回の接続に失敗した後も再接続する
+func (m *TelegramClientManager) Run(ctx context.Context) error {
 	ctx, m.stop = context.WithCancel(ctx)
 
-	ready := make(chan struct{})
+	ready := make(chan error, 1)
 	m.wg.Add(1)
-
 	go func() {
 		defer m.wg.Done()
-		err := m.client.Run(ctx, func(ctx context.Context) error {
-			// 認証状態を確認
-			status, err := m.client.Auth().Status(ctx)
-			if err != nil {
-				return fmt.Errorf("failed to get auth status: %w", err)
-			}
+		m.supervise(ctx, ready)
+	}()
 
-			// 未認証の場合、電話番号で認証フローを開始
-			if !status.Authorized {
-				if err := m.authFlow(ctx, phone, hash, password); err != nil {
-					return fmt.Errorf("failed auth flow: %w \n please set sent auth code in telegram message", err)
-				}
-			}
+	// 準備が完了するか、コンテキストがキャンセルされるまで待機
+	select {
+	case err := <-ready:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
-			// APIクライアントを取得して保持
-			m.api = m.client.API()
+// クライアントを実行し、終了した場合は待機時間を空けて再接続
+// 初回の接続結果を ready に送信
+func (m *TelegramClientManager) supervise(ctx context.Context, ready chan<- error) {
+	if m.runOnce == nil {
+		m.runOnce = m.runClient
+	}
+	if m.minBackoff <= 0 || m.maxBackoff <= 0 {
+		m.minBackoff, m.maxBackoff = minReconnectBackoff, maxReconnectBackoff
+	}
 
-			// 準備完了を通知
-			close(ready)
+	backoff := m.minBackoff
+	for attempt := 0; ; attempt++ {
+		m.setStatus(gateway.ConnectionConnecting, nil)
 
-			// サーバーがシャットダウンするまで待機
-			<-ctx.Done()
-			return nil
+		var connectedAt time.Time
+		err := m.runOnce(ctx, func() {
+			connectedAt = time.Now()
+			if attempt == 0 {
+				ready <- nil
+			}
 		})
-		if err != nil && !errors.Is(err, context.Canceled) {
-			// 準備完了前にエラーが発生した場合
-			fmt.Fprintf(os.Stderr, "gotd client run error: %v\n", err)
-			// readyがクローズされていない場合、クローズしてエラーを通知
-			select {
-			case <-ready:
-			default:
-				close(ready)
+		m.setAPI(nil)
+
+		// シャットダウン
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = errors.New("telegram client stopped unexpectedly")
+		}
+		m.setStatus(gateway.ConnectionFailed, err)
+
+		// 初回の接続に失敗した場合は起動時のエラーとして返す
+		if attempt == 0 && connectedAt.IsZero() {
+			ready <- err
+			if !m.retryFirstConnection {
+				return
 			}
 		}
-	}()
 
-	// 準備が完了するか、コンテキストがキャンセルされるまで待機
-	select {
-	case <-ready:
-		if m.api == nil {
-			return errors.New("failed to initialize telegram client")
+		if !connectedAt.IsZero() && time.Since(connectedAt) >= stableConnectionDuration {
+			backoff = m.minBackoff
 		}
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
+		log.Printf("Telegram client stopped: %v. Reconnecting in %s", err, backoff)
+		if err := sleepContext(ctx, backoff); err != nil {
+			return
+		}
+		backoff = min(backoff*2, m.maxBackoff)
 	}
 }
 
-// 認証情報が設定されていれば、認証を実行
-// 認証情報が設定されていなければ、認証情報を取得してサーバーを停止
-func (m *TelegramClientManager) authFlow(ctx context.Context, phone string, hash string, code string) error {
-	if hash == "" || code == "" {
-		sentCode, err := m.client.Auth().SendCode(ctx, phone, auth.SendCodeOptions{})
+// クライアントを1回実行し、接続が終了するまでブロック
+// 認証済みのAPIクライアントを取得した時点、またはログインの待機を開始した時点で onConnected を呼び出す
+func (m *TelegramClientManager) runClient(ctx context.Context, onConnected func()) error {
+	// 終了したクライアントは再利用できないため、接続毎に生成
+	m.mu.Lock()
+	client := m.client
+	if client == nil {
+		client = telegram.NewClient(m.appID, m.appHash, m.options)
+	}
+	m.client = nil
+	m.mu.Unlock()
+
+	return client.Run(ctx, func(ctx context.Context) error {
+		status, err := client.Auth().Status(ctx)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to get auth status: %w", err)
 		}
-		log.Println("Send code")
-		authSendCode, ok := sentCode.(*tg.AuthSentCode)
-		if !ok {
-			return fmt.Errorf("failed to get auth code")
+
+		// 未認証の場合は、ログインが完了するまで待機
+		if !status.Authorized {
+			onConnected()
+			if err := m.waitForLogin(ctx, client); err != nil {
+				return err
+			}
 		}
-		hash := authSendCode.PhoneCodeHash
-		log.Printf("Hash: %s", hash)
-		return fmt.Errorf("check code in telegram\nAuth Hash: %s", hash)
 
-	} else {
-		_, err := m.client.Auth().SignIn(ctx, phone, strings.TrimSpace(code), hash)
-		if err != nil {
-			return err
+		// APIクライアントを取得して保持
+		m.setAPI(client.API())
+		m.setStatus(gateway.ConnectionReady, nil)
+		if status.Authorized {
+			onConnected()
 		}
-	}
 
-	return nil
+		// サーバーのシャットダウンか、接続が終了するまで待機
+		<-ctx.Done()
+		return nil
+	})
 }
 
 // クライアントの接続を安全に停止
 func (m *TelegramClientManager) Stop() error {
 	if m.stop != nil {
+		m.handlersMu.Lock()
+		m.stopped = true
+		m.handlersMu.Unlock()
+
 		m.stop()
 		m.wg.Wait()
 	}
 	return nil
 }
 
-// 認証済みの *tg.Client を返す
-func (m *TelegramClientManager) API() *tg.Client {
-	return m.api
+// 呼び出し可能な *tg.Client を返す
+// 接続・認証済みでない場合は、接続状態を含むエラーを返す
+func (m *TelegramClientManager) readyAPI() (*tg.Client, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.api == nil || m.status.State != gateway.ConnectionReady {
+		return nil, fmt.Errorf("%w (state: %s)", ErrTelegramNotReady, m.status.State)
+	}
+	return m.api, nil
+}
+
+func (m *TelegramClientManager) setAPI(api *tg.Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.api = api
+}
+
+// アカウント名
+func (m *TelegramClientManager) Name() string {
+	return m.name
+}
+
+// 単一のアカウントで全てのチャンネルを取得
+func (m *TelegramClientManager) accountsFor(channelUsername string) []*TelegramClientManager {
+	return []*TelegramClientManager{m}
+}
+
+// 初めて接続・認証済みになった時点で閉じられるチャネル
+// 起動時にログインを待機している場合、ログインの完了まで閉じられない
+func (m *TelegramClientManager) Ready() <-chan struct{} {
+	return m.ready
+}
+
+// 現在の接続状態
+func (m *TelegramClientManager) Status() gateway.ConnectionStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// 接続状態を更新し、変化した場合はログとメトリクスに記録
+func (m *TelegramClientManager) setStatus(state gateway.ConnectionState, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := gateway.ConnectionStatus{State: state, Since: m.status.Since}
+	if err != nil {
+		status.LastError = err.Error()
+	}
+	if state != m.status.State {
+		status.Since = time.Now()
+		if m.status.State != "" {
+			log.Printf("Telegram connection state of %s: %s -> %s", m.name, m.status.State, state)
+		}
+		var value expvar.String
+		value.Set(string(state))
+		telegramConnectionState.Set(m.name, &value)
+		telegramConnectionTransitions.Add(m.name+"/"+string(state), 1)
+	}
+	if state == gateway.ConnectionReady && m.ready != nil {
+		m.readyOnce.Do(func() { close(m.ready) })
+	}
+	m.status = status
+}
+
+// 指定したチャンネルの新しい投稿の通知を購読
+func (m *TelegramClientManager) OnChannelMessage(channelID int64, handler channelMessageHandler) {
+	m.handlersMu.Lock()
+	defer m.handlersMu.Unlock()
+	m.handlers[channelID] = append(m.handlers[channelID], handler)
+}
+
+// 指定したチャンネルの投稿の編集・削除の通知を購読
+func (m *TelegramClientManager) OnChannelChange(channelID int64, handler channelChangeHandler) {
+	m.handlersMu.Lock()
+	defer m.handlersMu.Unlock()
+	m.changeHandlers[channelID] = append(m.changeHandlers[channelID], handler)
+}
+
+// 更新を取りこぼした可能性がある場合に通知するチャネル
+// 通知を受けたら、ポーリングで最後に取得した投稿以降を取得する
+func (m *TelegramClientManager) GapFills() <-chan struct{} {
+	return m.gapFill
+}
+
+// 通知済みで未処理の場合は重ねて通知しない
+func (m *TelegramClientManager) notifyGap() {
+	select {
+	case m.gapFill <- struct{}{}:
+	default:
+	}
+}
+
+// チャンネルの新しい投稿を購読者に通知
+// 投稿の処理に時間が掛かっても更新の受信を妨げないよう、購読者は別のゴルーチンで実行
+// Stop の後は実行しない
+func (m *TelegramClientManager) onNewChannelMessage(ctx context.Context, e tg.Entities, u *tg.UpdateNewChannelMessage) error {
+	message, ok := u.Message.(*tg.Message)
+	if !ok {
+		return nil
+	}
+	peer, ok := message.PeerID.(*tg.PeerChannel)
+	if !ok {
+		return nil
+	}
+
+	m.handlersMu.RLock()
+	defer m.handlersMu.RUnlock()
+	if m.stopped {
+		return nil
+	}
+
+	for _, handler := range m.handlers[peer.ChannelID] {
+		m.wg.Add(1)
+		go func(handler channelMessageHandler) {
+			defer m.wg.Done()
+			handler(ctx, message.ID)
+		}(handler)
+	}
+	return nil
+}
+
+// チャンネルの投稿の編集を購読者に通知
+func (m *TelegramClientManager) onEditChannelMessage(ctx context.Context, e tg.Entities, u *tg.UpdateEditChannelMessage) error {
+	message, ok := u.Message.(*tg.Message)
+	if !ok {
+		return nil
+	}
+	peer, ok := message.PeerID.(*tg.PeerChannel)
+	if !ok {
+		return nil
+	}
+
+	m.notifyChange(ctx, peer.ChannelID)
+	return nil
+}
+
+// チャンネルの投稿の削除を購読者に通知
+func (m *TelegramClientManager) onDeleteChannelMessages(ctx context.Context, e tg.Entities, u *tg.UpdateDeleteChannelMessages) error {
+	m.notifyChange(ctx, u.ChannelID)
+	return nil
+}
+
+// 編集・削除の購読者を別のゴルーチンで実行
+// Stop の後は実行しない
+func (m *TelegramClientManager) notifyChange(ctx context.Context, channelID int64) {
+	m.handlersMu.RLock()
+	defer m.handlersMu.RUnlock()
+	if m.stopped {
+		return
+	}
+
+	for _, handler := range m.changeHandlers[channelID] {
+		m.wg.Add(1)
+		go func(handler channelChangeHandler) {
+			defer m.wg.Done()
+			handler(ctx)
+		}(handler)
+	}
+}
+
+// セッションの保存時に通知するSessionStorage
+// gotdは接続（再接続を含む）の確立毎にセッションを保存するため、再接続の検知に使用
+type reconnectNotifyingStorage struct {
+	telegram.SessionStorage
+	notify func()
+}
+
+func (s *reconnectNotifyingStorage) StoreSession(ctx context.Context, data []byte) error {
+	if err := s.SessionStorage.StoreSession(ctx, data); err != nil {
+		return err
+	}
+	s.notify()
+	return nil
 }
@@ -0,0 +1,252 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+
+	"github.com/gotd/td/session"
+	"github.com/gotd/td/tg"
+)
+
+func TestTelegramClientManager_OnChannelMessage(t *testing.T) {
+	m := &TelegramClientManager{
+		handlers: make(map[int64][]channelMessageHandler),
+		gapFill:  make(chan struct{}, 1),
+	}
+
+	var mu sync.Mutex
+	var received []int
+	m.OnChannelMessage(1001, func(ctx context.Context, messageID int) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, messageID)
+	})
+
+	updates := []*tg.UpdateNewChannelMessage{
+		{Message: &tg.Message{ID: 10, PeerID: &tg.PeerChannel{ChannelID: 1001}, Message: "alert"}},
+		// 購読していないチャンネルの投稿は通知しない
+		{Message: &tg.Message{ID: 11, PeerID: &tg.PeerChannel{ChannelID: 2002}, Message: "alert"}},
+		// サービスメッセージは通知しない
+		{Message: &tg.MessageService{ID: 12, PeerID: &tg.PeerChannel{ChannelID: 1001}}},
+	}
+	for _, u := range updates {
+		if err := m.onNewChannelMessage(context.Background(), tg.Entities{}, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.wg.Wait()
+
+	if len(received) != 1 || received[0] != 10 {
+		t.Errorf("received = %v, want [10]", received)
+	}
+}
+
+func TestTelegramClientManager_OnChannelChange(t *testing.T) {
+	m := &TelegramClientManager{
+		handlers:       make(map[int64][]channelMessageHandler),
+		changeHandlers: make(map[int64][]channelChangeHandler),
+		gapFill:        make(chan struct{}, 1),
+	}
+
+	var notified atomic.Int32
+	m.OnChannelChange(1001, func(ctx context.Context) {
+		notified.Add(1)
+	})
+
+	ctx := context.Background()
+	if err := m.onEditChannelMessage(ctx, tg.Entities{}, &tg.UpdateEditChannelMessage{
+		Message: &tg.Message{ID: 10, PeerID: &tg.PeerChannel{ChannelID: 1001}, Message: "edited"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.onDeleteChannelMessages(ctx, tg.Entities{}, &tg.UpdateDeleteChannelMessages{ChannelID: 1001, Messages: []int{11}}); err != nil {
+		t.Fatal(err)
+	}
+	// 購読していないチャンネルは通知しない
+	if err := m.onDeleteChannelMessages(ctx, tg.Entities{}, &tg.UpdateDeleteChannelMessages{ChannelID: 2002, Messages: []int{12}}); err != nil {
+		t.Fatal(err)
+	}
+	m.wg.Wait()
+
+	if got := notified.Load(); got != 2 {
+		t.Errorf("notified = %d, want 2", got)
+	}
+}
+
+func TestTelegramClientManager_StopWhileReceiving(t *testing.T) {
+	m := &TelegramClientManager{
+		handlers:       make(map[int64][]channelMessageHandler),
+		changeHandlers: make(map[int64][]channelChangeHandler),
+		gapFill:        make(chan struct{}, 1),
+		ready:          make(chan struct{}),
+		minBackoff:     time.Millisecond,
+		maxBackoff:     time.Millisecond,
+	}
+	m.runOnce = func(ctx context.Context, onConnected func()) error {
+		m.setAPI(&tg.Client{})
+		m.setStatus(gateway.ConnectionReady, nil)
+		onConnected()
+		<-ctx.Done()
+		return nil
+	}
+
+	var notified atomic.Int32
+	m.OnChannelMessage(1001, func(ctx context.Context, messageID int) {
+		notified.Add(1)
+	})
+	if err := m.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 更新の受信中に停止しても、購読者の実行と停止の待機が競合しない
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			m.onNewChannelMessage(context.Background(), tg.Entities{}, &tg.UpdateNewChannelMessage{
+				Message: &tg.Message{ID: i, PeerID: &tg.PeerChannel{ChannelID: 1001}, Message: "alert"},
+			})
+		}
+	}()
+	if err := m.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	// 停止後の更新は通知しない
+	before := notified.Load()
+	m.onNewChannelMessage(context.Background(), tg.Entities{}, &tg.UpdateNewChannelMessage{
+		Message: &tg.Message{ID: 100, PeerID: &tg.PeerChannel{ChannelID: 1001}, Message: "alert"},
+	})
+	m.notifyChange(context.Background(), 1001)
+	if got := notified.Load(); got != before {
+		t.Errorf("notified after Stop = %d, want %d", got, before)
+	}
+}
+
+func TestReconnectNotifyingStorage(t *testing.T) {
+	m := &TelegramClientManager{gapFill: make(chan struct{}, 1)}
+	storage := &reconnectNotifyingStorage{SessionStorage: &session.StorageMemory{}, notify: m.notifyGap}
+
+	// 複数回の再接続は1回の通知にまとめる
+	for i := 0; i < 3; i++ {
+		if err := storage.StoreSession(context.Background(), []byte(`{}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case <-m.GapFills():
+	default:
+		t.Fatal("gap fill is not notified")
+	}
+	select {
+	case <-m.GapFills():
+		t.Fatal("gap fill is notified more than once")
+	default:
+	}
+}
+
+func TestTelegramClientManager_Supervise(t *testing.T) {
+	m := &TelegramClientManager{
+		loggedIn:   make(chan struct{}, 1),
+		ready:      make(chan struct{}),
+		minBackoff: time.Millisecond,
+		maxBackoff: 4 * time.Millisecond,
+	}
+
+	// 1回目: 接続後に切断、2回目: 接続に失敗、3回目: 未認証のためログインを待機
+	attempts := 0
+	disconnected := make(chan struct{})
+	m.runOnce = func(ctx context.Context, onConnected func()) error {
+		attempts++
+		switch attempts {
+		case 1:
+			m.setAPI(&tg.Client{})
+			m.setStatus(gateway.ConnectionReady, nil)
+			onConnected()
+			<-disconnected
+			return errors.New("connection reset")
+		case 2:
+			return errors.New("dial failed")
+		default:
+			onConnected()
+			if err := m.waitForLogin(ctx, nil); err != nil {
+				return err
+			}
+			m.setAPI(&tg.Client{})
+			m.setStatus(gateway.ConnectionReady, nil)
+			<-ctx.Done()
+			return nil
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := m.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if _, err := m.readyAPI(); err != nil {
+		t.Fatalf("readyAPI() error = %v", err)
+	}
+	// 認証済みの場合はログインを受け付けない
+	if err := m.StartLogin(ctx, "+10000000000"); !errors.Is(err, gateway.ErrTelegramLoginUnavailable) {
+		t.Errorf("StartLogin() error = %v, want ErrTelegramLoginUnavailable", err)
+	}
+
+	// 切断後は再接続し、未認証の場合はログインを待機
+	close(disconnected)
+	waitForState(t, m, gateway.ConnectionUnauthorized)
+	if _, err := m.readyAPI(); !errors.Is(err, ErrTelegramNotReady) {
+		t.Errorf("readyAPI() error = %v, want ErrTelegramNotReady", err)
+	}
+	if _, err := m.SubmitCode(ctx, "12345"); !errors.Is(err, gateway.ErrTelegramLoginNotStarted) {
+		t.Errorf("SubmitCode() error = %v, want ErrTelegramLoginNotStarted", err)
+	}
+	if err := m.SubmitPassword(ctx, "password"); !errors.Is(err, gateway.ErrTelegramLoginNotStarted) {
+		t.Errorf("SubmitPassword() error = %v, want ErrTelegramLoginNotStarted", err)
+	}
+
+	// ログインが完了すると接続済みになる
+	m.completeLogin()
+	waitForState(t, m, gateway.ConnectionReady)
+
+	cancel()
+	m.wg.Wait()
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+}
+
+func waitForState(t *testing.T, m *TelegramClientManager, state gateway.ConnectionState) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for m.Status().State != state {
+		if time.Now().After(deadline) {
+			t.Fatalf("state = %s, want %s", m.Status().State, state)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTelegramClientManager_RunFailsOnFirstAttempt(t *testing.T) {
+	m := &TelegramClientManager{minBackoff: time.Millisecond, maxBackoff: time.Millisecond}
+	m.runOnce = func(ctx context.Context, onConnected func()) error {
+		return errors.New("dial failed")
+	}
+
+	// 初回の接続に失敗した場合は再接続せずにエラーを返す
+	if err := m.Run(context.Background()); err == nil {
+		t.Fatal("Run() error = nil, want error")
+	}
+	m.wg.Wait()
+	if m.Status().State != gateway.ConnectionFailed {
+		t.Errorf("state = %s, want failed", m.Status().State)
+	}
+}
@@ -0,0 +1,164 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+// 割り当てられたアカウント以外で取得した回数（/v1/admin/metrics で公開）
+var telegramFailovers = expvar.NewMap("telegram_failovers")
+
+// 複数のTelegramアカウントを管理し、チャンネルをアカウントに割り当てる
+// 割り当てられたアカウントが使用できない場合、そのチャンネルは一時的に他のアカウントで取得する
+type TelegramClientPool struct {
+	accounts []*TelegramClientManager
+	// 明示的に割り当てたチャンネルとアカウントのインデックス
+	assignments map[string]int
+
+	gapFill   chan struct{}
+	ready     chan struct{}
+	readyOnce sync.Once
+}
+
+// アカウントを指定して、TelegramClientPoolを生成
+// channelAccounts はチャンネル名とアカウント名の明示的な割り当て
+// 割り当てのないチャンネルは、チャンネル名のハッシュでアカウントに分散
+func NewTelegramClientPool(accounts []*TelegramClientManager, channelAccounts map[string]string) (*TelegramClientPool, error) {
+	if len(accounts) == 0 {
+		return nil, errors.New("no telegram account is configured")
+	}
+
+	indexes := make(map[string]int, len(accounts))
+	for i, m := range accounts {
+		if _, ok := indexes[m.name]; ok {
+			return nil, fmt.Errorf("duplicate telegram account: %s", m.name)
+		}
+		indexes[m.name] = i
+	}
+
+	assignments := make(map[string]int, len(channelAccounts))
+	for channel, account := range channelAccounts {
+		i, ok := indexes[account]
+		if !ok {
+			return nil, fmt.Errorf("telegram account %s assigned to %s is not configured", account, channel)
+		}
+		assignments[channel] = i
+	}
+
+	p := &TelegramClientPool{
+		accounts:    accounts,
+		assignments: assignments,
+		gapFill:     make(chan struct{}, 1),
+		ready:       make(chan struct{}),
+	}
+	// いずれかのアカウントで更新を取りこぼした可能性がある場合に通知
+	// 初回の接続に失敗したアカウントも、他のアカウントで取得を続けながら再接続
+	for _, m := range accounts {
+		m.gapFill = p.gapFill
+		m.retryFirstConnection = true
+	}
+	return p, nil
+}
+
+// 全てのアカウントのクライアントを実行
+// 全てのアカウントで初回の接続に失敗した場合のみエラーを返す
+// 初回の接続に失敗したアカウントは、バックグラウンドで再接続を続ける
+func (p *TelegramClientPool) Run(ctx context.Context) error {
+	var errs []error
+	for _, m := range p.accounts {
+		if err := m.Run(ctx); err != nil {
+			log.Printf("Failed to run Telegram account %s: %v. Reconnecting in background", m.name, err)
+			errs = append(errs, fmt.Errorf("account %s: %w", m.name, err))
+		}
+
+		go func(m *TelegramClientManager) {
+			select {
+			case <-m.Ready():
+				p.readyOnce.Do(func() { close(p.ready) })
+			case <-ctx.Done():
+			}
+		}(m)
+	}
+
+	if len(errs) == len(p.accounts) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// 全てのアカウントのクライアントを停止
+func (p *TelegramClientPool) Stop() error {
+	var errs []error
+	for _, m := range p.accounts {
+		if err := m.Stop(); err != nil {
+			errs = append(errs, fmt.Errorf("account %s: %w", m.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// いずれかのアカウントが初めて接続・認証済みになった時点で閉じられるチャネル
+func (p *TelegramClientPool) Ready() <-chan struct{} {
+	return p.ready
+}
+
+// いずれかのアカウントで更新を取りこぼした可能性がある場合に通知されるチャネル
+func (p *TelegramClientPool) GapFills() <-chan struct{} {
+	return p.gapFill
+}
+
+// 名前で指定したアカウント
+// 見つからない場合は nil を返す
+func (p *TelegramClientPool) Account(name string) *TelegramClientManager {
+	for _, m := range p.accounts {
+		if m.name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// 全てのアカウント（設定順）
+func (p *TelegramClientPool) Accounts() []*TelegramClientManager {
+	return p.accounts
+}
+
+// アカウント毎の接続状態
+func (p *TelegramClientPool) Connections() map[string]gateway.TelegramConnection {
+	connections := make(map[string]gateway.TelegramConnection, len(p.accounts))
+	for _, m := range p.accounts {
+		connections[m.name] = m
+	}
+	return connections
+}
+
+// チャンネルに割り当てられたアカウント名
+func (p *TelegramClientPool) AccountFor(channelUsername string) string {
+	return p.accounts[p.assignedIndex(channelUsername)].name
+}
+
+// 割り当てられたアカウントを先頭に、続くアカウントを順に返す
+func (p *TelegramClientPool) accountsFor(channelUsername string) []*TelegramClientManager {
+	primary := p.assignedIndex(channelUsername)
+	accounts := make([]*TelegramClientManager, 0, len(p.accounts))
+	for i := range p.accounts {
+		accounts = append(accounts, p.accounts[(primary+i)%len(p.accounts)])
+	}
+	return accounts
+}
+
+func (p *TelegramClientPool) assignedIndex(channelUsername string) int {
+	if i, ok := p.assignments[channelUsername]; ok {
+		return i
+	}
+	h := fnv.New32a()
+	h.Write([]byte(channelUsername))
+	return int(h.Sum32() % uint32(len(p.accounts)))
+}
@@ -0,0 +1,169 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+
+	"github.com/gotd/td/tg"
+)
+
+func newTestAccount(name string, state gateway.ConnectionState) *TelegramClientManager {
+	m := &TelegramClientManager{name: name, gapFill: make(chan struct{}, 1)}
+	if state == gateway.ConnectionReady {
+		m.api = &tg.Client{}
+	}
+	m.status = gateway.ConnectionStatus{State: state}
+	return m
+}
+
+func accountNames(accounts []*TelegramClientManager) []string {
+	var names []string
+	for _, m := range accounts {
+		names = append(names, m.name)
+	}
+	return names
+}
+
+func TestTelegramClientPool_AccountsFor(t *testing.T) {
+	accounts := []*TelegramClientManager{
+		newTestAccount("a", gateway.ConnectionReady),
+		newTestAccount("b", gateway.ConnectionReady),
+		newTestAccount("c", gateway.ConnectionReady),
+	}
+	pool, err := NewTelegramClientPool(accounts, map[string]string{"channel1": "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 明示的に割り当てたチャンネルは、割り当てたアカウントを先頭に順に返す
+	if got := accountNames(pool.accountsFor("channel1")); !reflect.DeepEqual(got, []string{"b", "c", "a"}) {
+		t.Errorf("accountsFor(channel1) = %v", got)
+	}
+	// 割り当てのないチャンネルは、チャンネル名で決まるアカウントに割り当てる
+	if got := pool.accountsFor("channel2"); got[0].name != pool.AccountFor("channel2") || len(got) != 3 {
+		t.Errorf("accountsFor(channel2) = %v", accountNames(pool.accountsFor("channel2")))
+	}
+	// 全てのアカウントで更新の取りこぼしを共有
+	accounts[2].notifyGap()
+	select {
+	case <-pool.GapFills():
+	default:
+		t.Error("gap fill is not notified")
+	}
+
+	if _, err := NewTelegramClientPool(accounts, map[string]string{"channel1": "unknown"}); err == nil {
+		t.Error("NewTelegramClientPool() with unknown account error = nil, want error")
+	}
+	if _, err := NewTelegramClientPool([]*TelegramClientManager{accounts[0], accounts[0]}, nil); err == nil {
+		t.Error("NewTelegramClientPool() with duplicate accounts error = nil, want error")
+	}
+}
+
+func TestChannelClients_Failover(t *testing.T) {
+	ctx := context.Background()
+	primary := newTestAccount("primary", gateway.ConnectionReady)
+	loggedOut := newTestAccount("logged-out", gateway.ConnectionUnauthorized)
+	backup := newTestAccount("backup", gateway.ConnectionReady)
+	pool, err := NewTelegramClientPool([]*TelegramClientManager{primary, loggedOut, backup}, map[string]string{"channel1": "primary"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clients := newChannelClients(pool, "channel1")
+	clients.primaryPeer().set(&gateway.ChannelPeer{ID: 1001, AccessHash: 1})
+	clients.peerOf("backup").set(&gateway.ChannelPeer{ID: 1001, AccessHash: 2})
+
+	t.Run("use primary account", func(t *testing.T) {
+		var accessHashes []int64
+		err := clients.withChannel(ctx, func(api *tg.Client, channel *tg.InputChannel) error {
+			accessHashes = append(accessHashes, channel.AccessHash)
+			return nil
+		})
+		if err != nil || !reflect.DeepEqual(accessHashes, []int64{1}) {
+			t.Errorf("access hashes = %v, err = %v", accessHashes, err)
+		}
+	})
+
+	t.Run("fail over on flood wait and unauthorized", func(t *testing.T) {
+		var accessHashes []int64
+		err := clients.withChannel(ctx, func(api *tg.Client, channel *tg.InputChannel) error {
+			accessHashes = append(accessHashes, channel.AccessHash)
+			if channel.AccessHash == 1 {
+				return ErrFloodWait
+			}
+			return nil
+		})
+		// 未認証のアカウントは呼び出さずに次のアカウントで取得
+		if err != nil || !reflect.DeepEqual(accessHashes, []int64{1, 2}) {
+			t.Errorf("access hashes = %v, err = %v", accessHashes, err)
+		}
+		// 割り当てられたアカウントのチャンネル情報は変更しない
+		if peer := clients.primaryPeer().get(); peer.AccessHash != 1 {
+			t.Errorf("primary peer = %+v", peer)
+		}
+	})
+
+	t.Run("other errors are returned without failover", func(t *testing.T) {
+		calls := 0
+		wantErr := errors.New("internal error")
+		err := clients.withChannel(ctx, func(api *tg.Client, channel *tg.InputChannel) error {
+			calls++
+			return wantErr
+		})
+		if !errors.Is(err, wantErr) || calls != 1 {
+			t.Errorf("err = %v, calls = %d", err, calls)
+		}
+	})
+
+	t.Run("all accounts unavailable", func(t *testing.T) {
+		err := clients.withChannel(ctx, func(api *tg.Client, channel *tg.InputChannel) error {
+			return ErrFloodWait
+		})
+		if !errors.Is(err, ErrFloodWait) || !errors.Is(err, ErrTelegramNotReady) {
+			t.Errorf("err = %v, want joined errors", err)
+		}
+	})
+}
+
+func TestTelegramClientPool_RetryFirstConnection(t *testing.T) {
+	newAccount := func(name string, failures int) *TelegramClientManager {
+		m := &TelegramClientManager{name: name, ready: make(chan struct{}), minBackoff: time.Millisecond, maxBackoff: time.Millisecond}
+		attempts := 0
+		m.runOnce = func(ctx context.Context, onConnected func()) error {
+			attempts++
+			if attempts <= failures {
+				return errors.New("dial failed")
+			}
+			m.setAPI(&tg.Client{})
+			m.setStatus(gateway.ConnectionReady, nil)
+			onConnected()
+			<-ctx.Done()
+			return nil
+		}
+		return m
+	}
+	healthy := newAccount("main", 0)
+	// 初回の接続に失敗するアカウント
+	flaky := newAccount("sub", 2)
+	pool, err := NewTelegramClientPool([]*TelegramClientManager{healthy, flaky}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pool.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	defer pool.Stop()
+
+	// 初回の接続に失敗したアカウントも、バックグラウンドで再接続
+	select {
+	case <-flaky.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("account sub is not reconnected: %+v", flaky.Status())
+	}
+}
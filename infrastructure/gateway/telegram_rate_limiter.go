@@ -0,0 +1,179 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+	"golang.org/x/time/rate"
+)
+
+// Telegram API の呼び出し間隔（全チャンネル共通）
+const (
+	defaultTelegramCallInterval = 200 * time.Millisecond
+	defaultTelegramCallBurst    = 5
+)
+
+// FLOOD_WAIT の待機時間がこの値以下の場合は待機して再試行
+// 超える場合は、待機時間が明けるまでそのチャンネルへの呼び出しを停止
+const defaultMaxFloodWaitSleep = 30 * time.Second
+
+// FLOOD_WAIT の待機中のため呼び出しを行わなかった場合のエラー
+var ErrFloodWait = errors.New("telegram flood wait")
+
+// Telegram API の呼び出し状況（/v1/admin/metrics で公開）
+var (
+	// メソッド毎の呼び出し回数
+	telegramCalls = expvar.NewMap("telegram_calls")
+	// メソッド毎の FLOOD_WAIT の発生回数
+	telegramFloodWaits = expvar.NewMap("telegram_flood_waits")
+	// 呼び出しを停止しているチャンネルと再開時刻
+	telegramFloodWaitUntil = expvar.NewMap("telegram_flood_wait_until")
+)
+
+// 全てのTelegram API呼び出しに適用するミドルウェア
+// 呼び出し間隔を制限し、FLOOD_WAIT を受けたチャンネルのみ待機させる
+type telegramRateLimiter struct {
+	// メトリクスに記録するアカウント名
+	account  string
+	limiter  *rate.Limiter
+	maxSleep time.Duration
+	now      func() time.Time
+	sleep    func(ctx context.Context, d time.Duration) error
+
+	mu         sync.Mutex
+	floodUntil map[string]time.Time
+}
+
+func newTelegramRateLimiter(interval time.Duration, burst int, maxSleep time.Duration) *telegramRateLimiter {
+	return &telegramRateLimiter{
+		limiter:    rate.NewLimiter(rate.Every(interval), burst),
+		maxSleep:   maxSleep,
+		now:        time.Now,
+		sleep:      sleepContext,
+		floodUntil: make(map[string]time.Time),
+	}
+}
+
+func (l *telegramRateLimiter) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		method := telegramMethod(input)
+		key := floodWaitKey(input, method)
+
+		// 待機中のチャンネルは呼び出さず、次回の取得に回す
+		if until, ok := l.waitingUntil(key); ok {
+			return fmt.Errorf("%w: %s is waiting until %s", ErrFloodWait, key, until.Format(time.RFC3339))
+		}
+
+		// 短い待機は一度だけ待って再試行
+		for attempt := 0; ; attempt++ {
+			if err := l.limiter.Wait(ctx); err != nil {
+				return err
+			}
+
+			telegramCalls.Add(method, 1)
+			err := next.Invoke(ctx, input, output)
+			d, ok := tgerr.AsFloodWait(err)
+			if !ok {
+				return err
+			}
+			telegramFloodWaits.Add(method, 1)
+
+			if d > l.maxSleep || attempt > 0 {
+				until := l.markWaiting(key, d)
+				log.Printf("Telegram FLOOD_WAIT on %s (%s): %s, skipping until %s", method, key, d, until.Format(time.RFC3339))
+				return err
+			}
+
+			log.Printf("Telegram FLOOD_WAIT on %s (%s): sleeping %s", method, key, d)
+			if err := l.sleep(ctx, d); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// 待機中であれば再開時刻を返す
+func (l *telegramRateLimiter) waitingUntil(key string) (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	until, ok := l.floodUntil[key]
+	if !ok {
+		return time.Time{}, false
+	}
+	if !l.now().Before(until) {
+		delete(l.floodUntil, key)
+		telegramFloodWaitUntil.Delete(l.metricKey(key))
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// 待機時間が明けるまで呼び出しを停止
+func (l *telegramRateLimiter) markWaiting(key string, d time.Duration) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	until := l.now().Add(d)
+	l.floodUntil[key] = until
+
+	var value expvar.String
+	value.Set(until.Format(time.RFC3339))
+	telegramFloodWaitUntil.Set(l.metricKey(key), &value)
+	return until
+}
+
+// アカウント毎に区別したメトリクスのキー
+func (l *telegramRateLimiter) metricKey(key string) string {
+	if l.account == "" {
+		return key
+	}
+	return l.account + "/" + key
+}
+
+// リクエストのメソッド名（例: messages.getHistory）
+func telegramMethod(input bin.Encoder) string {
+	if named, ok := input.(interface{ TypeName() string }); ok {
+		return named.TypeName()
+	}
+	return fmt.Sprintf("%T", input)
+}
+
+// FLOOD_WAIT の待機を管理する単位
+// チャンネルへのリクエストはチャンネル毎、それ以外はメソッド毎
+func floodWaitKey(input bin.Encoder, method string) string {
+	switch req := input.(type) {
+	case *tg.MessagesGetHistoryRequest:
+		if peer, ok := req.Peer.(*tg.InputPeerChannel); ok {
+			return "channel:" + strconv.FormatInt(peer.ChannelID, 10)
+		}
+	case *tg.ChannelsGetMessagesRequest:
+		if channel, ok := req.Channel.(*tg.InputChannel); ok {
+			return "channel:" + strconv.FormatInt(channel.ChannelID, 10)
+		}
+	case *tg.ContactsResolveUsernameRequest:
+		return "username:" + req.Username
+	}
+	return method
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,90 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+// チャンネル毎に指定したエラーを順に返すInvoker
+type floodInvoker struct {
+	errs  map[int64][]error
+	calls map[int64]int
+}
+
+func (i *floodInvoker) Invoke(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+	channelID := input.(*tg.MessagesGetHistoryRequest).Peer.(*tg.InputPeerChannel).ChannelID
+	call := i.calls[channelID]
+	i.calls[channelID]++
+	if call < len(i.errs[channelID]) {
+		return i.errs[channelID][call]
+	}
+	return nil
+}
+
+func historyRequest(channelID int64) *tg.MessagesGetHistoryRequest {
+	return &tg.MessagesGetHistoryRequest{Peer: &tg.InputPeerChannel{ChannelID: channelID}}
+}
+
+func TestTelegramRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	invoker := &floodInvoker{
+		errs: map[int64][]error{
+			// 短い待機は待って再試行
+			1: {tgerr.New(420, "FLOOD_WAIT_3")},
+			// 長い待機はチャンネルを停止
+			2: {tgerr.New(420, "FLOOD_WAIT_120")},
+		},
+		calls: make(map[int64]int),
+	}
+	limiter := newTelegramRateLimiter(time.Millisecond, 10, 30*time.Second)
+	limiter.now = func() time.Time { return now }
+	var slept []time.Duration
+	limiter.sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	invoke := limiter.Handle(invoker)
+
+	if err := invoke(ctx, historyRequest(1), &tg.MessagesMessagesBox{}); err != nil {
+		t.Fatalf("short flood wait: %v", err)
+	}
+	if len(slept) != 1 || slept[0] != 3*time.Second || invoker.calls[1] != 2 {
+		t.Errorf("slept = %v, calls = %d", slept, invoker.calls[1])
+	}
+
+	err := invoke(ctx, historyRequest(2), &tg.MessagesMessagesBox{})
+	if d, ok := tgerr.AsFloodWait(err); !ok || d != 120*time.Second {
+		t.Fatalf("long flood wait: err = %v", err)
+	}
+
+	// 待機中のチャンネルは呼び出さない
+	err = invoke(ctx, historyRequest(2), &tg.MessagesMessagesBox{})
+	if !errors.Is(err, ErrFloodWait) || invoker.calls[2] != 1 {
+		t.Errorf("waiting channel: err = %v, calls = %d", err, invoker.calls[2])
+	}
+	if got := telegramFloodWaitUntil.Get("channel:2"); got == nil {
+		t.Error("flood wait state is not published")
+	}
+
+	// 他のチャンネルは待機の影響を受けない
+	if err := invoke(ctx, historyRequest(1), &tg.MessagesMessagesBox{}); err != nil {
+		t.Errorf("other channel: %v", err)
+	}
+
+	// 待機時間が明けたら再開
+	now = now.Add(121 * time.Second)
+	if err := invoke(ctx, historyRequest(2), &tg.MessagesMessagesBox{}); err != nil || invoker.calls[2] != 2 {
+		t.Errorf("resumed channel: err = %v, calls = %d", err, invoker.calls[2])
+	}
+	if got := telegramFloodWaitUntil.Get("channel:2"); got != nil {
+		t.Errorf("flood wait state = %v, want removed", got)
+	}
+}
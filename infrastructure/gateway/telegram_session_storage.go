@@ -0,0 +1,95 @@
+package gateway
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/repository"
+
+	"github.com/gotd/td/session"
+	"github.com/gotd/td/telegram"
+)
+
+// DBに保存するセッションの名前（全てのレプリカで共有）
+const DefaultTelegramSessionName = "default"
+
+// セッションの暗号化キーの長さ（AES-256）
+const telegramSessionKeySize = 32
+
+// DBに暗号化して保存する telegram.SessionStorage
+// AES-256-GCMで暗号化し、セッション名を追加データとして認証する
+type encryptedSessionStorage struct {
+	repo repository.TelegramSessionRepository
+	name string
+	aead cipher.AEAD
+}
+
+// 暗号化キーを指定して、DBに保存するセッションストレージを生成
+func NewEncryptedSessionStorage(repo repository.TelegramSessionRepository, name string, key []byte) (telegram.SessionStorage, error) {
+	if len(key) != telegramSessionKeySize {
+		return nil, fmt.Errorf("session key must be %d bytes, got %d", telegramSessionKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session cipher: %w", err)
+	}
+	return &encryptedSessionStorage{repo: repo, name: name, aead: aead}, nil
+}
+
+// base64でエンコードされた暗号化キーを読み込み
+func ParseTelegramSessionKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode session key: %w", err)
+	}
+	if len(key) != telegramSessionKeySize {
+		return nil, fmt.Errorf("session key must be %d bytes, got %d", telegramSessionKeySize, len(key))
+	}
+	return key, nil
+}
+
+// セッションを取得して復号
+// 保存されていない場合は session.ErrNotFound を返す
+func (s *encryptedSessionStorage) LoadSession(ctx context.Context) ([]byte, error) {
+	stored, err := s.repo.GetSession(ctx, s.name)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, session.ErrNotFound
+	}
+
+	nonceSize := s.aead.NonceSize()
+	if len(stored.Data) < nonceSize {
+		return nil, errors.New("failed to decrypt session: data is too short")
+	}
+	data, err := s.aead.Open(nil, stored.Data[:nonceSize], stored.Data[nonceSize:], []byte(s.name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt session: %w", err)
+	}
+	return data, nil
+}
+
+// セッションを暗号化して保存
+// 保存するデータは nonce と暗号文を連結したもの
+func (s *encryptedSessionStorage) StoreSession(ctx context.Context, data []byte) error {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return s.repo.StoreSession(ctx, &entity.TelegramSession{
+		Name: s.name,
+		Data: s.aead.Seal(nonce, nonce, data, []byte(s.name)),
+	})
+}
@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+
+	"github.com/gotd/td/session"
+)
+
+type memorySessionRepository struct {
+	sessions map[string]*entity.TelegramSession
+}
+
+func (r *memorySessionRepository) GetSession(ctx context.Context, name string) (*entity.TelegramSession, error) {
+	return r.sessions[name], nil
+}
+
+func (r *memorySessionRepository) StoreSession(ctx context.Context, s *entity.TelegramSession) error {
+	r.sessions[s.Name] = s
+	return nil
+}
+
+func TestEncryptedSessionStorage(t *testing.T) {
+	ctx := context.Background()
+	repo := &memorySessionRepository{sessions: make(map[string]*entity.TelegramSession)}
+	key := bytes.Repeat([]byte{1}, telegramSessionKeySize)
+	data := []byte(`{"Version":1,"Data":{"AuthKey":"c2VjcmV0"}}`)
+
+	storage, err := NewEncryptedSessionStorage(repo, DefaultTelegramSessionName, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 保存されていない場合
+	if _, err := storage.LoadSession(ctx); !errors.Is(err, session.ErrNotFound) {
+		t.Errorf("LoadSession() error = %v, want session.ErrNotFound", err)
+	}
+
+	if err := storage.StoreSession(ctx, data); err != nil {
+		t.Fatal(err)
+	}
+	// 平文では保存しない
+	stored := repo.sessions[DefaultTelegramSessionName]
+	if stored == nil || bytes.Contains(stored.Data, []byte("AuthKey")) {
+		t.Fatalf("stored session is not encrypted: %q", stored)
+	}
+
+	loaded, err := storage.LoadSession(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded, data) {
+		t.Errorf("LoadSession() = %q, want %q", loaded, data)
+	}
+
+	// 異なるキーでは復号できない
+	otherStorage, _ := NewEncryptedSessionStorage(repo, DefaultTelegramSessionName, bytes.Repeat([]byte{2}, telegramSessionKeySize))
+	if _, err := otherStorage.LoadSession(ctx); err == nil {
+		t.Error("LoadSession() with another key error = nil, want error")
+	}
+
+	// 別の名前のセッションとして読み込むことはできない
+	repo.sessions["other"] = &entity.TelegramSession{Name: "other", Data: stored.Data}
+	renamedStorage, _ := NewEncryptedSessionStorage(repo, "other", key)
+	if _, err := renamedStorage.LoadSession(ctx); err == nil {
+		t.Error("LoadSession() with another name error = nil, want error")
+	}
+}
+
+func TestParseTelegramSessionKey(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, telegramSessionKeySize)
+	if got, err := ParseTelegramSessionKey(base64.StdEncoding.EncodeToString(key)); err != nil || !bytes.Equal(got, key) {
+		t.Errorf("ParseTelegramSessionKey() = %v, %v", got, err)
+	}
+	if _, err := ParseTelegramSessionKey(base64.StdEncoding.EncodeToString(key[:16])); err == nil {
+		t.Error("ParseTelegramSessionKey() with short key error = nil, want error")
+	}
+	if _, err := ParseTelegramSessionKey("not base64"); err == nil {
+		t.Error("ParseTelegramSessionKey() with invalid encoding error = nil, want error")
+	}
+}
@@ -10,29 +10,42 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf16"
 
 	"github.com/gotd/td/tg"
 )
 
 // TransferHackingPostGatewayを実装する構造体
 type telegramTransferPostGateway struct {
-	manager         *TelegramClientManager
+	clients         *channelClients
 	channelUsername string
-	channelPeer     *tg.InputPeerChannel
+	parse           TransferPostParser
+	rejected        rejectedPostNotifier
+	refetched       refetchedPosts[gateway.TransferPost]
 	lastMessageID   int
 	mu              sync.Mutex
 }
 
 // 新しいtelegramTransferPostGatewayを生成
-func NewTelegramTransferPostGateway(manager *TelegramClientManager, channelUsername string) gateway.TelegramTransferPostGateway {
-	return &telegramTransferPostGateway{manager: manager, channelUsername: channelUsername}
+// selector には単一のアカウントの TelegramClientManager か、複数のアカウントの TelegramClientPool を指定
+// parse にはチャンネルの投稿の形式に合わせたパーサーを指定（PostParserRegistry で取得）
+func NewTelegramTransferPostGateway(selector TelegramClientSelector, channelUsername string, parse TransferPostParser) gateway.TelegramTransferPostGateway {
+	return &telegramTransferPostGateway{
+		clients:         newChannelClients(selector, channelUsername),
+		channelUsername: channelUsername,
+		parse:           parse,
+	}
 }
 
 func (g *telegramTransferPostGateway) SetLastMessageID(lastMessageID int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	g.lastMessageID = lastMessageID
 }
 
 func (g *telegramTransferPostGateway) LastMessageID() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	return g.lastMessageID
 }
 
@@ -40,45 +53,108 @@ func (g *telegramTransferPostGateway) ChannelUsername() string {
 	return g.channelUsername
 }
 
+func (g *telegramTransferPostGateway) SetChannelPeer(peer *gateway.ChannelPeer) {
+	g.clients.primaryPeer().set(peer)
+}
+
+func (g *telegramTransferPostGateway) ChannelPeer() *gateway.ChannelPeer {
+	return g.clients.primaryPeer().get()
+}
+
+func (g *telegramTransferPostGateway) SetRejectedPostHandler(handler gateway.RejectedPostHandler) {
+	g.rejected.set(handler)
+}
+
 // 最後に取得した投稿以降、最新の投稿を取得
 func (g *telegramTransferPostGateway) GetPosts(ctx context.Context, limit int) ([]*gateway.TransferPost, error) {
-	api := g.manager.API()
-	if api == nil {
-		return nil, errors.New("telegram client is not ready")
+	// パースに失敗した投稿は、ロックを解放してから通知
+	rejected := g.rejected.collect()
+	defer rejected.flush(ctx)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	// 解決済みのチャンネル情報で、最後に取得した投稿以降、最新の投稿を取得
+	var history tg.MessagesMessagesClass
+	err := g.clients.withChannel(ctx, func(api *tg.Client, channel *tg.InputChannel) error {
+		var err error
+		history, err = api.MessagesGetHistory(ctx, &tg.MessagesGetHistoryRequest{
+			Peer:  inputPeer(channel),
+			MinID: g.lastMessageID,
+			Limit: limit,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel history: %w", err)
 	}
 
-	// チャンネル名を解決してPeer情報を取得
-	resolved, err := api.ContactsResolveUsername(ctx, &tg.ContactsResolveUsernameRequest{
-		Username: g.channelUsername,
+	// 取得した投稿の内、送金情報を含むものをTransferPostに変換
+	return g.convertMessages(history, rejected)
+}
+
+// maxID より前の投稿を遡って取得
+// 最後に取得した投稿のIDは更新しない
+func (g *telegramTransferPostGateway) GetPostsBefore(ctx context.Context, maxID int, limit int) ([]*gateway.TransferPost, *gateway.HistoryPage, error) {
+	rejected := g.rejected.collect()
+	defer rejected.flush(ctx)
+
+	var history tg.MessagesMessagesClass
+	err := g.clients.withChannel(ctx, func(api *tg.Client, channel *tg.InputChannel) error {
+		var err error
+		history, err = api.MessagesGetHistory(ctx, &tg.MessagesGetHistoryRequest{
+			Peer:  inputPeer(channel),
+			MaxID: maxID,
+			Limit: limit,
+		})
+		return err
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve username %s: %w", g.channelUsername, err)
+		return nil, nil, fmt.Errorf("failed to get channel history: %w", err)
 	}
-	channel, ok := resolved.Chats[0].(*tg.Channel)
+	channelMessages, ok := history.(*tg.MessagesChannelMessages)
 	if !ok {
-		return nil, fmt.Errorf("resolved peer is not a channel")
+		return nil, nil, fmt.Errorf("failed to cast history to ChannelMessages")
 	}
-	inputPeer := channel.AsInputPeer()
-	g.channelPeer = inputPeer
 
-	g.mu.Lock()
-	defer g.mu.Unlock()
-	// 最後に取得した投稿以降、最新の投稿を取得
-	history, err := api.MessagesGetHistory(ctx, &tg.MessagesGetHistoryRequest{
-		Peer:  inputPeer,
-		MinID: g.lastMessageID,
-		Limit: limit,
-	})
+	var posts []*gateway.TransferPost
+	for _, msg := range channelMessages.Messages {
+		if message, ok := msg.(*tg.Message); ok && message.Message != "" {
+			if post := g.convertMessage(message, false, rejected); post != nil {
+				posts = append(posts, post)
+			}
+		}
+	}
+	return posts, historyPage(channelMessages.Messages), nil
+}
+
+// チャンネルの新しい投稿の通知を購読
+// 通知を受けたら、最後に取得した投稿以降の投稿を取得して handler に渡す
+// 通知はチャンネル名の解決に使用したアカウントで受信
+func (g *telegramTransferPostGateway) SubscribeNewPosts(ctx context.Context, handler func(ctx context.Context, posts []*gateway.TransferPost)) error {
+	// 解決済みのチャンネル情報からチャンネルIDを取得
+	m, channel, err := g.clients.resolve(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get channel history: %w", err)
+		return err
 	}
 
-	// 取得した投稿の内、送金情報を含むものをTransferPostに変換
-	return g.convertMessages(history)
+	m.OnChannelMessage(channel.ChannelID, func(ctx context.Context, messageID int) {
+		// 取得済みの投稿は無視
+		if messageID <= g.LastMessageID() {
+			return
+		}
+
+		// 通知された投稿だけでなく、最後に取得した投稿以降を取得して取りこぼしを防ぐ
+		posts, err := g.GetPosts(ctx, realtimeFetchLimit)
+		if err != nil {
+			log.Printf("Failed to get new posts from %s: %v", g.channelUsername, err)
+			return
+		}
+		handler(ctx, posts)
+	})
+	return nil
 }
 
 // 取得した投稿の内、送金情報を含むものをHackingPostに変換
-func (g *telegramTransferPostGateway) convertMessages(history tg.MessagesMessagesClass) ([]*gateway.TransferPost, error) {
+func (g *telegramTransferPostGateway) convertMessages(history tg.MessagesMessagesClass, rejected *rejectedPosts) ([]*gateway.TransferPost, error) {
 	// 取得したデータを投稿のスライスに変換
 	channelMessages, ok := history.(*tg.MessagesChannelMessages)
 	if !ok {
@@ -89,22 +165,8 @@ func (g *telegramTransferPostGateway) convertMessages(history tg.MessagesMessage
 	for _, msg := range channelMessages.Messages {
 		// チャンネルの投稿か確認
 		if message, ok := msg.(*tg.Message); ok && message.Message != "" {
-
-			// 投稿から送金情報を取得
-			post, err := g.parseTransferMessage(message.Message)
-			if err == nil {
-				// 投稿から時間を取得
-				date := message.GetDate()
-				post.ReportTime = time.Unix(int64(date), 0)
-				post.MessageID = message.ID
-
-				// 投稿からタグを取得
-				tagNames := g.extractTags(message.Message, message.Entities)
-				post.TagNames = tagNames
-
+			if post := g.convertMessage(message, false, rejected); post != nil {
 				posts = append(posts, post)
-			} else {
-				log.Printf("Failed to parse transfer message with error: %v", err)
 			}
 
 			// 取得した投稿の中で最も新しい投稿のIDを更新
@@ -116,8 +178,110 @@ func (g *telegramTransferPostGateway) convertMessages(history tg.MessagesMessage
 	return posts, nil
 }
 
+// 投稿から送金情報を取得し、TransferPostに変換
+// 送金情報を含まない投稿の場合は nil を返し、パースに失敗した投稿は rejected に追加
+// refetch は編集の確認での再取得の場合に指定し、本文が変わっていない投稿は前回の結果を使用してパースの失敗を再度通知しない
+func (g *telegramTransferPostGateway) convertMessage(message *tg.Message, refetch bool, rejected *rejectedPosts) *gateway.TransferPost {
+	// 投稿から送金情報を取得
+	var post *gateway.TransferPost
+	var err error
+	reused := false
+	if refetch {
+		post, reused, err = g.refetched.parse(message.ID, message.Message, func() (*gateway.TransferPost, error) {
+			return g.parse(message.Message)
+		})
+	} else {
+		post, err = g.parse(message.Message)
+	}
+	if err != nil {
+		if reused {
+			return nil
+		}
+		rejected.add(&gateway.RejectedPost{
+			ChannelUsername: g.channelUsername,
+			MessageID:       message.ID,
+			Text:            message.Message,
+			ReportTime:      time.Unix(int64(message.GetDate()), 0),
+			Error:           err.Error(),
+		})
+		return nil
+	}
+
+	// 投稿から時間を取得
+	date := message.GetDate()
+	post.ReportTime = time.Unix(int64(date), 0)
+	post.MessageID = message.ID
+	post.ChannelUsername = g.channelUsername
+	post.Text = message.Message
+
+	// 投稿からタグを取得
+	tagNames := g.extractTags(message.Message, message.Entities)
+	post.TagNames = tagNames
+
+	return post
+}
+
+// 指定したメッセージIDの投稿を再取得してTransferPostに変換
+// 削除されている投稿は、そのメッセージIDを deletedIDs として返す
+func (g *telegramTransferPostGateway) GetPostsByMessageIDs(ctx context.Context, messageIDs []int) ([]*gateway.TransferPost, []int, error) {
+	if len(messageIDs) == 0 {
+		return nil, nil, nil
+	}
+	rejected := g.rejected.collect()
+	defer rejected.flush(ctx)
+
+	ids := make([]tg.InputMessageClass, len(messageIDs))
+	for i, messageID := range messageIDs {
+		ids[i] = &tg.InputMessageID{ID: messageID}
+	}
+	var msgs tg.MessagesMessagesClass
+	err := g.clients.withChannel(ctx, func(api *tg.Client, channel *tg.InputChannel) error {
+		var err error
+		msgs, err = api.ChannelsGetMessages(ctx, &tg.ChannelsGetMessagesRequest{
+			Channel: channel,
+			ID:      ids,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+	channelMessages, ok := msgs.(*tg.MessagesChannelMessages)
+	if !ok {
+		return nil, nil, fmt.Errorf("failed to cast messages to ChannelMessages")
+	}
+
+	var posts []*gateway.TransferPost
+	var deletedIDs []int
+	for _, msg := range channelMessages.Messages {
+		switch message := msg.(type) {
+		case *tg.MessageEmpty:
+			deletedIDs = append(deletedIDs, message.ID)
+		case *tg.Message:
+			if post := g.convertMessage(message, true, rejected); post != nil {
+				posts = append(posts, post)
+			}
+		}
+	}
+	g.refetched.retain(messageIDs)
+	return posts, deletedIDs, nil
+}
+
+// チャンネルの投稿の編集・削除の通知を購読
+// 通知はチャンネル名の解決に使用したアカウントで受信
+func (g *telegramTransferPostGateway) SubscribeChanges(ctx context.Context, handler func(ctx context.Context)) error {
+	m, channel, err := g.clients.resolve(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.OnChannelChange(channel.ChannelID, handler)
+	return nil
+}
+
 // 投稿の形式からパースして送金情報を取得
-func (g *telegramTransferPostGateway) parseTransferMessage(message string) (*gateway.TransferPost, error) {
+// MTProto と Bot API のゲートウェイで共通
+func parseTransferPost(message string) (*gateway.TransferPost, error) {
 	// スペースで分割
 	tokens := strings.Fields(message)
 
@@ -129,7 +293,7 @@ func (g *telegramTransferPostGateway) parseTransferMessage(message string) (*gat
 	for i, token := range tokens {
 		if token == "transferred" && i > 1 && i+3 < len(tokens) {
 			// "transferred" の前の単語が「送金額」と「トークン」
-			amount = strings.ReplaceAll(tokens[i-2], ",", "")
+			amount = tokens[i-2]
 			post.Token = strings.TrimPrefix(tokens[i-1], "#")
 
 			// "transferred" の後の単語が "from", "送金元", "to", "送金先"
@@ -142,8 +306,7 @@ func (g *telegramTransferPostGateway) parseTransferMessage(message string) (*gat
 		}
 	}
 
-	re := regexp.MustCompile(`^[^0-9]+`)
-	post.Amount = re.ReplaceAllString(amount, "")
+	post.Amount = normalizeTransferAmount(amount)
 
 	if !found {
 		return nil, errors.New("TransferPost pattern not found in message")
@@ -152,6 +315,14 @@ func (g *telegramTransferPostGateway) parseTransferMessage(message string) (*gat
 	return &post, nil
 }
 
+// 金額の先頭の記号
+var transferAmountPrefix = regexp.MustCompile(`^[^0-9]+`)
+
+// 金額の区切り文字と、先頭の記号（"⚠️⚠️⚠️141,271.0" の絵文字など）を取り除く
+func normalizeTransferAmount(amount string) string {
+	return transferAmountPrefix.ReplaceAllString(strings.ReplaceAll(amount, ",", ""), "")
+}
+
 // 投稿に付けられたタグを取得
 func (g *telegramTransferPostGateway) extractTags(message string, entities []tg.MessageEntityClass) []string {
 	var tags []string
@@ -159,8 +330,9 @@ func (g *telegramTransferPostGateway) extractTags(message string, entities []tg.
 		return tags
 	}
 
-	// UTF-16オフセットに対応するため、メッセージをruneのスライスに変換
-	messageRunes := []rune(message)
+	// エンティティのオフセットはUTF-16のコード単位のため、メッセージをUTF-16に変換
+	// 絵文字など、2つのコード単位で表される文字を含む場合にずれないようにする
+	units := utf16.Encode([]rune(message))
 
 	for _, entity := range entities {
 		// エンティティがハッシュタグ型か判定
@@ -168,8 +340,8 @@ func (g *telegramTransferPostGateway) extractTags(message string, entities []tg.
 			// OffsetとLengthを使ってハッシュタグ部分を取得
 			start := e.Offset
 			end := e.Offset + e.Length
-			if start >= 0 && end <= len(messageRunes) {
-				tag := string(messageRunes[start:end])
+			if start >= 0 && end <= len(units) {
+				tag := string(utf16.Decode(units[start:end]))
 				cleanTag := strings.TrimPrefix(tag, "#")
 				tags = append(tags, cleanTag)
 			}
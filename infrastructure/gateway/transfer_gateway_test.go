@@ -1,101 +1,17 @@
 package gateway
 
 import (
-	"testing"
-
 	"context"
 	"errors"
-	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
-	"log"
-	"os"
-	"os/signal"
-	"path/filepath"
+	"reflect"
 	"regexp"
-	"strconv"
 	"strings"
-	"syscall"
+	"testing"
+	"time"
 
-	"github.com/gotd/td/session"
-	"github.com/gotd/td/telegram"
-	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
-	"go.uber.org/zap"
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
 )
 
-func TestTransferGatewayGetPosts(t *testing.T) {
-	logger, _ := zap.NewDevelopment()
-
-	err := godotenv.Load()
-	if err != nil {
-		log.Println("Warning: .env file not found")
-	}
-
-	// 設定の読み込み
-	telegramAppIDStr := os.Getenv("TELEGRAM_APP_ID")
-	telegramAppHash := os.Getenv("TELEGRAM_APP_HASH")
-	phone := os.Getenv("TELEGRAM_PHONE_NUMBER")
-	hash := os.Getenv("TELEGRAM_AUTH_HASH")
-	code := os.Getenv("TELEGRAM_CODE")
-	telegramHackingChannel := os.Getenv("TELEGRAM_HACKING_CHANNEL_USERNAME")
-	telegramTransferChannel := os.Getenv("TELEGRAM_TRANSFER_CHANNEL_USERNAME")
-
-	if telegramAppIDStr == "" || telegramAppHash == "" || telegramHackingChannel == "" ||
-		telegramTransferChannel == "" || phone == "" {
-		log.Fatal("Telegram user client environment variables not fully set.")
-	}
-	telegramAppID, err := strconv.Atoi(telegramAppIDStr)
-	if err != nil {
-		log.Fatalf("Invalid TELEGRAM_APP_ID: %v", err)
-	}
-
-	// 依存性の注入 (DI)
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer stop()
-
-	sessionDir := ".td"
-	os.MkdirAll(sessionDir, 0755)
-	client := telegram.NewClient(telegramAppID, telegramAppHash, telegram.Options{
-		Logger: logger,
-		SessionStorage: &session.FileStorage{
-			Path: filepath.Join(sessionDir, "session.json"),
-		},
-	})
-	telegramClientManager := &TelegramClientManager{client: client}
-
-	// Runメソッドを呼び出して接続を開始
-	if err := telegramClientManager.Run(ctx, phone, hash, code); err != nil {
-		log.Fatalf("Failed to run Telegram Gateway: %v", err)
-	}
-	log.Println("Telegram client connected and ready.")
-
-	// 各gatewayの初期化
-	telegramTransferGateway := NewTelegramTransferPostGateway(
-		telegramClientManager,
-		telegramTransferChannel,
-	)
-
-	transferPosts, err := telegramTransferGateway.GetPosts(ctx, 100)
-	if err != nil {
-		t.Error(err)
-	}
-
-	stop() // 他のコンテキストユーザーにキャンセルを通知
-	log.Println("Shutting down server...")
-
-	// Telegramクライアントを停止
-	if err := telegramClientManager.Stop(); err != nil {
-		log.Println("Failed to stop telegram client:", err)
-	}
-
-	log.Println("Server exiting")
-	t.Log(len(transferPosts))
-
-	for i := 0; i < 5; i++ {
-		t.Log(transferPosts[i])
-	}
-
-}
-
 func TestParseTransferMessage(t *testing.T) {
 	testString := `⚠️⚠️⚠️141,271.0 #USDT transferred from Guarantee-Merchant to TUtjxCskyxs4WbPP1bT7GCA4zsZUVaHqHn.
 
@@ -144,3 +60,65 @@ func parseTransferMessage(message string) (*gateway.TransferPost, error) {
 
 	return &post, nil
 }
+
+func TestTransferGatewayGetPosts_Fixture(t *testing.T) {
+	m, _ := newFixtureAccount(t, telegramFixturePath)
+	gw := NewTelegramTransferPostGateway(m, "transferchannel", parseTransferPost)
+	gw.SetLastMessageID(100)
+	var rejected []*gateway.RejectedPost
+	gw.SetRejectedPostHandler(func(ctx context.Context, post *gateway.RejectedPost) {
+		rejected = append(rejected, post)
+	})
+
+	posts, err := gw.GetPosts(context.Background(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 最後に取得した投稿より新しい投稿の内、送金情報の投稿のみ返す
+	want := []*gateway.TransferPost{
+		{
+			Text:            "🚨 3,250 #ETH transferred from #Bybit to #Unknown.",
+			Token:           "ETH",
+			Amount:          "3250",
+			From:            "Bybit",
+			To:              "Unknown",
+			ReportTime:      time.Unix(1700000180, 0),
+			MessageID:       103,
+			ChannelUsername: "transferchannel",
+			TagNames:        []string{"ETH", "Bybit", "Unknown"},
+		},
+		{
+			Text:            "⚠️⚠️⚠️ 2,500,000 #USDC transferred from #Coinbase to 0x28C6c06298d514Db089934071355E5743bf21d60.",
+			Token:           "USDC",
+			Amount:          "2500000",
+			From:            "Coinbase",
+			To:              "0x28C6c06298d514Db089934071355E5743bf21d60",
+			ReportTime:      time.Unix(1700000060, 0),
+			MessageID:       101,
+			ChannelUsername: "transferchannel",
+			// 絵文字を含む投稿でも、UTF-16のオフセットでハッシュタグを取得
+			TagNames: []string{"USDC", "Coinbase"},
+		},
+	}
+	if !reflect.DeepEqual(posts, want) {
+		t.Errorf("posts = %+v, want %+v", posts, want)
+	}
+	if gw.LastMessageID() != 103 {
+		t.Errorf("LastMessageID() = %d, want 103", gw.LastMessageID())
+	}
+
+	// 送金情報の形式でない投稿は handler に渡す
+	wantRejected := []*gateway.RejectedPost{
+		{
+			ChannelUsername: "transferchannel",
+			MessageID:       102,
+			Text:            "🔥 1,000,000,000 #USDT burned at Tether Treasury",
+			ReportTime:      time.Unix(1700000120, 0),
+			Error:           "TransferPost pattern not found in message",
+		},
+	}
+	if !reflect.DeepEqual(rejected, wantRejected) {
+		t.Errorf("rejected = %+v, want %+v", rejected, wantRejected)
+	}
+}
@@ -0,0 +1,394 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+	"github.com/itout-datetoya/hack-info-timeline/domain/repository"
+	"github.com/itout-datetoya/hack-info-timeline/usecases"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 一覧取得時のデフォルトの件数
+const defaultFailedPostLimit = 100
+
+// 1回のリクエストで再分析する情報の最大件数
+// リクエストの処理中にLLMの呼び出しを続けないよう、続きは afterID を指定して再分析
+const maxReanalysisLimitPerRequest = 100
+
+type AdminHandler struct {
+	hackingUsecase       *usecases.HackingUsecase
+	transferUsecase      *usecases.TransferUsecase
+	analysisCacheUsecase *usecases.AnalysisCacheUsecase
+}
+
+func NewAdminHandler(hackingUsecase *usecases.HackingUsecase, transferUsecase *usecases.TransferUsecase, analysisCacheUsecase *usecases.AnalysisCacheUsecase) *AdminHandler {
+	return &AdminHandler{
+		hackingUsecase:       hackingUsecase,
+		transferUsecase:      transferUsecase,
+		analysisCacheUsecase: analysisCacheUsecase,
+	}
+}
+
+// 処理に失敗した投稿の詳細
+// リトライ情報に加えて、Telegramの投稿本文を返す
+type failedPostResponse struct {
+	*entity.RetryPost
+	Text string
+}
+
+func (h *AdminHandler) GetHackingFailedPosts(c *gin.Context) {
+	limit, ok := parseFailedPostLimit(c)
+	if !ok {
+		return
+	}
+
+	retryPosts, err := h.hackingUsecase.GetFailedPosts(c.Request.Context(), c.Query("status"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		log.Printf("Failed to get hacking failed posts: %v", err)
+		return
+	}
+	c.JSON(http.StatusOK, retryPosts)
+}
+
+func (h *AdminHandler) GetHackingFailedPost(c *gin.Context) {
+	id, ok := parseFailedPostID(c)
+	if !ok {
+		return
+	}
+
+	retryPost, post, err := h.hackingUsecase.GetFailedPost(c.Request.Context(), id)
+	if err != nil {
+		respondFailedPostError(c, "Failed to get hacking failed post", err)
+		return
+	}
+	c.JSON(http.StatusOK, failedPostResponse{RetryPost: retryPost, Text: post.Text})
+}
+
+func (h *AdminHandler) ReplayHackingFailedPost(c *gin.Context) {
+	id, ok := parseFailedPostID(c)
+	if !ok {
+		return
+	}
+
+	err := h.hackingUsecase.ReplayFailedPost(analysisContext(c), id)
+	respondReplayResult(c, "Failed to replay hacking failed post", err)
+}
+
+func (h *AdminHandler) ReplayHackingFailedPosts(c *gin.Context) {
+	limit, ok := parseFailedPostLimit(c)
+	if !ok {
+		return
+	}
+
+	processedCount, skippedCount, errs := h.hackingUsecase.ReplayFailedPosts(analysisContext(c), c.Query("status"), limit)
+	respondReplayCounts(c, processedCount, skippedCount, errs)
+}
+
+func (h *AdminHandler) GetTransferFailedPosts(c *gin.Context) {
+	limit, ok := parseFailedPostLimit(c)
+	if !ok {
+		return
+	}
+
+	retryPosts, err := h.transferUsecase.GetFailedPosts(c.Request.Context(), c.Query("status"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		log.Printf("Failed to get transfer failed posts: %v", err)
+		return
+	}
+	c.JSON(http.StatusOK, retryPosts)
+}
+
+func (h *AdminHandler) GetTransferFailedPost(c *gin.Context) {
+	id, ok := parseFailedPostID(c)
+	if !ok {
+		return
+	}
+
+	retryPost, post, err := h.transferUsecase.GetFailedPost(c.Request.Context(), id)
+	if err != nil {
+		respondFailedPostError(c, "Failed to get transfer failed post", err)
+		return
+	}
+	c.JSON(http.StatusOK, failedPostResponse{RetryPost: retryPost, Text: post.Text})
+}
+
+func (h *AdminHandler) ReplayTransferFailedPost(c *gin.Context) {
+	id, ok := parseFailedPostID(c)
+	if !ok {
+		return
+	}
+
+	err := h.transferUsecase.ReplayFailedPost(c.Request.Context(), id)
+	respondReplayResult(c, "Failed to replay transfer failed post", err)
+}
+
+func (h *AdminHandler) ReplayTransferFailedPosts(c *gin.Context) {
+	limit, ok := parseFailedPostLimit(c)
+	if !ok {
+		return
+	}
+
+	processedCount, skippedCount, errs := h.transferUsecase.ReplayFailedPosts(c.Request.Context(), c.Query("status"), limit)
+	respondReplayCounts(c, processedCount, skippedCount, errs)
+}
+
+// パースに失敗して隔離したハッキング情報の投稿を返す
+func (h *AdminHandler) GetHackingQuarantinedPosts(c *gin.Context) {
+	limit, ok := parseFailedPostLimit(c)
+	if !ok {
+		return
+	}
+
+	posts, err := h.hackingUsecase.GetQuarantinedPosts(c.Request.Context(), c.Query("channel"), c.Query("status"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		log.Printf("Failed to get hacking quarantined posts: %v", err)
+		return
+	}
+	c.JSON(http.StatusOK, posts)
+}
+
+// 確認を待つハッキング情報の投稿のLLMによる抽出結果を公開
+func (h *AdminHandler) PublishHackingQuarantinedPost(c *gin.Context) {
+	id, ok := parseFailedPostID(c)
+	if !ok {
+		return
+	}
+
+	err := h.hackingUsecase.PublishQuarantinedPost(analysisContext(c), id)
+	respondPublishResult(c, "Failed to publish hacking quarantined post", err)
+}
+
+// パースに失敗して隔離した送金情報の投稿を返す
+func (h *AdminHandler) GetTransferQuarantinedPosts(c *gin.Context) {
+	limit, ok := parseFailedPostLimit(c)
+	if !ok {
+		return
+	}
+
+	posts, err := h.transferUsecase.GetQuarantinedPosts(c.Request.Context(), c.Query("channel"), c.Query("status"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		log.Printf("Failed to get transfer quarantined posts: %v", err)
+		return
+	}
+	c.JSON(http.StatusOK, posts)
+}
+
+// 確認を待つ送金情報の投稿のLLMによる抽出結果を公開
+func (h *AdminHandler) PublishTransferQuarantinedPost(c *gin.Context) {
+	id, ok := parseFailedPostID(c)
+	if !ok {
+		return
+	}
+
+	err := h.transferUsecase.PublishQuarantinedPost(c.Request.Context(), id)
+	respondPublishResult(c, "Failed to publish transfer quarantined post", err)
+}
+
+// 保存済みのハッキング情報を再分析し、変更の差分を返す
+// 1回で再分析する件数には上限があり、続きがある場合は報告の NextAfterID を afterID に指定して再度リクエストする
+func (h *AdminHandler) ReanalyzeHackingInfos(c *gin.Context) {
+	limit, ok := parseOptionalInt(c, "limit")
+	if !ok {
+		return
+	}
+	if limit == 0 || limit > maxReanalysisLimitPerRequest {
+		limit = maxReanalysisLimitPerRequest
+	}
+	batchSize, ok := parseOptionalInt(c, "batchSize")
+	if !ok {
+		return
+	}
+	afterID, ok := parseOptionalInt(c, "afterID")
+	if !ok {
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.Query("dryRun"))
+	useCache, _ := strconv.ParseBool(c.Query("useCache"))
+
+	report, err := h.hackingUsecase.ReanalyzeInfos(c.Request.Context(), usecases.ReanalysisOptions{
+		PromptVersion: c.Query("promptVersion"),
+		BatchSize:     batchSize,
+		Limit:         limit,
+		DryRun:        dryRun,
+		AfterID:       int64(afterID),
+		UseCache:      useCache,
+	})
+	if err != nil {
+		// 途中までの結果も返す
+		log.Printf("Failed to reanalyze hacking infos: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Reanalysis aborted", "report": report})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// LLMによる分析結果のキャッシュを無効化
+// プロンプトを変更した場合に使用
+// 誤って全てのキャッシュを削除しないよう、promptVersion か all=true のいずれかの指定が必要
+func (h *AdminHandler) InvalidateAnalysisCache(c *gin.Context) {
+	promptVersion := c.Query("promptVersion")
+	all, _ := strconv.ParseBool(c.Query("all"))
+	if (promptVersion == "" && !all) || (promptVersion != "" && all) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Specify either promptVersion or all=true"})
+		return
+	}
+
+	deletedCount, err := h.analysisCacheUsecase.InvalidateAnalysisCache(c.Request.Context(), promptVersion)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		log.Printf("Failed to invalidate analysis cache: %v", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":       fmt.Sprintf("Invalidated %d analysis caches.", deletedCount),
+		"deleted_count": deletedCount,
+	})
+}
+
+// チャンネル毎の過去の投稿の取得（バックフィル）の進捗を返す
+func (h *AdminHandler) GetBackfills(c *gin.Context) {
+	hackingBackfills, err := h.hackingUsecase.GetBackfillProgress(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		log.Printf("Failed to get hacking backfills: %v", err)
+		return
+	}
+	transferBackfills, err := h.transferUsecase.GetBackfillProgress(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		log.Printf("Failed to get transfer backfills: %v", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"hacking":  hackingBackfills,
+		"transfer": transferBackfills,
+	})
+}
+
+// クエリパラメータ bypassCache が true の場合、分析結果のキャッシュを参照しないコンテキストを返す
+func analysisContext(c *gin.Context) context.Context {
+	ctx := c.Request.Context()
+	if bypass, _ := strconv.ParseBool(c.Query("bypassCache")); bypass {
+		return gateway.WithAnalysisCacheBypass(ctx)
+	}
+	return ctx
+}
+
+// パスパラメータからリトライキューのIDを取得
+func parseFailedPostID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id format"})
+		return 0, false
+	}
+	return id, true
+}
+
+// クエリパラメータから取得件数を取得
+// 指定がない場合はデフォルトの件数
+func parseFailedPostLimit(c *gin.Context) (int, bool) {
+	limitQuery := c.Query("limit")
+	if limitQuery == "" {
+		return defaultFailedPostLimit, true
+	}
+
+	limit, err := strconv.Atoi(limitQuery)
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit format"})
+		return 0, false
+	}
+	return limit, true
+}
+
+// 省略可能な正の整数のクエリパラメータを取得
+// 指定がない場合は 0
+func parseOptionalInt(c *gin.Context, key string) (int, bool) {
+	query := c.Query(key)
+	if query == "" {
+		return 0, true
+	}
+
+	value, err := strconv.Atoi(query)
+	if err != nil || value <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s format", key)})
+		return 0, false
+	}
+	return value, true
+}
+
+func respondFailedPostError(c *gin.Context, message string, err error) {
+	if errors.Is(err, usecases.ErrRetryPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Failed post not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+	log.Printf("%s: %v", message, err)
+}
+
+func respondReplayResult(c *gin.Context, message string, err error) {
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "Successfully replayed the post."})
+	case errors.Is(err, repository.ErrDuplicateInfo):
+		// 保存済みの投稿はキューから削除済み
+		c.JSON(http.StatusOK, gin.H{"message": "The post was already stored."})
+	case errors.Is(err, usecases.ErrRetryPostNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Failed post not found"})
+	default:
+		// 失敗した投稿は試行回数を増やしてキューに残る
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "Replay failed",
+			"last_error": err.Error(),
+		})
+	}
+}
+
+func respondPublishResult(c *gin.Context, message string, err error) {
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "Successfully published the post."})
+	case errors.Is(err, repository.ErrDuplicateInfo):
+		c.JSON(http.StatusOK, gin.H{"message": "The post was already stored."})
+	case errors.Is(err, usecases.ErrQuarantinedPostNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quarantined post not found"})
+	case errors.Is(err, usecases.ErrQuarantinedPostNotExtracted):
+		c.JSON(http.StatusConflict, gin.H{"error": "The post has not been extracted by LLM"})
+	default:
+		// 失敗した投稿はリトライキューに登録済み
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "Publish failed",
+			"last_error": err.Error(),
+		})
+	}
+}
+
+func respondReplayCounts(c *gin.Context, processedCount, skippedCount int, errs []error) {
+	for _, err := range errs {
+		log.Printf("Replay error: %v", err)
+	}
+
+	status := http.StatusOK
+	if len(errs) > 0 {
+		status = http.StatusInternalServerError
+	}
+	c.JSON(status, gin.H{
+		"message":         fmt.Sprintf("Replay completed with %d errors.", len(errs)),
+		"processed_count": processedCount,
+		"skipped_count":   skippedCount,
+		"error_count":     len(errs),
+	})
+}
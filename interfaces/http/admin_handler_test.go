@@ -0,0 +1,74 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/usecases"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 削除の呼び出しを記録するキャッシュのリポジトリ
+type mockLLMCacheRepository struct {
+	deleted       bool
+	promptVersion string
+}
+
+func (m *mockLLMCacheRepository) GetAnalysisCache(ctx context.Context, key string) (*entity.LLMAnalysisCache, error) {
+	return nil, nil
+}
+
+func (m *mockLLMCacheRepository) StoreAnalysisCache(ctx context.Context, analysisCache *entity.LLMAnalysisCache) error {
+	return nil
+}
+
+func (m *mockLLMCacheRepository) DeleteAnalysisCaches(ctx context.Context, promptVersion string) (int64, error) {
+	m.deleted = true
+	m.promptVersion = promptVersion
+	return 3, nil
+}
+
+func TestInvalidateAnalysisCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name              string
+		query             string
+		wantStatus        int
+		wantDeleted       bool
+		wantPromptVersion string
+	}{
+		{name: "prompt version", query: "?promptVersion=hacking-v1", wantStatus: http.StatusOK, wantDeleted: true, wantPromptVersion: "hacking-v1"},
+		{name: "all", query: "?all=true", wantStatus: http.StatusOK, wantDeleted: true},
+		{name: "no parameters", query: "", wantStatus: http.StatusBadRequest},
+		{name: "all is false", query: "?all=false", wantStatus: http.StatusBadRequest},
+		{name: "both parameters", query: "?promptVersion=hacking-v1&all=true", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockLLMCacheRepository{}
+			handler := NewAdminHandler(nil, nil, usecases.NewAnalysisCacheUsecase(repo))
+			router := gin.New()
+			router.DELETE("/v1/admin/llm-cache", handler.InvalidateAnalysisCache)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodDelete, "/v1/admin/llm-cache"+tt.query, nil)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if repo.deleted != tt.wantDeleted {
+				t.Errorf("DeleteAnalysisCaches() called = %v, want %v", repo.deleted, tt.wantDeleted)
+			}
+			if repo.promptVersion != tt.wantPromptVersion {
+				t.Errorf("DeleteAnalysisCaches() promptVersion = %q, want %q", repo.promptVersion, tt.wantPromptVersion)
+			}
+		})
+	}
+}
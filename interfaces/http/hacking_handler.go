@@ -2,6 +2,7 @@ package http
 
 import (
 	"fmt"
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
 	"github.com/itout-datetoya/hack-info-timeline/usecases"
 	"log"
 	"net/http"
@@ -19,6 +20,38 @@ func NewHackingHandler(hackingUsecase *usecases.HackingUsecase) *HackingHandler
 	return &HackingHandler{hackingUsecase: hackingUsecase}
 }
 
+// 元の投稿本文を含めたハッキング情報
+// includePostText が指定された場合のみ GetInfo で返す
+type hackingInfoWithPostTextResponse struct {
+	*entity.HackingInfo
+	PostText    string
+	ReplyToText string
+	Edits       []hackingInfoEditWithPostTextResponse `json:",omitempty"`
+}
+
+// 元の投稿本文を含めた編集履歴
+type hackingInfoEditWithPostTextResponse struct {
+	*entity.HackingInfoEdit
+	PostText    string
+	ReplyToText string
+}
+
+func newHackingInfoWithPostTextResponse(info *entity.HackingInfo) hackingInfoWithPostTextResponse {
+	resp := hackingInfoWithPostTextResponse{
+		HackingInfo: info,
+		PostText:    info.PostText,
+		ReplyToText: info.ReplyToText,
+	}
+	for _, edit := range info.Edits {
+		resp.Edits = append(resp.Edits, hackingInfoEditWithPostTextResponse{
+			HackingInfoEdit: edit,
+			PostText:        edit.PostText,
+			ReplyToText:     edit.ReplyToText,
+		})
+	}
+	return resp
+}
+
 func (h *HackingHandler) GetLatestTimeline(c *gin.Context) {
 	tagsQuery := c.Query("tags")
 	infoNumberQuery := c.Query("infoNumber")
@@ -27,13 +60,17 @@ func (h *HackingHandler) GetLatestTimeline(c *gin.Context) {
 	if tagsQuery != "" {
 		tags = strings.Split(tagsQuery, ",")
 	}
+	attackVectors, ok := parseAttackVectors(c)
+	if !ok {
+		return
+	}
 	infoNumber, err := strconv.Atoi(infoNumberQuery)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid infoNumber format"})
 		return
 	}
 
-	infos, err := h.hackingUsecase.GetLatestTimeline(c.Request.Context(), tags, infoNumber)
+	infos, err := h.hackingUsecase.GetLatestTimeline(c.Request.Context(), tags, attackVectors, infoNumber)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
 		log.Printf("Failed to get latest hacking timeline: %v", err)
@@ -52,6 +89,11 @@ func (h *HackingHandler) GetPrevTimeline(c *gin.Context) {
 		tags = strings.Split(tagsQuery, ",")
 	}
 
+	attackVectors, ok := parseAttackVectors(c)
+	if !ok {
+		return
+	}
+
 	prevInfoID, err := strconv.ParseInt(prevInfoIDQuery, 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prevInfoID format"})
@@ -64,7 +106,7 @@ func (h *HackingHandler) GetPrevTimeline(c *gin.Context) {
 		return
 	}
 
-	infos, err := h.hackingUsecase.GetPrevTimeline(c.Request.Context(), tags, prevInfoID, infoNumber)
+	infos, err := h.hackingUsecase.GetPrevTimeline(c.Request.Context(), tags, attackVectors, prevInfoID, infoNumber)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
 		log.Printf("Failed to get previous hacking timeline: %v", err)
@@ -73,6 +115,32 @@ func (h *HackingHandler) GetPrevTimeline(c *gin.Context) {
 	c.JSON(http.StatusOK, infos)
 }
 
+// IDで指定した情報を、編集履歴と削除フラグを含めて返す
+// クエリパラメータ includePostText が true の場合は元の投稿本文も返す
+func (h *HackingHandler) GetInfo(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id format"})
+		return
+	}
+
+	info, err := h.hackingUsecase.GetInfo(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		log.Printf("Failed to get hacking info: %v", err)
+		return
+	}
+	if info == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Info not found"})
+		return
+	}
+	if includePostText, _ := strconv.ParseBool(c.Query("includePostText")); includePostText {
+		c.JSON(http.StatusOK, newHackingInfoWithPostTextResponse(info))
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
 func (h *HackingHandler) GetAllTags(c *gin.Context) {
 	tags, err := h.hackingUsecase.GetAllTags(c.Request.Context())
 	if err != nil {
@@ -91,7 +159,7 @@ func (h *HackingHandler) ScrapeNewInfos(c *gin.Context) {
 		return
 	}
 
-	processedCount, errs := h.hackingUsecase.ScrapeAndStore(c.Request.Context(), limit)
+	processedCount, skippedCount, errs := h.hackingUsecase.ScrapeAndStore(c.Request.Context(), limit)
 
 	if len(errs) > 0 {
 		// エラーはサーバー側でログに記録
@@ -102,6 +170,7 @@ func (h *HackingHandler) ScrapeNewInfos(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message":         fmt.Sprintf("Scraping completed with %d errors.", len(errs)),
 			"processed_count": processedCount,
+			"skipped_count":   skippedCount,
 			"error_count":     len(errs),
 		})
 		return
@@ -111,6 +180,7 @@ func (h *HackingHandler) ScrapeNewInfos(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"message":         "No new messages to process.",
 			"processed_count": 0,
+			"skipped_count":   skippedCount,
 			"error_count":     0,
 		})
 		return
@@ -119,6 +189,25 @@ func (h *HackingHandler) ScrapeNewInfos(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message":         fmt.Sprintf("Successfully processed %d new infos.", processedCount),
 		"processed_count": processedCount,
+		"skipped_count":   skippedCount,
 		"error_count":     0,
 	})
 }
+
+// クエリパラメータから攻撃手法を取得
+// 分類に含まれない値が指定された場合はエラーを返す
+func parseAttackVectors(c *gin.Context) ([]string, bool) {
+	attackVectorsQuery := c.Query("attackVectors")
+	if attackVectorsQuery == "" {
+		return nil, true
+	}
+
+	attackVectors := strings.Split(attackVectorsQuery, ",")
+	for _, attackVector := range attackVectors {
+		if !entity.IsAttackVector(attackVector) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid attackVector: %s", attackVector)})
+			return nil, false
+		}
+	}
+	return attackVectors, true
+}
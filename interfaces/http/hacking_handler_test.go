@@ -0,0 +1,59 @@
+package http
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+)
+
+func TestHackingInfoPostTextJSON(t *testing.T) {
+	info := &entity.HackingInfo{
+		ID:          1,
+		Protocol:    "Resupply",
+		PostText:    "Attack on Resupply.fi",
+		ReplyToText: "Earlier post",
+		Edits: []*entity.HackingInfoEdit{
+			{ID: 2, Protocol: "Resupply.fi", PostText: "Attack on Resupply"},
+		},
+	}
+
+	t.Run("post text is omitted by default", func(t *testing.T) {
+		data, err := json.Marshal(info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, text := range []string{"PostText", "ReplyToText", "Attack on Resupply"} {
+			if strings.Contains(string(data), text) {
+				t.Errorf("json = %s, want without %q", data, text)
+			}
+		}
+	})
+
+	t.Run("post text is included when requested", func(t *testing.T) {
+		data, err := json.Marshal(newHackingInfoWithPostTextResponse(info))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got struct {
+			ID          int64
+			PostText    string
+			ReplyToText string
+			Edits       []struct {
+				ID       int64
+				PostText string
+			}
+		}
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != 1 || got.PostText != "Attack on Resupply.fi" || got.ReplyToText != "Earlier post" {
+			t.Errorf("info = %+v, want post text of info 1", got)
+		}
+		if len(got.Edits) != 1 || got.Edits[0].ID != 2 || got.Edits[0].PostText != "Attack on Resupply" {
+			t.Errorf("edits = %+v, want post text of edit 2", got.Edits)
+		}
+	})
+}
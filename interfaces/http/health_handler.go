@@ -0,0 +1,40 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/itout-datetoya/hack-info-timeline/usecases"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	healthUsecase *usecases.HealthUsecase
+}
+
+func NewHealthHandler(healthUsecase *usecases.HealthUsecase) *HealthHandler {
+	return &HealthHandler{healthUsecase: healthUsecase}
+}
+
+// アカウント毎のTelegramクライアントの接続状態を返す
+// 接続・認証済みでないアカウントがある場合は、投稿の取得が低下・停止しているため 503 を返す
+func (h *HealthHandler) GetHealth(c *gin.Context) {
+	telegram := gin.H{}
+	for name, status := range h.healthUsecase.TelegramStatuses() {
+		account := gin.H{
+			"state": status.State,
+			"since": status.Since.Format(time.RFC3339),
+		}
+		if status.LastError != "" {
+			account["last_error"] = status.LastError
+		}
+		telegram[name] = account
+	}
+
+	if !h.healthUsecase.Healthy() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "degraded", "telegram": telegram})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "telegram": telegram})
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+	"github.com/itout-datetoya/hack-info-timeline/usecases"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LoginHandler struct {
+	telegramLoginUsecase *usecases.TelegramLoginUsecase
+}
+
+func NewLoginHandler(telegramLoginUsecase *usecases.TelegramLoginUsecase) *LoginHandler {
+	return &LoginHandler{telegramLoginUsecase: telegramLoginUsecase}
+}
+
+type startLoginRequest struct {
+	Account string `json:"account"`
+	Phone   string `json:"phone"`
+}
+
+type submitLoginRequest struct {
+	Account  string `json:"account"`
+	Code     string `json:"code"`
+	Password string `json:"password"`
+}
+
+// 認証コードを送信してTelegramへのログインを開始
+// アカウント名を省略した場合は先頭のアカウント、電話番号を省略した場合はアカウントの電話番号を使用
+func (h *LoginHandler) StartTelegramLogin(c *gin.Context) {
+	var req startLoginRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	if err := h.telegramLoginUsecase.StartLogin(c.Request.Context(), req.Account, req.Phone); err != nil {
+		respondLoginError(c, "Failed to start telegram login", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Sent auth code."})
+}
+
+// 認証コード、または2段階認証のパスワードを送信してサインイン
+func (h *LoginHandler) SubmitTelegramLogin(c *gin.Context) {
+	var req submitLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	passwordRequired, err := h.telegramLoginUsecase.Submit(c.Request.Context(), req.Account, req.Code, req.Password)
+	if err != nil {
+		respondLoginError(c, "Failed to submit telegram login", err)
+		return
+	}
+	if passwordRequired {
+		c.JSON(http.StatusOK, gin.H{"message": "2FA password is required.", "password_required": true})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Successfully logged in.", "password_required": false})
+}
+
+func respondLoginError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, gateway.ErrTelegramInvalidCredential):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid credential", "last_error": err.Error()})
+	case errors.Is(err, usecases.ErrTelegramAccountNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+	case errors.Is(err, gateway.ErrTelegramLoginUnavailable), errors.Is(err, gateway.ErrTelegramLoginNotStarted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		log.Printf("%s: %v", message, err)
+	}
+}
@@ -1,20 +1,57 @@
 package http
 
-import "github.com/gin-gonic/gin"
+import (
+	"expvar"
 
-func NewRouter(hackingHandler HackingHandler, transferHandler TransferHandler) *gin.Engine {
+	"github.com/gin-gonic/gin"
+)
+
+func NewRouter(hackingHandler HackingHandler, transferHandler TransferHandler, adminHandler AdminHandler, healthHandler HealthHandler, loginHandler LoginHandler, adminToken string) *gin.Engine {
 	router := gin.Default()
 	api := router.Group("/v1")
 	{
+		api.GET("/health", healthHandler.GetHealth)
+
 		api.GET("/hacking/latest-infos", hackingHandler.GetLatestTimeline)
 		api.GET("/hacking/prev-infos", hackingHandler.GetPrevTimeline)
+		api.GET("/hacking/infos/:id", hackingHandler.GetInfo)
 		api.GET("/hacking/tags", hackingHandler.GetAllTags)
 		api.POST("/hacking/scrape-new-infos", hackingHandler.ScrapeNewInfos)
 
 		api.GET("/transfer/latest-infos", transferHandler.GetLatestTimeline)
 		api.GET("/transfer/prev-infos", transferHandler.GetPrevTimeline)
+		api.GET("/transfer/infos/:id", transferHandler.GetInfo)
 		api.GET("/transfer/tags", transferHandler.GetAllTags)
 		api.POST("/transfer/scrape-new-infos", transferHandler.ScrapeNewInfos)
 	}
+
+	admin := api.Group("/admin", AdminAuth(adminToken))
+	{
+		admin.GET("/hacking/failed-posts", adminHandler.GetHackingFailedPosts)
+		admin.GET("/hacking/failed-posts/:id", adminHandler.GetHackingFailedPost)
+		admin.POST("/hacking/failed-posts/:id/replay", adminHandler.ReplayHackingFailedPost)
+		admin.POST("/hacking/failed-posts/replay", adminHandler.ReplayHackingFailedPosts)
+		admin.POST("/hacking/reanalyze", adminHandler.ReanalyzeHackingInfos)
+		admin.GET("/hacking/quarantined-posts", adminHandler.GetHackingQuarantinedPosts)
+		admin.POST("/hacking/quarantined-posts/:id/publish", adminHandler.PublishHackingQuarantinedPost)
+
+		admin.GET("/transfer/failed-posts", adminHandler.GetTransferFailedPosts)
+		admin.GET("/transfer/failed-posts/:id", adminHandler.GetTransferFailedPost)
+		admin.POST("/transfer/failed-posts/:id/replay", adminHandler.ReplayTransferFailedPost)
+		admin.POST("/transfer/failed-posts/replay", adminHandler.ReplayTransferFailedPosts)
+		admin.GET("/transfer/quarantined-posts", adminHandler.GetTransferQuarantinedPosts)
+		admin.POST("/transfer/quarantined-posts/:id/publish", adminHandler.PublishTransferQuarantinedPost)
+
+		admin.DELETE("/llm-cache", adminHandler.InvalidateAnalysisCache)
+
+		admin.GET("/backfills", adminHandler.GetBackfills)
+
+		// サーバーを再起動せずにTelegramへログイン
+		admin.POST("/telegram/login", loginHandler.StartTelegramLogin)
+		admin.POST("/telegram/login/submit", loginHandler.SubmitTelegramLogin)
+
+		// Telegram API の呼び出し回数・FLOOD_WAIT の待機状況などを expvar 形式で返す
+		admin.GET("/metrics", gin.WrapH(expvar.Handler()))
+	}
 	return router
 }
@@ -73,6 +73,27 @@ func (h *TransferHandler) GetPrevTimeline(c *gin.Context) {
 	c.JSON(http.StatusOK, infos)
 }
 
+// IDで指定した情報を、編集履歴と削除フラグを含めて返す
+func (h *TransferHandler) GetInfo(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id format"})
+		return
+	}
+
+	info, err := h.transferUsecase.GetInfo(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		log.Printf("Failed to get transfer info: %v", err)
+		return
+	}
+	if info == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Info not found"})
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
 func (h *TransferHandler) GetAllTags(c *gin.Context) {
 	tags, err := h.transferUsecase.GetAllTags(c.Request.Context())
 	if err != nil {
@@ -91,7 +112,7 @@ func (h *TransferHandler) ScrapeNewInfos(c *gin.Context) {
 		return
 	}
 
-	processedCount, errs := h.transferUsecase.ScrapeAndStore(c.Request.Context(), limit)
+	processedCount, skippedCount, errs := h.transferUsecase.ScrapeAndStore(c.Request.Context(), limit)
 
 	if len(errs) > 0 {
 		// エラーはサーバー側でログに記録
@@ -102,6 +123,7 @@ func (h *TransferHandler) ScrapeNewInfos(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message":         fmt.Sprintf("Scraping completed with %d errors.", len(errs)),
 			"processed_count": processedCount,
+			"skipped_count":   skippedCount,
 			"error_count":     len(errs),
 		})
 		return
@@ -111,6 +133,7 @@ func (h *TransferHandler) ScrapeNewInfos(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"message":         "No new messages to process.",
 			"processed_count": 0,
+			"skipped_count":   skippedCount,
 			"error_count":     0,
 		})
 		return
@@ -119,6 +142,7 @@ func (h *TransferHandler) ScrapeNewInfos(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message":         fmt.Sprintf("Successfully processed %d new infos.", processedCount),
 		"processed_count": processedCount,
+		"skipped_count":   skippedCount,
 		"error_count":     0,
 	})
 }
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	dm_gateway "github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+	"github.com/itout-datetoya/hack-info-timeline/infrastructure/gateway"
+	"github.com/itout-datetoya/hack-info-timeline/usecases"
+
+	"github.com/gotd/td/telegram"
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/term"
+)
+
+// 端末で認証コード・2段階認証のパスワードを入力してTelegramにログインするサブコマンド
+// セッションは .td/session.json（TELEGRAM_SESSION_KEY が設定されている場合はDB）に保存される
+// 使用例: ./main login -phone +819012345678
+func runLogin(args []string) {
+	flags := flag.NewFlagSet("login", flag.ExitOnError)
+	account := flags.String("account", telegramAccountNames()[0], "telegram account to log in")
+	phone := flags.String("phone", "", "phone number of the telegram account (default: TELEGRAM_PHONE_NUMBER_<ACCOUNT> or TELEGRAM_PHONE_NUMBER)")
+	flags.Parse(args)
+
+	// 設定の読み込み
+	telegramAppID, err := strconv.Atoi(os.Getenv("TELEGRAM_APP_ID"))
+	if err != nil {
+		log.Fatalf("Invalid TELEGRAM_APP_ID: %v", err)
+	}
+	telegramAppHash := os.Getenv("TELEGRAM_APP_HASH")
+	if telegramAppHash == "" {
+		log.Fatal("TELEGRAM_APP_HASH is not set.")
+	}
+
+	// TELEGRAM_SESSION_KEY が設定されている場合は、セッションをDBに保存
+	var sessionStorage telegram.SessionStorage
+	if os.Getenv("TELEGRAM_SESSION_KEY") != "" {
+		dbConnStr := os.Getenv("DATABASE_URL")
+		if dbConnStr == "" {
+			log.Fatal("DATABASE_URL is not set.")
+		}
+		if err := migrateDatabase(dbConnStr); err != nil {
+			log.Fatalf("%v", err)
+		}
+		db, err := sqlx.Connect("postgres", dbConnStr)
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		defer db.Close()
+
+		sessionStorage, err = telegramSessionStorageFromEnv(db, *account)
+		if err != nil {
+			log.Fatalf("Invalid TELEGRAM_SESSION_KEY: %v", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	telegramClientManager := gateway.NewTelegramClientManager(*account, telegramAppID, telegramAppHash, sessionStorage)
+	if err := telegramClientManager.Run(ctx); err != nil {
+		log.Fatalf("Failed to run Telegram client: %v", err)
+	}
+	defer telegramClientManager.Stop()
+
+	if telegramClientManager.Status().State == dm_gateway.ConnectionReady {
+		log.Println("Already logged in.")
+		return
+	}
+
+	loginUsecase := usecases.NewTelegramLoginUsecase([]usecases.TelegramLoginAccount{{
+		Name:  *account,
+		Login: telegramClientManager,
+		Phone: telegramPhoneNumber(*account),
+	}})
+	reader := bufio.NewReader(os.Stdin)
+
+	phoneNumber := *phone
+	if phoneNumber == "" && telegramPhoneNumber(*account) == "" {
+		phoneNumber = prompt(reader, "Phone number: ")
+	}
+	if err := loginUsecase.StartLogin(ctx, *account, phoneNumber); err != nil {
+		log.Fatalf("Failed to start login: %v", err)
+	}
+
+	passwordRequired, err := loginUsecase.Submit(ctx, *account, prompt(reader, "Auth code: "), "")
+	if err != nil {
+		log.Fatalf("Failed to sign in: %v", err)
+	}
+	if passwordRequired {
+		if _, err := loginUsecase.Submit(ctx, *account, "", promptPassword("2FA password: ")); err != nil {
+			log.Fatalf("Failed to sign in: %v", err)
+		}
+	}
+
+	// セッションが保存されるまで待機
+	select {
+	case <-telegramClientManager.Ready():
+		if sessionStorage != nil {
+			log.Println("Successfully logged in. The session is saved to the database.")
+		} else {
+			log.Println("Successfully logged in. The session is saved to the .td directory.")
+		}
+	case <-ctx.Done():
+		log.Fatal("Login interrupted.")
+	}
+}
+
+// 端末から1行を読み込み
+func prompt(reader *bufio.Reader, message string) string {
+	fmt.Print(message)
+	line, err := reader.ReadString('\n')
+	if err != nil && line == "" {
+		log.Fatalf("Failed to read input: %v", err)
+	}
+	return strings.TrimSpace(line)
+}
+
+// 端末に表示せずにパスワードを読み込み
+func promptPassword(message string) string {
+	fmt.Print(message)
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	// 入力後の改行は表示されないため出力
+	fmt.Println()
+	if err != nil {
+		log.Fatalf("Failed to read password: %v", err)
+	}
+	return strings.TrimSpace(string(password))
+}
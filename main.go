@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	dm_gateway "github.com/itout-datetoya/hack-info-timeline/domain/gateway"
 	"github.com/itout-datetoya/hack-info-timeline/infrastructure/datastore"
 	"github.com/itout-datetoya/hack-info-timeline/infrastructure/gateway"
@@ -18,6 +19,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -27,48 +29,58 @@ import (
 	"github.com/patrickmn/go-cache"
 )
 
+// 取りこぼしを補完するポーリングのデフォルトの間隔
+const defaultPollInterval = 30 * time.Minute
+
 func main() {
 	err := godotenv.Load()
 	if err != nil {
 		log.Println("Warning: .env file not found")
 	}
 
+	// サブコマンドの実行
+	if len(os.Args) > 1 && os.Args[1] == "reanalyze" {
+		runReanalyze(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "login" {
+		runLogin(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import-session" {
+		runImportSession(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import-prices" {
+		runImportPrices(os.Args[2:])
+		return
+	}
+
 	// 設定の読み込
 	dbConnStr := os.Getenv("DATABASE_URL")
 
 	jsonString := os.Getenv("SESSION_JSON")
 
-	geminiAPIKey := os.Getenv("GEMINI_API_KEY")
+	llmConfig, err := gateway.LLMConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid LLM configuration: %v", err)
+		return
+	}
+
+	adminAPIToken := os.Getenv("ADMIN_API_TOKEN")
 
-	telegramAppIDStr := os.Getenv("TELEGRAM_APP_ID")
-	telegramAppHash := os.Getenv("TELEGRAM_APP_HASH")
-	telegramPhoneNumber := os.Getenv("TELEGRAM_PHONE_NUMBER")
-	telegramHash := os.Getenv("TELEGRAM_AUTH_HASH")
-	teregramCode := os.Getenv("TELEGRAM_CODE")
 	telegramHackingChannels := strings.Split(os.Getenv("TELEGRAM_HACKING_CHANNEL_USERNAMES"), ",")
 	telegramTransferChannels := strings.Split(os.Getenv("TELEGRAM_TRANSFER_CHANNEL_USERNAMES"), ",")
 
-	if telegramAppIDStr == "" || telegramAppHash == "" || telegramHackingChannels[0] == "" ||
-		telegramTransferChannels[0] == "" || telegramPhoneNumber == "" ||
-		geminiAPIKey == "" ||
-		dbConnStr == "" ||
-		jsonString == "" {
+	if telegramHackingChannels[0] == "" ||
+		telegramTransferChannels[0] == "" ||
+		(llmConfig.Provider == gateway.LLMProviderGemini && llmConfig.APIKey == "") ||
+		dbConnStr == "" {
 		log.Fatal("User environment variables not fully set.")
 		return
 	}
-	telegramAppID, err := strconv.Atoi(telegramAppIDStr)
-	if err != nil {
-		log.Fatalf("Invalid TELEGRAM_APP_ID: %v", err)
-		return
-	}
-
-	m, err := migrate.New("file://migrations", dbConnStr)
-	if err != nil {
-		log.Fatalf("Failed to create migrate instance: %v", err)
-		return
-	}
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		log.Fatalf("Failed to run migrations: %v", err)
+	if err := migrateDatabase(dbConnStr); err != nil {
+		log.Fatalf("%v", err)
 		return
 	}
 
@@ -87,21 +99,29 @@ func main() {
 	dirPath := ".td"
 	filePath := filepath.Join(dirPath, "session.json")
 
-	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0755)
-	if err != nil {
-		if os.IsExist(err) {
-		} else {
-			log.Fatalf("Failed to open file %v", err)
-			return
-		}
-	} else {
-		_, err = file.WriteString(jsonString)
+	// SESSION_JSON は default アカウントのセッションとして使用
+	// 未設定の場合は、ログイン後に保存されたセッションを使用
+	// TELEGRAM_SESSION_KEY が設定されている場合は、セッションをDBに暗号化して保存
+	if os.Getenv("TELEGRAM_SESSION_KEY") != "" && jsonString != "" {
+		log.Println("Warning: SESSION_JSON is ignored because TELEGRAM_SESSION_KEY is set. Use the import-session subcommand to import it.")
+	} else if jsonString != "" {
+		os.MkdirAll(dirPath, 0755)
+		file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0755)
 		if err != nil {
-			log.Fatalf("Failed to write file %v", err)
-			return
+			if os.IsExist(err) {
+			} else {
+				log.Fatalf("Failed to open file %v", err)
+				return
+			}
+		} else {
+			_, err = file.WriteString(jsonString)
+			if err != nil {
+				log.Fatalf("Failed to write file %v", err)
+				return
+			}
 		}
+		file.Close()
 	}
-	file.Close()
 
 	// 依存性の注入 (DI)
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
@@ -110,122 +130,212 @@ func main() {
 	dbTransferRepo := datastore.NewDbTransferRepository(db)
 	hackingRepo := datastore.NewHackingRepository(dbHackingRepo, cache)
 	transferRepo := datastore.NewTransferRepository(dbTransferRepo, cache)
+	llmCacheRepo := datastore.NewDbLLMCacheRepository(db)
 
-	// Telegram Client Managerの初期化と接続
-	telegramClientManager := gateway.NewTelegramClientManager(
-		telegramAppID,
-		telegramAppHash,
-	)
+	// 投稿を取得するTelegramクライアント（アカウント毎の Client Manager、またはボット）の初期化
+	telegramSource, err := newTelegramSource(db)
+	if err != nil {
+		log.Fatalf("Invalid Telegram configuration: %v", err)
+		return
+	}
 
-	// Runメソッドを呼び出して接続を開始
-	if err := telegramClientManager.Run(ctx, telegramPhoneNumber, telegramHash, teregramCode); err != nil {
-		log.Fatalf("Failed to run Telegram Gateway: %v", err)
+	// チャンネル毎の投稿のパーサー（POST_PARSER_CONFIG で指定）
+	postParserConfig, err := gateway.PostParserConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load post parser config: %v", err)
+		return
+	}
+	postParsers, err := gateway.NewPostParserRegistry(postParserConfig)
+	if err != nil {
+		log.Fatalf("Invalid post parser config: %v", err)
 		return
 	}
-	log.Println("Telegram client connected and ready.")
 
 	// 各gatewayの初期化
 	var telegramHackingGateways []dm_gateway.TelegramHackingPostGateway
 	for _, channel := range telegramHackingChannels {
+		parser, err := postParsers.HackingParser(channel)
+		if err != nil {
+			log.Fatalf("Invalid post parser config: %v", err)
+			return
+		}
 		telegramHackingGateways = append(telegramHackingGateways,
-			gateway.NewTelegramHackingPostGateway(
-				telegramClientManager,
-				channel,
-			))
+			telegramSource.NewHackingPostGateway(channel, parser))
 	}
 
 	var telegramTransferGateways []dm_gateway.TelegramTransferPostGateway
 	for _, channel := range telegramTransferChannels {
+		parser, err := postParsers.TransferParser(channel)
+		if err != nil {
+			log.Fatalf("Invalid post parser config: %v", err)
+			return
+		}
 		telegramTransferGateways = append(telegramTransferGateways,
-			gateway.NewTelegramTransferPostGateway(
-				telegramClientManager,
-				channel,
-			))
+			telegramSource.NewTransferPostGateway(channel, parser))
 	}
 
-	geminiGateway, err := gateway.NewGeminiGateway(ctx, geminiAPIKey)
+	// Runメソッドを呼び出して接続を開始
+	// ボットは受信した更新を登録済みのチャンネルに振り分けるため、各gatewayの初期化後に開始
+	if err := telegramSource.Run(ctx); err != nil {
+		log.Fatalf("Failed to run Telegram Gateway: %v", err)
+		return
+	}
+
+	llmGateway, err := gateway.NewLLMGateway(ctx, llmConfig)
 	if err != nil {
-		log.Fatalf("Failed to initialize Gemini Gateway: %v", err)
+		log.Fatalf("Failed to initialize LLM Gateway: %v", err)
 		return
 	}
+	// 分析結果をDBにキャッシュ
+	llmGateway = gateway.NewCachedLLMGateway(llmGateway, llmCacheRepo)
 
 	// 各ハンドラーの初期化
-	hackingUsecase := usecases.NewHackingUsecase(hackingRepo, telegramHackingGateways, geminiGateway)
+	hackingUsecase := usecases.NewHackingUsecase(hackingRepo, telegramHackingGateways, llmGateway)
 	transferUsecase := usecases.NewTransferUsecase(transferRepo, telegramTransferGateways)
+	// 金額をUSD建てに変換する価格の取得元
+	priceSource, err := priceSourceFromEnv(db)
+	if err != nil {
+		log.Fatalf("Failed to load prices: %v", err)
+		return
+	}
+	hackingUsecase.SetPriceSource(priceSource)
+	transferUsecase.SetPriceSource(priceSource)
+	// パースに失敗した投稿をLLMで抽出するフォールバック
+	if enabled, _ := strconv.ParseBool(os.Getenv("LLM_FALLBACK_ENABLED")); enabled {
+		minConfidence := usecases.DefaultLLMFallbackMinConfidence
+		if minConfidenceStr := os.Getenv("LLM_FALLBACK_MIN_CONFIDENCE"); minConfidenceStr != "" {
+			minConfidence, err = strconv.ParseFloat(minConfidenceStr, 64)
+			if err != nil || minConfidence < 0 || minConfidence > 1 {
+				log.Fatalf("Invalid LLM_FALLBACK_MIN_CONFIDENCE: %s", minConfidenceStr)
+				return
+			}
+		}
+		hackingUsecase.EnableLLMFallback(llmGateway, minConfidence)
+		transferUsecase.EnableLLMFallback(llmGateway, minConfidence)
+	}
 	hackingHandler := if_http.NewHackingHandler(hackingUsecase)
 	transferHandler := if_http.NewTransferHandler(transferUsecase)
+	analysisCacheUsecase := usecases.NewAnalysisCacheUsecase(llmCacheRepo)
+	adminHandler := if_http.NewAdminHandler(hackingUsecase, transferUsecase, analysisCacheUsecase)
+	healthUsecase := usecases.NewHealthUsecase(telegramSource.Connections())
+	healthHandler := if_http.NewHealthHandler(healthUsecase)
+	telegramLoginUsecase := usecases.NewTelegramLoginUsecase(telegramSource.LoginAccounts())
+	loginHandler := if_http.NewLoginHandler(telegramLoginUsecase)
+
+	// 取りこぼしを補完するポーリングの間隔
+	pollInterval := defaultPollInterval
+	if pollIntervalStr := os.Getenv("POLL_INTERVAL"); pollIntervalStr != "" {
+		pollInterval, err = time.ParseDuration(pollIntervalStr)
+		if err != nil || pollInterval <= 0 {
+			log.Fatalf("Invalid POLL_INTERVAL: %s", pollIntervalStr)
+			return
+		}
+	}
+	ticker := time.NewTicker(pollInterval)
 
-	// 10分毎のTickerを作成
-	ticker := time.NewTicker(10 * time.Minute)
+	// 過去の投稿を遡って取得する設定
+	backfillOptions, backfillEnabled, err := backfillOptionsFromEnv()
+	if err != nil {
+		log.Fatalf("%v", err)
+		return
+	}
 
-	// 定期実行処理
-	go func() {
-		// サーバー起動時に一度即時実行
-		log.Println("Initial scraping process started...")
-		initialScrapeCtx, cancel := context.WithTimeout(ctx, 3*time.Minute)
+	// ポーリングで最後に取得した投稿以降を取得し、DBに保存
+	scrape := func(reason string, limit int) {
+		log.Printf("%s scraping process started...", reason)
 
-		err = hackingUsecase.SetLastMessageIDToGateway(initialScrapeCtx)
+		scrapeCtx, cancel := context.WithTimeout(ctx, 3*time.Minute)
+		defer cancel()
+
+		if _, _, errs := hackingUsecase.ScrapeAndStore(scrapeCtx, limit); len(errs) > 0 {
+			log.Printf("%s hacking info scraping finished with errors: %v", reason, errs)
+		} else {
+			log.Printf("%s hacking info scraping finished successfully.", reason)
+		}
+
+		if _, _, errs := transferUsecase.ScrapeAndStore(scrapeCtx, limit); len(errs) > 0 {
+			log.Printf("%s transfer info scraping finished with errors: %v", reason, errs)
+		} else {
+			log.Printf("%s transfer info scraping finished successfully.", reason)
+		}
+
+		// 通知を取りこぼした投稿の編集・削除を反映
+		hackingUsecase.SyncEditedPosts(scrapeCtx, usecases.DefaultEditSyncWindow)
+		transferUsecase.SyncEditedPosts(scrapeCtx, usecases.DefaultEditSyncWindow)
+
+		err := hackingUsecase.StoreLastMessageID(scrapeCtx)
 		if err != nil {
 			log.Printf("%v", err)
 		}
-		err = transferUsecase.SetLastMessageIDToGateway(initialScrapeCtx)
+		err = transferUsecase.StoreLastMessageID(scrapeCtx)
 		if err != nil {
 			log.Printf("%v", err)
 		}
 
-		if _, errs := hackingUsecase.ScrapeAndStore(initialScrapeCtx, 200); len(errs) > 0 {
-			log.Printf("Initial hacking info scraping finished with errors: %v", errs)
-		} else {
-			log.Println("Initial hacking info finished successfully.")
+		err = hackingUsecase.SetTagToCache(scrapeCtx)
+		if err != nil {
+			log.Printf("%v", err)
 		}
+	}
 
-		if _, errs := transferUsecase.ScrapeAndStore(initialScrapeCtx, 200); len(errs) > 0 {
-			log.Printf("Initial transfer info scraping finished with errors: %v", errs)
-		} else {
-			log.Println("Initial transfer info scraping finished successfully.")
+	// 新しい投稿は更新通知で即時に取得し、ポーリングは再接続後などの取りこぼしの補完に使用
+	go func() {
+		// ログインを待機している場合は、ログインの完了後に開始
+		select {
+		case <-telegramSource.Ready():
+		case <-ctx.Done():
+			return
 		}
 
-		err = hackingUsecase.SetTagToCache(initialScrapeCtx)
+		initialCtx, cancel := context.WithTimeout(ctx, 3*time.Minute)
+
+		err = hackingUsecase.SetLastMessageIDToGateway(initialCtx)
+		if err != nil {
+			log.Printf("%v", err)
+		}
+		err = transferUsecase.SetLastMessageIDToGateway(initialCtx)
 		if err != nil {
 			log.Printf("%v", err)
 		}
-
 		cancel()
 
-		// Tickerとシャットダウンシグナルを待機
+		// 初回のスクレイピング中の投稿も取りこぼさないよう、先に購読を開始
+		// 購読はサーバーの停止まで続けるため、初回の処理のタイムアウトは適用しない
+		if errs := hackingUsecase.SubscribeNewPosts(ctx); len(errs) > 0 {
+			log.Printf("Failed to subscribe to hacking channels, falling back to polling: %v", errs)
+		}
+		if errs := transferUsecase.SubscribeNewPosts(ctx); len(errs) > 0 {
+			log.Printf("Failed to subscribe to transfer channels, falling back to polling: %v", errs)
+		}
+		if errs := hackingUsecase.SubscribePostChanges(ctx); len(errs) > 0 {
+			log.Printf("Failed to subscribe to hacking post changes: %v", errs)
+		}
+		if errs := transferUsecase.SubscribePostChanges(ctx); len(errs) > 0 {
+			log.Printf("Failed to subscribe to transfer post changes: %v", errs)
+		}
+
+		// サーバー起動時に一度即時実行
+		scrape("Initial", 200)
+
+		// 新しい投稿の取得と並行して、過去の投稿を少しずつ遡って取得
+		if backfillEnabled {
+			go runBackfill(ctx, hackingUsecase, transferUsecase, backfillOptions)
+		}
+
+		// 初回の接続による通知は初回のスクレイピングで処理済み
+		select {
+		case <-telegramSource.GapFills():
+		default:
+		}
+
+		// 再接続の通知、Ticker、シャットダウンシグナルを待機
 		for {
 			select {
+			case <-telegramSource.GapFills():
+				scrape("Gap-filling", 100)
+
 			case <-ticker.C:
-				log.Println("Periodic scraping process started...")
-
-				scrapeCtx, cancel := context.WithTimeout(ctx, 3*time.Minute)
-				if _, errs := hackingUsecase.ScrapeAndStore(scrapeCtx, 100); len(errs) > 0 {
-					log.Printf("Periodic hacking info scraping finished with errors: %v", errs)
-				} else {
-					log.Println("Periodic hacking info scraping finished successfully.")
-				}
-
-				if _, errs := transferUsecase.ScrapeAndStore(scrapeCtx, 100); len(errs) > 0 {
-					log.Printf("Periodic transfer info scraping finished with errors: %v", errs)
-				} else {
-					log.Println("Periodic transfer info scraping finished successfully.")
-				}
-
-				err := hackingUsecase.StoreLastMessageID(scrapeCtx)
-				if err != nil {
-					log.Printf("%v", err)
-				}
-				err = transferUsecase.StoreLastMessageID(scrapeCtx)
-				if err != nil {
-					log.Printf("%v", err)
-				}
-
-				err = hackingUsecase.SetTagToCache(scrapeCtx)
-				if err != nil {
-					log.Printf("%v", err)
-				}
-
-				cancel()
+				scrape("Periodic", 100)
 
 			case <-ctx.Done():
 				ticker.Stop()
@@ -235,7 +345,10 @@ func main() {
 	}()
 
 	// ルーターとHTTPサーバーのセットアップ
-	router := if_http.NewRouter(*hackingHandler, *transferHandler)
+	router := if_http.NewRouter(*hackingHandler, *transferHandler, *adminHandler, *healthHandler, *loginHandler, adminAPIToken)
+	if webhookHandler := telegramSource.WebhookHandler(); webhookHandler != nil {
+		router.POST(telegramBotWebhookPath, gin.WrapH(webhookHandler))
+	}
 	srv := &http.Server{
 		Addr:    ":10000",
 		Handler: router,
@@ -262,14 +375,26 @@ func main() {
 	}
 
 	// Telegramクライアントを停止
-	if err := telegramClientManager.Stop(); err != nil {
+	if err := telegramSource.Stop(); err != nil {
 		log.Println("Failed to stop telegram client:", err)
 	}
 
-	// Geminiクライアントを停止
-	if err := geminiGateway.Stop(); err != nil {
-		log.Println("Failed to stop gemini client:", err)
+	// LLMクライアントを停止
+	if err := llmGateway.Stop(); err != nil {
+		log.Println("Failed to stop llm client:", err)
 	}
 
 	log.Println("Server exiting")
 }
+
+// マイグレーションを実行
+func migrateDatabase(dbConnStr string) error {
+	m, err := migrate.New("file://migrations", dbConnStr)
+	if err != nil {
+		return fmt.Errorf("failed to create migrate instance: %w", err)
+	}
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS hacking_infos_channel_message_key;
+DROP INDEX IF EXISTS transfer_infos_channel_message_key;
+
+ALTER TABLE hacking_infos DROP COLUMN IF EXISTS channel_username;
+ALTER TABLE transfer_infos DROP COLUMN IF EXISTS channel_username;
@@ -0,0 +1,11 @@
+ALTER TABLE hacking_infos ADD COLUMN channel_username VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE transfer_infos ADD COLUMN channel_username VARCHAR(255) NOT NULL DEFAULT '';
+
+-- チャンネル未記録の既存データは一意制約の対象外とする
+CREATE UNIQUE INDEX hacking_infos_channel_message_key
+    ON hacking_infos (channel_username, message_id)
+    WHERE channel_username <> '';
+
+CREATE UNIQUE INDEX transfer_infos_channel_message_key
+    ON transfer_infos (channel_username, message_id)
+    WHERE channel_username <> '';
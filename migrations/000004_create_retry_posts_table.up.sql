@@ -0,0 +1,16 @@
+CREATE TABLE retry_posts (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(32) NOT NULL,
+    channel_username VARCHAR(255) NOT NULL,
+    message_id INT NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (kind, channel_username, message_id)
+);
+
+CREATE INDEX retry_posts_due_idx ON retry_posts (kind, channel_username, status, next_attempt_at);
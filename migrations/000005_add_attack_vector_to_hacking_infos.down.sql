@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS hacking_infos_attack_vector_idx;
+
+ALTER TABLE hacking_infos DROP COLUMN IF EXISTS attack_vector;
@@ -0,0 +1,4 @@
+-- 分類前の既存データは空文字とする
+ALTER TABLE hacking_infos ADD COLUMN attack_vector VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE INDEX hacking_infos_attack_vector_idx ON hacking_infos (attack_vector);
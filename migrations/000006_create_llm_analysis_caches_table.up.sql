@@ -0,0 +1,8 @@
+CREATE TABLE llm_analysis_caches (
+    cache_key CHAR(64) PRIMARY KEY,
+    prompt_version VARCHAR(64) NOT NULL,
+    result JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX llm_analysis_caches_prompt_version_idx ON llm_analysis_caches (prompt_version);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS hacking_infos_prompt_version_idx;
+
+ALTER TABLE hacking_infos DROP COLUMN IF EXISTS prompt_version;
+ALTER TABLE hacking_infos DROP COLUMN IF EXISTS llm_provider;
+ALTER TABLE hacking_infos DROP COLUMN IF EXISTS llm_model;
@@ -0,0 +1,6 @@
+-- 記録前の既存データは空文字とする
+ALTER TABLE hacking_infos ADD COLUMN prompt_version VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE hacking_infos ADD COLUMN llm_provider VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE hacking_infos ADD COLUMN llm_model VARCHAR(128) NOT NULL DEFAULT '';
+
+CREATE INDEX hacking_infos_prompt_version_idx ON hacking_infos (prompt_version);
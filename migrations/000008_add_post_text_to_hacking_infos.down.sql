@@ -0,0 +1,2 @@
+ALTER TABLE hacking_infos DROP COLUMN IF EXISTS post_text;
+ALTER TABLE hacking_infos DROP COLUMN IF EXISTS reply_to_text;
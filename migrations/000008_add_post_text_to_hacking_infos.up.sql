@@ -0,0 +1,3 @@
+-- 記録前の既存データは空文字とし、再分析の対象外とする
+ALTER TABLE hacking_infos ADD COLUMN post_text TEXT NOT NULL DEFAULT '';
+ALTER TABLE hacking_infos ADD COLUMN reply_to_text TEXT NOT NULL DEFAULT '';
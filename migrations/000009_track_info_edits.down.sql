@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS transfer_info_edits;
+DROP TABLE IF EXISTS hacking_info_edits;
+
+ALTER TABLE transfer_infos DROP COLUMN IF EXISTS deleted;
+ALTER TABLE hacking_infos DROP COLUMN IF EXISTS deleted;
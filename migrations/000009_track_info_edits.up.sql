@@ -0,0 +1,31 @@
+-- チャンネルで削除された投稿の情報は削除せずに論理削除する
+ALTER TABLE hacking_infos ADD COLUMN deleted BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE transfer_infos ADD COLUMN deleted BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- 投稿の編集による変更前の情報
+CREATE TABLE hacking_info_edits (
+    id BIGSERIAL PRIMARY KEY,
+    info_id BIGINT NOT NULL REFERENCES hacking_infos(id) ON DELETE CASCADE,
+    protocol VARCHAR(255) NOT NULL,
+    network VARCHAR(255) NOT NULL,
+    amount VARCHAR(255) NOT NULL,
+    tx_hash VARCHAR(255) NOT NULL,
+    attack_vector VARCHAR(64) NOT NULL,
+    post_text TEXT NOT NULL,
+    reply_to_text TEXT NOT NULL,
+    edited_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX hacking_info_edits_info_id_idx ON hacking_info_edits (info_id);
+
+CREATE TABLE transfer_info_edits (
+    id BIGSERIAL PRIMARY KEY,
+    info_id BIGINT NOT NULL REFERENCES transfer_infos(id) ON DELETE CASCADE,
+    token VARCHAR(255) NOT NULL,
+    amount VARCHAR(255) NOT NULL,
+    from_address VARCHAR(255) NOT NULL,
+    to_address VARCHAR(255) NOT NULL,
+    edited_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX transfer_info_edits_info_id_idx ON transfer_info_edits (info_id);
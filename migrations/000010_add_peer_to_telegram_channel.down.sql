@@ -0,0 +1,3 @@
+ALTER TABLE telegram_channel DROP COLUMN IF EXISTS title;
+ALTER TABLE telegram_channel DROP COLUMN IF EXISTS access_hash;
+ALTER TABLE telegram_channel DROP COLUMN IF EXISTS channel_id;
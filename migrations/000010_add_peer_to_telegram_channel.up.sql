@@ -0,0 +1,4 @@
+-- 解決済みのチャンネル情報を保存し、再起動後もチャンネル名の解決を省略する
+ALTER TABLE telegram_channel ADD COLUMN channel_id BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE telegram_channel ADD COLUMN access_hash BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE telegram_channel ADD COLUMN title TEXT NOT NULL DEFAULT '';
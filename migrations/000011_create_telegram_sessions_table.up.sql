@@ -0,0 +1,6 @@
+-- 暗号化したTelegramのセッションを保存し、複数のレプリカで共有する
+CREATE TABLE telegram_sessions (
+    name VARCHAR(64) PRIMARY KEY,
+    data BYTEA NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,19 @@
+-- チャンネルの過去の投稿を遡って取得する処理の進捗
+-- 再起動後も続きから取得できるよう、チャンネル毎に取得位置を保存する
+CREATE TABLE channel_backfills (
+    kind VARCHAR(32) NOT NULL,
+    channel_username VARCHAR(255) NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'running',
+    cursor_message_id INT NOT NULL DEFAULT 0,
+    oldest_post_at TIMESTAMPTZ,
+    until_date TIMESTAMPTZ,
+    until_message_id INT NOT NULL DEFAULT 0,
+    processed INT NOT NULL DEFAULT 0,
+    skipped INT NOT NULL DEFAULT 0,
+    failed INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ,
+    PRIMARY KEY (kind, channel_username)
+);
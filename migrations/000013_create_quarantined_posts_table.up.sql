@@ -0,0 +1,17 @@
+-- パースに失敗した投稿
+-- 投稿の形式の変更を調査できるよう、投稿本文とエラーを保存する
+CREATE TABLE quarantined_posts (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(32) NOT NULL,
+    channel_username VARCHAR(255) NOT NULL,
+    message_id INT NOT NULL,
+    text TEXT NOT NULL DEFAULT '',
+    reply_to_text TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    report_time TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (kind, channel_username, message_id)
+);
+
+CREATE INDEX quarantined_posts_updated_at_idx ON quarantined_posts (kind, updated_at DESC);
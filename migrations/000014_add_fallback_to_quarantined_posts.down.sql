@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS quarantined_posts_status_idx;
+
+ALTER TABLE quarantined_posts
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS extracted,
+    DROP COLUMN IF EXISTS confidence;
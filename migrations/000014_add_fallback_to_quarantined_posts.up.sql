@@ -0,0 +1,8 @@
+-- LLMによる抽出結果
+-- 確信度が閾値未満の投稿は needs_review として確認を待つ
+ALTER TABLE quarantined_posts
+    ADD COLUMN status VARCHAR(32) NOT NULL DEFAULT 'quarantined',
+    ADD COLUMN extracted TEXT NOT NULL DEFAULT '',
+    ADD COLUMN confidence DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+CREATE INDEX quarantined_posts_status_idx ON quarantined_posts (kind, status, updated_at DESC);
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS token_prices;
+
+DROP INDEX IF EXISTS hacking_infos_amount_usd_idx;
+DROP INDEX IF EXISTS transfer_infos_amount_usd_idx;
+
+ALTER TABLE hacking_infos
+    DROP COLUMN IF EXISTS amount_value,
+    DROP COLUMN IF EXISTS amount_unit,
+    DROP COLUMN IF EXISTS amount_usd;
+ALTER TABLE transfer_infos
+    DROP COLUMN IF EXISTS amount_value,
+    DROP COLUMN IF EXISTS amount_unit,
+    DROP COLUMN IF EXISTS amount_usd;
@@ -0,0 +1,21 @@
+-- 数値に正規化した金額と単位（USD、またはトークンのシンボル）、報告時点のUSD建ての金額
+-- 元の文字列は amount に残す
+ALTER TABLE hacking_infos
+    ADD COLUMN amount_value NUMERIC,
+    ADD COLUMN amount_unit VARCHAR(32) NOT NULL DEFAULT '',
+    ADD COLUMN amount_usd NUMERIC;
+ALTER TABLE transfer_infos
+    ADD COLUMN amount_value NUMERIC,
+    ADD COLUMN amount_unit VARCHAR(32) NOT NULL DEFAULT '',
+    ADD COLUMN amount_usd NUMERIC;
+
+CREATE INDEX hacking_infos_amount_usd_idx ON hacking_infos (amount_usd);
+CREATE INDEX transfer_infos_amount_usd_idx ON transfer_infos (amount_usd);
+
+-- トークンの日毎のUSD建ての価格（CSVから読み込む）
+CREATE TABLE token_prices (
+    symbol VARCHAR(32) NOT NULL,
+    price_date DATE NOT NULL,
+    price_usd NUMERIC NOT NULL,
+    PRIMARY KEY (symbol, price_date)
+);
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	dm_gateway "github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+	"github.com/itout-datetoya/hack-info-timeline/infrastructure/datastore"
+	"github.com/itout-datetoya/hack-info-timeline/infrastructure/gateway"
+	"github.com/itout-datetoya/hack-info-timeline/usecases"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/patrickmn/go-cache"
+)
+
+// 金額をUSD建てに変換する価格の取得元を返す
+// PRICE_CSV が設定されている場合はCSVファイル、未設定の場合はDBの価格表（import-prices で読み込み）
+func priceSourceFromEnv(db *sqlx.DB) (dm_gateway.PriceSource, error) {
+	if path := os.Getenv("PRICE_CSV"); path != "" {
+		return gateway.NewCSVPriceSource(path)
+	}
+	return gateway.NewDBPriceSource(datastore.NewDbPriceRepository(db)), nil
+}
+
+// 価格表のCSVをDBに読み込み、保存済みの情報の金額をUSD建てに変換するサブコマンド
+// 使用例: ./main import-prices -file prices.csv
+func runImportPrices(args []string) {
+	flags := flag.NewFlagSet("import-prices", flag.ExitOnError)
+	filePath := flags.String("file", "", "price csv to import (symbol,date,price_usd)")
+	normalize := flags.Bool("normalize", true, "convert the amounts of stored infos without a USD amount")
+	batchSize := flags.Int("batch-size", 0, "number of infos to fetch per batch")
+	flags.Parse(args)
+
+	// 設定の読み込み
+	dbConnStr := os.Getenv("DATABASE_URL")
+	if dbConnStr == "" {
+		log.Fatal("DATABASE_URL is not set.")
+	}
+	if *filePath == "" {
+		log.Fatal("-file is required.")
+	}
+
+	file, err := os.Open(*filePath)
+	if err != nil {
+		log.Fatalf("Failed to open price csv: %v", err)
+	}
+	prices, err := gateway.ParsePriceCSV(file)
+	file.Close()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if err := migrateDatabase(dbConnStr); err != nil {
+		log.Fatalf("%v", err)
+	}
+	db, err := sqlx.Connect("postgres", dbConnStr)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	priceRepo := datastore.NewDbPriceRepository(db)
+	if err := priceRepo.StorePrices(ctx, prices); err != nil {
+		log.Fatalf("Failed to store prices: %v", err)
+	}
+	log.Printf("Successfully imported %d prices.", len(prices))
+
+	if !*normalize {
+		return
+	}
+
+	// 依存性の注入 (DI)
+	cache := cache.New(15*time.Minute, 20*time.Minute)
+	hackingUsecase := usecases.NewHackingUsecase(datastore.NewHackingRepository(datastore.NewDbHackingRepository(db), cache), nil, nil)
+	transferUsecase := usecases.NewTransferUsecase(datastore.NewTransferRepository(datastore.NewDbTransferRepository(db), cache), nil)
+	priceSource := gateway.NewDBPriceSource(priceRepo)
+	hackingUsecase.SetPriceSource(priceSource)
+	transferUsecase.SetPriceSource(priceSource)
+
+	if _, err := hackingUsecase.NormalizeAmounts(ctx, *batchSize); err != nil {
+		log.Fatalf("Failed to normalize hacking info amounts: %v", err)
+	}
+	if _, err := transferUsecase.NormalizeAmounts(ctx, *batchSize); err != nil {
+		log.Fatalf("Failed to normalize transfer info amounts: %v", err)
+	}
+}
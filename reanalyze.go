@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/itout-datetoya/hack-info-timeline/infrastructure/datastore"
+	"github.com/itout-datetoya/hack-info-timeline/infrastructure/gateway"
+	"github.com/itout-datetoya/hack-info-timeline/usecases"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/patrickmn/go-cache"
+)
+
+// 保存済みのハッキング情報を再分析するサブコマンド
+// 使用例: ./main reanalyze -prompt-version hacking-v1 -dry-run
+func runReanalyze(args []string) {
+	flags := flag.NewFlagSet("reanalyze", flag.ExitOnError)
+	promptVersion := flags.String("prompt-version", "", "reanalyze only infos analyzed with this prompt version (default: all)")
+	limit := flags.Int("limit", 0, "maximum number of infos to reanalyze (default: all)")
+	batchSize := flags.Int("batch-size", 0, "number of infos to fetch per batch")
+	dryRun := flags.Bool("dry-run", false, "report the diff without updating infos")
+	afterID := flags.Int64("after-id", 0, "reanalyze infos with an ID greater than this (resume from NextAfterID of a previous report)")
+	useCache := flags.Bool("use-cache", false, "read the analysis cache instead of calling the LLM for every info")
+	flags.Parse(args)
+
+	// 設定の読み込み
+	dbConnStr := os.Getenv("DATABASE_URL")
+	llmConfig, err := gateway.LLMConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid LLM configuration: %v", err)
+	}
+	if dbConnStr == "" {
+		log.Fatal("DATABASE_URL is not set.")
+	}
+
+	// データベース接続の初期化
+	db, err := sqlx.Connect("postgres", dbConnStr)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// 依存性の注入 (DI)
+	hackingRepo := datastore.NewHackingRepository(datastore.NewDbHackingRepository(db), cache.New(15*time.Minute, 20*time.Minute))
+	llmCacheRepo := datastore.NewDbLLMCacheRepository(db)
+
+	llmGateway, err := gateway.NewLLMGateway(ctx, llmConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize LLM Gateway: %v", err)
+	}
+	llmGateway = gateway.NewCachedLLMGateway(llmGateway, llmCacheRepo)
+	defer llmGateway.Stop()
+
+	hackingUsecase := usecases.NewHackingUsecase(hackingRepo, nil, llmGateway)
+
+	report, err := hackingUsecase.ReanalyzeInfos(ctx, usecases.ReanalysisOptions{
+		PromptVersion: *promptVersion,
+		BatchSize:     *batchSize,
+		Limit:         *limit,
+		DryRun:        *dryRun,
+		AfterID:       *afterID,
+		UseCache:      *useCache,
+	})
+
+	// 差分を標準出力に出力
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if encodeErr := encoder.Encode(report); encodeErr != nil {
+		log.Printf("Failed to write report: %v", encodeErr)
+	}
+
+	if err != nil {
+		log.Fatalf("Reanalysis aborted: %v", err)
+	}
+}
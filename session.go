@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/itout-datetoya/hack-info-timeline/infrastructure/datastore"
+	"github.com/itout-datetoya/hack-info-timeline/infrastructure/gateway"
+
+	"github.com/gotd/td/session"
+	"github.com/gotd/td/telegram"
+	"github.com/jmoiron/sqlx"
+)
+
+// TELEGRAM_SESSION_KEY が設定されていれば、アカウントのセッションをDBに暗号化して保存するセッションストレージを返す
+// 未設定の場合は nil を返す（.td 以下のファイルを使用）
+func telegramSessionStorageFromEnv(db *sqlx.DB, account string) (telegram.SessionStorage, error) {
+	encodedKey := os.Getenv("TELEGRAM_SESSION_KEY")
+	if encodedKey == "" {
+		return nil, nil
+	}
+	key, err := gateway.ParseTelegramSessionKey(encodedKey)
+	if err != nil {
+		return nil, err
+	}
+	return gateway.NewEncryptedSessionStorage(datastore.NewDbTelegramSessionRepository(db), account, key)
+}
+
+// 既存のセッションファイルをDBに暗号化して保存するサブコマンド
+// 使用例: ./main import-session -file .td/session.json
+func runImportSession(args []string) {
+	flags := flag.NewFlagSet("import-session", flag.ExitOnError)
+	account := flags.String("account", telegramAccountNames()[0], "telegram account to import the session for")
+	filePath := flags.String("file", ".td/session.json", "session file to import")
+	fromEnv := flags.Bool("from-env", false, "import SESSION_JSON instead of the session file")
+	force := flags.Bool("force", false, "overwrite the session stored in the database")
+	flags.Parse(args)
+
+	// 設定の読み込み
+	dbConnStr := os.Getenv("DATABASE_URL")
+	if dbConnStr == "" || os.Getenv("TELEGRAM_SESSION_KEY") == "" {
+		log.Fatal("DATABASE_URL and TELEGRAM_SESSION_KEY must be set.")
+	}
+
+	var data []byte
+	if *fromEnv {
+		data = []byte(os.Getenv("SESSION_JSON"))
+		if len(data) == 0 {
+			log.Fatal("SESSION_JSON is not set.")
+		}
+	} else {
+		var err error
+		data, err = os.ReadFile(*filePath)
+		if err != nil {
+			log.Fatalf("Failed to read session file: %v", err)
+		}
+	}
+
+	// 読み込めないセッションは保存しない
+	memory := &session.StorageMemory{}
+	if err := memory.StoreSession(context.Background(), data); err != nil {
+		log.Fatalf("Failed to read session: %v", err)
+	}
+	if _, err := (&session.Loader{Storage: memory}).Load(context.Background()); err != nil {
+		log.Fatalf("Invalid session: %v", err)
+	}
+
+	if err := migrateDatabase(dbConnStr); err != nil {
+		log.Fatalf("%v", err)
+	}
+	db, err := sqlx.Connect("postgres", dbConnStr)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	storage, err := telegramSessionStorageFromEnv(db, *account)
+	if err != nil {
+		log.Fatalf("Invalid TELEGRAM_SESSION_KEY: %v", err)
+	}
+
+	// 保存済みのセッションは -force を指定した場合のみ上書き
+	if !*force {
+		_, err := storage.LoadSession(ctx)
+		switch {
+		case err == nil:
+			log.Fatal("A session is already stored in the database. Use -force to overwrite it.")
+		case !errors.Is(err, session.ErrNotFound):
+			log.Fatalf("Failed to load the stored session: %v. Use -force to overwrite it.", err)
+		}
+	}
+
+	if err := storage.StoreSession(ctx, data); err != nil {
+		log.Fatalf("Failed to store session: %v", err)
+	}
+	log.Printf("Successfully imported the session of %s.", *account)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/itout-datetoya/hack-info-timeline/infrastructure/gateway"
+	"github.com/itout-datetoya/hack-info-timeline/usecases"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TELEGRAM_ACCOUNTS で指定したアカウント毎にクライアントを生成し、TelegramClientPool にまとめる
+func newTelegramClientPool(db *sqlx.DB, appID int, appHash string) (*gateway.TelegramClientPool, error) {
+	var managers []*gateway.TelegramClientManager
+	for _, name := range telegramAccountNames() {
+		sessionStorage, err := telegramSessionStorageFromEnv(db, name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TELEGRAM_SESSION_KEY: %w", err)
+		}
+		managers = append(managers, gateway.NewTelegramClientManager(name, appID, appHash, sessionStorage))
+	}
+
+	channelAccounts, err := telegramChannelAccounts()
+	if err != nil {
+		return nil, err
+	}
+	return gateway.NewTelegramClientPool(managers, channelAccounts)
+}
+
+// ログインを受け付けるアカウント
+func telegramLoginAccounts(pool *gateway.TelegramClientPool) []usecases.TelegramLoginAccount {
+	var accounts []usecases.TelegramLoginAccount
+	for _, m := range pool.Accounts() {
+		accounts = append(accounts, usecases.TelegramLoginAccount{
+			Name:  m.Name(),
+			Login: m,
+			Phone: telegramPhoneNumber(m.Name()),
+		})
+	}
+	return accounts
+}
+
+// TELEGRAM_ACCOUNTS のアカウント名
+// 省略時は default のアカウントのみ
+func telegramAccountNames() []string {
+	var names []string
+	for _, name := range strings.Split(os.Getenv("TELEGRAM_ACCOUNTS"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return []string{gateway.DefaultTelegramAccountName}
+	}
+	return names
+}
+
+// アカウントの電話番号（TELEGRAM_PHONE_NUMBER_<アカウント名>）
+// 先頭のアカウントは省略時 TELEGRAM_PHONE_NUMBER を使用
+func telegramPhoneNumber(name string) string {
+	envName := "TELEGRAM_PHONE_NUMBER_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+	if phone := os.Getenv(envName); phone != "" {
+		return phone
+	}
+	if name == telegramAccountNames()[0] {
+		return os.Getenv("TELEGRAM_PHONE_NUMBER")
+	}
+	return ""
+}
+
+// TELEGRAM_CHANNEL_ACCOUNTS で明示的に割り当てたチャンネルとアカウント
+// 形式: channel1:account1,channel2:account2
+func telegramChannelAccounts() (map[string]string, error) {
+	channelAccounts := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv("TELEGRAM_CHANNEL_ACCOUNTS"), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		channel, account, ok := strings.Cut(pair, ":")
+		if !ok || strings.TrimSpace(channel) == "" || strings.TrimSpace(account) == "" {
+			return nil, fmt.Errorf("invalid TELEGRAM_CHANNEL_ACCOUNTS: %s", pair)
+		}
+		channelAccounts[strings.TrimSpace(channel)] = strings.TrimSpace(account)
+	}
+	return channelAccounts, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	dm_gateway "github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+	"github.com/itout-datetoya/hack-info-timeline/infrastructure/gateway"
+	"github.com/itout-datetoya/hack-info-timeline/usecases"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 投稿の取得方法（TELEGRAM_INGESTION_MODE）
+const (
+	// ユーザーアカウントで MTProto を使用（デフォルト）
+	telegramIngestionMTProto = "mtproto"
+	// ボットで Bot API を使用
+	telegramIngestionBot = "bot"
+)
+
+// Webhook で Bot API の更新を受信するパス
+const telegramBotWebhookPath = "/v1/telegram/webhook"
+
+// 投稿の取得に使用するTelegramクライアント
+type telegramSource interface {
+	Run(ctx context.Context) error
+	Stop() error
+	// 初めて投稿を取得できる状態になった時点で閉じられる
+	Ready() <-chan struct{}
+	// 更新を取りこぼした可能性がある場合に通知
+	GapFills() <-chan struct{}
+	// アカウント名毎の接続状態
+	Connections() map[string]dm_gateway.TelegramConnection
+	// ログインを受け付けるアカウント
+	LoginAccounts() []usecases.TelegramLoginAccount
+	NewHackingPostGateway(channelUsername string, parse gateway.HackingPostParser) dm_gateway.TelegramHackingPostGateway
+	NewTransferPostGateway(channelUsername string, parse gateway.TransferPostParser) dm_gateway.TelegramTransferPostGateway
+	// Webhook で更新を受信するハンドラー（使用しない場合は nil）
+	WebhookHandler() http.Handler
+}
+
+// TELEGRAM_INGESTION_MODE で指定した方法のクライアントを生成
+func newTelegramSource(db *sqlx.DB) (telegramSource, error) {
+	switch mode := os.Getenv("TELEGRAM_INGESTION_MODE"); mode {
+	case "", telegramIngestionMTProto:
+		appIDStr := os.Getenv("TELEGRAM_APP_ID")
+		appHash := os.Getenv("TELEGRAM_APP_HASH")
+		if appIDStr == "" || appHash == "" {
+			return nil, fmt.Errorf("TELEGRAM_APP_ID and TELEGRAM_APP_HASH are required")
+		}
+		appID, err := strconv.Atoi(appIDStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TELEGRAM_APP_ID: %w", err)
+		}
+		pool, err := newTelegramClientPool(db, appID, appHash)
+		if err != nil {
+			return nil, err
+		}
+		return &mtprotoSource{pool}, nil
+
+	case telegramIngestionBot:
+		config, err := gateway.TelegramBotConfigFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		client, err := gateway.NewTelegramBotClient(config)
+		if err != nil {
+			return nil, err
+		}
+		return &botSource{TelegramBotClient: client, webhook: config.WebhookURL != ""}, nil
+
+	default:
+		return nil, fmt.Errorf("invalid TELEGRAM_INGESTION_MODE: %s", mode)
+	}
+}
+
+// ユーザーアカウントで MTProto を使用して取得
+type mtprotoSource struct {
+	*gateway.TelegramClientPool
+}
+
+// 接続を開始し、ログインが必要なアカウントを記録
+func (s *mtprotoSource) Run(ctx context.Context) error {
+	if err := s.TelegramClientPool.Run(ctx); err != nil {
+		return err
+	}
+	for _, m := range s.Accounts() {
+		if m.Status().State == dm_gateway.ConnectionReady {
+			log.Printf("Telegram account %s connected and ready.", m.Name())
+		} else {
+			log.Printf("Telegram account %s is not ready (%s). Log in with the login subcommand or POST /v1/admin/telegram/login.", m.Name(), m.Status().State)
+		}
+	}
+	return nil
+}
+
+func (s *mtprotoSource) LoginAccounts() []usecases.TelegramLoginAccount {
+	return telegramLoginAccounts(s.TelegramClientPool)
+}
+
+func (s *mtprotoSource) NewHackingPostGateway(channelUsername string, parse gateway.HackingPostParser) dm_gateway.TelegramHackingPostGateway {
+	return gateway.NewTelegramHackingPostGateway(s.TelegramClientPool, channelUsername, parse)
+}
+
+func (s *mtprotoSource) NewTransferPostGateway(channelUsername string, parse gateway.TransferPostParser) dm_gateway.TelegramTransferPostGateway {
+	return gateway.NewTelegramTransferPostGateway(s.TelegramClientPool, channelUsername, parse)
+}
+
+func (s *mtprotoSource) WebhookHandler() http.Handler {
+	return nil
+}
+
+// ボットで Bot API を使用して取得
+type botSource struct {
+	*gateway.TelegramBotClient
+	// Webhook で更新を受信するか
+	webhook bool
+}
+
+// 未取得の更新はTelegram側で保持されるため、取りこぼしは通知しない
+func (s *botSource) GapFills() <-chan struct{} {
+	return nil
+}
+
+func (s *botSource) Connections() map[string]dm_gateway.TelegramConnection {
+	return map[string]dm_gateway.TelegramConnection{gateway.TelegramBotAccountName: s.TelegramBotClient}
+}
+
+// ボットにはログインが不要
+func (s *botSource) LoginAccounts() []usecases.TelegramLoginAccount {
+	return nil
+}
+
+func (s *botSource) NewHackingPostGateway(channelUsername string, parse gateway.HackingPostParser) dm_gateway.TelegramHackingPostGateway {
+	return gateway.NewTelegramBotHackingPostGateway(s.TelegramBotClient, channelUsername, parse)
+}
+
+func (s *botSource) NewTransferPostGateway(channelUsername string, parse gateway.TransferPostParser) dm_gateway.TelegramTransferPostGateway {
+	return gateway.NewTelegramBotTransferPostGateway(s.TelegramBotClient, channelUsername, parse)
+}
+
+func (s *botSource) WebhookHandler() http.Handler {
+	// getUpdates で受信する場合は Webhook を受け付けない
+	if !s.webhook {
+		return nil
+	}
+	return s.TelegramBotClient.WebhookHandler()
+}
@@ -0,0 +1,197 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+// 金額の正規化時に1回で取得する情報のデフォルトの件数
+const defaultAmountNormalizationBatchSize = 100
+
+// 金額の文字列のパターン
+// 例: "$9,600,000"、"$1.2M"、"100 ETH"、"141271.0"、"2.5 million #USDC"
+var amountPattern = regexp.MustCompile(`^(?P<usd>(?i:US)?\$)?\s*(?P<number>\d[\d,]*(?:\.\d+)?)\s*(?P<multiplier>(?i:thousand|million|billion|mn|bn|k|m|b)\b)?\s*#?(?P<unit>[A-Za-z][A-Za-z0-9]*)?$`)
+
+// 金額の単位の倍率
+var amountMultipliers = map[string]int64{
+	"k": 1e3, "thousand": 1e3,
+	"m": 1e6, "mn": 1e6, "million": 1e6,
+	"b": 1e9, "bn": 1e9, "billion": 1e9,
+}
+
+// 正規化した金額を文字列に変換する際の小数点以下の最大桁数
+const amountDecimalScale = 18
+
+// USDを表す単位
+var usdUnits = map[string]bool{"USD": true, "DOLLAR": true, "DOLLARS": true}
+
+// 数値に正規化した金額
+// 精度を保つため、浮動小数点数ではなく有理数で計算
+type normalizedAmount struct {
+	Value *big.Rat
+	// 単位（USD、またはトークンのシンボル）。不明な場合は空
+	Unit string
+}
+
+// 金額の文字列を数値と単位に変換
+// 単位が含まれない場合は defaultUnit を単位とする
+func parseAmount(raw string, defaultUnit string) (normalizedAmount, bool) {
+	// 概算を表す記号と末尾の句点は取り除く
+	raw = strings.TrimSuffix(strings.TrimLeft(strings.TrimSpace(raw), "~≈ "), ".")
+
+	match := amountPattern.FindStringSubmatch(raw)
+	if match == nil {
+		return normalizedAmount{}, false
+	}
+	group := func(name string) string { return match[amountPattern.SubexpIndex(name)] }
+
+	value, ok := new(big.Rat).SetString(strings.ReplaceAll(group("number"), ",", ""))
+	if !ok {
+		return normalizedAmount{}, false
+	}
+	if multiplier := group("multiplier"); multiplier != "" {
+		value.Mul(value, new(big.Rat).SetInt64(amountMultipliers[strings.ToLower(multiplier)]))
+	}
+
+	unit := strings.ToUpper(group("unit"))
+	switch {
+	case group("usd") != "" || usdUnits[unit]:
+		unit = "USD"
+	case unit == "":
+		unit = strings.ToUpper(strings.TrimPrefix(defaultUnit, "#"))
+	}
+
+	return normalizedAmount{Value: value, Unit: unit}, true
+}
+
+// 金額を10進数の文字列に変換
+// DBの NUMERIC 型の列にそのまま保存できる形式（"1200000"、"0.5" など）とする
+func formatAmount(value *big.Rat) *string {
+	formatted := value.FloatString(amountDecimalScale)
+	formatted = strings.TrimRight(strings.TrimRight(formatted, "0"), ".")
+	return &formatted
+}
+
+// 金額を正規化し、報告時点のUSD建ての金額に変換
+// 正規化できない場合は value を nil、価格が見つからない場合は usd を nil とする
+func normalizeAmount(ctx context.Context, priceSource gateway.PriceSource, raw string, defaultUnit string, at time.Time) (value *string, unit string, usd *string) {
+	amount, ok := parseAmount(raw, defaultUnit)
+	if !ok {
+		return nil, "", nil
+	}
+
+	value = formatAmount(amount.Value)
+	switch {
+	case amount.Unit == "USD":
+		usd = value
+	case amount.Unit != "" && priceSource != nil:
+		price, err := priceSource.PriceUSD(ctx, amount.Unit, at)
+		if err != nil {
+			if !errors.Is(err, gateway.ErrPriceNotFound) {
+				log.Printf("Failed to get price of %s at %s: %v", amount.Unit, at.Format(time.DateOnly), err)
+			}
+			break
+		}
+		usd = formatAmount(new(big.Rat).Mul(amount.Value, price))
+	}
+
+	return value, amount.Unit, usd
+}
+
+// 金額の正規化に必要な操作
+type amountRepository[T any] interface {
+	GetInfosWithoutAmountUSD(ctx context.Context, afterID int64, limit int) ([]*T, error)
+	UpdateInfoAmount(ctx context.Context, info *T) error
+}
+
+// USD建ての金額が未設定の情報を batchSize 件ずつ取得し、normalize で正規化して更新
+// normalize が false を返した（正規化できない）情報は更新しない。更新した件数を返す
+func normalizeAmounts[T any](ctx context.Context, repo amountRepository[T], batchSize int, infoID func(info *T) int64, normalize func(ctx context.Context, info *T) bool) (int, error) {
+	if batchSize <= 0 {
+		batchSize = defaultAmountNormalizationBatchSize
+	}
+
+	updatedCount := 0
+	var afterID int64
+	for {
+		infos, err := repo.GetInfosWithoutAmountUSD(ctx, afterID, batchSize)
+		if err != nil {
+			return updatedCount, fmt.Errorf("failed to get infos without amount usd: %w", err)
+		}
+		for _, info := range infos {
+			afterID = infoID(info)
+			if !normalize(ctx, info) {
+				continue
+			}
+			if err := repo.UpdateInfoAmount(ctx, info); err != nil {
+				return updatedCount, fmt.Errorf("failed to update amount of info %d: %w", afterID, err)
+			}
+			updatedCount++
+		}
+		if len(infos) < batchSize {
+			break
+		}
+	}
+
+	return updatedCount, nil
+}
+
+// USD建ての価格の取得元を設定
+// 設定しない場合、USD以外の単位の金額はUSD建てに変換しない
+func (uc *HackingUsecase) SetPriceSource(priceSource gateway.PriceSource) {
+	uc.priceSource = priceSource
+}
+
+// ハッキング情報の金額を正規化して設定
+// 正規化できた場合は true を返す
+func (uc *HackingUsecase) setNormalizedAmount(ctx context.Context, info *entity.HackingInfo) bool {
+	info.AmountValue, info.AmountUnit, info.AmountUSD = normalizeAmount(ctx, uc.priceSource, info.Amount, "", info.ReportTime)
+	return info.AmountValue != nil
+}
+
+// USD建ての金額が未設定のハッキング情報の金額を正規化して更新
+// 価格表を読み込んだ後に、保存済みの情報をUSD建てに変換するために使用。更新した件数を返す
+func (uc *HackingUsecase) NormalizeAmounts(ctx context.Context, batchSize int) (int, error) {
+	updatedCount, err := normalizeAmounts(ctx, uc.repo, batchSize, func(info *entity.HackingInfo) int64 { return info.ID }, uc.setNormalizedAmount)
+	if err != nil {
+		return updatedCount, err
+	}
+
+	log.Printf("Hacking Post: Amount normalization finished. Updated: %d", updatedCount)
+	return updatedCount, nil
+}
+
+// USD建ての価格の取得元を設定
+// 設定しない場合、USD以外の単位の金額はUSD建てに変換しない
+func (uc *TransferUsecase) SetPriceSource(priceSource gateway.PriceSource) {
+	uc.priceSource = priceSource
+}
+
+// 送金情報の金額を正規化して設定
+// 送金情報の金額は数値のみのため、トークンを単位とする
+// 正規化できた場合は true を返す
+func (uc *TransferUsecase) setNormalizedAmount(ctx context.Context, info *entity.TransferInfo) bool {
+	info.AmountValue, info.AmountUnit, info.AmountUSD = normalizeAmount(ctx, uc.priceSource, info.Amount, info.Token, info.ReportTime)
+	return info.AmountValue != nil
+}
+
+// USD建ての金額が未設定の送金情報の金額を正規化して更新
+// 価格表を読み込んだ後に、保存済みの情報をUSD建てに変換するために使用。更新した件数を返す
+func (uc *TransferUsecase) NormalizeAmounts(ctx context.Context, batchSize int) (int, error) {
+	updatedCount, err := normalizeAmounts(ctx, uc.repo, batchSize, func(info *entity.TransferInfo) int64 { return info.ID }, uc.setNormalizedAmount)
+	if err != nil {
+		return updatedCount, err
+	}
+
+	log.Printf("Transfer Post: Amount normalization finished. Updated: %d", updatedCount)
+	return updatedCount, nil
+}
@@ -0,0 +1,162 @@
+package usecases
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+// 固定の価格を返す PriceSource
+type stubPriceSource map[string]string
+
+func (s stubPriceSource) PriceUSD(ctx context.Context, symbol string, at time.Time) (*big.Rat, error) {
+	price, ok := s[symbol]
+	if !ok {
+		return nil, gateway.ErrPriceNotFound
+	}
+	priceUSD, _ := new(big.Rat).SetString(price)
+	return priceUSD, nil
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		raw         string
+		defaultUnit string
+		wantValue   string
+		wantUnit    string
+		wantOK      bool
+	}{
+		{raw: "$9,600,000", wantValue: "9600000", wantUnit: "USD", wantOK: true},
+		{raw: "$1.2M", wantValue: "1200000", wantUnit: "USD", wantOK: true},
+		{raw: "~$11m", wantValue: "11000000", wantUnit: "USD", wantOK: true},
+		{raw: "US$ 3.5 billion", wantValue: "3500000000", wantUnit: "USD", wantOK: true},
+		{raw: "500K USD", wantValue: "500000", wantUnit: "USD", wantOK: true},
+		{raw: "100 ETH", wantValue: "100", wantUnit: "ETH", wantOK: true},
+		{raw: "2.5 million #USDC", wantValue: "2500000", wantUnit: "USDC", wantOK: true},
+		// 倍率と同じ文字から始まるトークン
+		{raw: "1,000 MATIC", wantValue: "1000", wantUnit: "MATIC", wantOK: true},
+		{raw: "5 BNB", wantValue: "5", wantUnit: "BNB", wantOK: true},
+		{raw: "141271.0", defaultUnit: "USDT", wantValue: "141271", wantUnit: "USDT", wantOK: true},
+		{raw: "1500000", wantValue: "1500000", wantOK: true},
+		// 浮動小数点数では丸められる桁数の金額
+		{raw: "123,456,789.123456789123 ETH", wantValue: "123456789.123456789123", wantUnit: "ETH", wantOK: true},
+		{raw: "$0.1M", wantValue: "100000", wantUnit: "USD", wantOK: true},
+		{raw: "", wantOK: false},
+		{raw: "unknown", wantOK: false},
+		{raw: "$1.2M and 300 ETH", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, ok := parseAmount(tt.raw, tt.defaultUnit)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (*formatAmount(got.Value) != tt.wantValue || got.Unit != tt.wantUnit) {
+				t.Errorf("amount = %+v, want %v %s", got, tt.wantValue, tt.wantUnit)
+			}
+		})
+	}
+}
+
+func TestNormalizeAmount(t *testing.T) {
+	prices := stubPriceSource{"ETH": "2000", "USDT": "1.0001"}
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	value, unit, usd := normalizeAmount(context.Background(), prices, "100 ETH", "", at)
+	if value == nil || *value != "100" || unit != "ETH" || usd == nil || *usd != "200000" {
+		t.Errorf("normalized = %v %s %v, want 100 ETH 200000", value, unit, usd)
+	}
+
+	// 金額と価格の積を丸めずに変換
+	value, unit, usd = normalizeAmount(context.Background(), prices, "12345678901234567.89", "USDT", at)
+	if value == nil || *value != "12345678901234567.89" || unit != "USDT" || usd == nil || *usd != "12346913469124691.346789" {
+		t.Errorf("normalized = %v %s %v, want 12345678901234567.89 USDT 12346913469124691.346789", value, unit, usd)
+	}
+
+	// 価格が不明なトークンはUSD建てに変換しない
+	value, unit, usd = normalizeAmount(context.Background(), prices, "100", "UNKNOWN", at)
+	if value == nil || *value != "100" || unit != "UNKNOWN" || usd != nil {
+		t.Errorf("normalized = %v %s %v, want 100 UNKNOWN nil", value, unit, usd)
+	}
+
+	// 価格の取得元がなくてもUSD建ての金額は変換
+	value, unit, usd = normalizeAmount(context.Background(), nil, "$1.2M", "", at)
+	if value == nil || *value != "1200000" || unit != "USD" || usd == nil || *usd != "1200000" {
+		t.Errorf("normalized = %v %s %v, want 1200000 USD 1200000", value, unit, usd)
+	}
+
+	value, unit, usd = normalizeAmount(context.Background(), prices, "unknown", "", at)
+	if value != nil || unit != "" || usd != nil {
+		t.Errorf("normalized = %v %s %v, want nil", value, unit, usd)
+	}
+}
+
+func TestTransferProcessSinglePost_NormalizesAmount(t *testing.T) {
+	var stored *entity.TransferInfo
+	mockRepo := &mockTransferRepository{
+		storeInfoFunc: func(ctx context.Context, info *entity.TransferInfo, tagNames []string) (int64, error) {
+			stored = info
+			return 1, nil
+		},
+	}
+	uc := NewTransferUsecase(mockRepo, nil)
+	uc.SetPriceSource(stubPriceSource{"ETH": "2000"})
+
+	if err := uc.processSinglePost(context.Background(), createTestTransferPost(1, "ETH", "3250")); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Amount != "3250" || stored.AmountValue == nil || *stored.AmountValue != "3250" ||
+		stored.AmountUnit != "ETH" || stored.AmountUSD == nil || *stored.AmountUSD != "6500000" {
+		t.Errorf("stored amount = %s, %v %s, usd %v", stored.Amount, stored.AmountValue, stored.AmountUnit, stored.AmountUSD)
+	}
+}
+
+func TestHackingNormalizeAmounts(t *testing.T) {
+	stored := []*entity.HackingInfo{
+		{ID: 1, Amount: "$9,600,000"},
+		{ID: 2, Amount: "100 ETH"},
+		{ID: 3, Amount: "unknown"},
+	}
+	var afterIDs []int64
+	updated := map[int64]*entity.HackingInfo{}
+	mockRepo := &mockHackingRepository{
+		getInfosWithoutAmountUSDFunc: func(ctx context.Context, afterID int64, limit int) ([]*entity.HackingInfo, error) {
+			afterIDs = append(afterIDs, afterID)
+			var infos []*entity.HackingInfo
+			for _, info := range stored {
+				if info.ID > afterID && len(infos) < limit {
+					infos = append(infos, info)
+				}
+			}
+			return infos, nil
+		},
+		updateInfoAmountFunc: func(ctx context.Context, info *entity.HackingInfo) error {
+			updated[info.ID] = info
+			return nil
+		},
+	}
+	uc := NewHackingUsecase(mockRepo, nil, &mockLLMGateway{})
+	uc.SetPriceSource(stubPriceSource{"ETH": "2000"})
+
+	updatedCount, err := uc.NormalizeAmounts(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updatedCount != 2 || len(updated) != 2 {
+		t.Errorf("updated = %d, want 2", updatedCount)
+	}
+	if info := updated[2]; info == nil || info.AmountUSD == nil || *info.AmountUSD != "200000" {
+		t.Errorf("updated info 2 = %+v", info)
+	}
+	if _, ok := updated[3]; ok {
+		t.Error("info with unparsable amount should not be updated")
+	}
+	if len(afterIDs) != 2 || afterIDs[1] != 2 {
+		t.Errorf("after IDs = %v, want [0 2]", afterIDs)
+	}
+}
@@ -0,0 +1,28 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/repository"
+)
+
+// LLMによる分析結果のキャッシュに関するユースケース
+type AnalysisCacheUsecase struct {
+	repo repository.LLMCacheRepository
+}
+
+// 新しいAnalysisCacheUsecaseを生成
+func NewAnalysisCacheUsecase(repo repository.LLMCacheRepository) *AnalysisCacheUsecase {
+	return &AnalysisCacheUsecase{repo: repo}
+}
+
+// 指定したプロンプトのバージョンのキャッシュを無効化し、無効化した件数を返す
+// promptVersion が空の場合は全てのキャッシュを無効化
+func (uc *AnalysisCacheUsecase) InvalidateAnalysisCache(ctx context.Context, promptVersion string) (int64, error) {
+	deleted, err := uc.repo.DeleteAnalysisCaches(ctx, promptVersion)
+	if err != nil {
+		return 0, fmt.Errorf("failed to invalidate analysis cache: %w", err)
+	}
+	return deleted, nil
+}
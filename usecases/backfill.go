@@ -0,0 +1,259 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+// バックフィルで1回に遡って取得する投稿のデフォルトの件数
+const defaultBackfillBatchSize = 100
+
+// バックフィルで1回取得するごとに待機するデフォルトの時間
+// 新しい投稿の取得を優先するため、API の呼び出しを間引く
+const defaultBackfillInterval = 10 * time.Second
+
+// バックフィルの設定
+type BackfillOptions struct {
+	// この日時より前の投稿は取得しない。ゼロ値の場合は最初の投稿まで遡る
+	Until time.Time
+	// チャンネル名毎の、取得しない最も新しいメッセージID（このID以前は取得しない）
+	UntilMessageIDs map[string]int
+	// 1回で遡って取得する投稿の件数
+	BatchSize int
+	// 1回取得するごとに待機する時間
+	Interval time.Duration
+}
+
+// バックフィルの進捗の永続化に必要な操作
+type backfillRepository interface {
+	GetBackfill(ctx context.Context, channelUsername string) (*entity.ChannelBackfill, error)
+	StoreBackfill(ctx context.Context, backfill *entity.ChannelBackfill) error
+	GetBackfills(ctx context.Context) ([]*entity.ChannelBackfill, error)
+}
+
+// 過去の投稿を遡って取得できるチャンネルのゲートウェイ
+type historyGateway[T any] interface {
+	ChannelUsername() string
+	GetPostsBefore(ctx context.Context, maxID int, limit int) ([]*T, *gateway.HistoryPage, error)
+}
+
+// ハッキング情報・送金情報で共通の、過去の投稿の取得（バックフィル）
+type backfiller[T any, G historyGateway[T]] struct {
+	// ログに出力する投稿の種類
+	name     string
+	repo     backfillRepository
+	gateways []G
+	// 投稿のメッセージIDと報告日時
+	position func(post *T) (int, time.Time)
+	// 投稿を処理し、失敗した場合はリトライキューに追加
+	process func(ctx context.Context, post *T, result *processResult)
+	// 1件以上処理した場合に実行する後処理
+	refresh func(ctx context.Context) error
+}
+
+// 各チャンネルの過去の投稿を遡って取得し、DBに保存
+// 中断した場合は保存済みの進捗から再開する
+// 処理件数、重複によるスキップ件数、エラーを返す
+func (b *backfiller[T, G]) Backfill(ctx context.Context, opts BackfillOptions) (int, int, []error) {
+	processed, skipped, errs := b.run(ctx, opts)
+	log.Printf("%s: Backfill finished. Processed: %d, Skipped: %d, Errors: %d", b.name, processed, skipped, len(errs))
+
+	if processed > 0 {
+		if err := b.refresh(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return processed, skipped, errs
+}
+
+// 全てのチャンネルのバックフィルの進捗を取得
+func (b *backfiller[T, G]) GetBackfillProgress(ctx context.Context) ([]*entity.ChannelBackfill, error) {
+	return b.repo.GetBackfills(ctx)
+}
+
+// maxID より前の投稿を取得し、backfill の範囲内の投稿を処理して取得した範囲を返す
+func (b *backfiller[T, G]) step(ctx context.Context, gw G, backfill *entity.ChannelBackfill, maxID int, limit int, result *processResult) (*gateway.HistoryPage, error) {
+	posts, page, err := gw.GetPostsBefore(ctx, maxID, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, post := range posts {
+		messageID, reportTime := b.position(post)
+		if backfillIncludes(backfill, messageID, reportTime) {
+			b.process(ctx, post, result)
+		}
+	}
+	return page, nil
+}
+
+// 全てのチャンネルが完了するまで、チャンネルを順に1回ずつ遡って取得
+// 一時的なエラーが発生したチャンネルは、次の順番で同じ位置から再試行
+func (b *backfiller[T, G]) run(ctx context.Context, opts BackfillOptions) (int, int, []error) {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBackfillBatchSize
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultBackfillInterval
+	}
+
+	var allProcessed, allSkipped int
+	var allErrors []error
+
+	// 保存済みの進捗を読み込み、完了していないチャンネルを対象とする
+	type activeChannel struct {
+		gw       G
+		backfill *entity.ChannelBackfill
+	}
+	var active []*activeChannel
+	for _, gw := range b.gateways {
+		backfill, err := loadBackfill(ctx, b.repo, gw.ChannelUsername(), opts)
+		if err != nil {
+			allErrors = append(allErrors, err)
+			continue
+		}
+		if backfill.Status == entity.BackfillStatusCompleted {
+			continue
+		}
+		active = append(active, &activeChannel{gw: gw, backfill: backfill})
+	}
+
+	for len(active) > 0 {
+		var next []*activeChannel
+		for _, ch := range active {
+			backfill := ch.backfill
+
+			var result processResult
+			page, err := b.step(ctx, ch.gw, backfill, backfill.CursorMessageID, batchSize, &result)
+			switch {
+			case errors.Is(err, gateway.ErrHistoryUnavailable):
+				// 過去の投稿を取得できないチャンネルは再試行しない
+				backfill.Status = entity.BackfillStatusFailed
+				backfill.LastError = err.Error()
+				log.Printf("Backfill of %s stopped: %v", backfill.ChannelUsername, err)
+			case err != nil:
+				backfill.LastError = err.Error()
+				allErrors = append(allErrors, fmt.Errorf("failed to backfill %s: %w", backfill.ChannelUsername, err))
+			default:
+				advanceBackfill(backfill, page, &result)
+				allProcessed += result.processed
+				allSkipped += result.skipped
+				allErrors = append(allErrors, result.errs...)
+				log.Printf("Backfill of %s: cursor %d, processed %d, skipped %d, failed %d",
+					backfill.ChannelUsername, backfill.CursorMessageID, backfill.Processed, backfill.Skipped, backfill.Failed)
+			}
+
+			if err := b.repo.StoreBackfill(ctx, backfill); err != nil {
+				allErrors = append(allErrors, fmt.Errorf("failed to store backfill of %s: %w", backfill.ChannelUsername, err))
+			}
+			if backfill.Status == entity.BackfillStatusRunning {
+				next = append(next, ch)
+			}
+		}
+		active = next
+
+		if len(active) == 0 || ctx.Err() != nil {
+			break
+		}
+		if err := sleepContext(ctx, interval); err != nil {
+			break
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		allErrors = append(allErrors, fmt.Errorf("backfill interrupted: %w", err))
+	}
+	return allProcessed, allSkipped, allErrors
+}
+
+// 保存済みの進捗を取得し、存在しない場合は新たに開始
+// 完了済みでも、遡る範囲が広げられた場合は続きから再開
+func loadBackfill(ctx context.Context, repo backfillRepository, channelUsername string, opts BackfillOptions) (*entity.ChannelBackfill, error) {
+	backfill, err := repo.GetBackfill(ctx, channelUsername)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backfill of %s: %w", channelUsername, err)
+	}
+
+	var until *time.Time
+	if !opts.Until.IsZero() {
+		until = &opts.Until
+	}
+	untilMessageID := opts.UntilMessageIDs[channelUsername]
+
+	if backfill == nil {
+		return &entity.ChannelBackfill{
+			ChannelUsername: channelUsername,
+			Status:          entity.BackfillStatusRunning,
+			UntilDate:       until,
+			UntilMessageID:  untilMessageID,
+		}, nil
+	}
+
+	if backfill.Status == entity.BackfillStatusCompleted && !backfillExtended(backfill, until, untilMessageID) {
+		return backfill, nil
+	}
+	backfill.Status = entity.BackfillStatusRunning
+	backfill.UntilDate = until
+	backfill.UntilMessageID = untilMessageID
+	backfill.CompletedAt = nil
+	return backfill, nil
+}
+
+// 保存済みの範囲より古い投稿まで遡るよう指定されたか
+func backfillExtended(backfill *entity.ChannelBackfill, until *time.Time, untilMessageID int) bool {
+	if backfill.UntilDate != nil && (until == nil || until.Before(*backfill.UntilDate)) {
+		return true
+	}
+	return backfill.UntilMessageID > untilMessageID
+}
+
+// 投稿がバックフィルの範囲内か
+func backfillIncludes(backfill *entity.ChannelBackfill, messageID int, reportTime time.Time) bool {
+	if messageID <= backfill.UntilMessageID {
+		return false
+	}
+	return backfill.UntilDate == nil || !reportTime.Before(*backfill.UntilDate)
+}
+
+// 取得した範囲まで進捗を進め、範囲の下限に達した場合は完了とする
+func advanceBackfill(backfill *entity.ChannelBackfill, page *gateway.HistoryPage, result *processResult) {
+	backfill.Processed += result.processed
+	backfill.Skipped += result.skipped
+	backfill.Failed += len(result.errs)
+	backfill.LastError = ""
+
+	if page.OldestMessageID != 0 {
+		backfill.CursorMessageID = page.OldestMessageID
+		if !page.OldestDate.IsZero() {
+			oldest := page.OldestDate
+			backfill.OldestPostAt = &oldest
+		}
+	}
+
+	reachedMessageID := backfill.UntilMessageID > 0 && backfill.CursorMessageID <= backfill.UntilMessageID
+	reachedDate := backfill.UntilDate != nil && backfill.OldestPostAt != nil && backfill.OldestPostAt.Before(*backfill.UntilDate)
+	// 最初の投稿まで遡った場合は、それより古い投稿は返されない
+	if page.OldestMessageID == 0 || reachedMessageID || reachedDate {
+		now := time.Now()
+		backfill.Status = entity.BackfillStatusCompleted
+		backfill.CompletedAt = &now
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
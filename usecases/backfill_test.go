@@ -0,0 +1,104 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+func TestTransferBackfill(t *testing.T) {
+	stored := map[string]*entity.ChannelBackfill{
+		// 中断したバックフィルは保存済みの位置から再開
+		"channel1": {ChannelUsername: "channel1", Status: entity.BackfillStatusRunning, CursorMessageID: 300, Processed: 5},
+	}
+	var storedMessageIDs []int
+	mockRepo := &mockTransferRepository{
+		getBackfillFunc: func(ctx context.Context, channelUsername string) (*entity.ChannelBackfill, error) {
+			if backfill, ok := stored[channelUsername]; ok {
+				copied := *backfill
+				return &copied, nil
+			}
+			return nil, nil
+		},
+		storeBackfillFunc: func(ctx context.Context, backfill *entity.ChannelBackfill) error {
+			copied := *backfill
+			stored[backfill.ChannelUsername] = &copied
+			return nil
+		},
+		storeInfoFunc: func(ctx context.Context, info *entity.TransferInfo, tagNames []string) (int64, error) {
+			storedMessageIDs = append(storedMessageIDs, info.MessageID)
+			return int64(info.MessageID), nil
+		},
+	}
+
+	var requestedMaxIDs []int
+	failed := false
+	mockGW := &mockTelegramTransferPostGateway{
+		channelUsername: "channel1",
+		getPostsBeforeFunc: func(ctx context.Context, maxID int, limit int) ([]*gateway.TransferPost, *gateway.HistoryPage, error) {
+			requestedMaxIDs = append(requestedMaxIDs, maxID)
+			switch maxID {
+			case 300:
+				// 一時的なエラーは同じ位置から再試行
+				if !failed {
+					failed = true
+					return nil, nil, errors.New("timeout")
+				}
+				return []*gateway.TransferPost{createTestTransferPost(250, "USDT", "100"), createTestTransferPost(200, "USDC", "200")},
+					&gateway.HistoryPage{OldestMessageID: 200, OldestDate: time.Unix(1700000000, 0)}, nil
+			case 200:
+				return []*gateway.TransferPost{createTestTransferPost(150, "USDT", "300"), createTestTransferPost(90, "USDT", "400")},
+					&gateway.HistoryPage{OldestMessageID: 90, OldestDate: time.Unix(1600000000, 0)}, nil
+			}
+			t.Fatalf("unexpected maxID %d", maxID)
+			return nil, nil, nil
+		},
+	}
+	// 過去の投稿を取得できないチャンネル
+	botGW := &mockTelegramTransferPostGateway{
+		channelUsername: "channel2",
+		getPostsBeforeFunc: func(ctx context.Context, maxID int, limit int) ([]*gateway.TransferPost, *gateway.HistoryPage, error) {
+			return nil, nil, gateway.ErrHistoryUnavailable
+		},
+	}
+
+	uc := NewTransferUsecase(mockRepo, []gateway.TelegramTransferPostGateway{mockGW, botGW})
+	processed, skipped, errs := uc.Backfill(context.Background(), BackfillOptions{
+		UntilMessageIDs: map[string]int{"channel1": 100},
+		Interval:        time.Millisecond,
+	})
+
+	if processed != 3 || skipped != 0 || len(errs) != 1 {
+		t.Errorf("processed = %d, skipped = %d, errs = %v, want 3, 0, 1 error", processed, skipped, errs)
+	}
+	if !reflect.DeepEqual(requestedMaxIDs, []int{300, 300, 200}) {
+		t.Errorf("requested maxIDs = %v, want [300 300 200]", requestedMaxIDs)
+	}
+	// 指定したメッセージID以前の投稿は保存しない
+	if !reflect.DeepEqual(storedMessageIDs, []int{250, 200, 150}) {
+		t.Errorf("stored message IDs = %v, want [250 200 150]", storedMessageIDs)
+	}
+
+	got := stored["channel1"]
+	if got.Status != entity.BackfillStatusCompleted || got.CursorMessageID != 90 || got.Processed != 8 || got.LastError != "" || got.CompletedAt == nil {
+		t.Errorf("channel1 backfill = %+v", got)
+	}
+	if got.OldestPostAt == nil || !got.OldestPostAt.Equal(time.Unix(1600000000, 0)) {
+		t.Errorf("OldestPostAt = %v", got.OldestPostAt)
+	}
+	if stored["channel2"].Status != entity.BackfillStatusFailed {
+		t.Errorf("channel2 backfill = %+v, want failed", stored["channel2"])
+	}
+
+	// 完了済みのチャンネルは、範囲を広げない限り再度取得しない
+	requestedMaxIDs = nil
+	uc.Backfill(context.Background(), BackfillOptions{UntilMessageIDs: map[string]int{"channel1": 100}, Interval: time.Millisecond})
+	if len(requestedMaxIDs) != 0 {
+		t.Errorf("requested maxIDs after completion = %v, want none", requestedMaxIDs)
+	}
+}
@@ -0,0 +1,34 @@
+package usecases
+
+import (
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+// 保存済みのチャンネル情報から解決済みのチャンネル情報を取得
+// 未解決の場合は nil を返す
+func channelPeerOf(channelStatus *entity.TelegramChannel) *gateway.ChannelPeer {
+	if channelStatus == nil || channelStatus.ChannelID == 0 {
+		return nil
+	}
+	return &gateway.ChannelPeer{
+		ID:         channelStatus.ChannelID,
+		AccessHash: channelStatus.AccessHash,
+		Title:      channelStatus.Title,
+	}
+}
+
+// 保存するチャンネル情報を生成
+// ゲートウェイでチャンネルが未解決の場合は、保存済みのチャンネル情報を引き継ぐ
+func buildChannelStatus(username string, lastMessageID int, peer *gateway.ChannelPeer, stored *entity.TelegramChannel) *entity.TelegramChannel {
+	channelStatus := &entity.TelegramChannel{ChannelUsername: username, LastMessageID: lastMessageID}
+	if peer == nil {
+		peer = channelPeerOf(stored)
+	}
+	if peer != nil {
+		channelStatus.ChannelID = peer.ID
+		channelStatus.AccessHash = peer.AccessHash
+		channelStatus.Title = peer.Title
+	}
+	return channelStatus
+}
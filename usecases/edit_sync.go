@@ -0,0 +1,254 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+// 編集・削除を確認する直近の情報のチャンネル毎の件数
+const DefaultEditSyncWindow = 50
+
+// 投稿の編集・削除の通知を購読できるチャンネルのゲートウェイ
+type changeGateway interface {
+	ChannelUsername() string
+	SubscribeChanges(ctx context.Context, handler func(ctx context.Context)) error
+}
+
+// ハッキング情報・送金情報で共通の、投稿の編集・削除の同期
+type editSyncer[G changeGateway] struct {
+	// ログに出力する投稿の種類
+	name     string
+	gateways []G
+	// 指定したチャンネルの直近の情報を同期し、更新件数、削除件数、エラーを返す
+	syncChannel func(ctx context.Context, gw G, window int) (int, int, []error)
+	// 情報が変更された場合に実行する後処理
+	refresh func(ctx context.Context) error
+}
+
+// 各チャンネルの投稿の編集・削除の通知を購読し、通知を受けたチャンネルの直近の情報を同期
+// ctx には購読を続ける間有効なコンテキストを指定
+func (e *editSyncer[G]) SubscribePostChanges(ctx context.Context) []error {
+	var errs []error
+	for _, gw := range e.gateways {
+		err := gw.SubscribeChanges(ctx, func(ctx context.Context) {
+			updatedCount, deletedCount, syncErrs := e.syncChannel(ctx, gw, DefaultEditSyncWindow)
+			e.logEditSync(ctx, updatedCount, deletedCount, syncErrs)
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to subscribe to changes of %s: %w", gw.ChannelUsername(), err))
+		}
+	}
+	return errs
+}
+
+// 各チャンネルの直近の情報の投稿を再取得し、編集された情報を更新、削除された情報を論理削除
+// 更新件数、削除件数、エラーを返す
+func (e *editSyncer[G]) SyncEditedPosts(ctx context.Context, window int) (int, int, []error) {
+	var allUpdatedCount, allDeletedCount int
+	var allErrors []error
+	for _, gw := range e.gateways {
+		updatedCount, deletedCount, errs := e.syncChannel(ctx, gw, window)
+		allUpdatedCount += updatedCount
+		allDeletedCount += deletedCount
+		allErrors = append(allErrors, errs...)
+	}
+
+	e.logEditSync(ctx, allUpdatedCount, allDeletedCount, allErrors)
+	return allUpdatedCount, allDeletedCount, allErrors
+}
+
+// 同期結果を記録し、変更があれば後処理を実行
+func (e *editSyncer[G]) logEditSync(ctx context.Context, updatedCount, deletedCount int, errs []error) {
+	log.Printf("%s: Edit sync finished. Updated: %d, Deleted: %d, Errors: %d", e.name, updatedCount, deletedCount, len(errs))
+	for _, err := range errs {
+		log.Printf("%v", err)
+	}
+
+	if updatedCount+deletedCount > 0 {
+		if err := e.refresh(ctx); err != nil {
+			log.Printf("%v", err)
+		}
+	}
+}
+
+// IDで指定したハッキング情報を、編集履歴と削除フラグを含めて取得
+// 見つからない場合は nil を返す
+func (uc *HackingUsecase) GetInfo(ctx context.Context, id int64) (*entity.HackingInfo, error) {
+	return uc.repo.GetInfoByID(ctx, id)
+}
+
+// 指定したチャンネルの直近の情報を同期
+func (uc *HackingUsecase) syncChannelEdits(ctx context.Context, gw gateway.TelegramHackingPostGateway, window int) (int, int, []error) {
+	uc.editSyncMu.Lock()
+	defer uc.editSyncMu.Unlock()
+
+	infos, err := uc.repo.GetRecentInfosByChannel(ctx, gw.ChannelUsername(), window)
+	if err != nil {
+		return 0, 0, []error{fmt.Errorf("failed to get recent infos of %s: %w", gw.ChannelUsername(), err)}
+	}
+	if len(infos) == 0 {
+		return 0, 0, nil
+	}
+
+	infosByMessageID := make(map[int]*entity.HackingInfo, len(infos))
+	messageIDs := make([]int, len(infos))
+	for i, info := range infos {
+		infosByMessageID[info.MessageID] = info
+		messageIDs[i] = info.MessageID
+	}
+
+	posts, deletedIDs, err := gw.GetPostsByMessageIDs(ctx, messageIDs)
+	if err != nil {
+		return 0, 0, []error{fmt.Errorf("failed to get posts from telegram: %w", err)}
+	}
+
+	var updatedCount, deletedCount int
+	var errs []error
+
+	// 削除された投稿の情報を論理削除
+	for _, messageID := range deletedIDs {
+		info, ok := infosByMessageID[messageID]
+		if !ok {
+			continue
+		}
+		if err := uc.repo.MarkInfoDeleted(ctx, info.ID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to mark info %d deleted: %w", info.ID, err))
+			continue
+		}
+		log.Printf("Marked info %d deleted: %s/%d", info.ID, info.ChannelUsername, info.MessageID)
+		deletedCount++
+	}
+
+	// 編集された投稿を再分析して情報を更新
+	for _, post := range posts {
+		info, ok := infosByMessageID[post.MessageID]
+		if !ok || !hackingPostEdited(info, post) {
+			continue
+		}
+		if err := uc.applyEdit(ctx, info, post); err != nil {
+			errs = append(errs, fmt.Errorf("failed to apply edit to info %d: %w", info.ID, err))
+			continue
+		}
+		log.Printf("Updated info %d from edited post: %s/%d", info.ID, info.ChannelUsername, info.MessageID)
+		updatedCount++
+	}
+
+	return updatedCount, deletedCount, errs
+}
+
+// 投稿が保存済みの情報から編集されているか
+// 投稿本文を保存していない情報は、パースした値の変更のみで判定
+func hackingPostEdited(info *entity.HackingInfo, post *gateway.HackingPost) bool {
+	if info.Network != post.Network || info.Amount != post.Amount || info.TxHash != post.TxHash {
+		return true
+	}
+	if info.PostText == "" {
+		return false
+	}
+	return info.PostText != post.Text || info.ReplyToText != post.ReplyToText
+}
+
+// 編集された投稿を再分析し、変更前の情報を編集履歴に残して更新
+func (uc *HackingUsecase) applyEdit(ctx context.Context, info *entity.HackingInfo, post *gateway.HackingPost) error {
+	// LLMでテキストを分析
+	extractedInfo, err := uc.llmGateway.AnalyzeAndExtract(ctx, post)
+	if err != nil {
+		return fmt.Errorf("llm analysis failed: %w", err)
+	}
+
+	edited := *info
+	edited.Protocol = extractedInfo.Protocol
+	edited.Network = extractedInfo.Network
+	edited.Amount = extractedInfo.Amount
+	edited.TxHash = extractedInfo.TxHash
+	edited.AttackVector = extractedInfo.AttackVector
+	edited.PromptVersion = extractedInfo.PromptVersion
+	edited.LLMProvider = extractedInfo.Provider
+	edited.LLMModel = extractedInfo.Model
+	edited.PostText = post.Text
+	edited.ReplyToText = post.ReplyToText
+	uc.setNormalizedAmount(ctx, &edited)
+
+	return uc.repo.UpdateInfoFromEdit(ctx, &edited, extractedInfo.TagNames)
+}
+
+// IDで指定した送金情報を、編集履歴と削除フラグを含めて取得
+// 見つからない場合は nil を返す
+func (uc *TransferUsecase) GetInfo(ctx context.Context, id int64) (*entity.TransferInfo, error) {
+	return uc.repo.GetInfoByID(ctx, id)
+}
+
+// 指定したチャンネルの直近の情報を同期
+func (uc *TransferUsecase) syncChannelEdits(ctx context.Context, gw gateway.TelegramTransferPostGateway, window int) (int, int, []error) {
+	uc.editSyncMu.Lock()
+	defer uc.editSyncMu.Unlock()
+
+	infos, err := uc.repo.GetRecentInfosByChannel(ctx, gw.ChannelUsername(), window)
+	if err != nil {
+		return 0, 0, []error{fmt.Errorf("failed to get recent infos of %s: %w", gw.ChannelUsername(), err)}
+	}
+	if len(infos) == 0 {
+		return 0, 0, nil
+	}
+
+	infosByMessageID := make(map[int]*entity.TransferInfo, len(infos))
+	messageIDs := make([]int, len(infos))
+	for i, info := range infos {
+		infosByMessageID[info.MessageID] = info
+		messageIDs[i] = info.MessageID
+	}
+
+	posts, deletedIDs, err := gw.GetPostsByMessageIDs(ctx, messageIDs)
+	if err != nil {
+		return 0, 0, []error{fmt.Errorf("failed to get posts from telegram: %w", err)}
+	}
+
+	var updatedCount, deletedCount int
+	var errs []error
+
+	// 削除された投稿の情報を論理削除
+	for _, messageID := range deletedIDs {
+		info, ok := infosByMessageID[messageID]
+		if !ok {
+			continue
+		}
+		if err := uc.repo.MarkInfoDeleted(ctx, info.ID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to mark info %d deleted: %w", info.ID, err))
+			continue
+		}
+		log.Printf("Marked info %d deleted: %s/%d", info.ID, info.ChannelUsername, info.MessageID)
+		deletedCount++
+	}
+
+	// 編集された投稿の値で情報を更新
+	for _, post := range posts {
+		info, ok := infosByMessageID[post.MessageID]
+		if !ok || !transferPostEdited(info, post) {
+			continue
+		}
+
+		edited := *info
+		edited.Token = post.Token
+		edited.Amount = post.Amount
+		edited.From = post.From
+		edited.To = post.To
+		uc.setNormalizedAmount(ctx, &edited)
+		if err := uc.repo.UpdateInfoFromEdit(ctx, &edited, post.TagNames); err != nil {
+			errs = append(errs, fmt.Errorf("failed to apply edit to info %d: %w", info.ID, err))
+			continue
+		}
+		log.Printf("Updated info %d from edited post: %s/%d", info.ID, info.ChannelUsername, info.MessageID)
+		updatedCount++
+	}
+
+	return updatedCount, deletedCount, errs
+}
+
+// 投稿が保存済みの情報から編集されているか
+func transferPostEdited(info *entity.TransferInfo, post *gateway.TransferPost) bool {
+	return info.Token != post.Token || info.Amount != post.Amount || info.From != post.From || info.To != post.To
+}
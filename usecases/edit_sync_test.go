@@ -0,0 +1,207 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+func TestSyncEditedPosts(t *testing.T) {
+	infos := []*entity.HackingInfo{
+		{ID: 1, Protocol: "Resupply", Network: "Ethereum", Amount: "$9,600,000", TxHash: "0x01", MessageID: 101, ChannelUsername: "channel1", PostText: "resupply", PromptVersion: "hacking-v1"},
+		{ID: 2, Protocol: "Onyx Protocol", Network: "Ethereum", Amount: "$3,800,000", TxHash: "0x02", MessageID: 102, ChannelUsername: "channel1", PostText: "onyx", PromptVersion: "hacking-v1"},
+		{ID: 3, Protocol: "Sonne Finance", Network: "Optimism", Amount: "$20,000,000", TxHash: "0x03", MessageID: 103, ChannelUsername: "channel1", PostText: "sonne", PromptVersion: "hacking-v1"},
+	}
+
+	var edited []*entity.HackingInfo
+	var editedTags [][]string
+	var deleted []int64
+	tagCacheRefreshed := false
+	mockRepo := &mockHackingRepository{
+		getRecentInfosByChannelFunc: func(ctx context.Context, channelUsername string, limit int) ([]*entity.HackingInfo, error) {
+			if channelUsername != "channel1" {
+				return nil, errors.New("unknown channel")
+			}
+			return infos, nil
+		},
+		updateInfoFromEditFunc: func(ctx context.Context, info *entity.HackingInfo, tagNames []string) error {
+			edited = append(edited, info)
+			editedTags = append(editedTags, tagNames)
+			return nil
+		},
+		markInfoDeletedFunc: func(ctx context.Context, id int64) error {
+			deleted = append(deleted, id)
+			return nil
+		},
+		setTagToCacheFunc: func(ctx context.Context) error {
+			tagCacheRefreshed = true
+			return nil
+		},
+	}
+
+	var analyzed []string
+	mockLLM := &mockLLMGateway{
+		analyzeAndExtractFunc: func(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error) {
+			analyzed = append(analyzed, post.Text)
+			return &gateway.ExtractedHackingInfo{
+				Protocol: "Onyx", Network: post.Network, Amount: post.Amount, TxHash: post.TxHash,
+				AttackVector: entity.AttackVectorOracleManipulation, TagNames: []string{"onyx"},
+				PromptVersion: "hacking-v2", Provider: "gemini", Model: "gemini-2.5-flash",
+			}, nil
+		},
+	}
+
+	var requestedIDs []int
+	mockGW := &mockTelegramHackingPostGateway{
+		channelUsername: "channel1",
+		getPostsByMessageIDsFunc: func(ctx context.Context, messageIDs []int) ([]*gateway.HackingPost, []int, error) {
+			requestedIDs = messageIDs
+			return []*gateway.HackingPost{
+				// 変更なし
+				{Text: "resupply", Network: "Ethereum", Amount: "$9,600,000", TxHash: "0x01", MessageID: 101},
+				// 金額が訂正された投稿
+				{Text: "onyx (updated)", Network: "Ethereum", Amount: "$2,100,000", TxHash: "0x02", MessageID: 102},
+			}, []int{103}, nil
+		},
+	}
+	failingGW := &mockTelegramHackingPostGateway{channelUsername: "channel2"}
+
+	uc := NewHackingUsecase(mockRepo, []gateway.TelegramHackingPostGateway{mockGW, failingGW}, mockLLM)
+	updatedCount, deletedCount, errs := uc.SyncEditedPosts(context.Background(), DefaultEditSyncWindow)
+
+	if updatedCount != 1 || deletedCount != 1 {
+		t.Errorf("updated = %d, deleted = %d, want 1, 1", updatedCount, deletedCount)
+	}
+	// 情報を取得できなかったチャンネルのみエラー
+	if len(errs) != 1 {
+		t.Errorf("errs = %v, want 1 error", errs)
+	}
+	if !reflect.DeepEqual(requestedIDs, []int{101, 102, 103}) {
+		t.Errorf("requested message IDs = %v", requestedIDs)
+	}
+	// 変更された投稿のみ再分析
+	if !reflect.DeepEqual(analyzed, []string{"onyx (updated)"}) {
+		t.Errorf("analyzed posts = %v", analyzed)
+	}
+	if !reflect.DeepEqual(deleted, []int64{3}) {
+		t.Errorf("deleted infos = %v, want [3]", deleted)
+	}
+
+	if len(edited) != 1 {
+		t.Fatalf("edited infos = %d, want 1", len(edited))
+	}
+	got := edited[0]
+	if got.ID != 2 || got.Amount != "$2,100,000" || got.Protocol != "Onyx" || got.PostText != "onyx (updated)" || got.PromptVersion != "hacking-v2" {
+		t.Errorf("edited info = %+v", got)
+	}
+	if !reflect.DeepEqual(editedTags[0], []string{"onyx"}) {
+		t.Errorf("edited tags = %v", editedTags[0])
+	}
+	// 取得した情報自体は変更しない
+	if infos[1].Amount != "$3,800,000" {
+		t.Errorf("original info is modified: %+v", infos[1])
+	}
+	if !tagCacheRefreshed {
+		t.Error("tag cache is not refreshed")
+	}
+}
+
+func TestSubscribePostChanges(t *testing.T) {
+	deleted := 0
+	mockRepo := &mockHackingRepository{
+		getRecentInfosByChannelFunc: func(ctx context.Context, channelUsername string, limit int) ([]*entity.HackingInfo, error) {
+			if limit != DefaultEditSyncWindow {
+				t.Errorf("limit = %d, want %d", limit, DefaultEditSyncWindow)
+			}
+			return []*entity.HackingInfo{{ID: 1, MessageID: 101, ChannelUsername: channelUsername}}, nil
+		},
+		markInfoDeletedFunc: func(ctx context.Context, id int64) error {
+			deleted++
+			return nil
+		},
+	}
+
+	var handler func(ctx context.Context)
+	mockGW := &mockTelegramHackingPostGateway{
+		channelUsername: "channel1",
+		getPostsByMessageIDsFunc: func(ctx context.Context, messageIDs []int) ([]*gateway.HackingPost, []int, error) {
+			return nil, messageIDs, nil
+		},
+		subscribeChangesFunc: func(ctx context.Context, h func(ctx context.Context)) error {
+			handler = h
+			return nil
+		},
+	}
+
+	uc := NewHackingUsecase(mockRepo, []gateway.TelegramHackingPostGateway{mockGW}, &mockLLMGateway{})
+	ctx := context.Background()
+	if errs := uc.SubscribePostChanges(ctx); len(errs) != 0 {
+		t.Fatalf("errs = %v", errs)
+	}
+	if handler == nil {
+		t.Fatal("handler is not subscribed")
+	}
+
+	// 通知を受けると直近の情報を同期
+	handler(ctx)
+	if deleted != 1 {
+		t.Errorf("deleted = %d, want 1", deleted)
+	}
+}
+
+func TestTransferSyncEditedPosts(t *testing.T) {
+	infos := []*entity.TransferInfo{
+		createTestTransferInfo(1, "USDC", "1,000,000"),
+		createTestTransferInfo(2, "USDT", "2,000,000"),
+		createTestTransferInfo(3, "ETH", "500"),
+	}
+	for i, info := range infos {
+		info.MessageID = 101 + i
+		info.ChannelUsername = "channel1"
+	}
+
+	var edited []*entity.TransferInfo
+	var deleted []int64
+	mockRepo := &mockTransferRepository{
+		getRecentInfosByChannelFunc: func(ctx context.Context, channelUsername string, limit int) ([]*entity.TransferInfo, error) {
+			return infos, nil
+		},
+		updateInfoFromEditFunc: func(ctx context.Context, info *entity.TransferInfo, tagNames []string) error {
+			edited = append(edited, info)
+			return nil
+		},
+		markInfoDeletedFunc: func(ctx context.Context, id int64) error {
+			deleted = append(deleted, id)
+			return nil
+		},
+	}
+
+	mockGW := &mockTelegramTransferPostGateway{
+		channelUsername: "channel1",
+		getPostsByMessageIDsFunc: func(ctx context.Context, messageIDs []int) ([]*gateway.TransferPost, []int, error) {
+			return []*gateway.TransferPost{
+				// 変更なし
+				createTestTransferPost(101, "USDC", "1,000,000"),
+				// 金額が訂正された投稿
+				createTestTransferPost(102, "USDT", "2,500,000"),
+			}, []int{103}, nil
+		},
+	}
+
+	uc := NewTransferUsecase(mockRepo, []gateway.TelegramTransferPostGateway{mockGW})
+	updatedCount, deletedCount, errs := uc.SyncEditedPosts(context.Background(), DefaultEditSyncWindow)
+
+	if updatedCount != 1 || deletedCount != 1 || len(errs) != 0 {
+		t.Errorf("updated = %d, deleted = %d, errs = %v", updatedCount, deletedCount, errs)
+	}
+	if len(edited) != 1 || edited[0].ID != 2 || edited[0].Amount != "2,500,000" {
+		t.Errorf("edited infos = %+v", edited)
+	}
+	if !reflect.DeepEqual(deleted, []int64{3}) {
+		t.Errorf("deleted infos = %v, want [3]", deleted)
+	}
+}
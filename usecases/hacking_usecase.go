@@ -2,9 +2,11 @@ package usecases
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
 	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
@@ -15,29 +17,72 @@ import (
 type HackingUsecase struct {
 	repo             repository.HackingRepository
 	telegramGateways []gateway.TelegramHackingPostGateway
-	geminiGateway    gateway.GeminiGateway
-	retryQueue       [][]*gateway.HackingPost
+	llmGateway       gateway.LLMGateway
+	// USD建ての価格の取得元（nil の場合はUSD建ての金額のみ変換）
+	priceSource gateway.PriceSource
+	// 編集・削除の同期を直列化し、同じ編集を重複して記録しないようにする
+	editSyncMu sync.Mutex
+	// 投稿の処理と、リトライキュー・隔離した投稿の管理
+	postProcessor[gateway.HackingPost]
+	// 過去の投稿の取得（バックフィル）
+	backfiller[gateway.HackingPost, gateway.TelegramHackingPostGateway]
+	// 投稿の編集・削除の同期
+	editSyncer[gateway.TelegramHackingPostGateway]
 }
 
 // 新しいHackingUsecaseを生成
-func NewHackingUsecase(repo repository.HackingRepository, telegramGateways []gateway.TelegramHackingPostGateway, geminiGateway gateway.GeminiGateway) *HackingUsecase {
-	retryQueue := make([][]*gateway.HackingPost, len(telegramGateways))
-	return &HackingUsecase{
+func NewHackingUsecase(repo repository.HackingRepository, telegramGateways []gateway.TelegramHackingPostGateway, llmGateway gateway.LLMGateway) *HackingUsecase {
+	uc := &HackingUsecase{
 		repo:             repo,
 		telegramGateways: telegramGateways,
-		geminiGateway:    geminiGateway,
-		retryQueue:       retryQueue,
+		llmGateway:       llmGateway,
 	}
+	uc.postProcessor = postProcessor[gateway.HackingPost]{
+		name:        "Hacking Post",
+		repo:        repo,
+		retryPolicy: DefaultRetryPolicy,
+		process:     uc.processSinglePost,
+		describe: func(post *gateway.HackingPost) string {
+			return "post " + post.TxHash
+		},
+		source: func(post *gateway.HackingPost) (string, int) {
+			return post.ChannelUsername, post.MessageID
+		},
+		extract: gateway.LLMGateway.ExtractHackingPost,
+	}
+	uc.backfiller = backfiller[gateway.HackingPost, gateway.TelegramHackingPostGateway]{
+		name:     "Hacking Post",
+		repo:     repo,
+		gateways: telegramGateways,
+		position: func(post *gateway.HackingPost) (int, time.Time) {
+			return post.MessageID, post.ReportTime
+		},
+		process: func(ctx context.Context, post *gateway.HackingPost, result *processResult) {
+			uc.processAndRecord(ctx, nil, post, result)
+		},
+		refresh: uc.SetTagToCache,
+	}
+	uc.editSyncer = editSyncer[gateway.TelegramHackingPostGateway]{
+		name:        "Hacking Post",
+		gateways:    telegramGateways,
+		syncChannel: uc.syncChannelEdits,
+		refresh:     uc.SetTagToCache,
+	}
+	// パースに失敗した投稿は隔離して保存
+	for _, gw := range telegramGateways {
+		gw.SetRejectedPostHandler(uc.quarantinePost)
+	}
+	return uc
 }
 
 // 最新タイムライン情報を指定件数取得
-func (uc *HackingUsecase) GetLatestTimeline(ctx context.Context, tagNames []string, infoNumber int) ([]*entity.HackingInfo, error) {
-	return uc.repo.GetInfosByTagNames(ctx, tagNames, infoNumber)
+func (uc *HackingUsecase) GetLatestTimeline(ctx context.Context, tagNames []string, attackVectors []string, infoNumber int) ([]*entity.HackingInfo, error) {
+	return uc.repo.GetInfosByTagNames(ctx, tagNames, attackVectors, infoNumber)
 }
 
 // 指定情報より過去のタイムライン情報を指定件数取得
-func (uc *HackingUsecase) GetPrevTimeline(ctx context.Context, tagNames []string, prevInfoID int64, infoNumber int) ([]*entity.HackingInfo, error) {
-	return uc.repo.GetPrevInfosByTagNames(ctx, tagNames, prevInfoID, infoNumber)
+func (uc *HackingUsecase) GetPrevTimeline(ctx context.Context, tagNames []string, attackVectors []string, prevInfoID int64, infoNumber int) ([]*entity.HackingInfo, error) {
+	return uc.repo.GetPrevInfosByTagNames(ctx, tagNames, attackVectors, prevInfoID, infoNumber)
 }
 
 // 全てのタグを取得
@@ -64,6 +109,10 @@ func (uc *HackingUsecase) SetLastMessageIDToGateway(ctx context.Context) error {
 			}
 		} else {
 			gw.SetLastMessageID(channelStatus.LastMessageID)
+			// 保存済みのチャンネル情報を使い、チャンネル名の解決を省略
+			if peer := channelPeerOf(channelStatus); peer != nil {
+				gw.SetChannelPeer(peer)
+			}
 		}
 	}
 
@@ -72,18 +121,18 @@ func (uc *HackingUsecase) SetLastMessageIDToGateway(ctx context.Context) error {
 
 func (uc *HackingUsecase) StoreLastMessageID(ctx context.Context) error {
 	for _, gw := range uc.telegramGateways {
-		newChannelStatus := entity.TelegramChannel{ChannelUsername: gw.ChannelUsername(), LastMessageID: gw.LastMessageID()}
 		channelStatus, err := uc.repo.GetChannelStatusByUsername(ctx, gw.ChannelUsername())
 		if err != nil {
 			return fmt.Errorf("failed to get channel status: %w", err)
 		}
+		newChannelStatus := buildChannelStatus(gw.ChannelUsername(), gw.LastMessageID(), gw.ChannelPeer(), channelStatus)
 		if channelStatus == nil {
-			err = uc.repo.StoreChannelStatus(ctx, &newChannelStatus)
+			err = uc.repo.StoreChannelStatus(ctx, newChannelStatus)
 			if err != nil {
 				return fmt.Errorf("failed to store channel status: %w", err)
 			}
 		} else {
-			err = uc.repo.UpdateChannelStatus(ctx, &newChannelStatus)
+			err = uc.repo.UpdateChannelStatus(ctx, newChannelStatus)
 			if err != nil {
 				return fmt.Errorf("failed to update channel status: %w", err)
 			}
@@ -94,20 +143,20 @@ func (uc *HackingUsecase) StoreLastMessageID(ctx context.Context) error {
 }
 
 // Telegramから100件以下の投稿を取得し、DBに保存
-func (uc *HackingUsecase) ScrapeAndStore(ctx context.Context, limit int) (int, []error) {
+// 処理件数、重複によるスキップ件数、エラーを返す
+func (uc *HackingUsecase) ScrapeAndStore(ctx context.Context, limit int) (int, int, []error) {
 	// 全ての新しい投稿を取得
 	var wg sync.WaitGroup
 	errsChan := make(chan error, len(uc.telegramGateways))
-	var posts [][]*gateway.HackingPost
+	posts := make([][]*gateway.HackingPost, len(uc.telegramGateways))
 
 	for i, gw := range uc.telegramGateways {
 		wg.Add(1)
-		posts = append(posts, []*gateway.HackingPost{})
 		go func(gw gateway.TelegramHackingPostGateway) {
 			defer wg.Done()
 			newPosts, err := gw.GetPosts(ctx, limit)
 			if err != nil {
-				errsChan <- fmt.Errorf("failed to get posts from telegram: %w", err)
+				errsChan <- fmt.Errorf("failed to get posts from %s: %w", gw.ChannelUsername(), err)
 				return
 			}
 			posts[i] = newPosts
@@ -122,75 +171,30 @@ func (uc *HackingUsecase) ScrapeAndStore(ctx context.Context, limit int) (int, [
 		getPostsErrors = append(getPostsErrors, err)
 	}
 
-	if len(getPostsErrors) != 0 {
-		return 0, getPostsErrors
-	}
-
-	var allErrors []error
-	var allProcessedCount int
-
-	for i := range uc.telegramGateways {
-		if len(posts[i]) == 0 && len(uc.retryQueue[i]) == 0 {
-			continue
-		}
-
-		errsChan = make(chan error, len(uc.retryQueue[i])+len(posts[i]))
-
-		newRetryQueue := []*gateway.HackingPost{}
-
-		for _, post := range uc.retryQueue[i] {
-			err := uc.processSinglePost(ctx, post)
-			if err != nil {
-				// エラーが発生したらチャネルに送信、リトライキューに追加
-				errsChan <- fmt.Errorf("failed to process post %s: %w", post.TxHash, err)
-				newRetryQueue = append(newRetryQueue, post)
-			}
-		}
-
-		allProcessedCount = allProcessedCount + len(uc.retryQueue[i])
-
-		uc.retryQueue[i] = newRetryQueue
+	// 取得に失敗したチャンネルは次回に再取得し、他のチャンネルの投稿の処理は続行
+	allProcessedCount, allSkippedCount, allErrors := uc.processPosts(ctx, posts)
+	allErrors = append(getPostsErrors, allErrors...)
 
-		for _, post := range posts[i] {
-			err := uc.processSinglePost(ctx, post)
-			if err != nil {
-				// エラーが発生したらチャネルに送信、リトライキューに追加
-				errsChan <- fmt.Errorf("failed to process post %s: %w", post.TxHash, err)
-				uc.retryQueue[i] = append(uc.retryQueue[i], post)
-			}
-		}
+	log.Printf("Hacking Post: Scraping finished. Processed: %d, Skipped: %d, Errors: %d", allProcessedCount, allSkippedCount, len(allErrors))
 
-		close(errsChan)
-
-		var errors []error
-		for err := range errsChan {
-			errors = append(errors, err)
-		}
-
-		allErrors = append(allErrors, errors...)
-		allProcessedCount = allProcessedCount + len(posts[i]) - len(errors)
-	}
-
-	log.Printf("Hacking Post: Scraping finished. Processed: %d, Errors: %d", allProcessedCount, len(allErrors))
-
-	return allProcessedCount, allErrors
+	return allProcessedCount, allSkippedCount, allErrors
 }
 
 // Telegramから101件以上の投稿を取得し、DBに保存
-func (uc *HackingUsecase) InitialScrapeAndStore(ctx context.Context, limit int) (int, []error) {
+// 処理件数、重複によるスキップ件数、エラーを返す
+func (uc *HackingUsecase) InitialScrapeAndStore(ctx context.Context, limit int) (int, int, []error) {
 	// 全ての新しい投稿を取得
 	var wg sync.WaitGroup
 	errsChan := make(chan error, len(uc.telegramGateways))
-	var posts [][]*gateway.HackingPost
+	posts := make([][]*gateway.HackingPost, len(uc.telegramGateways))
 
 	for i, gw := range uc.telegramGateways {
 		wg.Add(1)
-		posts = append(posts, []*gateway.HackingPost{})
 		go func(gw gateway.TelegramHackingPostGateway) {
 			defer wg.Done()
 			newPosts, err := gw.GetPostsOver100(ctx, limit)
 			if err != nil {
-				errsChan <- fmt.Errorf("failed to get posts from telegram: %w", err)
+				errsChan <- fmt.Errorf("failed to get posts from %s: %w", gw.ChannelUsername(), err)
 				return
 			}
 			posts[i] = newPosts
@@ -205,84 +209,123 @@ func (uc *HackingUsecase) InitialScrapeAndStore(ctx context.Context, limit int)
 		getPostsErrors = append(getPostsErrors, err)
 	}
 
-	if len(getPostsErrors) != 0 {
-		return 0, getPostsErrors
-	}
+	// 取得に失敗したチャンネルは次回に再取得し、他のチャンネルの投稿の処理は続行
+	allProcessedCount, allSkippedCount, allErrors := uc.processPosts(ctx, posts)
+	allErrors = append(getPostsErrors, allErrors...)
 
-	var allErrors []error
-	var allProcessedCount int
+	log.Printf("Scraping finished. Processed: %d, Skipped: %d, Errors: %d", allProcessedCount, allSkippedCount, len(allErrors))
 
-	for i := range uc.telegramGateways {
-		if len(posts[i]) == 0 && len(uc.retryQueue[i]) == 0 {
-			continue
+	return allProcessedCount, allSkippedCount, allErrors
+}
+
+// 各チャンネルの新しい投稿の通知を購読し、受信した投稿を即時に処理
+// 購読に失敗したチャンネルはポーリングでのみ取得される
+// ctx には購読を続ける間有効なコンテキストを指定
+func (uc *HackingUsecase) SubscribeNewPosts(ctx context.Context) []error {
+	var errs []error
+	for _, gw := range uc.telegramGateways {
+		if err := gw.SubscribeNewPosts(ctx, uc.processNewPosts); err != nil {
+			errs = append(errs, fmt.Errorf("failed to subscribe to new posts of %s: %w", gw.ChannelUsername(), err))
 		}
+	}
+	return errs
+}
 
-		errsChan = make(chan error, len(uc.retryQueue[i])+len(posts[i]))
+// 通知を受けて取得した投稿を処理し、最後に取得した投稿のIDを保存
+func (uc *HackingUsecase) processNewPosts(ctx context.Context, posts []*gateway.HackingPost) {
+	var result processResult
+	for _, post := range posts {
+		uc.processAndRecord(ctx, nil, post, &result)
+	}
 
-		newRetryQueue := []*gateway.HackingPost{}
-		log.Printf("Start: %d posts in retry queue", len(uc.retryQueue[i]))
+	log.Printf("Hacking Post: Realtime ingestion finished. Processed: %d, Skipped: %d, Errors: %d", result.processed, result.skipped, len(result.errs))
+	for _, err := range result.errs {
+		log.Printf("%v", err)
+	}
 
-		for _, post := range uc.retryQueue[i] {
-			err := uc.processSinglePost(ctx, post)
-			if err != nil {
-				// エラーが発生したらチャネルに送信、リトライキューに追加
-				errsChan <- fmt.Errorf("failed to process post %s: %w", post.TxHash, err)
-				newRetryQueue = append(newRetryQueue, post)
-			}
+	if err := uc.StoreLastMessageID(ctx); err != nil {
+		log.Printf("%v", err)
+	}
+	if result.processed > 0 {
+		if err := uc.SetTagToCache(ctx); err != nil {
+			log.Printf("%v", err)
 		}
+	}
+}
 
-		allProcessedCount = allProcessedCount + len(uc.retryQueue[i])
+// リトライキューと取得した投稿をチャンネル毎に処理
+// 処理件数、重複によるスキップ件数、エラーを返す
+func (uc *HackingUsecase) processPosts(ctx context.Context, posts [][]*gateway.HackingPost) (int, int, []error) {
+	var result processResult
 
-		uc.retryQueue[i] = newRetryQueue
+	for i, gw := range uc.telegramGateways {
+		// 再試行時刻を過ぎたリトライキューの投稿を先に処理
+		retryPosts, err := uc.repo.GetDueRetryPosts(ctx, gw.ChannelUsername(), time.Now(), retryBatchSize)
+		if err != nil {
+			result.addError(fmt.Errorf("failed to get retry posts: %w", err))
+		}
 
-		for _, post := range posts[i] {
-			err := uc.processSinglePost(ctx, post)
-			if err != nil {
-				// エラーが発生したらチャネルに送信、リトライキューに追加
-				errsChan <- fmt.Errorf("failed to process post %s: %w", post.TxHash, err)
-				uc.retryQueue[i] = append(uc.retryQueue[i], post)
-			}
+		if len(posts[i]) == 0 && len(retryPosts) == 0 {
+			continue
 		}
 
-		log.Printf("End: %d posts in retry queue", len(uc.retryQueue[i]))
+		log.Printf("%s: %d posts in retry queue", gw.ChannelUsername(), len(retryPosts))
 
-		close(errsChan)
+		uc.processRetryPosts(ctx, retryPosts, &result)
 
-		var errors []error
-		for err := range errsChan {
-			errors = append(errors, err)
+		for _, post := range posts[i] {
+			uc.processAndRecord(ctx, nil, post, &result)
 		}
-
-		allErrors = append(allErrors, errors...)
-		allProcessedCount = allProcessedCount + len(posts[i]) - len(errors)
 	}
 
-	log.Printf("Scraping finished. Processed: %d, Errors: %d", allProcessedCount, len(allErrors))
-
-	return allProcessedCount, allErrors
+	return result.processed, result.skipped, result.errs
 }
 
 // 単一の投稿を処理するヘルパー関数
 func (uc *HackingUsecase) processSinglePost(ctx context.Context, post *gateway.HackingPost) error {
 	log.Printf("Processing post: %s", post.TxHash)
 
-	// Geminiでテキストを分析
-	extractedInfo, err := uc.geminiGateway.AnalyzeAndExtract(ctx, post)
+	// 保存済みの投稿は、LLMで分析する前にスキップ
+	if post.ChannelUsername != "" {
+		infoID, err := uc.repo.GetInfoIDByMessage(ctx, post.ChannelUsername, post.MessageID)
+		if err != nil {
+			return fmt.Errorf("failed to check stored info: %w", err)
+		}
+		if infoID != 0 {
+			log.Printf("Skipped duplicate info: %s (existing ID: %d)", post.TxHash, infoID)
+			return repository.ErrDuplicateInfo
+		}
+	}
+
+	// LLMでテキストを分析
+	extractedInfo, err := uc.llmGateway.AnalyzeAndExtract(ctx, post)
 	if err != nil {
-		return fmt.Errorf("gemini analysis failed: %w", err)
+		return fmt.Errorf("llm analysis failed: %w", err)
 	}
 
 	infoToStore := &entity.HackingInfo{
-		Protocol:   extractedInfo.Protocol,
-		Network:    extractedInfo.Network,
-		Amount:     extractedInfo.Amount,
-		TxHash:     extractedInfo.TxHash,
-		ReportTime: post.ReportTime,
-		MessageID:  post.MessageID,
+		Protocol:        extractedInfo.Protocol,
+		Network:         extractedInfo.Network,
+		Amount:          extractedInfo.Amount,
+		TxHash:          extractedInfo.TxHash,
+		ReportTime:      post.ReportTime,
+		MessageID:       post.MessageID,
+		ChannelUsername: post.ChannelUsername,
+		AttackVector:    extractedInfo.AttackVector,
+		PromptVersion:   extractedInfo.PromptVersion,
+		LLMProvider:     extractedInfo.Provider,
+		LLMModel:        extractedInfo.Model,
+		PostText:        post.Text,
+		ReplyToText:     post.ReplyToText,
 	}
+	uc.setNormalizedAmount(ctx, infoToStore)
 
 	// DBに保存
-	_, err = uc.repo.StoreInfo(ctx, infoToStore, extractedInfo.TagNames)
+	infoID, err := uc.repo.StoreInfo(ctx, infoToStore, extractedInfo.TagNames)
+	if errors.Is(err, repository.ErrDuplicateInfo) {
+		log.Printf("Skipped duplicate info: %s (existing ID: %d)", infoToStore.TxHash, infoID)
+		return err
+	}
 	if err != nil {
 		return fmt.Errorf("database store failed: %w", err)
 	}
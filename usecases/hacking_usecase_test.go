@@ -3,38 +3,61 @@ package usecases
 import (
 	"context"
 	"errors"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
 	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+	"github.com/itout-datetoya/hack-info-timeline/domain/repository"
 )
 
 // ==================== Mock Implementations ====================
 
 // mockHackingRepository は HackingRepository インターフェースのモック実装
 type mockHackingRepository struct {
-	getInfosByTagNamesFunc         func(ctx context.Context, tagNames []string, infoNumber int) ([]*entity.HackingInfo, error)
-	getPrevInfosByTagNamesFunc     func(ctx context.Context, tagNames []string, prevInfoID int64, infoNumber int) ([]*entity.HackingInfo, error)
+	getInfosByTagNamesFunc         func(ctx context.Context, tagNames []string, attackVectors []string, infoNumber int) ([]*entity.HackingInfo, error)
+	getPrevInfosByTagNamesFunc     func(ctx context.Context, tagNames []string, attackVectors []string, prevInfoID int64, infoNumber int) ([]*entity.HackingInfo, error)
 	getAllTagsFunc                 func(ctx context.Context) ([]*entity.Tag, error)
 	setTagToCacheFunc              func(ctx context.Context) error
 	storeInfoFunc                  func(ctx context.Context, info *entity.HackingInfo, tagNames []string) (int64, error)
+	getInfoIDByMessageFunc         func(ctx context.Context, channelUsername string, messageID int) (int64, error)
+	getInfosForReanalysisFunc      func(ctx context.Context, afterID int64, promptVersion string, limit int) ([]*entity.HackingInfo, error)
+	updateInfoAnalysisFunc         func(ctx context.Context, info *entity.HackingInfo, tagNames []string) error
 	storeChannelStatusFunc         func(ctx context.Context, channelStatus *entity.TelegramChannel) error
 	updateChannelStatusFunc        func(ctx context.Context, channelStatus *entity.TelegramChannel) error
 	getChannelStatusByUsernameFunc func(ctx context.Context, username string) (*entity.TelegramChannel, error)
+	storeRetryPostFunc             func(ctx context.Context, retryPost *entity.RetryPost) error
+	getDueRetryPostsFunc           func(ctx context.Context, channelUsername string, now time.Time, limit int) ([]*entity.RetryPost, error)
+	getRetryPostsFunc              func(ctx context.Context, status string, limit int) ([]*entity.RetryPost, error)
+	getRetryPostByIDFunc           func(ctx context.Context, id int64) (*entity.RetryPost, error)
+	deleteRetryPostFunc            func(ctx context.Context, id int64) error
+	getInfoByIDFunc                func(ctx context.Context, id int64) (*entity.HackingInfo, error)
+	getRecentInfosByChannelFunc    func(ctx context.Context, channelUsername string, limit int) ([]*entity.HackingInfo, error)
+	updateInfoFromEditFunc         func(ctx context.Context, info *entity.HackingInfo, tagNames []string) error
+	markInfoDeletedFunc            func(ctx context.Context, id int64) error
+	getBackfillFunc                func(ctx context.Context, channelUsername string) (*entity.ChannelBackfill, error)
+	storeBackfillFunc              func(ctx context.Context, backfill *entity.ChannelBackfill) error
+	getBackfillsFunc               func(ctx context.Context) ([]*entity.ChannelBackfill, error)
+	storeQuarantinedPostFunc       func(ctx context.Context, post *entity.QuarantinedPost) error
+	getQuarantinedPostsFunc        func(ctx context.Context, channelUsername string, status string, limit int) ([]*entity.QuarantinedPost, error)
+	getQuarantinedPostFunc         func(ctx context.Context, channelUsername string, messageID int) (*entity.QuarantinedPost, error)
+	getQuarantinedPostByIDFunc     func(ctx context.Context, id int64) (*entity.QuarantinedPost, error)
+	getInfosWithoutAmountUSDFunc   func(ctx context.Context, afterID int64, limit int) ([]*entity.HackingInfo, error)
+	updateInfoAmountFunc           func(ctx context.Context, info *entity.HackingInfo) error
 }
 
-func (m *mockHackingRepository) GetInfosByTagNames(ctx context.Context, tagNames []string, infoNumber int) ([]*entity.HackingInfo, error) {
+func (m *mockHackingRepository) GetInfosByTagNames(ctx context.Context, tagNames []string, attackVectors []string, infoNumber int) ([]*entity.HackingInfo, error) {
 	if m.getInfosByTagNamesFunc != nil {
-		return m.getInfosByTagNamesFunc(ctx, tagNames, infoNumber)
+		return m.getInfosByTagNamesFunc(ctx, tagNames, attackVectors, infoNumber)
 	}
 	return nil, nil
 }
 
-func (m *mockHackingRepository) GetPrevInfosByTagNames(ctx context.Context, tagNames []string, prevInfoID int64, infoNumber int) ([]*entity.HackingInfo, error) {
+func (m *mockHackingRepository) GetPrevInfosByTagNames(ctx context.Context, tagNames []string, attackVectors []string, prevInfoID int64, infoNumber int) ([]*entity.HackingInfo, error) {
 	if m.getPrevInfosByTagNamesFunc != nil {
-		return m.getPrevInfosByTagNamesFunc(ctx, tagNames, prevInfoID, infoNumber)
+		return m.getPrevInfosByTagNamesFunc(ctx, tagNames, attackVectors, prevInfoID, infoNumber)
 	}
 	return nil, nil
 }
@@ -60,6 +83,27 @@ func (m *mockHackingRepository) StoreInfo(ctx context.Context, info *entity.Hack
 	return 0, nil
 }
 
+func (m *mockHackingRepository) GetInfoIDByMessage(ctx context.Context, channelUsername string, messageID int) (int64, error) {
+	if m.getInfoIDByMessageFunc != nil {
+		return m.getInfoIDByMessageFunc(ctx, channelUsername, messageID)
+	}
+	return 0, nil
+}
+
+func (m *mockHackingRepository) GetInfosForReanalysis(ctx context.Context, afterID int64, promptVersion string, limit int) ([]*entity.HackingInfo, error) {
+	if m.getInfosForReanalysisFunc != nil {
+		return m.getInfosForReanalysisFunc(ctx, afterID, promptVersion, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockHackingRepository) UpdateInfoAnalysis(ctx context.Context, info *entity.HackingInfo, tagNames []string) error {
+	if m.updateInfoAnalysisFunc != nil {
+		return m.updateInfoAnalysisFunc(ctx, info, tagNames)
+	}
+	return nil
+}
+
 func (m *mockHackingRepository) StoreChannelStatus(ctx context.Context, channelStatus *entity.TelegramChannel) error {
 	if m.storeChannelStatusFunc != nil {
 		return m.storeChannelStatusFunc(ctx, channelStatus)
@@ -81,13 +125,145 @@ func (m *mockHackingRepository) GetChannelStatusByUsername(ctx context.Context,
 	return nil, nil
 }
 
+func (m *mockHackingRepository) StoreRetryPost(ctx context.Context, retryPost *entity.RetryPost) error {
+	if m.storeRetryPostFunc != nil {
+		return m.storeRetryPostFunc(ctx, retryPost)
+	}
+	return nil
+}
+
+func (m *mockHackingRepository) GetDueRetryPosts(ctx context.Context, channelUsername string, now time.Time, limit int) ([]*entity.RetryPost, error) {
+	if m.getDueRetryPostsFunc != nil {
+		return m.getDueRetryPostsFunc(ctx, channelUsername, now, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockHackingRepository) GetRetryPosts(ctx context.Context, status string, limit int) ([]*entity.RetryPost, error) {
+	if m.getRetryPostsFunc != nil {
+		return m.getRetryPostsFunc(ctx, status, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockHackingRepository) GetRetryPostByID(ctx context.Context, id int64) (*entity.RetryPost, error) {
+	if m.getRetryPostByIDFunc != nil {
+		return m.getRetryPostByIDFunc(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *mockHackingRepository) DeleteRetryPost(ctx context.Context, id int64) error {
+	if m.deleteRetryPostFunc != nil {
+		return m.deleteRetryPostFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *mockHackingRepository) GetInfoByID(ctx context.Context, id int64) (*entity.HackingInfo, error) {
+	if m.getInfoByIDFunc != nil {
+		return m.getInfoByIDFunc(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *mockHackingRepository) GetRecentInfosByChannel(ctx context.Context, channelUsername string, limit int) ([]*entity.HackingInfo, error) {
+	if m.getRecentInfosByChannelFunc != nil {
+		return m.getRecentInfosByChannelFunc(ctx, channelUsername, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockHackingRepository) UpdateInfoFromEdit(ctx context.Context, info *entity.HackingInfo, tagNames []string) error {
+	if m.updateInfoFromEditFunc != nil {
+		return m.updateInfoFromEditFunc(ctx, info, tagNames)
+	}
+	return nil
+}
+
+func (m *mockHackingRepository) MarkInfoDeleted(ctx context.Context, id int64) error {
+	if m.markInfoDeletedFunc != nil {
+		return m.markInfoDeletedFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *mockHackingRepository) GetBackfill(ctx context.Context, channelUsername string) (*entity.ChannelBackfill, error) {
+	if m.getBackfillFunc != nil {
+		return m.getBackfillFunc(ctx, channelUsername)
+	}
+	return nil, nil
+}
+
+func (m *mockHackingRepository) StoreBackfill(ctx context.Context, backfill *entity.ChannelBackfill) error {
+	if m.storeBackfillFunc != nil {
+		return m.storeBackfillFunc(ctx, backfill)
+	}
+	return nil
+}
+
+func (m *mockHackingRepository) GetBackfills(ctx context.Context) ([]*entity.ChannelBackfill, error) {
+	if m.getBackfillsFunc != nil {
+		return m.getBackfillsFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *mockHackingRepository) StoreQuarantinedPost(ctx context.Context, post *entity.QuarantinedPost) error {
+	if m.storeQuarantinedPostFunc != nil {
+		return m.storeQuarantinedPostFunc(ctx, post)
+	}
+	return nil
+}
+
+func (m *mockHackingRepository) GetQuarantinedPosts(ctx context.Context, channelUsername string, status string, limit int) ([]*entity.QuarantinedPost, error) {
+	if m.getQuarantinedPostsFunc != nil {
+		return m.getQuarantinedPostsFunc(ctx, channelUsername, status, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockHackingRepository) GetQuarantinedPost(ctx context.Context, channelUsername string, messageID int) (*entity.QuarantinedPost, error) {
+	if m.getQuarantinedPostFunc != nil {
+		return m.getQuarantinedPostFunc(ctx, channelUsername, messageID)
+	}
+	return nil, nil
+}
+
+func (m *mockHackingRepository) GetQuarantinedPostByID(ctx context.Context, id int64) (*entity.QuarantinedPost, error) {
+	if m.getQuarantinedPostByIDFunc != nil {
+		return m.getQuarantinedPostByIDFunc(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *mockHackingRepository) GetInfosWithoutAmountUSD(ctx context.Context, afterID int64, limit int) ([]*entity.HackingInfo, error) {
+	if m.getInfosWithoutAmountUSDFunc != nil {
+		return m.getInfosWithoutAmountUSDFunc(ctx, afterID, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockHackingRepository) UpdateInfoAmount(ctx context.Context, info *entity.HackingInfo) error {
+	if m.updateInfoAmountFunc != nil {
+		return m.updateInfoAmountFunc(ctx, info)
+	}
+	return nil
+}
+
 // mockTelegramHackingPostGateway は TelegramHackingPostGateway インターフェースのモック実装
 type mockTelegramHackingPostGateway struct {
-	channelUsername     string
-	lastMessageID       int
-	getPostsFunc        func(ctx context.Context, limit int) ([]*gateway.HackingPost, error)
-	getPostsOver100Func func(ctx context.Context, limit int) ([]*gateway.HackingPost, error)
-	mu                  sync.Mutex
+	channelUsername          string
+	lastMessageID            int
+	channelPeer              *gateway.ChannelPeer
+	getPostsFunc             func(ctx context.Context, limit int) ([]*gateway.HackingPost, error)
+	getPostsOver100Func      func(ctx context.Context, limit int) ([]*gateway.HackingPost, error)
+	subscribeFunc            func(ctx context.Context, handler func(ctx context.Context, posts []*gateway.HackingPost)) error
+	getPostsByMessageIDsFunc func(ctx context.Context, messageIDs []int) ([]*gateway.HackingPost, []int, error)
+	subscribeChangesFunc     func(ctx context.Context, handler func(ctx context.Context)) error
+	getPostsBeforeFunc       func(ctx context.Context, maxID int, limit int) ([]*gateway.HackingPost, *gateway.HistoryPage, error)
+	rejectedPostHandler      gateway.RejectedPostHandler
+	mu                       sync.Mutex
 }
 
 func (m *mockTelegramHackingPostGateway) SetLastMessageID(lastMessageID int) {
@@ -106,6 +282,18 @@ func (m *mockTelegramHackingPostGateway) ChannelUsername() string {
 	return m.channelUsername
 }
 
+func (m *mockTelegramHackingPostGateway) SetChannelPeer(peer *gateway.ChannelPeer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.channelPeer = peer
+}
+
+func (m *mockTelegramHackingPostGateway) ChannelPeer() *gateway.ChannelPeer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.channelPeer
+}
+
 func (m *mockTelegramHackingPostGateway) GetPosts(ctx context.Context, limit int) ([]*gateway.HackingPost, error) {
 	if m.getPostsFunc != nil {
 		return m.getPostsFunc(ctx, limit)
@@ -120,26 +308,172 @@ func (m *mockTelegramHackingPostGateway) GetPostsOver100(ctx context.Context, li
 	return nil, nil
 }
 
-// mockGeminiGateway は GeminiGateway インターフェースのモック実装
-type mockGeminiGateway struct {
-	analyzeAndExtractFunc func(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error)
-	stopFunc              func() error
+func (m *mockTelegramHackingPostGateway) SubscribeNewPosts(ctx context.Context, handler func(ctx context.Context, posts []*gateway.HackingPost)) error {
+	if m.subscribeFunc != nil {
+		return m.subscribeFunc(ctx, handler)
+	}
+	return nil
+}
+
+func (m *mockTelegramHackingPostGateway) GetPostsByMessageIDs(ctx context.Context, messageIDs []int) ([]*gateway.HackingPost, []int, error) {
+	if m.getPostsByMessageIDsFunc != nil {
+		return m.getPostsByMessageIDsFunc(ctx, messageIDs)
+	}
+	return nil, nil, nil
+}
+
+func (m *mockTelegramHackingPostGateway) SubscribeChanges(ctx context.Context, handler func(ctx context.Context)) error {
+	if m.subscribeChangesFunc != nil {
+		return m.subscribeChangesFunc(ctx, handler)
+	}
+	return nil
+}
+
+func (m *mockTelegramHackingPostGateway) GetPostsBefore(ctx context.Context, maxID int, limit int) ([]*gateway.HackingPost, *gateway.HistoryPage, error) {
+	if m.getPostsBeforeFunc != nil {
+		return m.getPostsBeforeFunc(ctx, maxID, limit)
+	}
+	return nil, &gateway.HistoryPage{}, nil
+}
+
+func (m *mockTelegramHackingPostGateway) SetRejectedPostHandler(handler gateway.RejectedPostHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rejectedPostHandler = handler
+}
+
+// パースに失敗した投稿を設定された handler に渡す
+func (m *mockTelegramHackingPostGateway) reject(ctx context.Context, post *gateway.RejectedPost) {
+	m.mu.Lock()
+	handler := m.rejectedPostHandler
+	m.mu.Unlock()
+	if handler != nil {
+		handler(ctx, post)
+	}
 }
 
-func (m *mockGeminiGateway) AnalyzeAndExtract(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error) {
+// mockLLMGateway は LLMGateway インターフェースのモック実装
+type mockLLMGateway struct {
+	analyzeAndExtractFunc   func(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error)
+	extractHackingPostFunc  func(ctx context.Context, post *gateway.RejectedPost) (*gateway.HackingPost, float64, error)
+	extractTransferPostFunc func(ctx context.Context, post *gateway.RejectedPost) (*gateway.TransferPost, float64, error)
+	stopFunc                func() error
+}
+
+func (m *mockLLMGateway) AnalyzeAndExtract(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error) {
 	if m.analyzeAndExtractFunc != nil {
 		return m.analyzeAndExtractFunc(ctx, post)
 	}
 	return nil, nil
 }
 
-func (m *mockGeminiGateway) Stop() error {
+func (m *mockLLMGateway) ExtractHackingPost(ctx context.Context, post *gateway.RejectedPost) (*gateway.HackingPost, float64, error) {
+	if m.extractHackingPostFunc != nil {
+		return m.extractHackingPostFunc(ctx, post)
+	}
+	return nil, 0, gateway.ErrPostNotExtracted
+}
+
+func (m *mockLLMGateway) ExtractTransferPost(ctx context.Context, post *gateway.RejectedPost) (*gateway.TransferPost, float64, error) {
+	if m.extractTransferPostFunc != nil {
+		return m.extractTransferPostFunc(ctx, post)
+	}
+	return nil, 0, gateway.ErrPostNotExtracted
+}
+
+func (m *mockLLMGateway) PromptVersion() string {
+	return "test"
+}
+
+func (m *mockLLMGateway) Provider() string {
+	return "test"
+}
+
+func (m *mockLLMGateway) Model() string {
+	return "test"
+}
+
+func (m *mockLLMGateway) Stop() error {
 	if m.stopFunc != nil {
 		return m.stopFunc()
 	}
 	return nil
 }
 
+// mockRetryQueue はリトライキューのインメモリ実装
+type mockRetryQueue struct {
+	mu     sync.Mutex
+	nextID int64
+	posts  map[int64]entity.RetryPost
+}
+
+func newMockRetryQueue() *mockRetryQueue {
+	return &mockRetryQueue{posts: map[int64]entity.RetryPost{}}
+}
+
+// DBと同様に、同じチャンネル・メッセージIDの投稿は試行回数を加算し、デッドレター状態を維持
+func (q *mockRetryQueue) store(ctx context.Context, retryPost *entity.RetryPost) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	stored := *retryPost
+	for id, existing := range q.posts {
+		if existing.ChannelUsername == retryPost.ChannelUsername && existing.MessageID == retryPost.MessageID {
+			stored.ID = id
+			stored.Attempts = existing.Attempts + 1
+			if existing.Status == entity.RetryStatusDead {
+				stored.Status = entity.RetryStatusDead
+			}
+		}
+	}
+	if stored.ID == 0 {
+		q.nextID++
+		stored.ID = q.nextID
+		retryPost.ID = stored.ID
+	}
+	q.posts[stored.ID] = stored
+	return nil
+}
+
+func (q *mockRetryQueue) getDue(ctx context.Context, channelUsername string, now time.Time, limit int) ([]*entity.RetryPost, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var due []*entity.RetryPost
+	for _, retryPost := range q.posts {
+		if retryPost.ChannelUsername == channelUsername && retryPost.Status == entity.RetryStatusPending && !retryPost.NextAttemptAt.After(now) {
+			rp := retryPost
+			due = append(due, &rp)
+		}
+	}
+	return due, nil
+}
+
+func (q *mockRetryQueue) getByID(ctx context.Context, id int64) (*entity.RetryPost, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	retryPost, ok := q.posts[id]
+	if !ok {
+		return nil, nil
+	}
+	return &retryPost, nil
+}
+
+func (q *mockRetryQueue) delete(ctx context.Context, id int64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.posts, id)
+	return nil
+}
+
+func (q *mockRetryQueue) list() []entity.RetryPost {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var posts []entity.RetryPost
+	for _, retryPost := range q.posts {
+		posts = append(posts, retryPost)
+	}
+	return posts
+}
+
 // ==================== Test Helper Functions ====================
 
 func createTestHackingPost(messageID int, txHash string) *gateway.HackingPost {
@@ -170,13 +504,14 @@ func createTestHackingInfo(id int64, txHash string) *entity.HackingInfo {
 
 func TestGetLatestTimeline(t *testing.T) {
 	tests := []struct {
-		name       string
-		tagNames   []string
-		infoNumber int
-		mockResult []*entity.HackingInfo
-		mockError  error
-		wantErr    bool
-		wantCount  int
+		name          string
+		tagNames      []string
+		attackVectors []string
+		infoNumber    int
+		mockResult    []*entity.HackingInfo
+		mockError     error
+		wantErr       bool
+		wantCount     int
 	}{
 		{
 			name:       "success case",
@@ -190,6 +525,15 @@ func TestGetLatestTimeline(t *testing.T) {
 			wantErr:   false,
 			wantCount: 2,
 		},
+		{
+			name:          "filter by attack vector",
+			attackVectors: []string{entity.AttackVectorReentrancy, entity.AttackVectorFlashLoan},
+			infoNumber:    10,
+			mockResult: []*entity.HackingInfo{
+				createTestHackingInfo(1, "0xabc123"),
+			},
+			wantCount: 1,
+		},
 		{
 			name:       "empty result",
 			tagNames:   []string{"NonExistent"},
@@ -213,7 +557,10 @@ func TestGetLatestTimeline(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := &mockHackingRepository{
-				getInfosByTagNamesFunc: func(ctx context.Context, tagNames []string, infoNumber int) ([]*entity.HackingInfo, error) {
+				getInfosByTagNamesFunc: func(ctx context.Context, tagNames []string, attackVectors []string, infoNumber int) ([]*entity.HackingInfo, error) {
+					if !reflect.DeepEqual(attackVectors, tt.attackVectors) {
+						t.Errorf("attackVectors = %v, want %v", attackVectors, tt.attackVectors)
+					}
 					return tt.mockResult, tt.mockError
 				},
 			}
@@ -221,7 +568,7 @@ func TestGetLatestTimeline(t *testing.T) {
 			uc := NewHackingUsecase(mockRepo, nil, nil)
 			ctx := context.Background()
 
-			result, err := uc.GetLatestTimeline(ctx, tt.tagNames, tt.infoNumber)
+			result, err := uc.GetLatestTimeline(ctx, tt.tagNames, tt.attackVectors, tt.infoNumber)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetLatestTimeline() error = %v, wantErr %v", err, tt.wantErr)
@@ -237,14 +584,15 @@ func TestGetLatestTimeline(t *testing.T) {
 
 func TestGetPrevTimeline(t *testing.T) {
 	tests := []struct {
-		name       string
-		tagNames   []string
-		prevInfoID int64
-		infoNumber int
-		mockResult []*entity.HackingInfo
-		mockError  error
-		wantErr    bool
-		wantCount  int
+		name          string
+		tagNames      []string
+		attackVectors []string
+		prevInfoID    int64
+		infoNumber    int
+		mockResult    []*entity.HackingInfo
+		mockError     error
+		wantErr       bool
+		wantCount     int
 	}{
 		{
 			name:       "success case",
@@ -259,6 +607,17 @@ func TestGetPrevTimeline(t *testing.T) {
 			wantErr:   false,
 			wantCount: 2,
 		},
+		{
+			name:          "filter by attack vector",
+			tagNames:      []string{"DeFi"},
+			attackVectors: []string{entity.AttackVectorOracleManipulation},
+			prevInfoID:    100,
+			infoNumber:    5,
+			mockResult: []*entity.HackingInfo{
+				createTestHackingInfo(99, "0xabc123"),
+			},
+			wantCount: 1,
+		},
 		{
 			name:       "no previous data",
 			tagNames:   []string{"DeFi"},
@@ -284,7 +643,10 @@ func TestGetPrevTimeline(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := &mockHackingRepository{
-				getPrevInfosByTagNamesFunc: func(ctx context.Context, tagNames []string, prevInfoID int64, infoNumber int) ([]*entity.HackingInfo, error) {
+				getPrevInfosByTagNamesFunc: func(ctx context.Context, tagNames []string, attackVectors []string, prevInfoID int64, infoNumber int) ([]*entity.HackingInfo, error) {
+					if !reflect.DeepEqual(attackVectors, tt.attackVectors) {
+						t.Errorf("attackVectors = %v, want %v", attackVectors, tt.attackVectors)
+					}
 					return tt.mockResult, tt.mockError
 				},
 			}
@@ -292,7 +654,7 @@ func TestGetPrevTimeline(t *testing.T) {
 			uc := NewHackingUsecase(mockRepo, nil, nil)
 			ctx := context.Background()
 
-			result, err := uc.GetPrevTimeline(ctx, tt.tagNames, tt.prevInfoID, tt.infoNumber)
+			result, err := uc.GetPrevTimeline(ctx, tt.tagNames, tt.attackVectors, tt.prevInfoID, tt.infoNumber)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetPrevTimeline() error = %v, wantErr %v", err, tt.wantErr)
@@ -366,6 +728,51 @@ func TestGetAllTags(t *testing.T) {
 	}
 }
 
+func TestChannelPeerPersistence(t *testing.T) {
+	ctx := context.Background()
+	stored := map[string]*entity.TelegramChannel{
+		"resolved":   {ChannelUsername: "resolved", LastMessageID: 100, ChannelID: 1001, AccessHash: 2002, Title: "Resolved"},
+		"unresolved": {ChannelUsername: "unresolved", LastMessageID: 200},
+	}
+	mockRepo := &mockHackingRepository{
+		getChannelStatusByUsernameFunc: func(ctx context.Context, username string) (*entity.TelegramChannel, error) {
+			return stored[username], nil
+		},
+		updateChannelStatusFunc: func(ctx context.Context, channelStatus *entity.TelegramChannel) error {
+			stored[channelStatus.ChannelUsername] = channelStatus
+			return nil
+		},
+	}
+
+	resolvedGW := &mockTelegramHackingPostGateway{channelUsername: "resolved"}
+	unresolvedGW := &mockTelegramHackingPostGateway{channelUsername: "unresolved"}
+	uc := NewHackingUsecase(mockRepo, []gateway.TelegramHackingPostGateway{resolvedGW, unresolvedGW}, &mockLLMGateway{})
+
+	// 保存済みのチャンネル情報をゲートウェイに設定
+	if err := uc.SetLastMessageIDToGateway(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if peer := resolvedGW.ChannelPeer(); !reflect.DeepEqual(peer, &gateway.ChannelPeer{ID: 1001, AccessHash: 2002, Title: "Resolved"}) {
+		t.Errorf("resolved peer = %+v", peer)
+	}
+	if peer := unresolvedGW.ChannelPeer(); peer != nil {
+		t.Errorf("unresolved peer = %+v, want nil", peer)
+	}
+
+	// ゲートウェイで解決したチャンネル情報を保存し、未解決の場合は保存済みの情報を維持
+	resolvedGW.SetChannelPeer(nil)
+	unresolvedGW.SetChannelPeer(&gateway.ChannelPeer{ID: 3003, AccessHash: 4004, Title: "Unresolved"})
+	if err := uc.StoreLastMessageID(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := stored["resolved"]; got.ChannelID != 1001 || got.AccessHash != 2002 {
+		t.Errorf("stored resolved channel = %+v", got)
+	}
+	if got := stored["unresolved"]; got.ChannelID != 3003 || got.AccessHash != 4004 || got.Title != "Unresolved" {
+		t.Errorf("stored unresolved channel = %+v", got)
+	}
+}
+
 func TestSetTagToCache(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -411,8 +818,9 @@ func TestProcessSinglePost(t *testing.T) {
 		name            string
 		post            *gateway.HackingPost
 		extractedInfo   *gateway.ExtractedHackingInfo
-		geminiError     error
+		llmError        error
 		storeError      error
+		storedInfoID    int64
 		wantErr         bool
 		wantErrContains string
 	}{
@@ -420,25 +828,29 @@ func TestProcessSinglePost(t *testing.T) {
 			name: "success case",
 			post: createTestHackingPost(100, "0xabc123"),
 			extractedInfo: &gateway.ExtractedHackingInfo{
-				Protocol: "Uniswap",
-				Network:  "Ethereum",
-				Amount:   "$1000000",
-				TxHash:   "0xabc123",
-				TagNames: []string{"DeFi", "DEX"},
+				Protocol:      "Uniswap",
+				Network:       "Ethereum",
+				Amount:        "$1000000",
+				TxHash:        "0xabc123",
+				TagNames:      []string{"DeFi", "DEX"},
+				AttackVector:  entity.AttackVectorReentrancy,
+				PromptVersion: "hacking-v1",
+				Provider:      "gemini",
+				Model:         "gemini-2.5-flash-lite",
 			},
-			geminiError:     nil,
+			llmError:        nil,
 			storeError:      nil,
 			wantErr:         false,
 			wantErrContains: "",
 		},
 		{
-			name:            "gemini analysis error",
+			name:            "llm analysis error",
 			post:            createTestHackingPost(100, "0xabc123"),
 			extractedInfo:   nil,
-			geminiError:     errors.New("API rate limit"),
+			llmError:        errors.New("API rate limit"),
 			storeError:      nil,
 			wantErr:         true,
-			wantErrContains: "gemini analysis failed",
+			wantErrContains: "llm analysis failed",
 		},
 		{
 			name: "database store error",
@@ -450,34 +862,60 @@ func TestProcessSinglePost(t *testing.T) {
 				TxHash:   "0xabc123",
 				TagNames: []string{"DeFi"},
 			},
-			geminiError:     nil,
+			llmError:        nil,
 			storeError:      errors.New("constraint violation"),
 			wantErr:         true,
 			wantErrContains: "database store failed",
 		},
+		{
+			// 保存済みの投稿はLLMで分析しない
+			name: "already stored post",
+			post: func() *gateway.HackingPost {
+				post := createTestHackingPost(100, "0xabc123")
+				post.ChannelUsername = "hackchannel"
+				return post
+			}(),
+			llmError:        errors.New("llm must not be called"),
+			storedInfoID:    7,
+			wantErr:         true,
+			wantErrContains: repository.ErrDuplicateInfo.Error(),
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := &mockHackingRepository{
+				getInfoIDByMessageFunc: func(ctx context.Context, channelUsername string, messageID int) (int64, error) {
+					if channelUsername != tt.post.ChannelUsername || messageID != tt.post.MessageID {
+						t.Errorf("GetInfoIDByMessage(%s, %d), want (%s, %d)", channelUsername, messageID, tt.post.ChannelUsername, tt.post.MessageID)
+					}
+					return tt.storedInfoID, nil
+				},
 				storeInfoFunc: func(ctx context.Context, info *entity.HackingInfo, tagNames []string) (int64, error) {
 					if tt.storeError != nil {
 						return 0, tt.storeError
 					}
+					if info.AttackVector != tt.extractedInfo.AttackVector {
+						t.Errorf("stored AttackVector = %q, want %q", info.AttackVector, tt.extractedInfo.AttackVector)
+					}
+					if info.PromptVersion != tt.extractedInfo.PromptVersion || info.LLMProvider != tt.extractedInfo.Provider || info.LLMModel != tt.extractedInfo.Model {
+						t.Errorf("stored provenance = %s/%s/%s, want %s/%s/%s", info.PromptVersion, info.LLMProvider, info.LLMModel,
+							tt.extractedInfo.PromptVersion, tt.extractedInfo.Provider, tt.extractedInfo.Model)
+					}
 					return 1, nil
 				},
 			}
 
-			mockGemini := &mockGeminiGateway{
+			mockLLM := &mockLLMGateway{
 				analyzeAndExtractFunc: func(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error) {
-					if tt.geminiError != nil {
-						return nil, tt.geminiError
+					if tt.llmError != nil {
+						return nil, tt.llmError
 					}
 					return tt.extractedInfo, nil
 				},
 			}
 
-			uc := NewHackingUsecase(mockRepo, nil, mockGemini)
+			uc := NewHackingUsecase(mockRepo, nil, mockLLM)
 			ctx := context.Background()
 
 			err := uc.processSinglePost(ctx, tt.post)
@@ -558,7 +996,7 @@ func TestScrapeAndStore(t *testing.T) {
 			},
 			getPostsErrors: []error{nil},
 			processErrors: map[string]error{
-				"0xdef456": errors.New("gemini error"),
+				"0xdef456": errors.New("llm error"),
 			},
 			wantProcessedCount: 2,
 			wantErrorCount:     1,
@@ -576,6 +1014,22 @@ func TestScrapeAndStore(t *testing.T) {
 			wantProcessedCount: 0,
 			wantErrorCount:     1,
 		},
+		{
+			name:        "get posts error does not block other channels",
+			limit:       10,
+			numGateways: 2,
+			postsPerGateway: [][]*gateway.HackingPost{
+				nil,
+				{
+					createTestHackingPost(201, "0xjkl012"),
+					createTestHackingPost(202, "0xmno345"),
+				},
+			},
+			getPostsErrors:     []error{errors.New("FLOOD_WAIT"), nil},
+			processErrors:      map[string]error{},
+			wantProcessedCount: 2,
+			wantErrorCount:     1,
+		},
 		{
 			name:               "no posts to process",
 			limit:              10,
@@ -599,7 +1053,7 @@ func TestScrapeAndStore(t *testing.T) {
 				},
 			}
 
-			mockGemini := &mockGeminiGateway{
+			mockLLM := &mockLLMGateway{
 				analyzeAndExtractFunc: func(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error) {
 					if err, exists := tt.processErrors[post.TxHash]; exists {
 						return nil, err
@@ -629,10 +1083,10 @@ func TestScrapeAndStore(t *testing.T) {
 				gateways = append(gateways, mockGW)
 			}
 
-			uc := NewHackingUsecase(mockRepo, gateways, mockGemini)
+			uc := NewHackingUsecase(mockRepo, gateways, mockLLM)
 			ctx := context.Background()
 
-			processedCount, errs := uc.ScrapeAndStore(ctx, tt.limit)
+			processedCount, _, errs := uc.ScrapeAndStore(ctx, tt.limit)
 
 			if processedCount != tt.wantProcessedCount {
 				t.Errorf("ScrapeAndStore() processedCount = %d, want %d", processedCount, tt.wantProcessedCount)
@@ -650,14 +1104,18 @@ func TestScrapeAndStore(t *testing.T) {
 
 func TestScrapeAndStore_RetryQueue(t *testing.T) {
 	t.Run("retry queue processing", func(t *testing.T) {
+		queue := newMockRetryQueue()
 		mockRepo := &mockHackingRepository{
 			storeInfoFunc: func(ctx context.Context, info *entity.HackingInfo, tagNames []string) (int64, error) {
 				return 1, nil
 			},
+			storeRetryPostFunc:   queue.store,
+			getDueRetryPostsFunc: queue.getDue,
+			deleteRetryPostFunc:  queue.delete,
 		}
 
 		callCount := 0
-		mockGemini := &mockGeminiGateway{
+		mockLLM := &mockLLMGateway{
 			analyzeAndExtractFunc: func(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error) {
 				callCount++
 				// First call fails, subsequent calls succeed
@@ -677,17 +1135,19 @@ func TestScrapeAndStore_RetryQueue(t *testing.T) {
 		mockGW := &mockTelegramHackingPostGateway{
 			channelUsername: "channel1",
 			getPostsFunc: func(ctx context.Context, limit int) ([]*gateway.HackingPost, error) {
-				return []*gateway.HackingPost{
-					createTestHackingPost(101, "0xabc123"),
-				}, nil
+				post := createTestHackingPost(101, "0xabc123")
+				post.ChannelUsername = "channel1"
+				return []*gateway.HackingPost{post}, nil
 			},
 		}
 
-		uc := NewHackingUsecase(mockRepo, []gateway.TelegramHackingPostGateway{mockGW}, mockGemini)
+		uc := NewHackingUsecase(mockRepo, []gateway.TelegramHackingPostGateway{mockGW}, mockLLM)
+		// 待機せずに再試行
+		uc.retryPolicy = RetryPolicy{MaxAttempts: 3}
 		ctx := context.Background()
 
 		// First run: should fail and add to retry queue
-		processedCount1, errs1 := uc.ScrapeAndStore(ctx, 10)
+		processedCount1, _, errs1 := uc.ScrapeAndStore(ctx, 10)
 		if processedCount1 != 0 {
 			t.Errorf("First run: processedCount = %d, want 0", processedCount1)
 		}
@@ -696,8 +1156,12 @@ func TestScrapeAndStore_RetryQueue(t *testing.T) {
 		}
 
 		// Verify retry queue has 1 item
-		if len(uc.retryQueue[0]) != 1 {
-			t.Errorf("Retry queue length = %d, want 1", len(uc.retryQueue[0]))
+		queued := queue.list()
+		if len(queued) != 1 {
+			t.Fatalf("Retry queue length = %d, want 1", len(queued))
+		}
+		if queued[0].Attempts != 1 || queued[0].LastError == "" || queued[0].MessageID != 101 {
+			t.Errorf("Retry post = %+v, want attempts 1 with last error for message 101", queued[0])
 		}
 
 		// Second run: should process retry queue successfully
@@ -705,7 +1169,7 @@ func TestScrapeAndStore_RetryQueue(t *testing.T) {
 			return []*gateway.HackingPost{}, nil // No new posts
 		}
 
-		processedCount2, errs2 := uc.ScrapeAndStore(ctx, 10)
+		processedCount2, _, errs2 := uc.ScrapeAndStore(ctx, 10)
 		if processedCount2 != 1 {
 			t.Errorf("Second run: processedCount = %d, want 1", processedCount2)
 		}
@@ -714,14 +1178,371 @@ func TestScrapeAndStore_RetryQueue(t *testing.T) {
 		}
 
 		// Verify retry queue is now empty
-		if len(uc.retryQueue[0]) != 0 {
-			t.Errorf("Retry queue length after success = %d, want 0", len(uc.retryQueue[0]))
+		if len(queue.list()) != 0 {
+			t.Errorf("Retry queue length after success = %d, want 0", len(queue.list()))
+		}
+	})
+
+	t.Run("posts move to dead letter after max attempts", func(t *testing.T) {
+		queue := newMockRetryQueue()
+		mockRepo := &mockHackingRepository{
+			storeRetryPostFunc:   queue.store,
+			getDueRetryPostsFunc: queue.getDue,
+			deleteRetryPostFunc:  queue.delete,
+		}
+
+		mockLLM := &mockLLMGateway{
+			analyzeAndExtractFunc: func(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error) {
+				return nil, errors.New("permanent error")
+			},
+		}
+
+		first := true
+		mockGW := &mockTelegramHackingPostGateway{
+			channelUsername: "channel1",
+			getPostsFunc: func(ctx context.Context, limit int) ([]*gateway.HackingPost, error) {
+				if !first {
+					return nil, nil
+				}
+				first = false
+				post := createTestHackingPost(101, "0xabc123")
+				post.ChannelUsername = "channel1"
+				return []*gateway.HackingPost{post}, nil
+			},
+		}
+
+		uc := NewHackingUsecase(mockRepo, []gateway.TelegramHackingPostGateway{mockGW}, mockLLM)
+		uc.retryPolicy = RetryPolicy{MaxAttempts: 2}
+		ctx := context.Background()
+
+		uc.ScrapeAndStore(ctx, 10)
+		uc.ScrapeAndStore(ctx, 10)
+
+		queued := queue.list()
+		if len(queued) != 1 {
+			t.Fatalf("Retry queue length = %d, want 1", len(queued))
+		}
+		if queued[0].Status != entity.RetryStatusDead || queued[0].Attempts != 2 {
+			t.Errorf("Retry post status = %s attempts = %d, want dead with 2 attempts", queued[0].Status, queued[0].Attempts)
+		}
+
+		// Dead letter posts are no longer retried
+		processedCount, _, errs := uc.ScrapeAndStore(ctx, 10)
+		if processedCount != 0 || len(errs) != 0 {
+			t.Errorf("Third run: processedCount = %d, errorCount = %d, want 0, 0", processedCount, len(errs))
+		}
+	})
+
+	t.Run("dead letter posts stay dead when fetched again", func(t *testing.T) {
+		queue := newMockRetryQueue()
+		mockRepo := &mockHackingRepository{
+			storeRetryPostFunc:   queue.store,
+			getDueRetryPostsFunc: queue.getDue,
+			deleteRetryPostFunc:  queue.delete,
+		}
+
+		mockLLM := &mockLLMGateway{
+			analyzeAndExtractFunc: func(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error) {
+				return nil, errors.New("permanent error")
+			},
+		}
+
+		uc := NewHackingUsecase(mockRepo, nil, mockLLM)
+		uc.retryPolicy = RetryPolicy{MaxAttempts: 2}
+		ctx := context.Background()
+
+		queue.store(ctx, &entity.RetryPost{ChannelUsername: "channel1", MessageID: 101, Attempts: 2, Status: entity.RetryStatusDead})
+
+		post := createTestHackingPost(101, "0xabc123")
+		post.ChannelUsername = "channel1"
+		var result processResult
+		uc.processAndRecord(ctx, nil, post, &result)
+
+		queued := queue.list()
+		if len(queued) != 1 {
+			t.Fatalf("Retry queue length = %d, want 1", len(queued))
+		}
+		if queued[0].Status != entity.RetryStatusDead || queued[0].Attempts != 3 {
+			t.Errorf("Retry post status = %s attempts = %d, want dead with 3 attempts", queued[0].Status, queued[0].Attempts)
+		}
+	})
+}
+
+func TestScrapeAndStore_Duplicate(t *testing.T) {
+	t.Run("duplicate posts are skipped", func(t *testing.T) {
+		storedRetryPosts := 0
+		mockRepo := &mockHackingRepository{
+			storeInfoFunc: func(ctx context.Context, info *entity.HackingInfo, tagNames []string) (int64, error) {
+				if info.TxHash == "0xdef456" {
+					return 10, repository.ErrDuplicateInfo
+				}
+				return 1, nil
+			},
+			storeRetryPostFunc: func(ctx context.Context, retryPost *entity.RetryPost) error {
+				storedRetryPosts++
+				return nil
+			},
+		}
+
+		mockLLM := &mockLLMGateway{
+			analyzeAndExtractFunc: func(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error) {
+				return &gateway.ExtractedHackingInfo{
+					Protocol: "TestProtocol",
+					Network:  "Ethereum",
+					Amount:   "$1000000",
+					TxHash:   post.TxHash,
+					TagNames: []string{"DeFi"},
+				}, nil
+			},
+		}
+
+		mockGW := &mockTelegramHackingPostGateway{
+			channelUsername: "channel1",
+			getPostsFunc: func(ctx context.Context, limit int) ([]*gateway.HackingPost, error) {
+				return []*gateway.HackingPost{
+					createTestHackingPost(101, "0xabc123"),
+					createTestHackingPost(102, "0xdef456"),
+				}, nil
+			},
+		}
+
+		uc := NewHackingUsecase(mockRepo, []gateway.TelegramHackingPostGateway{mockGW}, mockLLM)
+		ctx := context.Background()
+
+		processedCount, skippedCount, errs := uc.ScrapeAndStore(ctx, 10)
+		if processedCount != 1 {
+			t.Errorf("processedCount = %d, want 1", processedCount)
+		}
+		if skippedCount != 1 {
+			t.Errorf("skippedCount = %d, want 1", skippedCount)
+		}
+		if len(errs) != 0 {
+			t.Errorf("errorCount = %d, want 0", len(errs))
+		}
+
+		// 重複した投稿はリトライキューに追加されない
+		if storedRetryPosts != 0 {
+			t.Errorf("Stored retry posts = %d, want 0", storedRetryPosts)
 		}
 	})
 }
 
 // Usecase側でのLastMessageID更新テストは責務変更により削除しました。
 
+func TestSubscribeNewPosts(t *testing.T) {
+	t.Run("received posts are stored immediately", func(t *testing.T) {
+		var storedTxHashes []string
+		storedLastMessageIDs := make(map[string]int)
+		tagCacheRefreshed := false
+		mockRepo := &mockHackingRepository{
+			storeInfoFunc: func(ctx context.Context, info *entity.HackingInfo, tagNames []string) (int64, error) {
+				storedTxHashes = append(storedTxHashes, info.TxHash)
+				return int64(len(storedTxHashes)), nil
+			},
+			getChannelStatusByUsernameFunc: func(ctx context.Context, username string) (*entity.TelegramChannel, error) {
+				return &entity.TelegramChannel{ChannelUsername: username, LastMessageID: 100}, nil
+			},
+			updateChannelStatusFunc: func(ctx context.Context, channelStatus *entity.TelegramChannel) error {
+				storedLastMessageIDs[channelStatus.ChannelUsername] = channelStatus.LastMessageID
+				return nil
+			},
+			setTagToCacheFunc: func(ctx context.Context) error {
+				tagCacheRefreshed = true
+				return nil
+			},
+		}
+
+		mockLLM := &mockLLMGateway{
+			analyzeAndExtractFunc: func(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error) {
+				return &gateway.ExtractedHackingInfo{Protocol: "TestProtocol", TxHash: post.TxHash, TagNames: []string{"DeFi"}}, nil
+			},
+		}
+
+		var handler func(ctx context.Context, posts []*gateway.HackingPost)
+		mockGW := &mockTelegramHackingPostGateway{
+			channelUsername: "channel1",
+			subscribeFunc: func(ctx context.Context, h func(ctx context.Context, posts []*gateway.HackingPost)) error {
+				handler = h
+				return nil
+			},
+		}
+		failingGW := &mockTelegramHackingPostGateway{
+			channelUsername: "channel2",
+			subscribeFunc: func(ctx context.Context, h func(ctx context.Context, posts []*gateway.HackingPost)) error {
+				return errors.New("resolve failed")
+			},
+		}
+
+		uc := NewHackingUsecase(mockRepo, []gateway.TelegramHackingPostGateway{mockGW, failingGW}, mockLLM)
+		ctx := context.Background()
+
+		// 購読に失敗したチャンネルのみエラー
+		errs := uc.SubscribeNewPosts(ctx)
+		if len(errs) != 1 {
+			t.Fatalf("errs = %v, want 1 error", errs)
+		}
+		if handler == nil {
+			t.Fatal("handler is not subscribed")
+		}
+
+		// 通知を受けた投稿を処理
+		mockGW.SetLastMessageID(102)
+		handler(ctx, []*gateway.HackingPost{
+			createTestHackingPost(101, "0xabc123"),
+			createTestHackingPost(102, "0xdef456"),
+		})
+
+		if !reflect.DeepEqual(storedTxHashes, []string{"0xabc123", "0xdef456"}) {
+			t.Errorf("stored TxHashes = %v", storedTxHashes)
+		}
+		if storedLastMessageIDs["channel1"] != 102 {
+			t.Errorf("stored LastMessageID = %d, want 102", storedLastMessageIDs["channel1"])
+		}
+		if !tagCacheRefreshed {
+			t.Error("tag cache is not refreshed")
+		}
+	})
+}
+
+// ==================== Replay Tests ====================
+
+func TestReplayFailedPost(t *testing.T) {
+	newQueueWithPost := func(t *testing.T, status string) (*mockRetryQueue, int64) {
+		queue := newMockRetryQueue()
+		retryPost, err := newRetryPost("channel1", 101, createTestHackingPost(101, "0xabc123"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		retryPost.Attempts = 6
+		retryPost.Status = status
+		queue.store(context.Background(), retryPost)
+		return queue, retryPost.ID
+	}
+
+	tests := []struct {
+		name         string
+		status       string
+		llmError     error
+		id           int64
+		wantErr      error
+		wantQueueLen int
+	}{
+		{
+			name:         "dead letter post succeeds",
+			status:       entity.RetryStatusDead,
+			wantQueueLen: 0,
+		},
+		{
+			name:         "replay fails again",
+			status:       entity.RetryStatusDead,
+			llmError:     errors.New("still broken"),
+			wantQueueLen: 1,
+		},
+		{
+			name:         "post not found",
+			status:       entity.RetryStatusPending,
+			id:           999,
+			wantErr:      ErrRetryPostNotFound,
+			wantQueueLen: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue, id := newQueueWithPost(t, tt.status)
+			if tt.id != 0 {
+				id = tt.id
+			}
+
+			mockRepo := &mockHackingRepository{
+				storeRetryPostFunc:   queue.store,
+				getRetryPostByIDFunc: queue.getByID,
+				deleteRetryPostFunc:  queue.delete,
+			}
+			mockLLM := &mockLLMGateway{
+				analyzeAndExtractFunc: func(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error) {
+					if tt.llmError != nil {
+						return nil, tt.llmError
+					}
+					return &gateway.ExtractedHackingInfo{Protocol: "TestProtocol", TxHash: post.TxHash}, nil
+				},
+			}
+
+			uc := NewHackingUsecase(mockRepo, nil, mockLLM)
+			err := uc.ReplayFailedPost(context.Background(), id)
+
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("ReplayFailedPost() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (err != nil) != (tt.llmError != nil) {
+				t.Errorf("ReplayFailedPost() error = %v, want error %v", err, tt.llmError != nil)
+			}
+
+			queued := queue.list()
+			if len(queued) != tt.wantQueueLen {
+				t.Fatalf("Retry queue length = %d, want %d", len(queued), tt.wantQueueLen)
+			}
+			if tt.llmError != nil && (queued[0].Attempts != 7 || queued[0].Status != entity.RetryStatusDead) {
+				t.Errorf("Retry post attempts = %d status = %s, want 7 dead", queued[0].Attempts, queued[0].Status)
+			}
+		})
+	}
+}
+
+func TestReplayFailedPosts(t *testing.T) {
+	queue := newMockRetryQueue()
+	for i, txHash := range []string{"0xaaa111", "0xbbb222", "0xccc333"} {
+		retryPost, err := newRetryPost("channel1", 101+i, createTestHackingPost(101+i, txHash))
+		if err != nil {
+			t.Fatal(err)
+		}
+		retryPost.Status = entity.RetryStatusDead
+		queue.store(context.Background(), retryPost)
+	}
+
+	var requestedStatus string
+	mockRepo := &mockHackingRepository{
+		storeInfoFunc: func(ctx context.Context, info *entity.HackingInfo, tagNames []string) (int64, error) {
+			if info.TxHash == "0xbbb222" {
+				return 5, repository.ErrDuplicateInfo
+			}
+			return 1, nil
+		},
+		getRetryPostsFunc: func(ctx context.Context, status string, limit int) ([]*entity.RetryPost, error) {
+			requestedStatus = status
+			var posts []*entity.RetryPost
+			for _, retryPost := range queue.list() {
+				rp := retryPost
+				posts = append(posts, &rp)
+			}
+			return posts, nil
+		},
+		storeRetryPostFunc:  queue.store,
+		deleteRetryPostFunc: queue.delete,
+	}
+	mockLLM := &mockLLMGateway{
+		analyzeAndExtractFunc: func(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error) {
+			if post.TxHash == "0xccc333" {
+				return nil, errors.New("still broken")
+			}
+			return &gateway.ExtractedHackingInfo{Protocol: "TestProtocol", TxHash: post.TxHash}, nil
+		},
+	}
+
+	uc := NewHackingUsecase(mockRepo, nil, mockLLM)
+	processedCount, skippedCount, errs := uc.ReplayFailedPosts(context.Background(), entity.RetryStatusDead, 10)
+
+	if requestedStatus != entity.RetryStatusDead {
+		t.Errorf("requested status = %q, want %q", requestedStatus, entity.RetryStatusDead)
+	}
+	if processedCount != 1 || skippedCount != 1 || len(errs) != 1 {
+		t.Errorf("ReplayFailedPosts() = %d, %d, %d errors, want 1, 1, 1 error", processedCount, skippedCount, len(errs))
+	}
+	if len(queue.list()) != 1 {
+		t.Errorf("Retry queue length = %d, want 1", len(queue.list()))
+	}
+}
+
 // ==================== InitialScrapeAndStore Tests ====================
 
 func TestInitialScrapeAndStore(t *testing.T) {
@@ -766,7 +1587,7 @@ func TestInitialScrapeAndStore(t *testing.T) {
 			},
 			getPostsErrors: []error{nil},
 			processErrors: map[string]error{
-				"0xbbb222": errors.New("gemini error"),
+				"0xbbb222": errors.New("llm error"),
 				"0xddd444": errors.New("store error"),
 			},
 			wantProcessedCount: 3,
@@ -785,7 +1606,7 @@ func TestInitialScrapeAndStore(t *testing.T) {
 				},
 			}
 
-			mockGemini := &mockGeminiGateway{
+			mockLLM := &mockLLMGateway{
 				analyzeAndExtractFunc: func(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error) {
 					if err, exists := tt.processErrors[post.TxHash]; exists {
 						return nil, err
@@ -815,10 +1636,10 @@ func TestInitialScrapeAndStore(t *testing.T) {
 				gateways = append(gateways, mockGW)
 			}
 
-			uc := NewHackingUsecase(mockRepo, gateways, mockGemini)
+			uc := NewHackingUsecase(mockRepo, gateways, mockLLM)
 			ctx := context.Background()
 
-			processedCount, errs := uc.InitialScrapeAndStore(ctx, tt.limit)
+			processedCount, _, errs := uc.InitialScrapeAndStore(ctx, tt.limit)
 
 			if processedCount != tt.wantProcessedCount {
 				t.Errorf("InitialScrapeAndStore() processedCount = %d, want %d", processedCount, tt.wantProcessedCount)
@@ -846,7 +1667,7 @@ func TestScrapeAndStore_Concurrency(t *testing.T) {
 			},
 		}
 
-		mockGemini := &mockGeminiGateway{
+		mockLLM := &mockLLMGateway{
 			analyzeAndExtractFunc: func(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error) {
 				return &gateway.ExtractedHackingInfo{
 					Protocol: "TestProtocol",
@@ -873,10 +1694,10 @@ func TestScrapeAndStore_Concurrency(t *testing.T) {
 			gateways = append(gateways, mockGW)
 		}
 
-		uc := NewHackingUsecase(mockRepo, gateways, mockGemini)
+		uc := NewHackingUsecase(mockRepo, gateways, mockLLM)
 		ctx := context.Background()
 
-		processedCount, errs := uc.ScrapeAndStore(ctx, 10)
+		processedCount, _, errs := uc.ScrapeAndStore(ctx, 10)
 
 		expectedCount := numGateways * postsPerGateway
 		if processedCount != expectedCount {
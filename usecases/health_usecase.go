@@ -0,0 +1,36 @@
+package usecases
+
+import (
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+// サーバーの稼働状況に関するユースケース
+type HealthUsecase struct {
+	telegramConnections map[string]gateway.TelegramConnection
+}
+
+// 新しいHealthUsecaseを生成
+// telegramConnections はアカウント名毎の接続状態
+func NewHealthUsecase(telegramConnections map[string]gateway.TelegramConnection) *HealthUsecase {
+	return &HealthUsecase{telegramConnections: telegramConnections}
+}
+
+// アカウント毎のTelegramクライアントの接続状態を返す
+func (uc *HealthUsecase) TelegramStatuses() map[string]gateway.ConnectionStatus {
+	statuses := make(map[string]gateway.ConnectionStatus, len(uc.telegramConnections))
+	for name, connection := range uc.telegramConnections {
+		statuses[name] = connection.Status()
+	}
+	return statuses
+}
+
+// 全てのアカウントで投稿の取得が正常に行える状態か
+// 一部のアカウントが使用できない場合、そのチャンネルは他のアカウントで取得するが、取得能力が低下している
+func (uc *HealthUsecase) Healthy() bool {
+	for _, status := range uc.TelegramStatuses() {
+		if status.State != gateway.ConnectionReady {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+	"github.com/itout-datetoya/hack-info-timeline/domain/repository"
+)
+
+// 投稿の処理・リトライキュー・隔離した投稿の永続化に必要な操作
+type postProcessorRepository interface {
+	retryQueueRepository
+	quarantineRepository
+	GetRetryPosts(ctx context.Context, status string, limit int) ([]*entity.RetryPost, error)
+	GetRetryPostByID(ctx context.Context, id int64) (*entity.RetryPost, error)
+	GetQuarantinedPosts(ctx context.Context, channelUsername string, status string, limit int) ([]*entity.QuarantinedPost, error)
+}
+
+// 投稿の処理と、リトライキュー・隔離した投稿の管理
+// ハッキング情報・送金情報のユースケースに埋め込み、投稿の種類に依存しない処理を共通化する
+type postProcessor[T any] struct {
+	// ログに出力する投稿の種類（"Hacking Post" など）
+	name        string
+	repo        postProcessorRepository
+	retryPolicy RetryPolicy
+	// パースに失敗した投稿をLLMで抽出する設定（nil の場合は無効）
+	fallback *llmFallback
+	// 単一の投稿を処理
+	process func(ctx context.Context, post *T) error
+	// ログ・エラーに出力する投稿の説明
+	describe func(post *T) string
+	// 投稿のチャンネル名とメッセージID
+	source func(post *T) (channelUsername string, messageID int)
+	// LLMで投稿を抽出
+	extract func(llmGateway gateway.LLMGateway, ctx context.Context, post *gateway.RejectedPost) (*T, float64, error)
+}
+
+// リトライキューの投稿を元の投稿に復元して処理
+func (p *postProcessor[T]) processRetryPosts(ctx context.Context, retryPosts []*entity.RetryPost, result *processResult) {
+	for _, retryPost := range retryPosts {
+		var post T
+		if err := json.Unmarshal([]byte(retryPost.Payload), &post); err != nil {
+			result.addError(fmt.Errorf("failed to unmarshal retry post %d: %w", retryPost.ID, err))
+			continue
+		}
+		p.processAndRecord(ctx, retryPost, &post, result)
+	}
+}
+
+// 投稿を処理し、結果の集計とリトライキューの更新を実行
+// retryPost が nil の場合は新規の投稿として扱い、失敗時のみキューに追加
+func (p *postProcessor[T]) processAndRecord(ctx context.Context, retryPost *entity.RetryPost, post *T, result *processResult) {
+	err := p.process(ctx, post)
+	result.count(p.describe(post), err)
+
+	channelUsername, messageID := p.source(post)
+	if err := p.retryPolicy.record(ctx, p.repo, retryPost, channelUsername, messageID, post, err); err != nil {
+		result.addError(fmt.Errorf("failed to update retry queue for %s: %w", p.describe(post), err))
+	}
+}
+
+// リトライキューの投稿を指定の件数取得
+// status が空の場合は全ての状態の投稿を取得
+func (p *postProcessor[T]) GetFailedPosts(ctx context.Context, status string, limit int) ([]*entity.RetryPost, error) {
+	return p.repo.GetRetryPosts(ctx, status, limit)
+}
+
+// リトライキューの投稿と、復元した元の投稿を取得
+func (p *postProcessor[T]) GetFailedPost(ctx context.Context, id int64) (*entity.RetryPost, *T, error) {
+	retryPost, err := p.repo.GetRetryPostByID(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get retry post: %w", err)
+	}
+	if retryPost == nil {
+		return nil, nil, ErrRetryPostNotFound
+	}
+
+	var post T
+	if err := json.Unmarshal([]byte(retryPost.Payload), &post); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal retry post %d: %w", retryPost.ID, err)
+	}
+
+	return retryPost, &post, nil
+}
+
+// リトライキューの投稿を1件再処理
+// 成功した投稿はキューから削除し、失敗した投稿は試行回数を増やして再登録
+func (p *postProcessor[T]) ReplayFailedPost(ctx context.Context, id int64) error {
+	retryPost, post, err := p.GetFailedPost(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	var result processResult
+	p.processAndRecord(ctx, retryPost, post, &result)
+	if len(result.errs) > 0 {
+		return errors.Join(result.errs...)
+	}
+	if result.skipped > 0 {
+		return repository.ErrDuplicateInfo
+	}
+
+	return nil
+}
+
+// 指定した状態のリトライキューの投稿を指定の件数まで再処理
+// 処理件数、重複によるスキップ件数、エラーを返す
+func (p *postProcessor[T]) ReplayFailedPosts(ctx context.Context, status string, limit int) (int, int, []error) {
+	retryPosts, err := p.repo.GetRetryPosts(ctx, status, limit)
+	if err != nil {
+		return 0, 0, []error{fmt.Errorf("failed to get retry posts: %w", err)}
+	}
+
+	var result processResult
+	p.processRetryPosts(ctx, retryPosts, &result)
+
+	log.Printf("%s: Replay finished. Processed: %d, Skipped: %d, Errors: %d", p.name, result.processed, result.skipped, len(result.errs))
+
+	return result.processed, result.skipped, result.errs
+}
@@ -0,0 +1,184 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+	"github.com/itout-datetoya/hack-info-timeline/domain/repository"
+)
+
+// LLMで抽出した投稿を公開する確信度のデフォルトの閾値
+const DefaultLLMFallbackMinConfidence = 0.7
+
+var (
+	// 指定したIDの隔離した投稿が存在しない
+	ErrQuarantinedPostNotFound = errors.New("quarantined post not found")
+	// 隔離した投稿にLLMによる抽出結果がない
+	ErrQuarantinedPostNotExtracted = errors.New("quarantined post has no extracted post")
+)
+
+// 隔離した投稿の永続化に必要な操作
+type quarantineRepository interface {
+	StoreQuarantinedPost(ctx context.Context, post *entity.QuarantinedPost) error
+	GetQuarantinedPost(ctx context.Context, channelUsername string, messageID int) (*entity.QuarantinedPost, error)
+	GetQuarantinedPostByID(ctx context.Context, id int64) (*entity.QuarantinedPost, error)
+}
+
+// パースに失敗した投稿をLLMで抽出する設定
+type llmFallback struct {
+	llmGateway gateway.LLMGateway
+	// 抽出結果を確認せずに公開する確信度の閾値
+	minConfidence float64
+}
+
+// パースに失敗した投稿を隔離して保存し、公開する抽出結果を返す
+// extract が nil の場合（フォールバックが無効）は隔離のみ行い、nil を返す
+// 確信度が閾値未満の抽出結果は needs_review として保存し、nil を返す
+// 保存・抽出に失敗しても投稿の取得は続行するため、エラーはログに出力するのみ
+func quarantineRejectedPost[T any](ctx context.Context, repo quarantineRepository, post *gateway.RejectedPost, minConfidence float64, extract func(ctx context.Context, post *gateway.RejectedPost) (*T, float64, error)) *T {
+	// 編集の同期などで同じ投稿を再取得した場合、抽出・公開済みの投稿は再処理しない
+	existing, err := repo.GetQuarantinedPost(ctx, post.ChannelUsername, post.MessageID)
+	if err != nil {
+		log.Printf("Failed to get quarantined post %d in %s: %v", post.MessageID, post.ChannelUsername, err)
+	}
+	if existing != nil && existing.Status != entity.QuarantineStatusQuarantined &&
+		existing.Text == post.Text && existing.ReplyToText == post.ReplyToText {
+		return nil
+	}
+
+	quarantined := &entity.QuarantinedPost{
+		ChannelUsername: post.ChannelUsername,
+		MessageID:       post.MessageID,
+		Text:            post.Text,
+		ReplyToText:     post.ReplyToText,
+		Error:           post.Error,
+		ReportTime:      post.ReportTime,
+		Status:          entity.QuarantineStatusQuarantined,
+	}
+
+	var extracted *T
+	if extract != nil {
+		var confidence float64
+		extracted, confidence, err = extract(ctx, post)
+		switch {
+		case errors.Is(err, gateway.ErrPostNotExtracted):
+			extracted = nil
+		case err != nil:
+			log.Printf("Failed to extract post %d in %s with LLM: %v", post.MessageID, post.ChannelUsername, err)
+			extracted = nil
+		default:
+			payload, err := json.Marshal(extracted)
+			if err != nil {
+				log.Printf("Failed to marshal extracted post %d in %s: %v", post.MessageID, post.ChannelUsername, err)
+				extracted = nil
+				break
+			}
+			quarantined.Extracted = string(payload)
+			quarantined.Confidence = confidence
+			quarantined.Status = entity.QuarantineStatusPublished
+			if confidence < minConfidence {
+				quarantined.Status = entity.QuarantineStatusNeedsReview
+			}
+		}
+	}
+
+	if err := repo.StoreQuarantinedPost(ctx, quarantined); err != nil {
+		log.Printf("Failed to quarantine post %d in %s: %v", post.MessageID, post.ChannelUsername, err)
+	}
+
+	if quarantined.Status != entity.QuarantineStatusPublished {
+		if quarantined.Status == entity.QuarantineStatusNeedsReview {
+			log.Printf("Post %d in %s needs review (confidence: %.2f)", post.MessageID, post.ChannelUsername, quarantined.Confidence)
+		}
+		return nil
+	}
+	return extracted
+}
+
+// LLMの抽出結果を確認して公開する投稿を取得
+func getExtractedPost[T any](ctx context.Context, repo quarantineRepository, id int64) (*entity.QuarantinedPost, *T, error) {
+	quarantined, err := repo.GetQuarantinedPostByID(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get quarantined post: %w", err)
+	}
+	if quarantined == nil {
+		return nil, nil, ErrQuarantinedPostNotFound
+	}
+	if quarantined.Extracted == "" {
+		return nil, nil, ErrQuarantinedPostNotExtracted
+	}
+
+	var post T
+	if err := json.Unmarshal([]byte(quarantined.Extracted), &post); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal quarantined post %d: %w", quarantined.ID, err)
+	}
+
+	return quarantined, &post, nil
+}
+
+// パースに失敗した投稿をLLMで抽出するフォールバックを有効化
+// 確信度が minConfidence 以上の抽出結果は公開し、未満の抽出結果は確認を待つ
+func (p *postProcessor[T]) EnableLLMFallback(llmGateway gateway.LLMGateway, minConfidence float64) {
+	p.fallback = &llmFallback{llmGateway: llmGateway, minConfidence: minConfidence}
+}
+
+// パースに失敗した投稿を隔離
+// フォールバックが有効な場合、LLMで抽出し、確信度が閾値以上の投稿を処理
+func (p *postProcessor[T]) quarantinePost(ctx context.Context, rejected *gateway.RejectedPost) {
+	var minConfidence float64
+	var extract func(ctx context.Context, post *gateway.RejectedPost) (*T, float64, error)
+	if fallback := p.fallback; fallback != nil {
+		minConfidence = fallback.minConfidence
+		extract = func(ctx context.Context, post *gateway.RejectedPost) (*T, float64, error) {
+			return p.extract(fallback.llmGateway, ctx, post)
+		}
+	}
+
+	post := quarantineRejectedPost(ctx, p.repo, rejected, minConfidence, extract)
+	if post == nil {
+		return
+	}
+
+	// 失敗した投稿はリトライキューに登録
+	var result processResult
+	p.processAndRecord(ctx, nil, post, &result)
+	channelUsername, messageID := p.source(post)
+	log.Printf("%s: Published post %d in %s extracted by LLM. Processed: %d, Skipped: %d, Errors: %d",
+		p.name, messageID, channelUsername, result.processed, result.skipped, len(result.errs))
+}
+
+// 隔離した投稿を新しい順に指定の件数取得
+// channelUsername・status が空の場合は絞り込まない
+func (p *postProcessor[T]) GetQuarantinedPosts(ctx context.Context, channelUsername string, status string, limit int) ([]*entity.QuarantinedPost, error) {
+	return p.repo.GetQuarantinedPosts(ctx, channelUsername, status, limit)
+}
+
+// 確認を待つLLMの抽出結果を公開
+// 処理に失敗した投稿はリトライキューに登録
+func (p *postProcessor[T]) PublishQuarantinedPost(ctx context.Context, id int64) error {
+	quarantined, post, err := getExtractedPost[T](ctx, p.repo, id)
+	if err != nil {
+		return err
+	}
+
+	var result processResult
+	p.processAndRecord(ctx, nil, post, &result)
+	if len(result.errs) > 0 {
+		return errors.Join(result.errs...)
+	}
+
+	quarantined.Status = entity.QuarantineStatusPublished
+	if err := p.repo.StoreQuarantinedPost(ctx, quarantined); err != nil {
+		return fmt.Errorf("failed to update quarantined post %d: %w", quarantined.ID, err)
+	}
+	if result.skipped > 0 {
+		return repository.ErrDuplicateInfo
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+func TestTransferScrapeAndStore_QuarantinesRejectedPosts(t *testing.T) {
+	var quarantined []*entity.QuarantinedPost
+	var storedMessageIDs []int
+	mockRepo := &mockTransferRepository{
+		storeQuarantinedPostFunc: func(ctx context.Context, post *entity.QuarantinedPost) error {
+			quarantined = append(quarantined, post)
+			return nil
+		},
+		storeInfoFunc: func(ctx context.Context, info *entity.TransferInfo, tagNames []string) (int64, error) {
+			storedMessageIDs = append(storedMessageIDs, info.MessageID)
+			return int64(info.MessageID), nil
+		},
+	}
+
+	reportTime := time.Unix(1700000000, 0)
+	mockGW := &mockTelegramTransferPostGateway{channelUsername: "channel1"}
+	mockGW.getPostsFunc = func(ctx context.Context, limit int) ([]*gateway.TransferPost, error) {
+		// パースに失敗した投稿は handler に渡され、結果には含まれない
+		mockGW.reject(ctx, &gateway.RejectedPost{
+			ChannelUsername: "channel1",
+			MessageID:       2,
+			Text:            "1,000 #USDT moved from A to B",
+			ReportTime:      reportTime,
+			Error:           "TransferPost pattern not found in message",
+		})
+		return []*gateway.TransferPost{createTestTransferPost(1, "USDT", "100")}, nil
+	}
+
+	uc := NewTransferUsecase(mockRepo, []gateway.TelegramTransferPostGateway{mockGW})
+	processed, _, errs := uc.ScrapeAndStore(context.Background(), 100)
+
+	if processed != 1 || len(errs) != 0 {
+		t.Errorf("processed = %d, errs = %v, want 1, no errors", processed, errs)
+	}
+	if len(storedMessageIDs) != 1 || storedMessageIDs[0] != 1 {
+		t.Errorf("stored message IDs = %v, want [1]", storedMessageIDs)
+	}
+	if len(quarantined) != 1 {
+		t.Fatalf("quarantined %d posts, want 1", len(quarantined))
+	}
+	got := quarantined[0]
+	if got.ChannelUsername != "channel1" || got.MessageID != 2 || got.Text != "1,000 #USDT moved from A to B" ||
+		got.Error != "TransferPost pattern not found in message" || !got.ReportTime.Equal(reportTime) {
+		t.Errorf("quarantined post = %+v", got)
+	}
+}
+
+func TestTransferQuarantinePost_LLMFallback(t *testing.T) {
+	rejected := &gateway.RejectedPost{
+		ChannelUsername: "channel1",
+		MessageID:       2,
+		Text:            "1,000 #USDT moved from A to B",
+		ReportTime:      time.Unix(1700000000, 0),
+		Error:           "TransferPost pattern not found in message",
+	}
+
+	tests := []struct {
+		name        string
+		confidence  float64
+		extractErr  error
+		wantStatus  string
+		wantStored  bool
+		wantPayload bool
+	}{
+		{name: "high confidence is published", confidence: 0.9, wantStatus: entity.QuarantineStatusPublished, wantStored: true, wantPayload: true},
+		{name: "low confidence needs review", confidence: 0.5, wantStatus: entity.QuarantineStatusNeedsReview, wantPayload: true},
+		{name: "not extracted stays quarantined", extractErr: gateway.ErrPostNotExtracted, wantStatus: entity.QuarantineStatusQuarantined},
+		{name: "llm error stays quarantined", extractErr: errors.New("llm unavailable"), wantStatus: entity.QuarantineStatusQuarantined},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var quarantined []*entity.QuarantinedPost
+			var storedInfos []*entity.TransferInfo
+			mockRepo := &mockTransferRepository{
+				storeQuarantinedPostFunc: func(ctx context.Context, post *entity.QuarantinedPost) error {
+					quarantined = append(quarantined, post)
+					return nil
+				},
+				storeInfoFunc: func(ctx context.Context, info *entity.TransferInfo, tagNames []string) (int64, error) {
+					storedInfos = append(storedInfos, info)
+					return 1, nil
+				},
+			}
+			mockLLM := &mockLLMGateway{
+				extractTransferPostFunc: func(ctx context.Context, post *gateway.RejectedPost) (*gateway.TransferPost, float64, error) {
+					if tt.extractErr != nil {
+						return nil, 0, tt.extractErr
+					}
+					extracted := createTestTransferPost(post.MessageID, "USDT", "1000")
+					extracted.ChannelUsername = post.ChannelUsername
+					return extracted, tt.confidence, nil
+				},
+			}
+
+			mockGW := &mockTelegramTransferPostGateway{channelUsername: "channel1"}
+			uc := NewTransferUsecase(mockRepo, []gateway.TelegramTransferPostGateway{mockGW})
+			uc.EnableLLMFallback(mockLLM, DefaultLLMFallbackMinConfidence)
+			mockGW.reject(context.Background(), rejected)
+
+			if len(quarantined) != 1 {
+				t.Fatalf("quarantined %d posts, want 1", len(quarantined))
+			}
+			got := quarantined[0]
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", got.Status, tt.wantStatus)
+			}
+			if (got.Extracted != "") != tt.wantPayload || got.Confidence != tt.confidence {
+				t.Errorf("extracted = %q, confidence = %v", got.Extracted, got.Confidence)
+			}
+			if got.Error != rejected.Error {
+				t.Errorf("error = %q, want %q", got.Error, rejected.Error)
+			}
+			if stored := len(storedInfos) == 1 && storedInfos[0].MessageID == 2; stored != tt.wantStored {
+				t.Errorf("stored infos = %v, want stored = %v", storedInfos, tt.wantStored)
+			}
+		})
+	}
+}
+
+func TestTransferQuarantinePost_SkipsExtractedPost(t *testing.T) {
+	text := "1,000 #USDT moved from A to B"
+	var storeCount, extractCount int
+	mockRepo := &mockTransferRepository{
+		getQuarantinedPostFunc: func(ctx context.Context, channelUsername string, messageID int) (*entity.QuarantinedPost, error) {
+			return &entity.QuarantinedPost{ChannelUsername: channelUsername, MessageID: messageID, Text: text, Status: entity.QuarantineStatusPublished}, nil
+		},
+		storeQuarantinedPostFunc: func(ctx context.Context, post *entity.QuarantinedPost) error {
+			storeCount++
+			return nil
+		},
+	}
+	mockLLM := &mockLLMGateway{
+		extractTransferPostFunc: func(ctx context.Context, post *gateway.RejectedPost) (*gateway.TransferPost, float64, error) {
+			extractCount++
+			return createTestTransferPost(post.MessageID, "USDT", "1000"), 0.9, nil
+		},
+	}
+
+	mockGW := &mockTelegramTransferPostGateway{channelUsername: "channel1"}
+	uc := NewTransferUsecase(mockRepo, []gateway.TelegramTransferPostGateway{mockGW})
+	uc.EnableLLMFallback(mockLLM, DefaultLLMFallbackMinConfidence)
+
+	// 編集の同期で再取得した投稿は再処理しない
+	mockGW.reject(context.Background(), &gateway.RejectedPost{ChannelUsername: "channel1", MessageID: 2, Text: text})
+	if storeCount != 0 || extractCount != 0 {
+		t.Errorf("store = %d, extract = %d, want 0, 0", storeCount, extractCount)
+	}
+
+	// 編集された投稿は再度抽出
+	mockGW.reject(context.Background(), &gateway.RejectedPost{ChannelUsername: "channel1", MessageID: 2, Text: text + " (edited)"})
+	if storeCount != 1 || extractCount != 1 {
+		t.Errorf("store = %d, extract = %d, want 1, 1", storeCount, extractCount)
+	}
+}
+
+func TestHackingPublishQuarantinedPost(t *testing.T) {
+	var quarantined []*entity.QuarantinedPost
+	var storedInfos []*entity.HackingInfo
+	mockRepo := &mockHackingRepository{
+		getQuarantinedPostByIDFunc: func(ctx context.Context, id int64) (*entity.QuarantinedPost, error) {
+			switch id {
+			case 1:
+				return &entity.QuarantinedPost{
+					ID:              1,
+					ChannelUsername: "channel1",
+					MessageID:       10,
+					Status:          entity.QuarantineStatusNeedsReview,
+					Extracted:       `{"Text":"Test post","Network":"Ethereum","Amount":"100 ETH","TxHash":"0xabc","MessageID":10,"ChannelUsername":"channel1"}`,
+					Confidence:      0.4,
+				}, nil
+			case 2:
+				return &entity.QuarantinedPost{ID: 2, Status: entity.QuarantineStatusQuarantined}, nil
+			}
+			return nil, nil
+		},
+		storeQuarantinedPostFunc: func(ctx context.Context, post *entity.QuarantinedPost) error {
+			quarantined = append(quarantined, post)
+			return nil
+		},
+		storeInfoFunc: func(ctx context.Context, info *entity.HackingInfo, tagNames []string) (int64, error) {
+			storedInfos = append(storedInfos, info)
+			return 1, nil
+		},
+	}
+	mockLLM := &mockLLMGateway{
+		analyzeAndExtractFunc: func(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error) {
+			return &gateway.ExtractedHackingInfo{Protocol: "TestProtocol", Network: post.Network, Amount: post.Amount, TxHash: post.TxHash}, nil
+		},
+	}
+	uc := NewHackingUsecase(mockRepo, nil, mockLLM)
+
+	if err := uc.PublishQuarantinedPost(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(storedInfos) != 1 || storedInfos[0].TxHash != "0xabc" || storedInfos[0].MessageID != 10 {
+		t.Errorf("stored infos = %+v", storedInfos)
+	}
+	if len(quarantined) != 1 || quarantined[0].Status != entity.QuarantineStatusPublished {
+		t.Errorf("quarantined posts = %+v, want status published", quarantined)
+	}
+
+	if err := uc.PublishQuarantinedPost(context.Background(), 2); !errors.Is(err, ErrQuarantinedPostNotExtracted) {
+		t.Errorf("err = %v, want ErrQuarantinedPostNotExtracted", err)
+	}
+	if err := uc.PublishQuarantinedPost(context.Background(), 3); !errors.Is(err, ErrQuarantinedPostNotFound) {
+		t.Errorf("err = %v, want ErrQuarantinedPostNotFound", err)
+	}
+}
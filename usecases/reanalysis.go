@@ -0,0 +1,204 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+// 再分析時に1回で取得するハッキング情報のデフォルトの件数
+const defaultReanalysisBatchSize = 50
+
+// 再分析の設定
+type ReanalysisOptions struct {
+	// 再分析の対象とするプロンプトのバージョン。空の場合は全ての情報が対象
+	PromptVersion string
+	// 1回で取得するハッキング情報の件数
+	BatchSize int
+	// 再分析する情報の最大件数。0 の場合は全件
+	Limit int
+	// true の場合は更新せずに差分のみを報告
+	DryRun bool
+	// 指定したIDより大きいIDの情報から再分析。前回の報告の NextAfterID を指定すると続きから再分析
+	AfterID int64
+	// true の場合は分析結果のキャッシュを参照。デフォルトではLLMを呼び出して再分析
+	UseCache bool
+}
+
+// 再分析で変更されたハッキング情報の差分
+type ReanalysisDiff struct {
+	InfoID             int64
+	ChannelUsername    string
+	MessageID          int
+	ProtocolBefore     string
+	ProtocolAfter      string
+	AttackVectorBefore string
+	AttackVectorAfter  string
+	AddedTags          []string
+	RemovedTags        []string
+}
+
+// 再分析の結果
+type ReanalysisReport struct {
+	DryRun    bool
+	Processed int
+	Changed   int
+	Failed    int
+	Diffs     []*ReanalysisDiff
+	Errors    []string
+	// 件数の上限で中断した場合に、続きから再分析するための AfterID。全件を処理した場合は 0
+	NextAfterID int64
+}
+
+// 保存済みのハッキング情報を投稿本文から再分析し、分析結果を更新
+// 分析内容が変わった情報は差分を報告に記録
+func (uc *HackingUsecase) ReanalyzeInfos(ctx context.Context, opts ReanalysisOptions) (*ReanalysisReport, error) {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultReanalysisBatchSize
+	}
+
+	if !opts.UseCache {
+		ctx = gateway.WithAnalysisCacheBypass(ctx)
+	}
+
+	report := &ReanalysisReport{DryRun: opts.DryRun, Diffs: []*ReanalysisDiff{}, Errors: []string{}}
+	updated := false
+
+	afterID := opts.AfterID
+	finished := false
+	for opts.Limit <= 0 || report.Processed+report.Failed < opts.Limit {
+		limit := batchSize
+		if opts.Limit > 0 {
+			limit = min(limit, opts.Limit-report.Processed-report.Failed)
+		}
+
+		infos, err := uc.repo.GetInfosForReanalysis(ctx, afterID, opts.PromptVersion, limit)
+		if err != nil {
+			return report, fmt.Errorf("failed to get infos for reanalysis: %w", err)
+		}
+		if len(infos) == 0 {
+			finished = true
+			break
+		}
+
+		for _, info := range infos {
+			afterID = info.ID
+
+			changed, err := uc.reanalyzeInfo(ctx, info, opts.DryRun, report)
+			if err != nil {
+				report.Failed++
+				report.Errors = append(report.Errors, fmt.Sprintf("info %d: %v", info.ID, err))
+				continue
+			}
+			report.Processed++
+			updated = updated || (changed && !opts.DryRun)
+		}
+
+		// 指定した件数より少なければ、残りの情報はない
+		if len(infos) < limit {
+			finished = true
+			break
+		}
+	}
+	if !finished {
+		report.NextAfterID = afterID
+	}
+
+	// 新しいタグをキャッシュに反映
+	if updated {
+		if err := uc.SetTagToCache(ctx); err != nil {
+			log.Printf("Failed to refresh tag cache after reanalysis: %v", err)
+		}
+	}
+
+	log.Printf("Reanalysis finished: processed %d, changed %d, failed %d", report.Processed, report.Changed, report.Failed)
+	return report, nil
+}
+
+// ハッキング情報を1件再分析
+// 分析内容または分析の出所が変わった場合に更新し、更新した場合は true を返す
+func (uc *HackingUsecase) reanalyzeInfo(ctx context.Context, info *entity.HackingInfo, dryRun bool, report *ReanalysisReport) (bool, error) {
+	post := &gateway.HackingPost{
+		Text:            info.PostText,
+		ReplyToText:     info.ReplyToText,
+		Network:         info.Network,
+		Amount:          info.Amount,
+		TxHash:          info.TxHash,
+		ReportTime:      info.ReportTime,
+		MessageID:       info.MessageID,
+		ChannelUsername: info.ChannelUsername,
+	}
+
+	extractedInfo, err := uc.llmGateway.AnalyzeAndExtract(ctx, post)
+	if err != nil {
+		return false, fmt.Errorf("llm analysis failed: %w", err)
+	}
+
+	diff := diffAnalysis(info, extractedInfo)
+	if diff != nil {
+		report.Changed++
+		report.Diffs = append(report.Diffs, diff)
+	}
+
+	provenanceChanged := info.PromptVersion != extractedInfo.PromptVersion ||
+		info.LLMProvider != extractedInfo.Provider ||
+		info.LLMModel != extractedInfo.Model
+	if diff == nil && !provenanceChanged {
+		return false, nil
+	}
+	if dryRun {
+		return true, nil
+	}
+
+	info.Protocol = extractedInfo.Protocol
+	info.AttackVector = extractedInfo.AttackVector
+	info.PromptVersion = extractedInfo.PromptVersion
+	info.LLMProvider = extractedInfo.Provider
+	info.LLMModel = extractedInfo.Model
+	if err := uc.repo.UpdateInfoAnalysis(ctx, info, extractedInfo.TagNames); err != nil {
+		return false, fmt.Errorf("database update failed: %w", err)
+	}
+	return true, nil
+}
+
+// 保存済みの分析結果と再分析の結果を比較
+// 差分がない場合は nil を返す
+func diffAnalysis(info *entity.HackingInfo, extractedInfo *gateway.ExtractedHackingInfo) *ReanalysisDiff {
+	diff := &ReanalysisDiff{
+		InfoID:             info.ID,
+		ChannelUsername:    info.ChannelUsername,
+		MessageID:          info.MessageID,
+		ProtocolBefore:     info.Protocol,
+		ProtocolAfter:      extractedInfo.Protocol,
+		AttackVectorBefore: info.AttackVector,
+		AttackVectorAfter:  extractedInfo.AttackVector,
+		AddedTags:          []string{},
+		RemovedTags:        []string{},
+	}
+
+	var tagsBefore []string
+	for _, tag := range info.Tags {
+		tagsBefore = append(tagsBefore, tag.Name)
+	}
+	for _, tag := range extractedInfo.TagNames {
+		if !slices.Contains(tagsBefore, tag) && !slices.Contains(diff.AddedTags, tag) {
+			diff.AddedTags = append(diff.AddedTags, tag)
+		}
+	}
+	for _, tag := range tagsBefore {
+		if !slices.Contains(extractedInfo.TagNames, tag) {
+			diff.RemovedTags = append(diff.RemovedTags, tag)
+		}
+	}
+
+	if diff.ProtocolBefore == diff.ProtocolAfter && diff.AttackVectorBefore == diff.AttackVectorAfter &&
+		len(diff.AddedTags) == 0 && len(diff.RemovedTags) == 0 {
+		return nil
+	}
+	return diff
+}
@@ -0,0 +1,225 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+// 再分析の対象となるハッキング情報を生成
+func createReanalysisInfos() []*entity.HackingInfo {
+	return []*entity.HackingInfo{
+		{
+			ID: 1, Protocol: "Resupply", AttackVector: entity.AttackVectorOther,
+			PromptVersion: "hacking-v1", LLMProvider: "gemini", LLMModel: "gemini-2.5-flash-lite",
+			PostText: "resupply", Tags: []*entity.Tag{{ID: 1, Name: "resupply"}, {ID: 2, Name: "ETH"}},
+		},
+		{
+			ID: 2, Protocol: "Onyx Protocol", AttackVector: entity.AttackVectorReentrancy,
+			PromptVersion: "hacking-v0", LLMProvider: "gemini", LLMModel: "gemini-2.5-flash-lite",
+			PostText: "onyx", Tags: []*entity.Tag{{ID: 3, Name: "onyx"}},
+		},
+		{
+			ID: 3, Protocol: "Sonne Finance", AttackVector: entity.AttackVectorOther,
+			PromptVersion: "hacking-v1", LLMProvider: "gemini", LLMModel: "gemini-2.5-flash-lite",
+			PostText: "broken",
+		},
+	}
+}
+
+func TestReanalyzeInfos(t *testing.T) {
+	tests := []struct {
+		name          string
+		dryRun        bool
+		limit         int
+		wantProcessed int
+		wantChanged   int
+		wantFailed    int
+		wantUpdated   []int64
+		// 件数の上限で中断した場合は続きのID
+		wantNextAfterID int64
+	}{
+		{
+			name:          "update changed infos",
+			wantProcessed: 2,
+			wantChanged:   1,
+			wantFailed:    1,
+			// プロトコルが変わった情報と、出所のみ変わった情報を更新
+			wantUpdated: []int64{1, 2},
+		},
+		{
+			name:          "dry run",
+			dryRun:        true,
+			wantProcessed: 2,
+			wantChanged:   1,
+			wantFailed:    1,
+		},
+		{
+			name:          "limit",
+			limit:         1,
+			wantProcessed: 1,
+			wantChanged:   1,
+			wantUpdated:   []int64{1},
+
+			wantNextAfterID: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			infos := createReanalysisInfos()
+			var updated []int64
+			var updatedTags []string
+
+			mockRepo := &mockHackingRepository{
+				getInfosForReanalysisFunc: func(ctx context.Context, afterID int64, promptVersion string, limit int) ([]*entity.HackingInfo, error) {
+					// バッチサイズ2で取得
+					var batch []*entity.HackingInfo
+					for _, info := range infos {
+						if info.ID > afterID && len(batch) < min(limit, 2) {
+							batch = append(batch, info)
+						}
+					}
+					return batch, nil
+				},
+				updateInfoAnalysisFunc: func(ctx context.Context, info *entity.HackingInfo, tagNames []string) error {
+					updated = append(updated, info.ID)
+					if info.ID == 1 {
+						updatedTags = tagNames
+					}
+					return nil
+				},
+			}
+			mockLLM := &mockLLMGateway{
+				analyzeAndExtractFunc: func(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error) {
+					switch post.Text {
+					case "resupply":
+						return &gateway.ExtractedHackingInfo{
+							Protocol: "Resupply.fi", AttackVector: entity.AttackVectorFirstDeposit,
+							TagNames:      []string{"wstUSR", "resupply"},
+							PromptVersion: "test", Provider: "gemini", Model: "gemini-2.5-flash-lite",
+						}, nil
+					case "onyx":
+						return &gateway.ExtractedHackingInfo{
+							Protocol: "Onyx Protocol", AttackVector: entity.AttackVectorReentrancy,
+							TagNames:      []string{"onyx"},
+							PromptVersion: "test", Provider: "gemini", Model: "gemini-2.5-flash-lite",
+						}, nil
+					default:
+						return nil, errors.New("invalid analysis response")
+					}
+				},
+			}
+
+			uc := NewHackingUsecase(mockRepo, nil, mockLLM)
+			report, err := uc.ReanalyzeInfos(context.Background(), ReanalysisOptions{BatchSize: 2, Limit: tt.limit, DryRun: tt.dryRun})
+			if err != nil {
+				t.Fatalf("ReanalyzeInfos() unexpected error = %v", err)
+			}
+
+			if report.Processed != tt.wantProcessed || report.Changed != tt.wantChanged || report.Failed != tt.wantFailed {
+				t.Errorf("report = processed %d, changed %d, failed %d, want %d, %d, %d",
+					report.Processed, report.Changed, report.Failed, tt.wantProcessed, tt.wantChanged, tt.wantFailed)
+			}
+			if report.NextAfterID != tt.wantNextAfterID {
+				t.Errorf("NextAfterID = %d, want %d", report.NextAfterID, tt.wantNextAfterID)
+			}
+			if !reflect.DeepEqual(updated, tt.wantUpdated) {
+				t.Errorf("updated infos = %v, want %v", updated, tt.wantUpdated)
+			}
+			if len(report.Errors) != tt.wantFailed {
+				t.Errorf("report errors = %v, want %d errors", report.Errors, tt.wantFailed)
+			}
+
+			diff := report.Diffs[0]
+			if diff.InfoID != 1 || diff.ProtocolBefore != "Resupply" || diff.ProtocolAfter != "Resupply.fi" ||
+				diff.AttackVectorAfter != entity.AttackVectorFirstDeposit {
+				t.Errorf("diff = %+v", diff)
+			}
+			if !reflect.DeepEqual(diff.AddedTags, []string{"wstUSR"}) || !reflect.DeepEqual(diff.RemovedTags, []string{"ETH"}) {
+				t.Errorf("diff tags = added %v, removed %v, want [wstUSR], [ETH]", diff.AddedTags, diff.RemovedTags)
+			}
+			if !tt.dryRun && !reflect.DeepEqual(updatedTags, []string{"wstUSR", "resupply"}) {
+				t.Errorf("updated tags = %v, want [wstUSR resupply]", updatedTags)
+			}
+		})
+	}
+}
+
+func TestReanalyzeInfos_CacheAndCursor(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       ReanalysisOptions
+		wantBypass bool
+		wantIDs    []int64
+	}{
+		{
+			// モデルの変更後もキャッシュの結果を返さないよう、デフォルトではキャッシュを参照しない
+			name:       "bypass cache by default",
+			opts:       ReanalysisOptions{},
+			wantBypass: true,
+			wantIDs:    []int64{1, 2, 3},
+		},
+		{
+			name:    "use cache",
+			opts:    ReanalysisOptions{UseCache: true},
+			wantIDs: []int64{1, 2, 3},
+		},
+		{
+			name:       "continue after id",
+			opts:       ReanalysisOptions{AfterID: 1},
+			wantBypass: true,
+			wantIDs:    []int64{2, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			infos := createReanalysisInfos()
+			mockRepo := &mockHackingRepository{
+				getInfosForReanalysisFunc: func(ctx context.Context, afterID int64, promptVersion string, limit int) ([]*entity.HackingInfo, error) {
+					var batch []*entity.HackingInfo
+					for _, info := range infos {
+						if info.ID > afterID && len(batch) < limit {
+							batch = append(batch, info)
+						}
+					}
+					return batch, nil
+				},
+				updateInfoAnalysisFunc: func(ctx context.Context, info *entity.HackingInfo, tagNames []string) error {
+					return nil
+				},
+			}
+			var analyzed []int64
+			mockLLM := &mockLLMGateway{
+				analyzeAndExtractFunc: func(ctx context.Context, post *gateway.HackingPost) (*gateway.ExtractedHackingInfo, error) {
+					if gateway.IsAnalysisCacheBypassed(ctx) != tt.wantBypass {
+						t.Errorf("cache bypassed = %v, want %v", !tt.wantBypass, tt.wantBypass)
+					}
+					for _, info := range infos {
+						if info.PostText == post.Text {
+							analyzed = append(analyzed, info.ID)
+						}
+					}
+					return &gateway.ExtractedHackingInfo{Protocol: "Protocol", PromptVersion: "test"}, nil
+				},
+			}
+
+			uc := NewHackingUsecase(mockRepo, nil, mockLLM)
+			report, err := uc.ReanalyzeInfos(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatalf("ReanalyzeInfos() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(analyzed, tt.wantIDs) {
+				t.Errorf("analyzed infos = %v, want %v", analyzed, tt.wantIDs)
+			}
+			if report.NextAfterID != 0 {
+				t.Errorf("NextAfterID = %d, want 0", report.NextAfterID)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
+	"github.com/itout-datetoya/hack-info-timeline/domain/repository"
+)
+
+// 1回の処理でリトライキューから取得する投稿の最大件数
+const retryBatchSize = 100
+
+// 指定したリトライキューの投稿が存在しない場合のエラー
+var ErrRetryPostNotFound = errors.New("retry post not found")
+
+// 処理に失敗した投稿の再試行ポリシー
+type RetryPolicy struct {
+	// 最大試行回数。超えた投稿はデッドレター状態に移行
+	MaxAttempts int
+	// 初回失敗後の待機時間
+	BaseDelay time.Duration
+	// 待機時間の上限
+	MaxDelay time.Duration
+}
+
+// デフォルトの再試行ポリシー
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 6,
+	BaseDelay:   5 * time.Minute,
+	MaxDelay:    6 * time.Hour,
+}
+
+// 試行回数に応じた次回試行までの待機時間を指数バックオフで計算
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// リトライキューの永続化に必要な操作
+type retryQueueRepository interface {
+	StoreRetryPost(ctx context.Context, retryPost *entity.RetryPost) error
+	DeleteRetryPost(ctx context.Context, id int64) error
+}
+
+// 投稿をリトライキューに保存する形式に変換
+func newRetryPost(channelUsername string, messageID int, post any) (*entity.RetryPost, error) {
+	payload, err := json.Marshal(post)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal retry payload: %w", err)
+	}
+	return &entity.RetryPost{
+		ChannelUsername: channelUsername,
+		MessageID:       messageID,
+		Payload:         string(payload),
+		Status:          entity.RetryStatusPending,
+	}, nil
+}
+
+// 投稿の処理結果に応じてリトライキューを更新
+// 成功・重複の場合はキューから削除し、失敗の場合は試行回数を増やして次回試行時刻を設定
+// retryPost が nil の新規投稿は、失敗した場合のみキューに追加
+func (p RetryPolicy) record(ctx context.Context, repo retryQueueRepository, retryPost *entity.RetryPost, channelUsername string, messageID int, post any, processErr error) error {
+	succeeded := processErr == nil || errors.Is(processErr, repository.ErrDuplicateInfo)
+
+	if retryPost == nil {
+		if succeeded {
+			return nil
+		}
+		newPost, err := newRetryPost(channelUsername, messageID, post)
+		if err != nil {
+			return err
+		}
+		retryPost = newPost
+	}
+
+	if succeeded {
+		return repo.DeleteRetryPost(ctx, retryPost.ID)
+	}
+
+	retryPost.Attempts++
+	retryPost.LastError = processErr.Error()
+	retryPost.NextAttemptAt = time.Now().Add(p.Backoff(retryPost.Attempts))
+	retryPost.Status = entity.RetryStatusPending
+
+	// 最大試行回数に達した投稿はデッドレター状態に移行
+	if retryPost.Attempts >= p.MaxAttempts {
+		retryPost.Status = entity.RetryStatusDead
+		log.Printf("Retry post moved to dead letter: channel %s, message %d, attempts %d: %v",
+			retryPost.ChannelUsername, retryPost.MessageID, retryPost.Attempts, processErr)
+	}
+
+	return repo.StoreRetryPost(ctx, retryPost)
+}
+
+// 投稿の処理結果の集計
+// 並行して処理する場合に備えて排他制御を行う
+type processResult struct {
+	mu        sync.Mutex
+	processed int
+	skipped   int
+	errs      []error
+}
+
+// 処理結果を成功、重複によるスキップ、エラーのいずれかとして集計
+func (r *processResult) count(target string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case err == nil:
+		r.processed++
+	case errors.Is(err, repository.ErrDuplicateInfo):
+		// 保存済みの投稿はスキップ
+		r.skipped++
+	default:
+		r.errs = append(r.errs, fmt.Errorf("failed to process %s: %w", target, err))
+	}
+}
+
+// 投稿の処理以外で発生したエラーを記録
+func (r *processResult) addError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errs = append(r.errs, err)
+}
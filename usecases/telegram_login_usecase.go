@@ -0,0 +1,74 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+)
+
+// 指定したアカウントが設定されていない場合のエラー
+var ErrTelegramAccountNotFound = errors.New("telegram account not found")
+
+// ログインするTelegramアカウント
+type TelegramLoginAccount struct {
+	Name  string
+	Login gateway.TelegramLogin
+	// 電話番号が指定されなかった場合に使用
+	Phone string
+}
+
+// Telegramアカウントへのログインに関するユースケース
+type TelegramLoginUsecase struct {
+	accounts []TelegramLoginAccount
+}
+
+// 新しいTelegramLoginUsecaseを生成
+// アカウント名を指定しなかった場合は、先頭のアカウントにログイン
+func NewTelegramLoginUsecase(accounts []TelegramLoginAccount) *TelegramLoginUsecase {
+	return &TelegramLoginUsecase{accounts: accounts}
+}
+
+// 認証コードを送信してログインを開始
+func (uc *TelegramLoginUsecase) StartLogin(ctx context.Context, accountName string, phone string) error {
+	account, err := uc.account(accountName)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(phone) == "" {
+		phone = account.Phone
+	}
+	if strings.TrimSpace(phone) == "" {
+		return fmt.Errorf("%w: phone number is required", gateway.ErrTelegramInvalidCredential)
+	}
+	return account.Login.StartLogin(ctx, phone)
+}
+
+// 認証コード、または2段階認証のパスワードを送信
+// パスワードが追加で必要な場合は true を返す
+func (uc *TelegramLoginUsecase) Submit(ctx context.Context, accountName string, code string, password string) (bool, error) {
+	account, err := uc.account(accountName)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case password != "":
+		return false, account.Login.SubmitPassword(ctx, password)
+	case strings.TrimSpace(code) != "":
+		return account.Login.SubmitCode(ctx, code)
+	default:
+		return false, fmt.Errorf("%w: code or password is required", gateway.ErrTelegramInvalidCredential)
+	}
+}
+
+func (uc *TelegramLoginUsecase) account(name string) (*TelegramLoginAccount, error) {
+	for i := range uc.accounts {
+		if name == "" || uc.accounts[i].Name == name {
+			return &uc.accounts[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrTelegramAccountNotFound, name)
+}
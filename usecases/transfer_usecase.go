@@ -2,26 +2,74 @@ package usecases
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
 	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
 	"github.com/itout-datetoya/hack-info-timeline/domain/repository"
 	"log"
 	"sync"
+	"time"
 )
 
 // 送金情報に関するユースケース
 type TransferUsecase struct {
 	repo             repository.TransferRepository
 	telegramGateways []gateway.TelegramTransferPostGateway
+	// USD建ての価格の取得元（nil の場合はUSD建ての金額のみ変換）
+	priceSource gateway.PriceSource
+	// 編集・削除の同期を直列化し、同じ編集を重複して記録しないようにする
+	editSyncMu sync.Mutex
+	// 投稿の処理と、リトライキュー・隔離した投稿の管理
+	postProcessor[gateway.TransferPost]
+	// 過去の投稿の取得（バックフィル）
+	backfiller[gateway.TransferPost, gateway.TelegramTransferPostGateway]
+	// 投稿の編集・削除の同期
+	editSyncer[gateway.TelegramTransferPostGateway]
 }
 
 // 新しいTransferUsecaseを生成
 func NewTransferUsecase(repo repository.TransferRepository, telegramGateways []gateway.TelegramTransferPostGateway) *TransferUsecase {
-	return &TransferUsecase{
+	uc := &TransferUsecase{
 		repo:             repo,
 		telegramGateways: telegramGateways,
 	}
+	uc.postProcessor = postProcessor[gateway.TransferPost]{
+		name:        "Transfer Post",
+		repo:        repo,
+		retryPolicy: DefaultRetryPolicy,
+		process:     uc.processSinglePost,
+		describe: func(post *gateway.TransferPost) string {
+			return fmt.Sprintf("post %s %s Transfer", post.Amount, post.Token)
+		},
+		source: func(post *gateway.TransferPost) (string, int) {
+			return post.ChannelUsername, post.MessageID
+		},
+		extract: gateway.LLMGateway.ExtractTransferPost,
+	}
+	uc.backfiller = backfiller[gateway.TransferPost, gateway.TelegramTransferPostGateway]{
+		name:     "Transfer Post",
+		repo:     repo,
+		gateways: telegramGateways,
+		position: func(post *gateway.TransferPost) (int, time.Time) {
+			return post.MessageID, post.ReportTime
+		},
+		process: func(ctx context.Context, post *gateway.TransferPost, result *processResult) {
+			uc.processAndRecord(ctx, nil, post, result)
+		},
+		refresh: uc.SetTagToCache,
+	}
+	uc.editSyncer = editSyncer[gateway.TelegramTransferPostGateway]{
+		name:        "Transfer Post",
+		gateways:    telegramGateways,
+		syncChannel: uc.syncChannelEdits,
+		refresh:     uc.SetTagToCache,
+	}
+	// パースに失敗した投稿は隔離して保存
+	for _, gw := range telegramGateways {
+		gw.SetRejectedPostHandler(uc.quarantinePost)
+	}
+	return uc
 }
 
 // 最新タイムライン情報を指定件数取得
@@ -58,6 +106,10 @@ func (uc *TransferUsecase) SetLastMessageIDToGateway(ctx context.Context) error
 			}
 		} else {
 			gw.SetLastMessageID(channelStatus.LastMessageID)
+			// 保存済みのチャンネル情報を使い、チャンネル名の解決を省略
+			if peer := channelPeerOf(channelStatus); peer != nil {
+				gw.SetChannelPeer(peer)
+			}
 		}
 	}
 
@@ -66,18 +118,18 @@ func (uc *TransferUsecase) SetLastMessageIDToGateway(ctx context.Context) error
 
 func (uc *TransferUsecase) StoreLastMessageID(ctx context.Context) error {
 	for _, gw := range uc.telegramGateways {
-		newChannelStatus := entity.TelegramChannel{ChannelUsername: gw.ChannelUsername(), LastMessageID: gw.LastMessageID()}
 		channelStatus, err := uc.repo.GetChannelStatusByUsername(ctx, gw.ChannelUsername())
 		if err != nil {
 			return fmt.Errorf("failed to get channel status: %w", err)
 		}
+		newChannelStatus := buildChannelStatus(gw.ChannelUsername(), gw.LastMessageID(), gw.ChannelPeer(), channelStatus)
 		if channelStatus == nil {
-			err = uc.repo.StoreChannelStatus(ctx, &newChannelStatus)
+			err = uc.repo.StoreChannelStatus(ctx, newChannelStatus)
 			if err != nil {
 				return fmt.Errorf("failed to store channel status: %w", err)
 			}
 		} else {
-			err = uc.repo.UpdateChannelStatus(ctx, &newChannelStatus)
+			err = uc.repo.UpdateChannelStatus(ctx, newChannelStatus)
 			if err != nil {
 				return fmt.Errorf("failed to update channel status: %w", err)
 			}
@@ -88,20 +140,20 @@ func (uc *TransferUsecase) StoreLastMessageID(ctx context.Context) error {
 }
 
 // Telegramから投稿を取得し、DBに保存
-func (uc *TransferUsecase) ScrapeAndStore(ctx context.Context, limit int) (int, []error) {
+// 処理件数、重複によるスキップ件数、エラーを返す
+func (uc *TransferUsecase) ScrapeAndStore(ctx context.Context, limit int) (int, int, []error) {
 	// 全ての新しい投稿を取得
 	var wg sync.WaitGroup
 	errsChan := make(chan error, len(uc.telegramGateways))
-	var posts [][]*gateway.TransferPost
+	posts := make([][]*gateway.TransferPost, len(uc.telegramGateways))
 
 	for i, gw := range uc.telegramGateways {
 		wg.Add(1)
-		posts = append(posts, []*gateway.TransferPost{})
 		go func(gw gateway.TelegramTransferPostGateway) {
 			defer wg.Done()
 			newPosts, err := gw.GetPosts(ctx, limit)
 			if err != nil {
-				errsChan <- fmt.Errorf("failed to get posts from telegram: %w", err)
+				errsChan <- fmt.Errorf("failed to get posts from %s: %w", gw.ChannelUsername(), err)
 				return
 			}
 			posts[i] = newPosts
@@ -116,19 +168,21 @@ func (uc *TransferUsecase) ScrapeAndStore(ctx context.Context, limit int) (int,
 		getPostsErrors = append(getPostsErrors, err)
 	}
 
-	if len(getPostsErrors) != 0 {
-		return 0, getPostsErrors
-	}
+	// 取得に失敗したチャンネルは次回に再取得し、他のチャンネルの投稿の処理は続行
+	result := processResult{errs: getPostsErrors}
 
-	var allErrors []error
-	var allProcessedCount int
+	for i, gw := range uc.telegramGateways {
+		// 再試行時刻を過ぎたリトライキューの投稿を先に処理
+		retryPosts, err := uc.repo.GetDueRetryPosts(ctx, gw.ChannelUsername(), time.Now(), retryBatchSize)
+		if err != nil {
+			result.addError(fmt.Errorf("failed to get retry posts: %w", err))
+		}
 
-	for i := range uc.telegramGateways {
-		if len(posts[i]) == 0 {
+		if len(posts[i]) == 0 && len(retryPosts) == 0 {
 			continue
 		}
 
-		errsChan = make(chan error, len(posts[i]))
+		uc.processRetryPosts(ctx, retryPosts, &result)
 
 		for _, post := range posts[i] {
 			wg.Add(1)
@@ -136,29 +190,51 @@ func (uc *TransferUsecase) ScrapeAndStore(ctx context.Context, limit int) (int,
 				defer wg.Done()
 
 				// 個別の投稿を処理するヘルパー関数
-				err := uc.processSinglePost(ctx, p)
-				if err != nil {
-					// エラーが発生したらチャネルに送信
-					errsChan <- fmt.Errorf("failed to process post %s %s Transfer: %w", p.Amount, p.Token, err)
-				}
+				uc.processAndRecord(ctx, nil, p, &result)
 			}(post)
 		}
 
 		wg.Wait()
-		close(errsChan)
+	}
 
-		var errors []error
-		for err := range errsChan {
-			errors = append(errors, err)
+	log.Printf("Transfer Post: Scraping finished. Processed: %d, Skipped: %d, Errors: %d", result.processed, result.skipped, len(result.errs))
+
+	return result.processed, result.skipped, result.errs
+}
+
+// 各チャンネルの新しい投稿の通知を購読し、受信した投稿を即時に処理
+// 購読に失敗したチャンネルはポーリングでのみ取得される
+// ctx には購読を続ける間有効なコンテキストを指定
+func (uc *TransferUsecase) SubscribeNewPosts(ctx context.Context) []error {
+	var errs []error
+	for _, gw := range uc.telegramGateways {
+		if err := gw.SubscribeNewPosts(ctx, uc.processNewPosts); err != nil {
+			errs = append(errs, fmt.Errorf("failed to subscribe to new posts of %s: %w", gw.ChannelUsername(), err))
 		}
+	}
+	return errs
+}
 
-		allErrors = append(allErrors, errors...)
-		allProcessedCount = allProcessedCount + len(posts[i]) - len(errors)
+// 通知を受けて取得した投稿を処理し、最後に取得した投稿のIDを保存
+func (uc *TransferUsecase) processNewPosts(ctx context.Context, posts []*gateway.TransferPost) {
+	var result processResult
+	for _, post := range posts {
+		uc.processAndRecord(ctx, nil, post, &result)
 	}
 
-	log.Printf("Transfer Post: Scraping finished. Processed: %d, Errors: %d", allProcessedCount, len(allErrors))
+	log.Printf("Transfer Post: Realtime ingestion finished. Processed: %d, Skipped: %d, Errors: %d", result.processed, result.skipped, len(result.errs))
+	for _, err := range result.errs {
+		log.Printf("%v", err)
+	}
 
-	return allProcessedCount, allErrors
+	if err := uc.StoreLastMessageID(ctx); err != nil {
+		log.Printf("%v", err)
+	}
+	if result.processed > 0 {
+		if err := uc.SetTagToCache(ctx); err != nil {
+			log.Printf("%v", err)
+		}
+	}
 }
 
 // 単一の投稿を処理するヘルパー関数
@@ -166,16 +242,22 @@ func (uc *TransferUsecase) processSinglePost(ctx context.Context, post *gateway.
 	log.Printf("Processing post: %s %s Transfer", post.Amount, post.Token)
 
 	infoToStore := &entity.TransferInfo{
-		Token:      post.Token,
-		Amount:     post.Amount,
-		From:       post.From,
-		To:         post.To,
-		ReportTime: post.ReportTime,
-		MessageID:  post.MessageID,
+		Token:           post.Token,
+		Amount:          post.Amount,
+		From:            post.From,
+		To:              post.To,
+		ReportTime:      post.ReportTime,
+		MessageID:       post.MessageID,
+		ChannelUsername: post.ChannelUsername,
 	}
+	uc.setNormalizedAmount(ctx, infoToStore)
 
 	// DBに保存
-	_, err := uc.repo.StoreInfo(ctx, infoToStore, post.TagNames)
+	infoID, err := uc.repo.StoreInfo(ctx, infoToStore, post.TagNames)
+	if errors.Is(err, repository.ErrDuplicateInfo) {
+		log.Printf("Skipped duplicate %s %s Transfer (existing ID: %d)", post.Amount, post.Token, infoID)
+		return err
+	}
 	if err != nil {
 		return fmt.Errorf("database store failed: %w", err)
 	}
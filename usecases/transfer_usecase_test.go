@@ -9,6 +9,7 @@ import (
 
 	"github.com/itout-datetoya/hack-info-timeline/domain/entity"
 	"github.com/itout-datetoya/hack-info-timeline/domain/gateway"
+	"github.com/itout-datetoya/hack-info-timeline/domain/repository"
 )
 
 // ==================== Mock Implementations ====================
@@ -23,6 +24,24 @@ type mockTransferRepository struct {
 	storeChannelStatusFunc         func(ctx context.Context, channelStatus *entity.TelegramChannel) error
 	updateChannelStatusFunc        func(ctx context.Context, channelStatus *entity.TelegramChannel) error
 	getChannelStatusByUsernameFunc func(ctx context.Context, username string) (*entity.TelegramChannel, error)
+	storeRetryPostFunc             func(ctx context.Context, retryPost *entity.RetryPost) error
+	getDueRetryPostsFunc           func(ctx context.Context, channelUsername string, now time.Time, limit int) ([]*entity.RetryPost, error)
+	getRetryPostsFunc              func(ctx context.Context, status string, limit int) ([]*entity.RetryPost, error)
+	getRetryPostByIDFunc           func(ctx context.Context, id int64) (*entity.RetryPost, error)
+	deleteRetryPostFunc            func(ctx context.Context, id int64) error
+	getInfoByIDFunc                func(ctx context.Context, id int64) (*entity.TransferInfo, error)
+	getRecentInfosByChannelFunc    func(ctx context.Context, channelUsername string, limit int) ([]*entity.TransferInfo, error)
+	updateInfoFromEditFunc         func(ctx context.Context, info *entity.TransferInfo, tagNames []string) error
+	markInfoDeletedFunc            func(ctx context.Context, id int64) error
+	getBackfillFunc                func(ctx context.Context, channelUsername string) (*entity.ChannelBackfill, error)
+	storeBackfillFunc              func(ctx context.Context, backfill *entity.ChannelBackfill) error
+	getBackfillsFunc               func(ctx context.Context) ([]*entity.ChannelBackfill, error)
+	storeQuarantinedPostFunc       func(ctx context.Context, post *entity.QuarantinedPost) error
+	getQuarantinedPostsFunc        func(ctx context.Context, channelUsername string, status string, limit int) ([]*entity.QuarantinedPost, error)
+	getQuarantinedPostFunc         func(ctx context.Context, channelUsername string, messageID int) (*entity.QuarantinedPost, error)
+	getQuarantinedPostByIDFunc     func(ctx context.Context, id int64) (*entity.QuarantinedPost, error)
+	getInfosWithoutAmountUSDFunc   func(ctx context.Context, afterID int64, limit int) ([]*entity.TransferInfo, error)
+	updateInfoAmountFunc           func(ctx context.Context, info *entity.TransferInfo) error
 }
 
 func (m *mockTransferRepository) GetInfosByTagNames(ctx context.Context, tagNames []string, infoNumber int) ([]*entity.TransferInfo, error) {
@@ -81,12 +100,144 @@ func (m *mockTransferRepository) GetChannelStatusByUsername(ctx context.Context,
 	return nil, nil
 }
 
+func (m *mockTransferRepository) StoreRetryPost(ctx context.Context, retryPost *entity.RetryPost) error {
+	if m.storeRetryPostFunc != nil {
+		return m.storeRetryPostFunc(ctx, retryPost)
+	}
+	return nil
+}
+
+func (m *mockTransferRepository) GetDueRetryPosts(ctx context.Context, channelUsername string, now time.Time, limit int) ([]*entity.RetryPost, error) {
+	if m.getDueRetryPostsFunc != nil {
+		return m.getDueRetryPostsFunc(ctx, channelUsername, now, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockTransferRepository) GetRetryPosts(ctx context.Context, status string, limit int) ([]*entity.RetryPost, error) {
+	if m.getRetryPostsFunc != nil {
+		return m.getRetryPostsFunc(ctx, status, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockTransferRepository) GetRetryPostByID(ctx context.Context, id int64) (*entity.RetryPost, error) {
+	if m.getRetryPostByIDFunc != nil {
+		return m.getRetryPostByIDFunc(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *mockTransferRepository) DeleteRetryPost(ctx context.Context, id int64) error {
+	if m.deleteRetryPostFunc != nil {
+		return m.deleteRetryPostFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *mockTransferRepository) GetInfoByID(ctx context.Context, id int64) (*entity.TransferInfo, error) {
+	if m.getInfoByIDFunc != nil {
+		return m.getInfoByIDFunc(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *mockTransferRepository) GetRecentInfosByChannel(ctx context.Context, channelUsername string, limit int) ([]*entity.TransferInfo, error) {
+	if m.getRecentInfosByChannelFunc != nil {
+		return m.getRecentInfosByChannelFunc(ctx, channelUsername, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockTransferRepository) UpdateInfoFromEdit(ctx context.Context, info *entity.TransferInfo, tagNames []string) error {
+	if m.updateInfoFromEditFunc != nil {
+		return m.updateInfoFromEditFunc(ctx, info, tagNames)
+	}
+	return nil
+}
+
+func (m *mockTransferRepository) MarkInfoDeleted(ctx context.Context, id int64) error {
+	if m.markInfoDeletedFunc != nil {
+		return m.markInfoDeletedFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *mockTransferRepository) GetBackfill(ctx context.Context, channelUsername string) (*entity.ChannelBackfill, error) {
+	if m.getBackfillFunc != nil {
+		return m.getBackfillFunc(ctx, channelUsername)
+	}
+	return nil, nil
+}
+
+func (m *mockTransferRepository) StoreBackfill(ctx context.Context, backfill *entity.ChannelBackfill) error {
+	if m.storeBackfillFunc != nil {
+		return m.storeBackfillFunc(ctx, backfill)
+	}
+	return nil
+}
+
+func (m *mockTransferRepository) GetBackfills(ctx context.Context) ([]*entity.ChannelBackfill, error) {
+	if m.getBackfillsFunc != nil {
+		return m.getBackfillsFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *mockTransferRepository) StoreQuarantinedPost(ctx context.Context, post *entity.QuarantinedPost) error {
+	if m.storeQuarantinedPostFunc != nil {
+		return m.storeQuarantinedPostFunc(ctx, post)
+	}
+	return nil
+}
+
+func (m *mockTransferRepository) GetQuarantinedPosts(ctx context.Context, channelUsername string, status string, limit int) ([]*entity.QuarantinedPost, error) {
+	if m.getQuarantinedPostsFunc != nil {
+		return m.getQuarantinedPostsFunc(ctx, channelUsername, status, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockTransferRepository) GetQuarantinedPost(ctx context.Context, channelUsername string, messageID int) (*entity.QuarantinedPost, error) {
+	if m.getQuarantinedPostFunc != nil {
+		return m.getQuarantinedPostFunc(ctx, channelUsername, messageID)
+	}
+	return nil, nil
+}
+
+func (m *mockTransferRepository) GetQuarantinedPostByID(ctx context.Context, id int64) (*entity.QuarantinedPost, error) {
+	if m.getQuarantinedPostByIDFunc != nil {
+		return m.getQuarantinedPostByIDFunc(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *mockTransferRepository) GetInfosWithoutAmountUSD(ctx context.Context, afterID int64, limit int) ([]*entity.TransferInfo, error) {
+	if m.getInfosWithoutAmountUSDFunc != nil {
+		return m.getInfosWithoutAmountUSDFunc(ctx, afterID, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockTransferRepository) UpdateInfoAmount(ctx context.Context, info *entity.TransferInfo) error {
+	if m.updateInfoAmountFunc != nil {
+		return m.updateInfoAmountFunc(ctx, info)
+	}
+	return nil
+}
+
 // mockTelegramTransferPostGateway は TelegramTransferPostGateway インターフェースのモック実装
 type mockTelegramTransferPostGateway struct {
-	channelUsername string
-	lastMessageID   int
-	getPostsFunc    func(ctx context.Context, limit int) ([]*gateway.TransferPost, error)
-	mu              sync.Mutex
+	channelUsername          string
+	lastMessageID            int
+	channelPeer              *gateway.ChannelPeer
+	getPostsFunc             func(ctx context.Context, limit int) ([]*gateway.TransferPost, error)
+	subscribeFunc            func(ctx context.Context, handler func(ctx context.Context, posts []*gateway.TransferPost)) error
+	getPostsByMessageIDsFunc func(ctx context.Context, messageIDs []int) ([]*gateway.TransferPost, []int, error)
+	subscribeChangesFunc     func(ctx context.Context, handler func(ctx context.Context)) error
+	getPostsBeforeFunc       func(ctx context.Context, maxID int, limit int) ([]*gateway.TransferPost, *gateway.HistoryPage, error)
+	rejectedPostHandler      gateway.RejectedPostHandler
+	mu                       sync.Mutex
 }
 
 func (m *mockTelegramTransferPostGateway) SetLastMessageID(lastMessageID int) {
@@ -105,6 +256,18 @@ func (m *mockTelegramTransferPostGateway) ChannelUsername() string {
 	return m.channelUsername
 }
 
+func (m *mockTelegramTransferPostGateway) SetChannelPeer(peer *gateway.ChannelPeer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.channelPeer = peer
+}
+
+func (m *mockTelegramTransferPostGateway) ChannelPeer() *gateway.ChannelPeer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.channelPeer
+}
+
 func (m *mockTelegramTransferPostGateway) GetPosts(ctx context.Context, limit int) ([]*gateway.TransferPost, error) {
 	if m.getPostsFunc != nil {
 		return m.getPostsFunc(ctx, limit)
@@ -112,6 +275,50 @@ func (m *mockTelegramTransferPostGateway) GetPosts(ctx context.Context, limit in
 	return nil, nil
 }
 
+func (m *mockTelegramTransferPostGateway) SubscribeNewPosts(ctx context.Context, handler func(ctx context.Context, posts []*gateway.TransferPost)) error {
+	if m.subscribeFunc != nil {
+		return m.subscribeFunc(ctx, handler)
+	}
+	return nil
+}
+
+func (m *mockTelegramTransferPostGateway) GetPostsByMessageIDs(ctx context.Context, messageIDs []int) ([]*gateway.TransferPost, []int, error) {
+	if m.getPostsByMessageIDsFunc != nil {
+		return m.getPostsByMessageIDsFunc(ctx, messageIDs)
+	}
+	return nil, nil, nil
+}
+
+func (m *mockTelegramTransferPostGateway) SubscribeChanges(ctx context.Context, handler func(ctx context.Context)) error {
+	if m.subscribeChangesFunc != nil {
+		return m.subscribeChangesFunc(ctx, handler)
+	}
+	return nil
+}
+
+func (m *mockTelegramTransferPostGateway) GetPostsBefore(ctx context.Context, maxID int, limit int) ([]*gateway.TransferPost, *gateway.HistoryPage, error) {
+	if m.getPostsBeforeFunc != nil {
+		return m.getPostsBeforeFunc(ctx, maxID, limit)
+	}
+	return nil, &gateway.HistoryPage{}, nil
+}
+
+func (m *mockTelegramTransferPostGateway) SetRejectedPostHandler(handler gateway.RejectedPostHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rejectedPostHandler = handler
+}
+
+// パースに失敗した投稿を設定された handler に渡す
+func (m *mockTelegramTransferPostGateway) reject(ctx context.Context, post *gateway.RejectedPost) {
+	m.mu.Lock()
+	handler := m.rejectedPostHandler
+	m.mu.Unlock()
+	if handler != nil {
+		handler(ctx, post)
+	}
+}
+
 // ==================== Test Helper Functions ====================
 
 func createTestTransferPost(messageID int, token, amount string) *gateway.TransferPost {
@@ -513,6 +720,22 @@ func TestTransferScrapeAndStore(t *testing.T) {
 			wantProcessedCount: 0,
 			wantErrorCount:     1,
 		},
+		{
+			name:        "get posts error does not block other channels",
+			limit:       10,
+			numGateways: 2,
+			postsPerGateway: [][]*gateway.TransferPost{
+				nil,
+				{
+					createTestTransferPost(201, "USDC", "1000"),
+					createTestTransferPost(202, "USDT", "2000"),
+				},
+			},
+			getPostsErrors:     []error{errors.New("FLOOD_WAIT"), nil},
+			processErrors:      map[string]error{},
+			wantProcessedCount: 2,
+			wantErrorCount:     1,
+		},
 		{
 			name:               "no posts to process",
 			limit:              10,
@@ -555,7 +778,7 @@ func TestTransferScrapeAndStore(t *testing.T) {
 			uc := NewTransferUsecase(mockRepo, gateways)
 			ctx := context.Background()
 
-			processedCount, errs := uc.ScrapeAndStore(ctx, tt.limit)
+			processedCount, _, errs := uc.ScrapeAndStore(ctx, tt.limit)
 
 			if processedCount != tt.wantProcessedCount {
 				t.Errorf("ScrapeAndStore() processedCount = %d, want %d", processedCount, tt.wantProcessedCount)
@@ -571,8 +794,193 @@ func TestTransferScrapeAndStore(t *testing.T) {
 	}
 }
 
+func TestTransferScrapeAndStore_RetryQueue(t *testing.T) {
+	t.Run("failed posts are retried from the queue", func(t *testing.T) {
+		queue := newMockRetryQueue()
+		failing := true
+		mockRepo := &mockTransferRepository{
+			storeInfoFunc: func(ctx context.Context, info *entity.TransferInfo, tagNames []string) (int64, error) {
+				if failing {
+					return 0, errors.New("database unavailable")
+				}
+				return 1, nil
+			},
+			storeRetryPostFunc:   queue.store,
+			getDueRetryPostsFunc: queue.getDue,
+			deleteRetryPostFunc:  queue.delete,
+		}
+
+		first := true
+		mockGW := &mockTelegramTransferPostGateway{
+			channelUsername: "channel1",
+			getPostsFunc: func(ctx context.Context, limit int) ([]*gateway.TransferPost, error) {
+				if !first {
+					return nil, nil
+				}
+				first = false
+				post := createTestTransferPost(101, "USDC", "1000000")
+				post.ChannelUsername = "channel1"
+				return []*gateway.TransferPost{post}, nil
+			},
+		}
+
+		uc := NewTransferUsecase(mockRepo, []gateway.TelegramTransferPostGateway{mockGW})
+		// 待機せずに再試行
+		uc.retryPolicy = RetryPolicy{MaxAttempts: 3}
+		ctx := context.Background()
+
+		processedCount, _, errs := uc.ScrapeAndStore(ctx, 10)
+		if processedCount != 0 || len(errs) != 1 {
+			t.Errorf("First run: processedCount = %d, errorCount = %d, want 0, 1", processedCount, len(errs))
+		}
+		if len(queue.list()) != 1 {
+			t.Fatalf("Retry queue length = %d, want 1", len(queue.list()))
+		}
+
+		failing = false
+		processedCount, _, errs = uc.ScrapeAndStore(ctx, 10)
+		if processedCount != 1 || len(errs) != 0 {
+			t.Errorf("Second run: processedCount = %d, errorCount = %d, want 1, 0", processedCount, len(errs))
+		}
+		if len(queue.list()) != 0 {
+			t.Errorf("Retry queue length after success = %d, want 0", len(queue.list()))
+		}
+	})
+
+	t.Run("failed post is replayed by id", func(t *testing.T) {
+		queue := newMockRetryQueue()
+		failing := true
+		mockRepo := &mockTransferRepository{
+			storeInfoFunc: func(ctx context.Context, info *entity.TransferInfo, tagNames []string) (int64, error) {
+				if failing {
+					return 0, errors.New("database unavailable")
+				}
+				return 1, nil
+			},
+			storeRetryPostFunc:   queue.store,
+			getRetryPostByIDFunc: queue.getByID,
+			deleteRetryPostFunc:  queue.delete,
+		}
+
+		uc := NewTransferUsecase(mockRepo, nil)
+		ctx := context.Background()
+
+		post := createTestTransferPost(101, "USDC", "1000000")
+		post.ChannelUsername = "channel1"
+		var result processResult
+		uc.processAndRecord(ctx, nil, post, &result)
+		retryPosts := queue.list()
+		if len(retryPosts) != 1 || retryPosts[0].ChannelUsername != "channel1" || retryPosts[0].MessageID != 101 {
+			t.Fatalf("Retry queue = %+v, want post 101 in channel1", retryPosts)
+		}
+
+		if err := uc.ReplayFailedPost(ctx, retryPosts[0].ID); err == nil {
+			t.Error("ReplayFailedPost() error = nil, want error")
+		}
+		if retryPosts := queue.list(); len(retryPosts) != 1 || retryPosts[0].Attempts != 2 {
+			t.Errorf("Retry queue after failure = %+v, want 2 attempts", retryPosts)
+		}
+
+		failing = false
+		if err := uc.ReplayFailedPost(ctx, retryPosts[0].ID); err != nil {
+			t.Errorf("ReplayFailedPost() error = %v", err)
+		}
+		if len(queue.list()) != 0 {
+			t.Errorf("Retry queue length after success = %d, want 0", len(queue.list()))
+		}
+	})
+}
+
+func TestTransferScrapeAndStore_Duplicate(t *testing.T) {
+	t.Run("duplicate posts are skipped", func(t *testing.T) {
+		mockRepo := &mockTransferRepository{
+			storeInfoFunc: func(ctx context.Context, info *entity.TransferInfo, tagNames []string) (int64, error) {
+				if info.MessageID == 102 {
+					return 10, repository.ErrDuplicateInfo
+				}
+				return 1, nil
+			},
+		}
+
+		mockGW := &mockTelegramTransferPostGateway{
+			channelUsername: "channel1",
+			getPostsFunc: func(ctx context.Context, limit int) ([]*gateway.TransferPost, error) {
+				return []*gateway.TransferPost{
+					createTestTransferPost(101, "USDC", "1000000"),
+					createTestTransferPost(102, "ETH", "500000"),
+					createTestTransferPost(103, "DAI", "2000000"),
+				}, nil
+			},
+		}
+
+		uc := NewTransferUsecase(mockRepo, []gateway.TelegramTransferPostGateway{mockGW})
+		ctx := context.Background()
+
+		processedCount, skippedCount, errs := uc.ScrapeAndStore(ctx, 10)
+		if processedCount != 2 {
+			t.Errorf("processedCount = %d, want 2", processedCount)
+		}
+		if skippedCount != 1 {
+			t.Errorf("skippedCount = %d, want 1", skippedCount)
+		}
+		if len(errs) != 0 {
+			t.Errorf("errorCount = %d, want 0", len(errs))
+		}
+	})
+}
+
 // Usecase側でのLastMessageID更新テストは責務変更により削除しました。
 
+func TestTransferSubscribeNewPosts(t *testing.T) {
+	t.Run("received posts are stored immediately", func(t *testing.T) {
+		var storedMessageIDs []int
+		storedLastMessageIDs := make(map[string]int)
+		mockRepo := &mockTransferRepository{
+			storeInfoFunc: func(ctx context.Context, info *entity.TransferInfo, tagNames []string) (int64, error) {
+				storedMessageIDs = append(storedMessageIDs, info.MessageID)
+				return int64(len(storedMessageIDs)), nil
+			},
+			getChannelStatusByUsernameFunc: func(ctx context.Context, username string) (*entity.TelegramChannel, error) {
+				return &entity.TelegramChannel{ChannelUsername: username, LastMessageID: 100}, nil
+			},
+			updateChannelStatusFunc: func(ctx context.Context, channelStatus *entity.TelegramChannel) error {
+				storedLastMessageIDs[channelStatus.ChannelUsername] = channelStatus.LastMessageID
+				return nil
+			},
+		}
+
+		var handler func(ctx context.Context, posts []*gateway.TransferPost)
+		mockGW := &mockTelegramTransferPostGateway{
+			channelUsername: "channel1",
+			subscribeFunc: func(ctx context.Context, h func(ctx context.Context, posts []*gateway.TransferPost)) error {
+				handler = h
+				return nil
+			},
+		}
+
+		uc := NewTransferUsecase(mockRepo, []gateway.TelegramTransferPostGateway{mockGW})
+		ctx := context.Background()
+
+		if errs := uc.SubscribeNewPosts(ctx); len(errs) != 0 {
+			t.Fatalf("errs = %v, want none", errs)
+		}
+		if handler == nil {
+			t.Fatal("handler is not subscribed")
+		}
+
+		// 通知を受けた投稿を処理
+		mockGW.SetLastMessageID(101)
+		handler(ctx, []*gateway.TransferPost{createTestTransferPost(101, "USDC", "1000000")})
+
+		if len(storedMessageIDs) != 1 || storedMessageIDs[0] != 101 {
+			t.Errorf("stored MessageIDs = %v, want [101]", storedMessageIDs)
+		}
+		if storedLastMessageIDs["channel1"] != 101 {
+			t.Errorf("stored LastMessageID = %d, want 101", storedLastMessageIDs["channel1"])
+		}
+	})
+}
+
 // ==================== Concurrency Tests ====================
 
 func TestTransferScrapeAndStore_Concurrency(t *testing.T) {
@@ -606,7 +1014,7 @@ func TestTransferScrapeAndStore_Concurrency(t *testing.T) {
 		uc := NewTransferUsecase(mockRepo, gateways)
 		ctx := context.Background()
 
-		processedCount, errs := uc.ScrapeAndStore(ctx, 10)
+		processedCount, _, errs := uc.ScrapeAndStore(ctx, 10)
 
 		expectedCount := numGateways * postsPerGateway
 		if processedCount != expectedCount {